@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+)
+
+var errNotAdmin = errors.New("admin access required")
+
+// Authenticates the request and checks the user is an admin of the request's tenant, responding with 401/403
+// itself when not. The default tenant's admins run the whole server and pass for every tenant. Returns false if the
+// handler should stop.
+func (cfg *apiConfig) requireAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	return cfg.requireAdminOf(ctx, w, r, tenantFromContext(r.Context()))
+}
+
+// requireAdmin for endpoints that change state every tenant shares: settings, maintenance, rate limits, the IP deny
+// list, banned words, debugging and the search cluster. Only the default tenant's admins get through.
+func (cfg *apiConfig) requireGlobalAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	return cfg.requireAdminOf(ctx, w, r, defaultTenantID)
+}
+
+func (cfg *apiConfig) requireAdminOf(ctx context.Context, w http.ResponseWriter, r *http.Request, tenantID uuid.UUID) (uuid.UUID, bool) {
+	p, err := cfg.requestPrincipal(r)
+	if err != nil {
+		log.Println("Unauthenticated admin request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return uuid.UUID{}, false
+	}
+
+	userID := p.userID
+
+	admin, err := cfg.databaseQueries.GetUserIsAdmin(ctx, userID)
+	if err != nil {
+		log.Printf("GetUserIsAdmin failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return uuid.UUID{}, false
+	}
+
+	if !admin.IsAdmin || (admin.TenantID != tenantID && admin.TenantID != defaultTenantID) {
+		log.Printf("User %s is not an admin of tenant %s", userID, tenantID)
+		respondWithError(w, http.StatusForbidden, errNotAdmin.Error())
+		return uuid.UUID{}, false
+	}
+
+	// The user being an admin isn't enough when they've handed a script or an app a token without the admin scope
+	if !p.hasScope(auth.ScopeAdmin) {
+		respondWithError(w, http.StatusForbidden, errInsufficientScope.Error())
+		return uuid.UUID{}, false
+	}
+
+	return userID, true
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var otherTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+
+// A request for tenant carrying a token for testUserID
+func adminRequest(t *testing.T, cfg *apiConfig, tenant uuid.UUID, method, path, body string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+	return req.WithContext(context.WithValue(req.Context(), tenantKey, tenant))
+}
+
+// testUserID is an admin of testUserID's tenant, which isn't the default one
+func TestTenantAdminScope(t *testing.T) {
+	cfg := newFakeConfig(t, newFakeDB(map[string]driver.Value{"is_admin": true, "tenant_id": testUserID.String()}))
+
+	tests := []struct {
+		name   string
+		tenant uuid.UUID
+		global bool
+		ok     bool
+	}{
+		{"own tenant", testUserID, false, true},
+		{"other tenant", otherTenantID, false, false},
+		{"default tenant", defaultTenantID, false, false},
+		{"global from own tenant", testUserID, true, false},
+		{"global from default tenant", defaultTenantID, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := adminRequest(t, cfg, tt.tenant, http.MethodGet, "/admin", "")
+			rec := httptest.NewRecorder()
+
+			var ok bool
+			if tt.global {
+				_, ok = cfg.requireGlobalAdmin(req.Context(), rec, req)
+			} else {
+				_, ok = cfg.requireAdmin(req.Context(), rec, req)
+			}
+
+			if ok != tt.ok {
+				t.Fatalf("expected ok = %v, got %v: %d %s", tt.ok, ok, rec.Code, rec.Body)
+			}
+			if !ok && rec.Code != http.StatusForbidden {
+				t.Errorf("expected 403, got %d: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+// The default tenant's admins run the server, so they pass everywhere
+func TestDefaultTenantAdminScope(t *testing.T) {
+	cfg := newFakeConfig(t, newFakeDB(map[string]driver.Value{"is_admin": true, "tenant_id": defaultTenantID.String()}))
+
+	for _, tenant := range []uuid.UUID{defaultTenantID, otherTenantID} {
+		req := adminRequest(t, cfg, tenant, http.MethodGet, "/admin", "")
+		rec := httptest.NewRecorder()
+		if _, ok := cfg.requireAdmin(req.Context(), rec, req); !ok {
+			t.Errorf("tenant %s: expected requireAdmin to pass, got %d: %s", tenant, rec.Code, rec.Body)
+		}
+
+		rec = httptest.NewRecorder()
+		if _, ok := cfg.requireGlobalAdmin(req.Context(), rec, req); !ok {
+			t.Errorf("tenant %s: expected requireGlobalAdmin to pass, got %d: %s", tenant, rec.Code, rec.Body)
+		}
+	}
+}
+
+// Settings are shared by every tenant, another tenant's admin can't change them
+func TestPutSettingsOtherTenantAdmin(t *testing.T) {
+	fake := newFakeDB(map[string]driver.Value{"is_admin": true, "tenant_id": testUserID.String()})
+	cfg := newFakeConfig(t, fake)
+
+	req := adminRequest(t, cfg, testUserID, http.MethodPut, "/admin/settings", `{"signups_open":false}`)
+	rec := httptest.NewRecorder()
+	cfg.putSettingsHandler(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", rec.Code, rec.Body)
+	}
+	if fake.wasSaved("UpsertSetting") {
+		t.Error("expected the settings to be left alone")
+	}
+}
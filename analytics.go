@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// Users seen since the last flush. Recording is just a set insert so it can sit on every authenticated request,
+// the metrics flusher turns the set into user_activity rows.
+type activityTracker struct {
+	mu      sync.Mutex
+	pending map[uuid.UUID]struct{}
+}
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{pending: map[uuid.UUID]struct{}{}}
+}
+
+func (t *activityTracker) record(userID uuid.UUID) {
+	t.mu.Lock()
+	t.pending[userID] = struct{}{}
+	t.mu.Unlock()
+}
+
+// Marks everyone seen since the last flush as active today. If the write fails they're kept for the next one.
+func (t *activityTracker) flush(ctx context.Context, q *database.Queries) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = map[uuid.UUID]struct{}{}
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(pending))
+	for id := range pending {
+		ids = append(ids, id)
+	}
+
+	if err := q.RecordUserActivity(ctx, ids); err != nil {
+		t.mu.Lock()
+		for _, id := range ids {
+			t.pending[id] = struct{}{}
+		}
+		t.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+type analyticsDayResponse struct {
+	Day         string `json:"day"`
+	ActiveUsers int64  `json:"active_users"`
+	Signups     int64  `json:"signups"`
+	Chirps      int64  `json:"chirps"`
+}
+
+type analyticsResponse struct {
+	DAU    int64                  `json:"dau"`
+	WAU    int64                  `json:"wau"`
+	MAU    int64                  `json:"mau"`
+	Series []analyticsDayResponse `json:"series"`
+}
+
+// GET /admin/analytics, active user counts for the caller's tenant plus one entry per day for the last ?days=
+// (default 30, at most 365), oldest first and with days that had nothing in them filled in as zeros. A user is
+// active on a day they made any authenticated request, counts can lag by up to METRICS_FLUSH_INTERVAL.
+func (cfg *apiConfig) analyticsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	tenantID := tenantFromContext(r.Context())
+	days := queryLimit(r, "days", 30, 365)
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+
+	counts, err := cfg.databaseQueries.GetActiveUserCounts(ctx, tenantID)
+	if err != nil {
+		log.Printf("GetActiveUserCounts failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	active, err := cfg.databaseQueries.GetDailyActiveUsers(ctx, database.GetDailyActiveUsersParams{TenantID: tenantID, Since: since})
+	if err != nil {
+		log.Printf("GetDailyActiveUsers failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	signups, err := cfg.databaseQueries.GetSignupsPerDay(ctx, database.GetSignupsPerDayParams{TenantID: tenantID, Since: since})
+	if err != nil {
+		log.Printf("GetSignupsPerDay failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	chirps, err := cfg.chirpCountSeries(r, statsGranularities["day"], since)
+	if err != nil {
+		log.Printf("GetChirpCounts failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := analyticsResponse{
+		DAU:    counts.Dau,
+		WAU:    counts.Wau,
+		MAU:    counts.Mau,
+		Series: make([]analyticsDayResponse, days),
+	}
+
+	index := make(map[string]int, days)
+	for i := range resp.Series {
+		day := since.AddDate(0, 0, i).Format(time.DateOnly)
+		resp.Series[i].Day = day
+		index[day] = i
+	}
+
+	for _, row := range active {
+		if i, ok := index[row.Day.Format(time.DateOnly)]; ok {
+			resp.Series[i].ActiveUsers = row.Count
+		}
+	}
+
+	for _, row := range signups {
+		if i, ok := index[row.Day.Format(time.DateOnly)]; ok {
+			resp.Series[i].Signups = row.Count
+		}
+	}
+
+	for _, bucket := range chirps {
+		if i, ok := index[bucket.Start.Format(time.DateOnly)]; ok {
+			resp.Series[i].Chirps = bucket.Count
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, http.StatusOK, resp)
+}
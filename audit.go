@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// Actions recorded in the audit log
+const (
+	auditIPAllowed          = "ip.allowed"
+	auditIPDenied           = "ip.denied"
+	auditSettingChanged     = "setting.changed"
+	auditUserBanned         = "user.banned"
+	auditUserUnbanned       = "user.unbanned"
+	auditUserShadowBanned   = "user.shadow_banned"
+	auditUserShadowUnbanned = "user.shadow_unbanned"
+)
+
+type auditLogEntryResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	ActorID   *uuid.UUID      `json:"actor_id"`
+	Action    string          `json:"action"`
+	TargetID  *uuid.UUID      `json:"target_id,omitempty"`
+	Details   json.RawMessage `json:"details"`
+}
+
+type auditLogResponse struct {
+	Entries    []auditLogEntryResponse `json:"entries"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+func newAuditLogEntryResponse(e database.AuditLog) auditLogEntryResponse {
+	resp := auditLogEntryResponse{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		Action:    e.Action,
+		Details:   e.Details,
+	}
+
+	if e.ActorID.Valid {
+		resp.ActorID = &e.ActorID.UUID
+	}
+
+	if e.TargetID.Valid {
+		resp.TargetID = &e.TargetID.UUID
+	}
+
+	return resp
+}
+
+// Records that actorID did action to targetID, uuid.Nil when the action isn't about a user. q is passed in so
+// the entry can be part of the action's transaction.
+func recordAudit(ctx context.Context, q *database.Queries, actorID uuid.UUID, action string, targetID uuid.UUID, details map[string]any) error {
+	if details == nil {
+		details = map[string]any{}
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return q.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		TenantID: tenantFromContext(ctx),
+		ActorID:  uuid.NullUUID{UUID: actorID, Valid: true},
+		Action:   action,
+		TargetID: uuid.NullUUID{UUID: targetID, Valid: targetID != uuid.Nil},
+		Details:  data,
+	})
+}
+
+// GET /admin/audit_log, newest first, paged with ?limit= and ?cursor= like the other lists
+func (cfg *apiConfig) getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	cursor, err := queryCursor(r, newestFirst)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := queryLimit(r, "limit", 50, 200)
+
+	entries, err := cfg.databaseQueries.GetAuditLog(ctx, database.GetAuditLogParams{
+		TenantID:   tenantFromContext(r.Context()),
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageLimit:  int32(limit + 1),
+	})
+
+	if err != nil {
+		log.Printf("GetAuditLog failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := auditLogResponse{Entries: make([]auditLogEntryResponse, 0, len(entries))}
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		last := entries[len(entries)-1]
+		resp.NextCursor = pageCursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, newAuditLogEntryResponse(e))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
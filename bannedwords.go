@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/moderation"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// What happens to a chirp with a banned word or pattern in it
+const (
+	// The match is replaced with **** and the chirp flagged
+	severityCensor = "censor"
+	// The chirp is refused
+	severityReject = "reject"
+)
+
+var bannedWordSeverities = map[string]moderation.Verdict{severityCensor: moderation.Flag, severityReject: moderation.Reject}
+
+// In-memory copy of the banned_words table, compiled into a filter, so validating a chirp never hits the database
+type bannedWordCache struct {
+	mu     sync.RWMutex
+	filter *moderation.Filter
+}
+
+func newBannedWordCache() *bannedWordCache {
+	filter, _ := moderation.NewFilter(nil)
+	return &bannedWordCache{filter: filter}
+}
+
+// Current filter
+func (c *bannedWordCache) snapshot() *moderation.Filter {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.filter
+}
+
+// Replaces the cache with whatever is in the database right now. A stored pattern that no longer compiles is
+// logged and left out rather than failing the whole reload.
+func (c *bannedWordCache) reload(ctx context.Context, q *database.Queries) error {
+	rows, err := q.GetBannedWords(ctx)
+	if err != nil {
+		return err
+	}
+
+	terms := make([]moderation.Term, 0, len(rows))
+	for _, row := range rows {
+		terms = append(terms, moderation.Term{
+			Text:    row.Word,
+			Pattern: row.IsPattern,
+			Verdict: bannedWordSeverities[row.Severity],
+		})
+	}
+
+	filter, err := moderation.NewFilter(terms)
+	if err != nil {
+		log.Printf("Skipping banned patterns: %v", err)
+	}
+
+	// Swap the whole filter so readers holding the old snapshot aren't affected
+	c.mu.Lock()
+	c.filter = filter
+	c.mu.Unlock()
+	return nil
+}
+
+// Periodically reloads so edits made through another instance show up here too
+func (cfg *apiConfig) runBannedWordReloader(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloadCtx, cancel := cfg.dbContext(ctx)
+			if err := cfg.bannedWords.reload(reloadCtx, cfg.databaseQueries); err != nil {
+				log.Printf("Reloading banned words failed: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (cfg *apiConfig) getBannedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	words, err := cfg.databaseQueries.GetBannedWords(ctx)
+	if err != nil {
+		log.Printf("GetBannedWords failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if words == nil {
+		words = []database.BannedWord{}
+	}
+
+	respondWithJson(w, http.StatusOK, words)
+}
+
+func (cfg *apiConfig) createBannedWordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Word string `json:"word" validate:"required"`
+		// Word is a regular expression, matched anywhere in the chirp ignoring case
+		Pattern bool `json:"pattern"`
+		// censor (the default) or reject
+		Severity string `json:"severity"`
+	}
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	if params.Severity == "" {
+		params.Severity = severityCensor
+	}
+
+	if _, ok := bannedWordSeverities[params.Severity]; !ok {
+		respondWithFieldErrors(w, validate.Errors{"severity": "must be censor or reject"})
+		return
+	}
+
+	word := strings.TrimSpace(params.Word)
+	if params.Pattern {
+		if _, err := moderation.CompilePattern(word); err != nil {
+			respondWithFieldErrors(w, validate.Errors{"word": "must be a regular expression that doesn't match empty text"})
+			return
+		}
+	} else {
+		// Matching is case-insensitive, so store the lowercase form
+		word = strings.ToLower(word)
+		if word == "" || strings.ContainsAny(word, " \t\n") {
+			respondWithFieldErrors(w, validate.Errors{"word": "must be a single word"})
+			return
+		}
+	}
+
+	created, err := cfg.databaseQueries.CreateBannedWord(ctx, database.CreateBannedWordParams{
+		Word:      word,
+		IsPattern: params.Pattern,
+		Severity:  params.Severity,
+	})
+	if err != nil {
+		log.Printf("CreateBannedWord failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := cfg.bannedWords.reload(ctx, cfg.databaseQueries); err != nil {
+		log.Printf("Reloading banned words failed: %v", err)
+	}
+
+	respondWithJson(w, http.StatusCreated, created)
+}
+
+func (cfg *apiConfig) deleteBannedWordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	// Words are matched lowercase, patterns as written
+	deleted, err := cfg.databaseQueries.DeleteBannedWord(ctx, r.PathValue("word"))
+	if err != nil {
+		log.Printf("DeleteBannedWord failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Banned word not found")
+		return
+	}
+
+	if err := cfg.bannedWords.reload(ctx, cfg.databaseQueries); err != nil {
+		log.Printf("Reloading banned words failed: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+var (
+	errAccountBanned = errors.New("This account is banned")
+	errBanSelf       = errors.New("You can't ban yourself")
+)
+
+type banResponse struct {
+	UserID uuid.UUID  `json:"user_id"`
+	Banned bool       `json:"banned"`
+	Reason string     `json:"reason,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+type shadowBanResponse struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	ShadowBanned bool       `json:"shadow_banned"`
+	Since        *time.Time `json:"since,omitempty"`
+}
+
+// PUT /admin/users/{userID}/shadow_ban. The user can keep posting and sees their chirps as usual, but they're
+// left out of everyone else's listings. Nothing tells them.
+func (cfg *apiConfig) shadowBanUserHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setShadowBan(w, r, true)
+}
+
+// DELETE /admin/users/{userID}/shadow_ban, the user's chirps show up for everyone again
+func (cfg *apiConfig) liftShadowBanHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setShadowBan(w, r, false)
+}
+
+func (cfg *apiConfig) setShadowBan(w http.ResponseWriter, r *http.Request, banned bool) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	since := sql.NullTime{Time: time.Now(), Valid: banned}
+
+	action := auditUserShadowUnbanned
+	if banned {
+		action = auditUserShadowBanned
+	}
+
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		updated, err := qtx.SetUserShadowBan(ctx, database.SetUserShadowBanParams{
+			ID:             userID,
+			ShadowBannedAt: since,
+			TenantID:       tenantFromContext(r.Context()),
+		})
+		if err != nil {
+			return err
+		}
+
+		if updated == 0 {
+			return database.ErrNotFound
+		}
+
+		return recordAudit(ctx, qtx, adminID, action, userID, nil)
+	})
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("Setting shadow ban failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("Shadow ban on user %s set to %v by %s", userID, banned, adminID)
+
+	resp := shadowBanResponse{UserID: userID, ShadowBanned: banned}
+	if banned {
+		resp.Since = &since.Time
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// Whether a ban on user is in force right now
+func userBanned(user database.User) bool {
+	return user.BannedAt.Valid && (!user.BannedUntil.Valid || user.BannedUntil.Time.After(time.Now()))
+}
+
+// POST /admin/users/{userID}/ban with {"reason": ..., "expires_at": ...}, no expires_at bans for good. The user is
+// signed out everywhere, can't log in and their chirps are hidden until it runs out or is lifted.
+func (cfg *apiConfig) banUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	var params struct {
+		Reason    string     `json:"reason" validate:"required,max=500"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	adminID, ok := cfg.requireAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if userID == adminID {
+		respondWithError(w, http.StatusBadRequest, errBanSelf.Error())
+		return
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	var until sql.NullTime
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			respondWithFieldErrors(w, validate.Errors{"expires_at": "must be in the future"})
+			return
+		}
+
+		until = sql.NullTime{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	details := map[string]any{"reason": params.Reason}
+	if until.Valid {
+		details["expires_at"] = until.Time
+	}
+
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		updated, err := qtx.BanUser(ctx, database.BanUserParams{
+			ID:          userID,
+			BannedUntil: until,
+			BanReason:   sql.NullString{String: params.Reason, Valid: true},
+			TenantID:    tenantFromContext(r.Context()),
+		})
+		if err != nil {
+			return err
+		}
+
+		if updated == 0 {
+			return database.ErrNotFound
+		}
+
+		if err := qtx.RevokeRefreshTokensForUser(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := qtx.RevokePersonalAccessTokensForUser(ctx, userID); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, qtx, adminID, auditUserBanned, userID, details)
+	})
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("Banning user failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("User %s banned by %s", userID, adminID)
+
+	resp := banResponse{UserID: userID, Banned: true, Reason: params.Reason}
+	if until.Valid {
+		resp.Until = &until.Time
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// DELETE /admin/users/{userID}/ban, lifts a ban early. The user has to log in again, their old tokens stay revoked.
+func (cfg *apiConfig) unbanUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		updated, err := qtx.UnbanUser(ctx, database.UnbanUserParams{
+			ID:       userID,
+			TenantID: tenantFromContext(r.Context()),
+		})
+		if err != nil {
+			return err
+		}
+
+		if updated == 0 {
+			return database.ErrNotFound
+		}
+
+		return recordAudit(ctx, qtx, adminID, auditUserUnbanned, userID, nil)
+	})
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("Unbanning user failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("User %s unbanned by %s", userID, adminID)
+	respondWithJson(w, http.StatusOK, banResponse{UserID: userID, Banned: false})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/itsmandrew/server-go/internal/captcha"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Checks the captcha_token from a signup or login body when CAPTCHA_PROVIDER is set. Responds itself and returns
+// false when the request should stop: a 400 on the field for a bad token, 503 when the provider can't be reached.
+func (cfg *apiConfig) checkCaptcha(ctx context.Context, w http.ResponseWriter, r *http.Request, token string) bool {
+	err := cfg.verifyCaptcha(ctx, r, token)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, captcha.ErrFailed) {
+		respondWithFieldErrors(w, validate.Errors{"captcha_token": "CAPTCHA check failed, try again"})
+		return false
+	}
+
+	log.Printf("Verifying captcha failed: %v", err)
+	respondWithError(w, http.StatusServiceUnavailable, "Couldn't verify the CAPTCHA, try again shortly")
+	return false
+}
+
+// checkCaptcha without the response: nil when token is good or no captcha is needed, captcha.ErrFailed when it's
+// wrong, anything else when the provider couldn't be asked
+func (cfg *apiConfig) verifyCaptcha(ctx context.Context, r *http.Request, token string) error {
+	if cfg.captcha == nil {
+		return nil
+	}
+
+	return cfg.captcha.Verify(ctx, token, remoteIP(r))
+}
+
+// The captcha response a form posted, as captcha_token or under the name the provider's widget gives it
+func captchaFormToken(form url.Values) string {
+	for _, name := range []string{"captcha_token", "h-captcha-response", "cf-turnstile-response"} {
+		if token := form.Get(name); token != "" {
+			return token
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+)
+
+// Who besides its author can see a chirp
+const (
+	visibilityPublic = "public"
+	// The author's followers
+	visibilityFollowers = "followers"
+	// Nobody else
+	visibilityPrivate = "private"
+)
+
+var chirpVisibilities = []string{visibilityPublic, visibilityFollowers, visibilityPrivate}
+
+// Whether someone logged out could see chirp, which also rules out a protected author's public chirps.
+// A failed lookup counts as no.
+func (cfg *apiConfig) publiclyVisible(ctx context.Context, chirp database.Chirp) bool {
+	ctx, cancel := cfg.dbContext(ctx)
+	defer cancel()
+
+	_, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirp.ID,
+		TenantID: chirp.TenantID,
+		ViewerID: uuid.Nil,
+	})
+	err = database.Wrap(err)
+
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		log.Printf("Checking chirp visibility failed: %v", err)
+	}
+
+	return err == nil
+}
+
+// Builds responses for a batch of chirps, one links, one engagement and one author query however many chirps there are.
+// viewerID is the zero UUID when logged out, so liked_by_me is just false.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, chirps []database.Chirp, viewerID uuid.UUID) ([]api.Chirp, error) {
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	links, err := cfg.linksForChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	engagement, err := cfg.databaseQueries.GetChirpEngagement(ctx, database.GetChirpEngagementParams{
+		ViewerID: viewerID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authors, err := cfg.databaseQueries.GetChirpAuthors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byAuthor := make(map[uuid.UUID]api.Author, len(authors))
+	for _, a := range authors {
+		byAuthor[a.ID] = api.NewAuthor(a)
+	}
+
+	byChirp := make(map[uuid.UUID]database.GetChirpEngagementRow, len(engagement))
+	for _, e := range engagement {
+		byChirp[e.ChirpID] = e
+	}
+
+	result := make([]api.Chirp, len(chirps))
+	for i, chirp := range chirps {
+		e := byChirp[chirp.ID]
+		result[i] = api.NewChirp(chirp)
+		if a, ok := byAuthor[chirp.UserID]; ok {
+			result[i].Author = a
+		}
+		if l, ok := links[chirp.ID]; ok {
+			result[i].Links = l
+		}
+		result[i].LikeCount = e.LikeCount
+		result[i].ReplyCount = e.ReplyCount
+		result[i].RechirpCount = e.RechirpCount
+		result[i].ViewCount = e.ViewCount + cfg.chirpViews.unflushed(chirp.ID)
+		result[i].LikedByMe = e.LikedByMe
+	}
+
+	return result, nil
+}
+
+// Shared front half of the like/rechirp handlers, which sit behind requireAuth: who's asking and which chirp they
+// mean. Writes the error response itself and returns ok=false when something's wrong.
+func (cfg *apiConfig) chirpActionTarget(ctx context.Context, w http.ResponseWriter, r *http.Request) (uuid.UUID, database.Chirp, bool) {
+	userID := userIDFromContext(r.Context())
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID")
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+
+	chirp, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		TenantID: tenantFromContext(r.Context()),
+		ViewerID: userID,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+
+	if err != nil {
+		log.Printf("GetVisibleChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return uuid.UUID{}, database.Chirp{}, false
+	}
+
+	return userID, chirp, true
+}
+
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, chirp, ok := cfg.chirpActionTarget(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	added, err := cfg.databaseQueries.LikeChirp(ctx, database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		log.Printf("LikeChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Liking twice is a no-op, only the first one notifies the author
+	if added > 0 {
+		cfg.events.Publish(r.Context(), events.ChirpLiked{ChirpID: chirp.ID, AuthorID: chirp.UserID, UserID: userID})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, chirp, ok := cfg.chirpActionTarget(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := cfg.databaseQueries.UnlikeChirp(ctx, database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		log.Printf("UnlikeChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp isn't liked")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) rechirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, chirp, ok := cfg.chirpActionTarget(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	_, err := cfg.databaseQueries.Rechirp(ctx, database.RechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		log.Printf("Rechirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) undoRechirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, chirp, ok := cfg.chirpActionTarget(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := cfg.databaseQueries.UndoRechirp(ctx, database.UndoRechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		log.Printf("UndoRechirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp isn't rechirped")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type chirpListResponse struct {
+	XMLName    xml.Name    `json:"-" xml:"chirps"`
+	Chirps     []api.Chirp `json:"chirps" xml:"chirp"`
+	NextCursor string      `json:"next_cursor,omitempty" xml:"next_cursor,attr,omitempty"`
+}
+
+// GET /api/users/{userID}/chirps, newest first unless ?sort=oldest. Unlike an empty listing this
+// 404s when the user doesn't exist, so clients can tell "no chirps yet" from a bad link.
+func (cfg *apiConfig) getUserChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var oldest bool
+	switch r.URL.Query().Get("sort") {
+	case "", "newest":
+	case "oldest":
+		oldest = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "sort must be newest or oldest")
+		return
+	}
+
+	start := newestFirst
+	if oldest {
+		start = oldestFirst
+	}
+
+	cursor, err := queryCursor(r, start)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lang, err := queryLanguage(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !cfg.requireUser(ctx, w, r, userID) {
+		return
+	}
+
+	limit := queryLimit(r, "limit", 50, 100)
+	viewerID, _ := cfg.optionalUser(r)
+
+	// One extra row tells us whether there's another page, same as the follow lists
+	var chirps []database.Chirp
+	if oldest {
+		chirps, err = cfg.databaseQueries.GetChirpsByUserAsc(ctx, database.GetChirpsByUserAscParams{
+			UserID:     userID,
+			TenantID:   tenantFromContext(r.Context()),
+			CursorTime: cursor.Time,
+			CursorID:   cursor.ID,
+			ViewerID:   viewerID,
+			Language:   lang,
+			PageLimit:  int32(limit + 1),
+		})
+	} else {
+		chirps, err = cfg.databaseQueries.GetChirpsByUser(ctx, database.GetChirpsByUserParams{
+			UserID:     userID,
+			TenantID:   tenantFromContext(r.Context()),
+			CursorTime: cursor.Time,
+			CursorID:   cursor.ID,
+			ViewerID:   viewerID,
+			Language:   lang,
+			PageLimit:  int32(limit + 1),
+		})
+	}
+
+	if err != nil {
+		log.Printf("Listing chirps by user failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var next string
+	if len(chirps) > limit {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		next = pageCursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	// Dropped after the cursor is worked out, so a page can come back short but paging never skips anything
+	hidden, err := cfg.hiddenChirps(ctx, viewerID)
+	if err != nil {
+		log.Printf("Loading hidden chirp filters failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	chirps = slices.DeleteFunc(chirps, hidden)
+
+	cfg.chirpViews.record(chirps, viewerID)
+	response, err := cfg.chirpResponses(ctx, chirps, viewerID)
+
+	if err != nil {
+		log.Printf("Loading chirp links/engagement failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, chirpListResponse{Chirps: response, NextCursor: next})
+}
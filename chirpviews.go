@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// Counts chirp impressions in memory so a GET doesn't cost a write, the metrics flusher writes them out in one batch
+type chirpViewCounter struct {
+	mu      sync.Mutex
+	pending map[uuid.UUID]int64
+}
+
+func newChirpViewCounter() *chirpViewCounter {
+	return &chirpViewCounter{pending: map[uuid.UUID]int64{}}
+}
+
+// Counts one view of each chirp, except ones viewerID wrote: authors checking their own chirps aren't an audience.
+// viewerID is the zero UUID when logged out.
+func (c *chirpViewCounter) record(chirps []database.Chirp, viewerID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, chirp := range chirps {
+		if chirp.UserID != viewerID {
+			c.pending[chirp.ID]++
+		}
+	}
+}
+
+// Views counted but not flushed yet, added to what's in the database so counts don't lag behind by a flush interval
+func (c *chirpViewCounter) unflushed(chirpID uuid.UUID) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pending[chirpID]
+}
+
+// Writes the pending counts out and starts counting afresh. If the write fails they're put back for the next flush.
+func (c *chirpViewCounter) flush(ctx context.Context, q *database.Queries) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = map[uuid.UUID]int64{}
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	params := database.AddChirpViewsParams{
+		ChirpIds: make([]uuid.UUID, 0, len(pending)),
+		Views:    make([]int64, 0, len(pending)),
+	}
+
+	for id, views := range pending {
+		params.ChirpIds = append(params.ChirpIds, id)
+		params.Views = append(params.Views, views)
+	}
+
+	if err := q.AddChirpViews(ctx, params); err != nil {
+		c.mu.Lock()
+		for id, views := range pending {
+			c.pending[id] += views
+		}
+		c.mu.Unlock()
+		return err
+	}
+
+	return nil
+}
+
+type chirpViewDayResponse struct {
+	Day   string `json:"day"`
+	Views int64  `json:"views"`
+}
+
+type chirpAnalyticsResponse struct {
+	ChirpID      uuid.UUID              `json:"chirp_id"`
+	ViewCount    int64                  `json:"view_count"`
+	LikeCount    int64                  `json:"like_count"`
+	ReplyCount   int64                  `json:"reply_count"`
+	RechirpCount int64                  `json:"rechirp_count"`
+	ViewsByDay   []chirpViewDayResponse `json:"views_by_day"`
+}
+
+// GET /api/chirps/{chirpID}/analytics, only for the chirp's author. ?days= sets how far back views_by_day goes
+// (default 30, at most 365), the totals are all-time. Today's views include ones not flushed yet.
+func (cfg *apiConfig) chirpAnalyticsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, chirp, ok := cfg.chirpActionTarget(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "User not the author of the chirp")
+		return
+	}
+
+	days := queryLimit(r, "days", 30, 365)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	engagement, err := cfg.databaseQueries.GetChirpEngagement(ctx, database.GetChirpEngagementParams{
+		ViewerID: userID,
+		ChirpIds: []uuid.UUID{chirp.ID},
+	})
+	if err != nil {
+		log.Printf("GetChirpEngagement failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	byDay, err := cfg.databaseQueries.GetChirpViewsByDay(ctx, database.GetChirpViewsByDayParams{
+		ChirpID: chirp.ID,
+		Since:   today.AddDate(0, 0, 1-days),
+	})
+	if err != nil {
+		log.Printf("GetChirpViewsByDay failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	unflushed := cfg.chirpViews.unflushed(chirp.ID)
+
+	resp := chirpAnalyticsResponse{
+		ChirpID:    chirp.ID,
+		ViewCount:  unflushed,
+		ViewsByDay: make([]chirpViewDayResponse, 0, len(byDay)+1),
+	}
+
+	if len(engagement) > 0 {
+		e := engagement[0]
+		resp.ViewCount += e.ViewCount
+		resp.LikeCount = e.LikeCount
+		resp.ReplyCount = e.ReplyCount
+		resp.RechirpCount = e.RechirpCount
+	}
+
+	for _, d := range byDay {
+		resp.ViewsByDay = append(resp.ViewsByDay, chirpViewDayResponse{Day: d.Day.Format(time.DateOnly), Views: d.Views})
+	}
+
+	if unflushed > 0 {
+		todayKey := today.Format(time.DateOnly)
+		if n := len(resp.ViewsByDay); n > 0 && resp.ViewsByDay[n-1].Day == todayKey {
+			resp.ViewsByDay[n-1].Views += unflushed
+		} else {
+			resp.ViewsByDay = append(resp.ViewsByDay, chirpViewDayResponse{Day: todayKey, Views: unflushed})
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, http.StatusOK, resp)
+}
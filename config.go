@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Reads a duration like "30s" or "2m" from the environment, falling back when it's unset or invalid
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %v", key, v, fallback)
+		return fallback
+	}
+
+	return parsed
+}
+
+// Reads an integer from the environment, falling back when it's unset or invalid
+func envInt(key string, fallback int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", key, v, fallback)
+		return fallback
+	}
+
+	return parsed
+}
+
+// Reads an octal file mode like 0660 from the environment, falling back when it's unset or invalid
+func envFileMode(key string, fallback fs.FileMode) fs.FileMode {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseUint(v, 8, 32)
+	if err != nil || parsed > 0o777 {
+		log.Printf("Invalid %s %q, using %o", key, v, fallback)
+		return fallback
+	}
+
+	return fs.FileMode(parsed)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// How a user wants sensitive chirps shown. Blurring and expanding are up to the client, the server only acts on
+// hide by leaving them out of listings.
+const (
+	sensitiveContentBlur   = "blur"
+	sensitiveContentExpand = "expand"
+	sensitiveContentHide   = "hide"
+)
+
+var sensitiveContentModes = []string{sensitiveContentBlur, sensitiveContentExpand, sensitiveContentHide}
+
+// Reports whether a chirp should be left out of viewerID's listings: it has one of their muted words, or it's
+// marked sensitive and they've chosen to hide those. Their own chirps are always shown.
+func (cfg *apiConfig) hiddenChirps(ctx context.Context, viewerID uuid.UUID) (func(database.Chirp) bool, error) {
+	muted, err := cfg.mutedChirps(ctx, viewerID)
+	if err != nil || viewerID == uuid.Nil {
+		return muted, err
+	}
+
+	profile, err := cfg.databaseQueries.GetUserProfile(ctx, database.GetUserProfileParams{
+		ID:       viewerID,
+		TenantID: tenantFromContext(ctx),
+	})
+	err = database.Wrap(err)
+
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		return nil, err
+	}
+
+	hideSensitive := profile.SensitiveContent == sensitiveContentHide
+
+	return func(chirp database.Chirp) bool {
+		if hideSensitive && chirp.Sensitive && chirp.UserID != viewerID {
+			return true
+		}
+		return muted(chirp)
+	}, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+const (
+	// How far up a reply chain the conversation view goes looking for its root
+	conversationMaxAncestors = 50
+	// Deepest ?depth= a conversation's replies can be nested to
+	conversationMaxDepth = 10
+	// Most replies one conversation page returns, however many direct replies and levels were asked for
+	conversationMaxReplies = 500
+)
+
+// A reply in a conversation, with the replies to it nested underneath. Depth is 1 for direct replies to the
+// conversation's chirp.
+type conversationReply struct {
+	Chirp   api.Chirp            `json:"chirp"`
+	Depth   int32                `json:"depth"`
+	Replies []*conversationReply `json:"replies"`
+}
+
+type conversationResponse struct {
+	// Root first, ending with the chirp's parent
+	Ancestors  []api.Chirp          `json:"ancestors"`
+	Chirp      api.Chirp            `json:"chirp"`
+	Replies    []*conversationReply `json:"replies"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// GET /api/chirps/{chirpID}/conversation, the chirp with what it replies to and the replies under it in one go.
+// Direct replies are oldest first, ?limit= of them per page (next_cursor fetches more), each with ?depth= levels
+// of replies nested under it. A reply whose count is higher than its nested replies has more to load from its own
+// conversation. Replies the viewer has muted or hidden are left out with everything under them.
+func (cfg *apiConfig) getConversationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID")
+		return
+	}
+
+	cursor, err := queryCursor(r, oldestFirst)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := queryLimit(r, "limit", 20, 100)
+	depth := queryLimit(r, "depth", 3, conversationMaxDepth)
+	tenantID := tenantFromContext(r.Context())
+	viewerID, _ := cfg.optionalUser(r)
+
+	chirp, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		TenantID: tenantID,
+		ViewerID: viewerID,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetVisibleChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	ancestors, err := cfg.databaseQueries.GetChirpAncestors(ctx, database.GetChirpAncestorsParams{
+		ChirpID:  chirpID,
+		MaxDepth: conversationMaxAncestors,
+		TenantID: tenantID,
+		ViewerID: viewerID,
+	})
+
+	if err != nil {
+		log.Printf("GetChirpAncestors failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// One extra direct reply tells us whether there's another page, same as the other lists
+	tree, err := cfg.databaseQueries.GetChirpReplyTree(ctx, database.GetChirpReplyTreeParams{
+		ViewerID:   viewerID,
+		ChirpID:    chirpID,
+		TenantID:   tenantID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageLimit:  int32(limit + 1),
+		MaxDepth:   int32(depth),
+		MaxRows:    conversationMaxReplies,
+	})
+
+	if err != nil {
+		log.Printf("GetChirpReplyTree failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hidden, err := cfg.hiddenChirps(ctx, viewerID)
+	if err != nil {
+		log.Printf("Loading hidden chirp filters failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// The tree comes shallowest first, so a reply's parent has always been seen before it. Anything whose
+	// parent was dropped goes too.
+	var replies []database.GetChirpReplyTreeRow
+	var last pageCursor
+	kept := map[uuid.UUID]bool{chirpID: true}
+	direct := 0
+
+	for _, row := range tree {
+		if row.Depth == 1 {
+			if direct++; direct > limit {
+				continue
+			}
+			last = pageCursor{Time: row.Chirp.CreatedAt, ID: row.Chirp.ID}
+		}
+
+		if !kept[row.Chirp.ReplyToID.UUID] || hidden(row.Chirp) {
+			continue
+		}
+
+		kept[row.Chirp.ID] = true
+		replies = append(replies, row)
+	}
+
+	var next string
+	if direct > limit {
+		next = last.String()
+	}
+
+	chirps := make([]database.Chirp, 0, len(ancestors)+1+len(replies))
+	for _, row := range ancestors {
+		chirps = append(chirps, row.Chirp)
+	}
+	chirps = append(chirps, chirp)
+	for _, row := range replies {
+		chirps = append(chirps, row.Chirp)
+	}
+
+	cfg.chirpViews.record(chirps, viewerID)
+	response, err := cfg.chirpResponses(ctx, chirps, viewerID)
+
+	if err != nil {
+		log.Printf("Loading chirp links/engagement failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := conversationResponse{
+		Ancestors:  response[:len(ancestors)],
+		Chirp:      response[len(ancestors)],
+		Replies:    []*conversationReply{},
+		NextCursor: next,
+	}
+
+	nodes := make(map[uuid.UUID]*conversationReply, len(replies))
+	for i, row := range replies {
+		node := &conversationReply{
+			Chirp:   response[len(ancestors)+1+i],
+			Depth:   row.Depth,
+			Replies: []*conversationReply{},
+		}
+		nodes[row.Chirp.ID] = node
+
+		if row.Depth == 1 {
+			resp.Replies = append(resp.Replies, node)
+		} else {
+			parent := nodes[row.Chirp.ReplyToID.UUID]
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/itsmandrew/server-go/internal/auth"
+)
+
+// Double-submit CSRF protection for session cookies: GET /api/csrf hands out a token and sets it as a cookie too,
+// and state-changing requests authenticated by cookie have to echo it in the X-CSRF-Token header. Another site
+// can make the browser send the cookie but can't read it or the token to put in the header.
+const (
+	csrfCookie = "chirpy_csrf"
+	csrfHeader = "X-CSRF-Token"
+)
+
+var errCSRFToken = errors.New("CSRF token missing or invalid")
+
+// Tokens are auth.MakeRefreshToken's 32 random bytes in hex
+const csrfTokenLen = 64
+
+// Whether token looks like one csrfTokenHandler made, anything else in the cookie wasn't set by us
+func wellFormedCSRFToken(token string) bool {
+	if len(token) != csrfTokenLen {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// GET, HEAD and OPTIONS don't change anything, so they don't need a token
+func csrfSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || !wellFormedCSRFToken(cookie.Value) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.Header.Get(csrfHeader))) == 1
+}
+
+// GET /api/csrf, the token to send in X-CSRF-Token. An existing one is handed back so tabs don't invalidate each
+// other's, anything else in the cookie is replaced. Other sites can't read it: browsers ignore the wildcard
+// Access-Control-Allow-Origin on requests that carry cookies, and without cookies they only get a fresh token that
+// matches nobody's.
+func (cfg *apiConfig) csrfTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token := ""
+	if cookie, err := r.Cookie(csrfCookie); err == nil {
+		token = cookie.Value
+	}
+
+	if !wellFormedCSRFToken(token) {
+		var err error
+		if token, err = auth.MakeRefreshToken(); err != nil {
+			log.Printf("Making CSRF token failed: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sessionRefreshMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.platform != "dev",
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, http.StatusOK, map[string]string{"csrf_token": token})
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSRFTokenHandlerReplacesBadCookie(t *testing.T) {
+	cfg := &apiConfig{}
+	good := strings.Repeat("ab", csrfTokenLen/2)
+
+	for _, cookie := range []string{"", `x","admin":true,"y":"`, "short", strings.Repeat("zz", csrfTokenLen/2), good} {
+		req := httptest.NewRequest(http.MethodGet, "/api/csrf", nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: csrfCookie, Value: cookie})
+		}
+		rec := httptest.NewRecorder()
+		cfg.csrfTokenHandler(rec, req)
+
+		var body map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("cookie %q: response isn't JSON: %v: %s", cookie, err, rec.Body)
+		}
+		if len(body) != 1 || !wellFormedCSRFToken(body["csrf_token"]) {
+			t.Errorf("cookie %q: expected a well-formed token alone, got %v", cookie, body)
+		}
+
+		if kept := body["csrf_token"] == cookie; kept != (cookie == good) {
+			t.Errorf("cookie %q: kept = %v", cookie, kept)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/events"
+)
+
+// What changed since the previous event on a /admin/metrics/stream connection, plus the running hit total
+// the dashboard shows in its heading
+type dashboardDelta struct {
+	Hits         int64   `json:"hits"`
+	TotalHits    int64   `json:"total_hits"`
+	NewChirps    int64   `json:"new_chirps"`
+	Requests     uint64  `json:"requests"`
+	Errors       uint64  `json:"errors"`
+	ErrorsPerMin float64 `json:"errors_per_min"`
+}
+
+// Counts chirps posted since startup, the stream reports the difference between ticks
+func (cfg *apiConfig) subscribeDashboard(bus events.Bus) {
+	bus.Subscribe(events.TypeChirpCreated, func(ctx context.Context, event events.Event) {
+		cfg.chirpsCreated.Add(1)
+	})
+}
+
+// Totals the dashboard stream diffs between ticks
+type dashboardTotals struct {
+	at       time.Time
+	hits     int64
+	chirps   int64
+	requests uint64
+	errors   uint64
+}
+
+func (cfg *apiConfig) dashboardTotals() dashboardTotals {
+	t := dashboardTotals{
+		at:     time.Now(),
+		hits:   cfg.fileserverHits.Load(),
+		chirps: cfg.chirpsCreated.Load(),
+	}
+
+	for _, s := range cfg.requestMetrics.Snapshot() {
+		t.requests += s.Count
+		t.errors += s.Errors
+	}
+
+	return t
+}
+
+// GET /admin/metrics/stream, server-sent "metrics" events every METRICS_STREAM_INTERVAL with the deltas since
+// the last one. The first event goes out straight away with the totals so far.
+func (cfg *apiConfig) metricsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// WRITE_TIMEOUT is meant for ordinary responses, this one stays open until the dashboard is closed
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Clearing write deadline for metrics stream failed: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stops nginx holding events back in its buffer
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(cfg.metricsStreamInterval)
+	defer ticker.Stop()
+
+	prev := dashboardTotals{at: cfg.startedAt}
+	for {
+		cur := cfg.dashboardTotals()
+
+		delta := dashboardDelta{
+			Hits:      cur.hits - prev.hits,
+			TotalHits: cur.hits,
+			NewChirps: cur.chirps - prev.chirps,
+			Requests:  cur.requests - prev.requests,
+			Errors:    cur.errors - prev.errors,
+		}
+
+		if elapsed := cur.at.Sub(prev.at); elapsed > 0 {
+			delta.ErrorsPerMin = float64(delta.Errors) / elapsed.Minutes()
+		}
+
+		data, err := json.Marshal(delta)
+		if err != nil {
+			log.Printf("Encoding metrics event failed: %v", err)
+			return
+		}
+
+		if _, err := fmt.Fprintf(w, "event: metrics\ndata: %s\n\n", data); err != nil {
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		prev = cur
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/breaker"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/lib/pq"
+)
+
+// Derives the context used for database calls, capped at cfg.dbTimeout so a stalled Postgres can't hang the request
+func (cfg *apiConfig) dbContext(parent context.Context) (context.Context, context.CancelFunc) {
+	if cfg.dbTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, cfg.dbTimeout)
+}
+
+// How long clients are told to wait before retrying after a transient database failure
+const dbRetryAfter = 5 * time.Second
+
+// Delays between startup pings, doubling from the first up to the second
+const (
+	dbConnectBackoff    = 250 * time.Millisecond
+	dbConnectMaxBackoff = 5 * time.Second
+)
+
+// Pings db until it answers or timeout has passed, backing off between attempts. sql.Open never connects by itself,
+// so without this we'd start up fine and then fail every request. Errors that won't go away on their own (a wrong
+// password, a missing database) give up straight away.
+func waitForDB(db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	backoff := dbConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if !isTransientDBError(err) || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("Database not reachable yet (attempt %d), retrying in %s: %v", attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, dbConnectMaxBackoff)
+	}
+}
+
+// Responds to a failed database call. Transient failures (timeouts, Postgres unreachable or shedding load) become
+// a 503 with Retry-After, since retrying will likely work, and a lookup that found nothing becomes a 404. Anything
+// else gets code. The raw error means nothing to clients and can give away how we store things, so it's never sent.
+func respondWithDBError(w http.ResponseWriter, code int, err error) {
+	switch {
+	case isTransientDBError(err):
+		log.Printf("Transient database failure: %v", err)
+		w.Header().Set("Retry-After", strconv.Itoa(int(dbRetryAfter.Seconds())))
+		respondWithError(w, http.StatusServiceUnavailable, "Database temporarily unavailable, try again shortly")
+	case errors.Is(database.Wrap(err), database.ErrNotFound):
+		respondWithError(w, http.StatusNotFound, "Not found")
+	default:
+		respondWithError(w, code, "Something went wrong, try again shortly")
+	}
+}
+
+// True when err is the kind of database failure that goes away on its own: our deadline passing, the connection
+// being refused or dropped, Postgres refusing work while it starts up, shuts down or runs out of connections, or
+// a circuit breaker failing the call because of one of those
+func isTransientDBError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, breaker.ErrOpen) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code.Class() == "08": // connection_exception
+			return true
+		case pqErr.Code == "53300", pqErr.Code == "57P01", pqErr.Code == "57P03": // too_many_connections, admin_shutdown, cannot_connect_now
+			return true
+		}
+	}
+
+	return false
+}
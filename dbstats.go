@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+type dbPoolResponse struct {
+	MaxOpen        int   `json:"max_open"`
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`
+	WaitMs         int64 `json:"wait_ms"`
+	MaxIdleClosed  int64 `json:"max_idle_closed"`
+	LifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+type dbTableResponse struct {
+	Name string `json:"name"`
+	// Postgres's estimate, as of its last ANALYZE/autovacuum
+	Rows  int64 `json:"rows"`
+	Bytes int64 `json:"bytes"`
+}
+
+type dbQueryResponse struct {
+	Name   string  `json:"name"`
+	Calls  uint64  `json:"calls"`
+	Slow   uint64  `json:"slow"`
+	Errors uint64  `json:"errors"`
+	AvgMs  float64 `json:"avg_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+type dbBreakerResponse struct {
+	Group    string `json:"group"`
+	State    string `json:"state"`
+	Failures int    `json:"failures"`
+	Trips    uint64 `json:"trips"`
+	Rejected uint64 `json:"rejected"`
+}
+
+type dbStatsResponse struct {
+	Pool            dbPoolResponse    `json:"pool"`
+	Tables          []dbTableResponse `json:"tables"`
+	SlowThresholdMs int64             `json:"slow_threshold_ms"`
+	Queries         []dbQueryResponse `json:"queries"`
+	// Circuit breakers that have seen a call, open ones first. Empty when they're turned off.
+	Breakers []dbBreakerResponse `json:"breakers"`
+}
+
+// GET /admin/db, connection pool usage, table sizes, per-query timings since startup and circuit breaker states,
+// for capacity planning. Covers the whole database, not just the caller's tenant.
+func (cfg *apiConfig) dbStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	tables, err := cfg.databaseQueries.GetTableStats(ctx)
+	if err != nil {
+		log.Printf("GetTableStats failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	pool := cfg.db.Stats()
+	resp := dbStatsResponse{
+		Pool: dbPoolResponse{
+			MaxOpen:        pool.MaxOpenConnections,
+			Open:           pool.OpenConnections,
+			InUse:          pool.InUse,
+			Idle:           pool.Idle,
+			WaitCount:      pool.WaitCount,
+			WaitMs:         pool.WaitDuration.Milliseconds(),
+			MaxIdleClosed:  pool.MaxIdleClosed,
+			LifetimeClosed: pool.MaxLifetimeClosed,
+		},
+		Tables:          make([]dbTableResponse, 0, len(tables)),
+		SlowThresholdMs: cfg.queryStats.Threshold().Milliseconds(),
+	}
+
+	for _, t := range tables {
+		resp.Tables = append(resp.Tables, dbTableResponse{Name: t.TableName, Rows: t.RowEstimate, Bytes: t.TotalBytes})
+	}
+
+	snapshot := cfg.queryStats.Snapshot()
+	resp.Queries = make([]dbQueryResponse, 0, len(snapshot))
+	for _, q := range snapshot {
+		resp.Queries = append(resp.Queries, dbQueryResponse{
+			Name:   q.Name,
+			Calls:  q.Calls,
+			Slow:   q.Slow,
+			Errors: q.Errors,
+			AvgMs:  float64(q.Total.Microseconds()) / float64(q.Calls) / 1000,
+			MaxMs:  float64(q.Max.Microseconds()) / 1000,
+		})
+	}
+
+	resp.Breakers = []dbBreakerResponse{}
+	if cfg.dbBreakers != nil {
+		for _, b := range cfg.dbBreakers.Snapshot() {
+			resp.Breakers = append(resp.Breakers, dbBreakerResponse{
+				Group:    b.Group,
+				State:    b.State.String(),
+				Failures: b.Failures,
+				Trips:    b.Trips,
+				Rejected: b.Rejected,
+			})
+		}
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
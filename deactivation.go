@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+var errAccountDeactivated = errors.New("Account is deactivated, reactivate it to log in")
+
+// POST /api/users/deactivate, hides the caller's profile and chirps and signs them out everywhere without deleting
+// anything. Needs the password again so a stolen token can't do it. If the account isn't reactivated within
+// DEACTIVATED_USER_RETENTION it's deleted for good.
+func (cfg *apiConfig) deactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Password string `json:"password" validate:"required"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated deactivation request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByIDNoPassword(ctx, userID)
+	if err != nil {
+		log.Printf("GetUserByIDNoPassword failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !cfg.checkPassword(ctx, w, r, user.Email, params.Password) {
+		return
+	}
+
+	// Refresh tokens and personal access tokens go too, access tokens already handed out run out within the hour
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		if _, err := qtx.DeactivateUser(ctx, userID); err != nil {
+			return err
+		}
+
+		if err := qtx.RevokeRefreshTokensForUser(ctx, userID); err != nil {
+			return err
+		}
+
+		return qtx.RevokePersonalAccessTokensForUser(ctx, userID)
+	})
+
+	if err != nil {
+		log.Printf("Deactivating user failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("User %s deactivated", userID)
+	cfg.clearSessionCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/users/reactivate, brings a deactivated account back given its email and password. Logging in works
+// again afterwards, this doesn't log in itself.
+func (cfg *apiConfig) reactivateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByEmail(ctx, database.GetUserByEmailParams{
+		Email:    params.Email,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		log.Printf("GetUserByEmail failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err != nil || auth.CheckPasswordHash(user.HashedPassword, params.Password) != nil {
+		respondWithError(w, http.StatusUnauthorized, "Email or password is incorrect")
+		return
+	}
+
+	if user.DeactivatedAt.Valid {
+		if err := cfg.databaseQueries.ReactivateUser(ctx, user.ID); err != nil {
+			log.Printf("ReactivateUser failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		log.Printf("User %s reactivated", user.ID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Checks password is email's, responding with a 401 (or 503) itself when it isn't. Returns false if the handler
+// should stop.
+func (cfg *apiConfig) checkPassword(ctx context.Context, w http.ResponseWriter, r *http.Request, email, password string) bool {
+	user, err := cfg.databaseQueries.GetUserByEmail(ctx, database.GetUserByEmailParams{
+		Email:    email,
+		TenantID: tenantFromContext(r.Context()),
+	})
+
+	if err != nil {
+		log.Printf("GetUserByEmail failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if auth.CheckPasswordHash(user.HashedPassword, password) != nil {
+		respondWithError(w, http.StatusUnauthorized, "Password is incorrect")
+		return false
+	}
+
+	return true
+}
+
+// Deletes accounts that have stayed deactivated for longer than DEACTIVATED_USER_RETENTION, along with
+// everything that cascades from them
+func (cfg *apiConfig) runUserPurgeJob(ctx context.Context, _ json.RawMessage) error {
+	if cfg.deactivatedUserRetention <= 0 {
+		return nil
+	}
+
+	cutoff := sql.NullTime{Time: time.Now().Add(-cfg.deactivatedUserRetention), Valid: true}
+
+	deleted, err := cfg.databaseQueries.DeleteDeactivatedUsers(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if deleted > 0 {
+		log.Printf("Purged %d deactivated users", deleted)
+	}
+
+	return nil
+}
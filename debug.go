@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Wraps a net/http/pprof handler for /admin/debug/pprof/: admins only, and without WRITE_TIMEOUT cutting off
+// CPU profiles and traces, which take 30 seconds by default.
+func (cfg *apiConfig) pprofHandler(h http.HandlerFunc) http.HandlerFunc {
+	// pprof finds profiles under /debug/pprof/
+	stripped := http.StripPrefix("/admin", h)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := cfg.dbContext(r.Context())
+		_, ok := cfg.requireGlobalAdmin(ctx, w, r)
+		cancel()
+		if !ok {
+			return
+		}
+
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("Clearing write deadline for pprof failed: %v", err)
+		}
+
+		// pprof refuses a ?seconds= past the server's WriteTimeout, which the deadline cleared above no longer enforces
+		r = r.WithContext(context.WithValue(r.Context(), http.ServerContextKey, &http.Server{}))
+
+		stripped.ServeHTTP(w, r)
+	}
+}
+
+type debugMemoryResponse struct {
+	HeapAllocBytes  uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes  uint64 `json:"heap_inuse_bytes"`
+	HeapObjects     uint64 `json:"heap_objects"`
+	SysBytes        uint64 `json:"sys_bytes"`
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	StackInuseBytes uint64 `json:"stack_inuse_bytes"`
+}
+
+type debugGCResponse struct {
+	NumGC        uint32     `json:"num_gc"`
+	LastGC       *time.Time `json:"last_gc"`
+	LastPauseMs  float64    `json:"last_pause_ms"`
+	PauseTotalMs float64    `json:"pause_total_ms"`
+	NextGCBytes  uint64     `json:"next_gc_bytes"`
+	// Share of the CPU time since startup spent collecting garbage
+	CPUFraction float64 `json:"cpu_fraction"`
+}
+
+type debugModuleResponse struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+}
+
+type debugBuildResponse struct {
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path"`
+	// VCS details the go command stamped into the binary, empty for `go run` and builds outside a checkout
+	Revision string                `json:"revision,omitempty"`
+	Time     string                `json:"time,omitempty"`
+	Modified bool                  `json:"modified"`
+	Deps     []debugModuleResponse `json:"deps"`
+}
+
+type debugRuntimeResponse struct {
+	UptimeSeconds int64               `json:"uptime_seconds"`
+	Goroutines    int                 `json:"goroutines"`
+	NumCPU        int                 `json:"num_cpu"`
+	GOMAXPROCS    int                 `json:"gomaxprocs"`
+	Memory        debugMemoryResponse `json:"memory"`
+	GC            debugGCResponse     `json:"gc"`
+	Build         debugBuildResponse  `json:"build"`
+}
+
+func newDebugBuildResponse() debugBuildResponse {
+	resp := debugBuildResponse{GoVersion: runtime.Version(), Deps: []debugModuleResponse{}}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return resp
+	}
+
+	resp.Path = info.Path
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			resp.Revision = s.Value
+		case "vcs.time":
+			resp.Time = s.Value
+		case "vcs.modified":
+			resp.Modified = s.Value == "true"
+		}
+	}
+
+	for _, dep := range info.Deps {
+		resp.Deps = append(resp.Deps, debugModuleResponse{Path: dep.Path, Version: dep.Version})
+	}
+
+	return resp
+}
+
+// GET /admin/debug/runtime, goroutine count, memory and GC stats and what the binary was built from. Reading the
+// memory stats briefly stops the world, so this isn't something to poll every second.
+func (cfg *apiConfig) debugRuntimeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := debugRuntimeResponse{
+		UptimeSeconds: int64(time.Since(cfg.startedAt).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Memory: debugMemoryResponse{
+			HeapAllocBytes:  mem.HeapAlloc,
+			HeapInuseBytes:  mem.HeapInuse,
+			HeapObjects:     mem.HeapObjects,
+			SysBytes:        mem.Sys,
+			TotalAllocBytes: mem.TotalAlloc,
+			StackInuseBytes: mem.StackInuse,
+		},
+		GC: debugGCResponse{
+			NumGC:        mem.NumGC,
+			PauseTotalMs: float64(mem.PauseTotalNs) / 1e6,
+			NextGCBytes:  mem.NextGC,
+			CPUFraction:  mem.GCCPUFraction,
+		},
+		Build: newDebugBuildResponse(),
+	}
+
+	if mem.NumGC > 0 {
+		last := time.Unix(0, int64(mem.LastGC))
+		resp.GC.LastGC = &last
+		// PauseNs is a ring buffer, the most recent pause is at (NumGC+255)%256
+		resp.GC.LastPauseMs = float64(mem.PauseNs[(mem.NumGC+255)%256]) / 1e6
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
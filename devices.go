@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/mail"
+)
+
+const (
+	deviceCookieName = "chirpy_device"
+	// How long the emailed link to confirm a new device works for
+	deviceConfirmTTL = time.Hour
+)
+
+var errDeviceUnconfirmed = errors.New("Check your email to confirm this device")
+
+type deviceResponse struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Name       string    `json:"name,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	LastIP     string    `json:"last_ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Confirmed  bool      `json:"confirmed"`
+	// Whether it's the device the request came from
+	Current bool `json:"current"`
+}
+
+func newDeviceResponse(d database.Device, currentHash string) deviceResponse {
+	return deviceResponse{
+		ID:         d.ID,
+		CreatedAt:  d.CreatedAt,
+		Name:       d.Name.String,
+		UserAgent:  d.UserAgent,
+		LastIP:     d.LastIp,
+		LastSeenAt: d.LastSeenAt,
+		Confirmed:  d.ConfirmedAt.Valid,
+		Current:    d.TokenHash == currentHash,
+	}
+}
+
+// The hash of the request's device cookie, empty when it doesn't have one
+func deviceTokenHash(r *http.Request) string {
+	cookie, err := r.Cookie(deviceCookieName)
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+
+	return auth.HashOAuthSecret(cookie.Value)
+}
+
+// Called by login once the password checks out: records the device, giving it a cookie if it doesn't have one,
+// and with REQUIRE_DEVICE_CONFIRMATION=on holds back logins from devices the user hasn't confirmed by email.
+// A user's first device is trusted since there's nothing to compare it with. Responds itself and returns false
+// when login should stop.
+func (cfg *apiConfig) checkDevice(ctx context.Context, w http.ResponseWriter, r *http.Request, user database.User) bool {
+	err := cfg.verifyDevice(ctx, w, r, user)
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, errDeviceUnconfirmed) {
+		respondWithError(w, http.StatusForbidden, err.Error())
+		return false
+	}
+
+	respondWithDBError(w, http.StatusInternalServerError, err)
+	return false
+}
+
+// checkDevice without the response, for sign-ins that answer in their own format. Still sets the device cookie on
+// w. Returns errDeviceUnconfirmed when the sign-in has to wait on the emailed link.
+func (cfg *apiConfig) verifyDevice(ctx context.Context, w http.ResponseWriter, r *http.Request, user database.User) error {
+	hash := deviceTokenHash(r)
+
+	if hash == "" {
+		token, err := auth.MakeRefreshToken()
+		if err != nil {
+			log.Printf("Making device token failed: %v", err)
+			return err
+		}
+
+		// Sent everywhere rather than just /api, the OAuth consent form signs in too
+		http.SetCookie(w, &http.Cookie{
+			Name:     deviceCookieName,
+			Value:    token,
+			Path:     "/",
+			MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+			HttpOnly: true,
+			Secure:   cfg.platform != "dev",
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		hash = auth.HashOAuthSecret(token)
+	}
+
+	device, err := cfg.databaseQueries.GetDeviceByToken(ctx, database.GetDeviceByTokenParams{
+		UserID:    user.ID,
+		TokenHash: hash,
+	})
+	err = database.Wrap(err)
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		device, err = cfg.createDevice(ctx, r, user.ID, hash)
+	case err == nil:
+		err = cfg.databaseQueries.TouchDevice(ctx, database.TouchDeviceParams{
+			ID:        device.ID,
+			UserAgent: r.UserAgent(),
+			LastIp:    remoteIP(r),
+		})
+	}
+
+	if err != nil {
+		log.Printf("Recording device failed: %v", err)
+		return err
+	}
+
+	if device.ConfirmedAt.Valid || !cfg.requireDeviceConfirmation {
+		return nil
+	}
+
+	if err := cfg.sendDeviceConfirmation(ctx, r, user, device); err != nil {
+		log.Printf("Sending device confirmation failed: %v", err)
+		return err
+	}
+
+	return errDeviceUnconfirmed
+}
+
+func (cfg *apiConfig) createDevice(ctx context.Context, r *http.Request, userID uuid.UUID, hash string) (database.Device, error) {
+	// Without confirmation turned on every device is trusted
+	trusted := !cfg.requireDeviceConfirmation
+	if !trusted {
+		count, err := cfg.databaseQueries.CountDevicesForUser(ctx, userID)
+		if err != nil {
+			return database.Device{}, err
+		}
+
+		trusted = count == 0
+	}
+
+	confirmedAt := sql.NullTime{Time: time.Now(), Valid: trusted}
+
+	return cfg.databaseQueries.CreateDevice(ctx, database.CreateDeviceParams{
+		UserID:      userID,
+		TokenHash:   hash,
+		UserAgent:   r.UserAgent(),
+		LastIp:      remoteIP(r),
+		ConfirmedAt: confirmedAt,
+	})
+}
+
+// Emails the user a link that confirms device. Each login attempt gets a fresh link, older ones stop working.
+func (cfg *apiConfig) sendDeviceConfirmation(ctx context.Context, r *http.Request, user database.User, device database.Device) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	err = cfg.databaseQueries.SetDeviceConfirmToken(ctx, database.SetDeviceConfirmTokenParams{
+		ID:               device.ID,
+		ConfirmTokenHash: sql.NullString{String: auth.HashOAuthSecret(token), Valid: true},
+		ConfirmExpiresAt: sql.NullTime{Time: time.Now().Add(deviceConfirmTTL), Valid: true},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Confirm your new Chirpy login",
+		Body: fmt.Sprintf("Someone just signed in to your Chirpy account from a device you haven't used before:\n\n"+
+			"  %s\n  IP address %s\n\n"+
+			"If it was you, confirm the device within the hour:\n\n  %s/api/devices/confirm?token=%s\n\n"+
+			"If it wasn't, change your password.\n",
+			r.UserAgent(), remoteIP(r), cfg.publicURL, token),
+	})
+}
+
+// GET /api/devices/confirm?token=..., the link from the confirmation email. It's opened in a browser so the
+// answer is a page rather than JSON.
+func (cfg *apiConfig) confirmDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	token := r.URL.Query().Get("token")
+
+	device, err := cfg.databaseQueries.ConfirmDevice(ctx, sql.NullString{String: auth.HashOAuthSecret(token), Valid: true})
+	err = database.Wrap(err)
+
+	status, message := http.StatusOK, "Device confirmed, you can sign in on it now."
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		status, message = http.StatusBadRequest, "This link has expired or was already used. Sign in again to get a new one."
+	case err != nil:
+		log.Printf("ConfirmDevice failed: %v", err)
+		status, message = http.StatusServiceUnavailable, "Something went wrong, try the link again shortly."
+	default:
+		log.Printf("Device %s confirmed for user %s", device.ID, device.UserID)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<html><body><p>%s</p></body></html>", html.EscapeString(message))
+}
+
+func (cfg *apiConfig) getDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated device request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	devices, err := cfg.databaseQueries.GetDevicesForUser(ctx, userID)
+	if err != nil {
+		log.Printf("GetDevicesForUser failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	current := deviceTokenHash(r)
+
+	resp := make([]deviceResponse, 0, len(devices))
+	for _, device := range devices {
+		resp = append(resp, newDeviceResponse(device, current))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// PUT /api/devices/{deviceID}, names a device so it's recognisable in the list. An empty name clears it.
+func (cfg *apiConfig) renameDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Name string `json:"name" validate:"max=100"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated device request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	deviceID, err := uuid.Parse(r.PathValue("deviceID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid device ID")
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	renamed, err := cfg.databaseQueries.RenameDevice(ctx, database.RenameDeviceParams{
+		ID:     deviceID,
+		UserID: userID,
+		Name:   sql.NullString{String: params.Name, Valid: params.Name != ""},
+	})
+
+	if err != nil {
+		log.Printf("RenameDevice failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if renamed == 0 {
+		respondWithError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Forgets a device, the next login from it counts as a new one again
+func (cfg *apiConfig) deleteDeviceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated device request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	deviceID, err := uuid.Parse(r.PathValue("deviceID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid device ID")
+		return
+	}
+
+	deleted, err := cfg.databaseQueries.DeleteDevice(ctx, database.DeleteDeviceParams{
+		ID:     deviceID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		log.Printf("DeleteDevice failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Device not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/mail"
+)
+
+// How long the link sent to the new address works for
+const emailChangeTTL = 24 * time.Hour
+
+var errEmailTaken = errors.New("Email already in use")
+
+type emailChangeResponse struct {
+	PendingEmail string    `json:"pending_email"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Starts changing user's email to newEmail: the new address gets a link that makes the change, the old one is told
+// it was asked for so a stolen token can't quietly take the account over. Nothing changes until the link is used.
+func (cfg *apiConfig) startEmailChange(ctx context.Context, r *http.Request, user database.GetUserByIDNoPasswordRow, newEmail string) (database.EmailChange, error) {
+	change, token, err := createEmailChange(ctx, cfg.databaseQueries, r, user.ID, newEmail)
+	if err != nil {
+		return database.EmailChange{}, err
+	}
+
+	return change, cfg.sendEmailChangeMail(ctx, r, user, change, token)
+}
+
+// The database half of startEmailChange, taking q so it can run inside the caller's transaction. Returns the
+// pending change and the token for its link, which sendEmailChangeMail needs once the change is committed.
+func createEmailChange(ctx context.Context, q *database.Queries, r *http.Request, userID uuid.UUID, newEmail string) (database.EmailChange, string, error) {
+	_, err := q.GetUserByEmail(ctx, database.GetUserByEmailParams{
+		Email:    newEmail,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if err == nil {
+		return database.EmailChange{}, "", errEmailTaken
+	}
+
+	if !errors.Is(err, database.ErrNotFound) {
+		return database.EmailChange{}, "", err
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return database.EmailChange{}, "", err
+	}
+
+	change, err := q.CreateEmailChange(ctx, database.CreateEmailChangeParams{
+		UserID:    userID,
+		NewEmail:  newEmail,
+		TokenHash: auth.HashOAuthSecret(token),
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	})
+
+	if err != nil {
+		return database.EmailChange{}, "", err
+	}
+
+	return change, token, nil
+}
+
+// Sends the confirmation link for change to the new address and the warning to user's current one
+func (cfg *apiConfig) sendEmailChangeMail(ctx context.Context, r *http.Request, user database.GetUserByIDNoPasswordRow, change database.EmailChange, token string) error {
+	err := cfg.sendMail(ctx, mail.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new Chirpy email address",
+		Body: fmt.Sprintf("Someone asked to move a Chirpy account to this address. If it was you, confirm it within "+
+			"a day:\n\n  %s/api/users/email/confirm?token=%s\n\nIf it wasn't, ignore this email.\n",
+			cfg.publicURL, token),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change your Chirpy account's email address to %s from:\n\n"+
+			"  %s\n  IP address %s\n\n"+
+			"It only changes once that address confirms. If this wasn't you, change your password.\n",
+			change.NewEmail, r.UserAgent(), remoteIP(r)),
+	})
+}
+
+// Responds to a request that started an email change, or failed to
+func respondWithEmailChange(w http.ResponseWriter, r *http.Request, change database.EmailChange, err error) {
+	if errors.Is(err, errEmailTaken) {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	if err != nil {
+		log.Printf("Starting email change failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respond(w, r, http.StatusAccepted, emailChangeResponse{
+		PendingEmail: change.NewEmail,
+		ExpiresAt:    change.ExpiresAt,
+	})
+}
+
+// POST /api/users/email, asks to move the account to a new address. Answers 202, the change waits on the link
+// emailed to it.
+func (cfg *apiConfig) requestEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated email change request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByIDNoPassword(ctx, userID)
+	if err != nil {
+		log.Printf("GetUserByIDNoPassword failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if params.Email == user.Email {
+		respondWithError(w, http.StatusBadRequest, "That's already your email")
+		return
+	}
+
+	change, err := cfg.startEmailChange(ctx, r, user, params.Email)
+	respondWithEmailChange(w, r, change, err)
+}
+
+// GET /api/users/email/confirm?token=..., the link sent to the new address. It's opened in a browser so the
+// answer is a page rather than JSON.
+func (cfg *apiConfig) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	status, message := http.StatusOK, "Email address changed, use it to sign in from now on."
+
+	// Consuming the token and changing the email happen together, a failed update leaves the link usable
+	err := database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		change, err := qtx.ConsumeEmailChange(ctx, auth.HashOAuthSecret(r.URL.Query().Get("token")))
+		if err != nil {
+			return err
+		}
+
+		err = qtx.UpdateUserEmail(ctx, database.UpdateUserEmailParams{
+			ID:    change.UserID,
+			Email: change.NewEmail,
+		})
+
+		if err == nil {
+			log.Printf("Email changed for user %s", change.UserID)
+		}
+
+		return err
+	})
+
+	switch {
+	case errors.Is(err, database.ErrNotFound):
+		status, message = http.StatusBadRequest, "This link has expired or was already used. Ask for the change again to get a new one."
+	case errors.Is(err, database.ErrDuplicate):
+		status, message = http.StatusConflict, "Another account already uses this email address."
+	case err != nil:
+		log.Printf("Confirming email change failed: %v", err)
+		status, message = http.StatusServiceUnavailable, "Something went wrong, try the link again shortly."
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<html><body><p>%s</p></body></html>", html.EscapeString(message))
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+const (
+	// Size of the iframe the oEmbed snippet asks for, shrunk to fit the consumer's maxwidth/maxheight
+	embedWidth  = 550
+	embedHeight = 250
+	// How long consumers and caches can keep an embed before checking for edits or deletion
+	embedCacheAge = 3600
+)
+
+// Paths on PUBLIC_URL that name a chirp, either the embed page itself or the API URL
+var embedChirpPath = regexp.MustCompile(`^/(?:embed|api)/chirps/([0-9a-fA-F-]{36})/?$`)
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>{{if .Failure}}Chirpy{{else}}Chirp by {{.Name}}{{end}}</title>
+	{{if not .Failure}}<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}">{{end}}
+	<style>
+		body { margin: 0; font-family: system-ui, sans-serif; }
+		article { border: 1px solid #ccc; border-radius: 12px; padding: 12px 16px; }
+		header { display: flex; align-items: center; gap: 8px; }
+		header img { width: 36px; height: 36px; border-radius: 50%; }
+		.handle, footer { color: #666; font-size: 0.9em; }
+		p { white-space: pre-wrap; overflow-wrap: anywhere; }
+	</style>
+</head>
+<body>
+	{{if .Failure}}
+	<p>{{.Failure}}</p>
+	{{else}}
+	<article>
+		<header>
+			{{if .Chirp.Author.AvatarURL}}<img src="{{.Chirp.Author.AvatarURL}}" alt="">{{end}}
+			<div>
+				<strong>{{.Name}}</strong>
+				{{if .Chirp.Author.Handle}}<div class="handle">@{{.Chirp.Author.Handle}}</div>{{end}}
+			</div>
+		</header>
+		{{if .Chirp.Sensitive}}
+		<details>
+			<summary>{{if .Chirp.ContentWarning}}{{.Chirp.ContentWarning}}{{else}}Sensitive content{{end}}</summary>
+			<p>{{.Chirp.Body}}</p>
+		</details>
+		{{else}}
+		<p>{{.Chirp.Body}}</p>
+		{{end}}
+		<footer>
+			<a href="{{.ChirpURL}}" target="_blank" rel="noopener">{{.Chirp.CreatedAt.Format "3:04 PM · Jan 2, 2006"}}</a>
+			· {{.Chirp.LikeCount}} likes · {{.Chirp.ReplyCount}} replies
+		</footer>
+	</article>
+	{{end}}
+</body>
+</html>
+`))
+
+type embedPage struct {
+	Chirp     api.Chirp
+	Name      string
+	ChirpURL  string
+	OEmbedURL string
+	Failure   string
+}
+
+// The oEmbed response for a chirp, always the "rich" type: an iframe of its embed page
+type oembedResponse struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	AuthorName   string `json:"author_name"`
+	AuthorURL    string `json:"author_url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	CacheAge     int    `json:"cache_age"`
+}
+
+// What an embed shows as the author, their display name falling back to the handle
+func embedAuthorName(a api.Author) string {
+	if a.DisplayName != "" {
+		return a.DisplayName
+	}
+
+	if a.Handle != "" {
+		return "@" + a.Handle
+	}
+
+	return "Chirpy user"
+}
+
+// Loads a chirp the way an anonymous reader sees it. Returns the status to use when it can't.
+func (cfg *apiConfig) embeddableChirp(r *http.Request, chirpID uuid.UUID) (api.Chirp, int, error) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	// Only public chirps, embeds are shown to whoever visits the embedding site
+	chirp, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       chirpID,
+		TenantID: tenantFromContext(r.Context()),
+		ViewerID: uuid.Nil,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return api.Chirp{}, http.StatusNotFound, errors.New("Chirp not found")
+	}
+
+	if err != nil {
+		log.Printf("GetVisibleChirp failed: %v", err)
+		return api.Chirp{}, http.StatusServiceUnavailable, err
+	}
+
+	cfg.chirpViews.record([]database.Chirp{chirp}, uuid.Nil)
+	response, err := cfg.chirpResponses(ctx, []database.Chirp{chirp}, uuid.Nil)
+	if err != nil {
+		log.Printf("Loading chirp links/engagement failed: %v", err)
+		return api.Chirp{}, http.StatusServiceUnavailable, err
+	}
+
+	return response[0], http.StatusOK, nil
+}
+
+// GET /embed/chirps/{chirpID}, a self-contained page showing one chirp for other sites to put in an iframe.
+// Unlike every other page it can be framed from anywhere, and it never looks at who's logged in.
+func (cfg *apiConfig) embedChirpHandler(w http.ResponseWriter, r *http.Request) {
+	var page embedPage
+	status := http.StatusOK
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		status, page.Failure = http.StatusNotFound, "Chirp not found"
+	} else {
+		page.Chirp, status, err = cfg.embeddableChirp(r, chirpID)
+		switch {
+		case status == http.StatusNotFound:
+			page.Failure = "Chirp not found"
+		case err != nil:
+			page.Failure = "Something went wrong, try again shortly."
+		}
+	}
+
+	if page.Failure == "" {
+		page.Name = embedAuthorName(page.Chirp.Author)
+		page.ChirpURL = cfg.publicURL + "/api/chirps/" + chirpID.String()
+		page.OEmbedURL = cfg.publicURL + "/api/oembed?url=" + url.QueryEscape(page.ChirpURL)
+	}
+
+	w.Header().Set("Content-Security-Policy", "frame-ancestors *")
+	w.Header().Del("X-Frame-Options")
+	if status == http.StatusOK {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", embedCacheAge))
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := embedTemplate.Execute(w, page); err != nil {
+		log.Printf("Rendering embed page failed: %v", err)
+	}
+}
+
+// GET /api/oembed?url=..., the oEmbed endpoint (https://oembed.com) for chirp URLs on PUBLIC_URL. maxwidth and
+// maxheight shrink the iframe, only format=json is supported.
+func (cfg *apiConfig) oembedHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if format := query.Get("format"); format != "" && format != "json" {
+		respondWithError(w, http.StatusNotImplemented, "Only the json oEmbed format is supported")
+		return
+	}
+
+	target, err := url.Parse(query.Get("url"))
+	public, _ := url.Parse(cfg.publicURL)
+	if err != nil || target.Host == "" || public == nil || target.Host != public.Host {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	match := embedChirpPath.FindStringSubmatch(target.Path)
+	if match == nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	chirpID, err := uuid.Parse(match[1])
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	chirp, status, err := cfg.embeddableChirp(r, chirpID)
+	if err != nil {
+		respondWithError(w, status, err.Error())
+		return
+	}
+
+	width, height := embedWidth, embedHeight
+	if v, err := strconv.Atoi(query.Get("maxwidth")); err == nil && v > 0 {
+		width = min(width, v)
+	}
+	if v, err := strconv.Atoi(query.Get("maxheight")); err == nil && v > 0 {
+		height = min(height, v)
+	}
+
+	src := cfg.publicURL + "/embed/chirps/" + chirpID.String()
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", embedCacheAge))
+	respondWithJson(w, http.StatusOK, oembedResponse{
+		Type:         "rich",
+		Version:      "1.0",
+		ProviderName: "Chirpy",
+		ProviderURL:  cfg.publicURL,
+		AuthorName:   embedAuthorName(chirp.Author),
+		AuthorURL:    cfg.publicURL + "/api/users/" + chirp.UserID.String(),
+		HTML: fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" style="border:0" loading="lazy" title="Chirp"></iframe>`,
+			template.HTMLEscapeString(src), width, height),
+		Width:    width,
+		Height:   height,
+		CacheAge: embedCacheAge,
+	})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// One chirp in an export. Deliberately flat so the CSV and NDJSON versions carry exactly the same data.
+type chirpRecord struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Body      string     `json:"body"`
+	ReplyToID *uuid.UUID `json:"reply_to_id,omitempty"`
+}
+
+var chirpCSVHeader = []string{"id", "created_at", "user_id", "body", "reply_to_id"}
+
+// Timestamps are given in loc, the exporting user's timezone
+func newChirpRecord(c database.Chirp, loc *time.Location) chirpRecord {
+	rec := chirpRecord{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt.In(loc),
+		UserID:    c.UserID,
+		Body:      c.Body,
+	}
+
+	if c.ReplyToID.Valid {
+		rec.ReplyToID = &c.ReplyToID.UUID
+	}
+
+	return rec
+}
+
+func (c chirpRecord) csvRow() []string {
+	replyTo := ""
+	if c.ReplyToID != nil {
+		replyTo = c.ReplyToID.String()
+	}
+
+	return []string{c.ID.String(), c.CreatedAt.Format(time.RFC3339Nano), c.UserID.String(), c.Body, replyTo}
+}
+
+// Rows fetched per query while exporting, the export itself has no size limit
+const exportPageSize = 1000
+
+// GET /api/chirps/export?format=csv|ndjson. Users get their own chirps, admins can add all=true for everyone's.
+// Rows are paged out of the database and written as they arrive, so the export never sits in memory whole.
+func (cfg *apiConfig) exportChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+
+	if format != "csv" && format != "ndjson" {
+		respondWithError(w, http.StatusBadRequest, "format must be csv or ndjson")
+		return
+	}
+
+	all := r.URL.Query().Get("all") == "true"
+
+	var userID uuid.UUID
+	var err error
+	if all {
+		var ok bool
+		if userID, ok = cfg.requireAdmin(ctx, w, r); !ok {
+			return
+		}
+	} else {
+		userID, err = cfg.authenticateRequest(r)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+	}
+
+	loc := cfg.userLocation(ctx, userID)
+
+	fetch := func(ctx context.Context, cursor pageCursor) ([]database.Chirp, error) {
+		if all {
+			return cfg.databaseQueries.GetChirpsPage(ctx, database.GetChirpsPageParams{
+				TenantID:   tenantFromContext(r.Context()),
+				CursorTime: cursor.Time,
+				CursorID:   cursor.ID,
+				PageLimit:  exportPageSize,
+			})
+		}
+
+		return cfg.databaseQueries.GetChirpsByUserAsc(ctx, database.GetChirpsByUserAscParams{
+			UserID:     userID,
+			TenantID:   tenantFromContext(r.Context()),
+			CursorTime: cursor.Time,
+			CursorID:   cursor.ID,
+			ViewerID:   userID,
+			PageLimit:  exportPageSize,
+		})
+	}
+
+	// The first page is fetched before the headers go out, so a broken database still gets a proper status
+	page, err := fetch(ctx, oldestFirst)
+	cancel()
+
+	if err != nil {
+		log.Printf("Exporting chirps failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// WRITE_TIMEOUT is meant for ordinary responses, a big export would be cut off partway with a 200
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Clearing write deadline for export failed: %v", err)
+	}
+
+	filename := fmt.Sprintf("chirps-%s.%s", time.Now().In(loc).Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	var write func(chirpRecord) error
+	var flush func() error
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write(chirpCSVHeader)
+
+		write = func(rec chirpRecord) error { return cw.Write(rec.csvRow()) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		enc := json.NewEncoder(w)
+
+		write = func(rec chirpRecord) error { return enc.Encode(rec) }
+		flush = func() error { return nil }
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	for len(page) > 0 {
+		for _, chirp := range page {
+			if err := write(newChirpRecord(chirp, loc)); err != nil {
+				log.Printf("Writing export failed: %v", err)
+				return
+			}
+		}
+
+		if len(page) < exportPageSize {
+			break
+		}
+
+		last := page[len(page)-1]
+
+		// Each page gets its own DB timeout, a long export is many quick queries rather than one slow one
+		pageCtx, cancelPage := cfg.dbContext(r.Context())
+		page, err = fetch(pageCtx, pageCursor{Time: last.CreatedAt, ID: last.ID})
+		cancelPage()
+
+		if err != nil {
+			// Too late for an error status, the client sees a truncated file instead
+			log.Printf("Exporting chirps failed mid-stream: %v", err)
+			return
+		}
+	}
+
+	if err := flush(); err != nil {
+		log.Printf("Writing export failed: %v", err)
+	}
+}
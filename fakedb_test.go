@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+	"github.com/itsmandrew/server-go/internal/jobs"
+	"github.com/itsmandrew/server-go/internal/moderation"
+)
+
+// A database/sql connector that answers every sqlc query without Postgres, so handlers can run in tests. Queries
+// that return rows get one row, each column filled in from values by name or else a guess from the name. Execs
+// always affect one row. The sqlc names of the statements that took effect (ran outside a transaction or in one
+// that committed) are kept in saved. Queries that filter chirps by visibility apply the same rule to the row, see
+// visibleTo.
+type fakeDB struct {
+	values map[string]driver.Value
+	// Whether every viewer follows the row's author
+	follows bool
+
+	mu    sync.Mutex
+	saved []string
+}
+
+// The id every row the fake returns has, unless its values say otherwise
+var testUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+var queryName = regexp.MustCompile(`-- name: (\w+)`)
+
+// The follows check of the chirp visibility clause, capturing the viewer's parameter
+var visibilityViewer = regexp.MustCompile(`visibility <> 'private' AND user_id IN \(\s*SELECT followee_id FROM follows WHERE follower_id = \$(\d+)::uuid`)
+
+func (f *fakeDB) save(names ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved = append(f.saved, names...)
+}
+
+// Whether a statement called name took effect
+func (f *fakeDB) wasSaved(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Contains(f.saved, name)
+}
+
+func newFakeDB(values map[string]driver.Value) *fakeDB {
+	return &fakeDB{values: values}
+}
+
+func (f *fakeDB) open() *sql.DB {
+	return sql.OpenDB(fakeConnector{f})
+}
+
+// An apiConfig backed by fake with what the handlers expect main to have set up
+func newFakeConfig(t *testing.T, fake *fakeDB) *apiConfig {
+	t.Helper()
+
+	db := fake.open()
+	t.Cleanup(func() { db.Close() })
+
+	queries := database.New(db)
+	cfg := &apiConfig{
+		db:              db,
+		databaseQueries: queries,
+		jobs:            jobs.NewQueue(queries, jobs.Options{}),
+		jwt:             auth.JWTConfig{Secret: "test-secret"},
+		dbTimeout:       time.Second,
+		events:          events.NewLocalBus(),
+		activity:        newActivityTracker(),
+		chirpViews:      newChirpViewCounter(),
+	}
+	cfg.moderation.Store(moderation.NewPipeline())
+	return cfg
+}
+
+// A bearer token with every scope for testUserID
+func testAccessToken(t *testing.T, cfg *apiConfig) string {
+	t.Helper()
+
+	token, err := cfg.jwt.Make(testUserID, time.Hour, auth.Scopes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// Column names a query returns, from its RETURNING clause or its SELECT list
+func resultColumns(query string) []string {
+	var list string
+	if i := strings.LastIndex(query, "RETURNING "); i >= 0 {
+		list = query[i+len("RETURNING "):]
+	} else if i := strings.Index(query, "SELECT "); i >= 0 {
+		list = query[i+len("SELECT "):]
+	} else {
+		return nil
+	}
+
+	var cols []string
+	for _, col := range selectItems(list) {
+		col = strings.TrimSpace(col)
+		if i := strings.LastIndex(strings.ToLower(col), " as "); i >= 0 {
+			col = col[i+4:]
+		}
+		if i := strings.LastIndex(col, "."); i >= 0 {
+			col = col[i+1:]
+		}
+		cols = append(cols, strings.Trim(strings.TrimSpace(col), `"`))
+	}
+	return cols
+}
+
+// Splits a select list at its top-level commas, up to the FROM that ends it. Subqueries in the list have their own.
+func selectItems(list string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; {
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, list[start:i])
+			start = i + 1
+		case depth == 0 && (c == ' ' || c == '\n') && strings.HasPrefix(list[i+1:], "FROM"):
+			return append(items, list[start:i])
+		}
+	}
+	return append(items, list[start:])
+}
+
+var unsetTimes = map[string]bool{
+	"confirmed_at": true, "read_at": true, "revoked_at": true, "deactivated_at": true, "shadow_banned_at": true,
+	"banned_at": true, "banned_until": true,
+}
+
+func (f *fakeDB) value(col string) driver.Value {
+	if v, ok := f.values[col]; ok {
+		return v
+	}
+
+	switch {
+	case col == "id" || strings.HasSuffix(col, "_id"):
+		return testUserID.String()
+	// These mark something that happened to the row, leave them unset
+	case unsetTimes[col]:
+		return nil
+	case strings.HasSuffix(col, "_at"):
+		return time.Now().UTC()
+	case strings.HasPrefix(col, "is_") || strings.HasPrefix(col, "has_") || strings.HasSuffix(col, "_by_me") ||
+		col == "protected" || col == "sensitive":
+		return false
+	case strings.HasSuffix(col, "_count") || col == "count" || strings.HasSuffix(col, "attempts") || col == "position":
+		return int64(0)
+	// JSON columns scan into json.RawMessage, which only takes bytes
+	case col == "payload" || col == "metadata":
+		return []byte("{}")
+	}
+	return ""
+}
+
+// The visibility clause for the row: its author sees it, anyone sees it if it's public, and followers see it unless
+// it's private. Queries without the clause return the row to anyone.
+func (f *fakeDB) visibleTo(query string, args []driver.Value) bool {
+	m := visibilityViewer.FindStringSubmatch(query)
+	if m == nil {
+		return true
+	}
+
+	n, _ := strconv.Atoi(m[1])
+	viewer, _ := args[n-1].(string)
+	author, _ := f.value("user_id").(string)
+	visibility, _ := f.value("visibility").(string)
+
+	return viewer == author || visibility == visibilityPublic || (visibility != visibilityPrivate && f.follows)
+}
+
+func (f *fakeDB) row(query string) (cols []string, row []driver.Value) {
+	cols = resultColumns(query)
+	for _, col := range cols {
+		row = append(row, f.value(col))
+	}
+	return cols, row
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use fakeDB.open") }
+
+type fakeConn struct {
+	db *fakeDB
+	// Statements run in the open transaction, while inTx
+	pending []string
+	inTx    bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx, c.pending = true, nil
+	return fakeTx{c}, nil
+}
+
+type fakeTx struct{ conn *fakeConn }
+
+func (t fakeTx) Commit() error {
+	t.conn.db.save(t.conn.pending...)
+	t.conn.inTx, t.conn.pending = false, nil
+	return nil
+}
+
+func (t fakeTx) Rollback() error {
+	t.conn.inTx, t.conn.pending = false, nil
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+// Notes the statement in the transaction, or as saved when there isn't one
+func (s *fakeStmt) run() {
+	name := ""
+	if m := queryName.FindStringSubmatch(s.query); m != nil {
+		name = m[1]
+	}
+
+	if s.conn.inTx {
+		s.conn.pending = append(s.conn.pending, name)
+	} else {
+		s.conn.db.save(name)
+	}
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.run()
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.run()
+	cols, row := s.conn.db.row(s.query)
+	if !s.conn.db.visibleTo(s.query, args) {
+		return &fakeRows{cols: cols}, nil
+	}
+	return &fakeRows{cols: cols, rows: [][]driver.Value{row}}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
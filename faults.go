@@ -0,0 +1,182 @@
+package main
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Longest delay a fault can add, so a typo doesn't hang a request for hours
+const maxFaultDelay = time.Minute
+
+// Slows down and/or fails requests to one route. Path matches itself and everything under it,
+// an empty Method matches any method.
+type faultRule struct {
+	Method    string  `json:"method,omitempty"`
+	Path      string  `json:"path" validate:"required"`
+	DelayMs   int     `json:"delay_ms" validate:"min=0,max=60000"`
+	ErrorRate float64 `json:"error_rate"`
+	Status    int     `json:"status"`
+}
+
+func (f faultRule) matches(r *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+
+	return r.URL.Path == f.Path || strings.HasPrefix(r.URL.Path, strings.TrimSuffix(f.Path, "/")+"/")
+}
+
+// Fault rules set at /admin/faults, per process
+type faultInjector struct {
+	mu    sync.Mutex
+	rules []faultRule
+}
+
+func (fi *faultInjector) snapshot() []faultRule {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	return append([]faultRule{}, fi.rules...)
+}
+
+func (fi *faultInjector) set(rules []faultRule) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.rules = rules
+}
+
+// The fault to inject into r: the first matching rule, with the X-Fault-* headers laid over it.
+// X-Fault-Delay takes a duration like 500ms, X-Fault-Status a 5xx code and X-Fault-Error-Rate a probability
+// from 0 to 1 (1 when only a status is given). Bad header values are ignored.
+func (fi *faultInjector) faultFor(r *http.Request) (faultRule, bool) {
+	var fault faultRule
+	found := false
+
+	for _, rule := range fi.snapshot() {
+		if rule.matches(r) {
+			fault, found = rule, true
+			break
+		}
+	}
+
+	if d, err := time.ParseDuration(r.Header.Get("X-Fault-Delay")); err == nil && d > 0 {
+		fault.DelayMs, found = int(min(d, maxFaultDelay).Milliseconds()), true
+	}
+
+	if status, err := strconv.Atoi(r.Header.Get("X-Fault-Status")); err == nil && status >= 500 && status <= 599 {
+		fault.Status, fault.ErrorRate, found = status, 1, true
+	}
+
+	if rate, err := strconv.ParseFloat(r.Header.Get("X-Fault-Error-Rate"), 64); err == nil && rate >= 0 && rate <= 1 {
+		fault.ErrorRate, found = rate, true
+	}
+
+	return fault, found
+}
+
+// Development only: delays and fails requests as the fault rules and X-Fault-* headers say, so clients can test
+// their retries and timeouts. /admin/faults itself is never faulted so the rules can always be cleared.
+func (cfg *apiConfig) middlewareFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/admin/faults") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		fault, ok := cfg.faults.faultFor(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if fault.DelayMs > 0 {
+			timer := time.NewTimer(time.Duration(fault.DelayMs) * time.Millisecond)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+
+		if fault.ErrorRate > 0 && rand.Float64() < fault.ErrorRate {
+			status := fault.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+
+			w.Header().Set("X-Fault-Injected", "true")
+			respondWithError(w, status, "Injected fault")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type faultsResponse struct {
+	Rules []faultRule `json:"rules"`
+}
+
+// GET /admin/faults, the fault rules in force. Only on PLATFORM=dev, like the resets.
+func (cfg *apiConfig) getFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.platform != "dev" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, faultsResponse{Rules: cfg.faults.snapshot()})
+}
+
+// PUT /admin/faults, {"rules": [{"method": "POST", "path": "/api/chirps", "delay_ms": 2000, "error_rate": 0.5}]}
+// replaces the rules. A rule's status defaults to 503, an empty list turns fault injection back off.
+func (cfg *apiConfig) setFaultsHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.platform != "dev" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var params struct {
+		Rules []faultRule `json:"rules"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	fieldErrs := validate.Errors{}
+	for i, rule := range params.Rules {
+		prefix := "rules." + strconv.Itoa(i) + "."
+		for field, msg := range validate.Struct(rule) {
+			fieldErrs[prefix+field] = msg
+		}
+
+		if rule.ErrorRate < 0 || rule.ErrorRate > 1 {
+			fieldErrs[prefix+"error_rate"] = "must be between 0 and 1"
+		}
+
+		if rule.Status != 0 && (rule.Status < 500 || rule.Status > 599) {
+			fieldErrs[prefix+"status"] = "must be a 5xx status code"
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		respondWithFieldErrors(w, fieldErrs)
+		return
+	}
+
+	rules := params.Rules
+	if rules == nil {
+		rules = []faultRule{}
+	}
+
+	cfg.faults.set(rules)
+	log.Printf("Fault injection rules set: %d rules", len(rules))
+	respondWithJson(w, http.StatusOK, faultsResponse{Rules: cfg.faults.snapshot()})
+}
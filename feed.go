@@ -0,0 +1,137 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/ranking"
+)
+
+const (
+	// How many of the newest chirps from followed authors, and how many of the most popular overall,
+	// a ranked feed chooses from
+	feedFollowedCandidates = 500
+	feedPopularCandidates  = 200
+)
+
+// GET /api/feed, the logged-in user's home feed. ?ranking=latest (the default) is their own chirps and those of
+// everyone they follow, newest first and paged with ?cursor=. Any other ranking picks a ranker from
+// cfg.feedRankers, which orders recent chirps from followed authors mixed with popular ones from everyone;
+// that's a single page of up to ?limit= since the order shifts as engagement comes in.
+func (cfg *apiConfig) getFeedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	mode := r.URL.Query().Get("ranking")
+	if mode == "" {
+		mode = "latest"
+	}
+
+	ranker, ranked := cfg.feedRankers[mode]
+	if !ranked && mode != "latest" {
+		respondWithError(w, http.StatusBadRequest, "ranking must be top or latest")
+		return
+	}
+
+	lang, err := queryLanguage(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := queryLimit(r, "limit", 50, 100)
+
+	hidden, err := cfg.hiddenChirps(ctx, userID)
+	if err != nil {
+		log.Printf("Loading hidden chirp filters failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var chirps []database.Chirp
+	var next string
+
+	if ranked {
+		candidates, err := cfg.databaseQueries.GetFeedCandidates(ctx, database.GetFeedCandidatesParams{
+			ViewerID:      userID,
+			TenantID:      tenantFromContext(r.Context()),
+			Since:         time.Now().Add(-cfg.feedWindow),
+			Language:      lang,
+			FollowedLimit: feedFollowedCandidates,
+			PopularLimit:  feedPopularCandidates,
+		})
+
+		if err != nil {
+			log.Printf("GetFeedCandidates failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		candidates = slices.DeleteFunc(candidates, func(c database.GetFeedCandidatesRow) bool { return hidden(c.Chirp) })
+
+		ranking.Rank(candidates, func(c database.GetFeedCandidatesRow) ranking.Candidate {
+			return ranking.Candidate{
+				CreatedAt: c.Chirp.CreatedAt,
+				Likes:     c.LikeCount,
+				Replies:   c.ReplyCount,
+				Rechirps:  c.RechirpCount,
+				Followed:  c.Followed,
+			}
+		}, ranker, time.Now())
+
+		for _, c := range candidates[:min(limit, len(candidates))] {
+			chirps = append(chirps, c.Chirp)
+		}
+	} else {
+		cursor, err := queryCursor(r, newestFirst)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// One extra row tells us whether there's another page, same as the other lists
+		chirps, err = cfg.databaseQueries.GetLatestFeed(ctx, database.GetLatestFeedParams{
+			TenantID:   tenantFromContext(r.Context()),
+			ViewerID:   userID,
+			CursorTime: cursor.Time,
+			CursorID:   cursor.ID,
+			Language:   lang,
+			PageLimit:  int32(limit + 1),
+		})
+
+		if err != nil {
+			log.Printf("GetLatestFeed failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if len(chirps) > limit {
+			chirps = chirps[:limit]
+			last := chirps[len(chirps)-1]
+			next = pageCursor{Time: last.CreatedAt, ID: last.ID}.String()
+		}
+
+		// After the cursor, so a page can come back short but paging never skips anything
+		chirps = slices.DeleteFunc(chirps, hidden)
+	}
+
+	cfg.chirpViews.record(chirps, userID)
+	response, err := cfg.chirpResponses(ctx, chirps, userID)
+
+	if err != nil {
+		log.Printf("Loading chirp links/engagement failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, chirpListResponse{Chirps: response, NextCursor: next})
+}
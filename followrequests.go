@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+)
+
+var errFollowRequestMissing = errors.New("Follow request not found")
+
+// Answer to following a protected account, the follow waits on the owner approving it
+type followPendingResponse struct {
+	Status string `json:"status" xml:"status"`
+}
+
+type followRequestResponse struct {
+	ID          uuid.UUID `json:"id" xml:"id"`
+	Handle      string    `json:"handle,omitempty" xml:"handle,omitempty"`
+	RequestedAt time.Time `json:"requested_at" xml:"requested_at"`
+}
+
+type followRequestListResponse struct {
+	XMLName    xml.Name                `json:"-" xml:"follow_requests"`
+	Users      []followRequestResponse `json:"users" xml:"user"`
+	NextCursor string                  `json:"next_cursor,omitempty" xml:"next_cursor,attr,omitempty"`
+}
+
+// Asks followeeID, a protected account, to approve userID as a follower. Already following is a no-op like it is
+// for everyone else, and asking again doesn't notify them twice.
+func (cfg *apiConfig) requestFollow(ctx context.Context, w http.ResponseWriter, r *http.Request, userID, followeeID uuid.UUID) {
+	following, err := cfg.databaseQueries.IsFollowing(ctx, database.IsFollowingParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+
+	if err != nil {
+		log.Printf("IsFollowing failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if following {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	added, err := cfg.databaseQueries.CreateFollowRequest(ctx, database.CreateFollowRequestParams{
+		RequesterID: userID,
+		TargetID:    followeeID,
+	})
+
+	if err != nil {
+		log.Printf("CreateFollowRequest failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if added > 0 {
+		cfg.events.Publish(r.Context(), events.FollowRequested{RequesterID: userID, TargetID: followeeID})
+	}
+
+	respond(w, r, http.StatusAccepted, followPendingResponse{Status: "pending"})
+}
+
+// GET /api/follow_requests, who's waiting on the caller to approve them, newest first
+func (cfg *apiConfig) getFollowRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	cursor, err := queryCursor(r, newestFirst)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := queryLimit(r, "limit", 50, 100)
+
+	rows, err := cfg.databaseQueries.GetFollowRequests(ctx, database.GetFollowRequestsParams{
+		TargetID:   userID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageLimit:  int32(limit + 1),
+	})
+
+	if err != nil {
+		log.Printf("GetFollowRequests failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := followRequestListResponse{Users: make([]followRequestResponse, 0, len(rows))}
+	for _, row := range rows {
+		resp.Users = append(resp.Users, followRequestResponse{
+			ID:          row.ID,
+			Handle:      row.Handle.String,
+			RequestedAt: row.RequestedAt,
+		})
+	}
+
+	if len(resp.Users) > limit {
+		resp.Users = resp.Users[:limit]
+		last := resp.Users[limit-1]
+		resp.NextCursor = pageCursor{Time: last.RequestedAt, ID: last.ID}.String()
+	}
+
+	respond(w, r, http.StatusOK, resp)
+}
+
+// POST /api/follow_requests/{userID}, approves userID's request: they follow the caller from now on
+func (cfg *apiConfig) approveFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	requesterID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	var added int64
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		removed, err := qtx.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+			RequesterID: requesterID,
+			TargetID:    userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if removed == 0 {
+			return errFollowRequestMissing
+		}
+
+		added, err = qtx.FollowUser(ctx, database.FollowUserParams{
+			FollowerID: requesterID,
+			FolloweeID: userID,
+		})
+		return err
+	})
+
+	if errors.Is(err, errFollowRequestMissing) {
+		respondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if err != nil {
+		log.Printf("Approving follow request failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if added > 0 {
+		cfg.events.Publish(r.Context(), events.UserFollowed{FollowerID: requesterID, FolloweeID: userID})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/follow_requests/{userID}, turns userID down. They aren't told, they can ask again.
+func (cfg *apiConfig) denyFollowRequestHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	requesterID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	removed, err := cfg.databaseQueries.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+		RequesterID: requesterID,
+		TargetID:    userID,
+	})
+
+	if err != nil {
+		log.Printf("DeleteFollowRequest failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, errFollowRequestMissing.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /api/users/privacy, protects the caller's account or stops protecting it. Turning protection off approves
+// everyone still waiting, since anyone could follow them now anyway.
+func (cfg *apiConfig) setPrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var params struct {
+		Protected *bool `json:"protected" validate:"required"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	var approved []uuid.UUID
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		err := qtx.SetUserProtected(ctx, database.SetUserProtectedParams{
+			ID:        userID,
+			Protected: *params.Protected,
+		})
+		if err != nil || *params.Protected {
+			return err
+		}
+
+		approved, err = qtx.AcceptAllFollowRequests(ctx, userID)
+		return err
+	})
+
+	if err != nil {
+		log.Printf("SetUserProtected failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, followerID := range approved {
+		cfg.events.Publish(r.Context(), events.UserFollowed{FollowerID: followerID, FolloweeID: userID})
+	}
+
+	cfg.respondWithUserProfile(ctx, w, r, userID, userID)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+)
+
+type followResponse struct {
+	ID          uuid.UUID `json:"id" xml:"id"`
+	Handle      string    `json:"handle,omitempty" xml:"handle,omitempty"`
+	FollowedAt  time.Time `json:"followed_at" xml:"followed_at"`
+	IsFollowing bool      `json:"is_following" xml:"is_following"`
+}
+
+type followListResponse struct {
+	XMLName    xml.Name         `json:"-" xml:"users"`
+	Users      []followResponse `json:"users" xml:"user"`
+	NextCursor string           `json:"next_cursor,omitempty" xml:"next_cursor,attr,omitempty"`
+}
+
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself")
+		return
+	}
+
+	// Looking them up also keeps follows inside the tenant, the foreign key would happily let you follow someone in
+	// another one
+	followee, err := cfg.databaseQueries.GetUserProfile(ctx, database.GetUserProfileParams{
+		ID:       followeeID,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetUserProfile failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if followee.Protected {
+		cfg.requestFollow(ctx, w, r, userID, followeeID)
+		return
+	}
+
+	added, err := cfg.databaseQueries.FollowUser(ctx, database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrForeignKey) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("FollowUser failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Following someone you already follow is a no-op, so they don't get notified twice
+	if added > 0 {
+		cfg.events.Publish(r.Context(), events.UserFollowed{FollowerID: userID, FolloweeID: followeeID})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	removed, err := cfg.databaseQueries.UnfollowUser(ctx, database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+
+	if err != nil {
+		log.Printf("UnfollowUser failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Unfollowing a protected account before they've answered withdraws the request
+	if removed == 0 {
+		removed, err = cfg.databaseQueries.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+			RequesterID: userID,
+			TargetID:    followeeID,
+		})
+
+		if err != nil {
+			log.Printf("DeleteFollowRequest failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	if removed == 0 {
+		respondWithError(w, http.StatusNotFound, "Not following this user")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Who follows userID. is_following is whether the requester follows each of them, always false when logged out.
+func (cfg *apiConfig) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	cursor, err := queryCursor(r, newestFirst)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Otherwise this would list the follows of a user in another tenant
+	if cfg.tenants != nil && !cfg.requireUser(ctx, w, r, userID) {
+		return
+	}
+
+	viewerID, _ := cfg.optionalUser(r)
+	limit := queryLimit(r, "limit", 50, 100)
+
+	// One extra row tells us whether there's another page without a COUNT
+	rows, err := cfg.databaseQueries.GetFollowers(ctx, database.GetFollowersParams{
+		ViewerID:   viewerID,
+		UserID:     userID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageLimit:  int32(limit + 1),
+	})
+
+	if err != nil {
+		log.Printf("GetFollowers failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	users := make([]followResponse, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, followResponse{
+			ID:          row.ID,
+			Handle:      row.Handle.String,
+			FollowedAt:  row.FollowedAt,
+			IsFollowing: row.IsFollowing,
+		})
+	}
+
+	respond(w, r, http.StatusOK, newFollowListResponse(users, limit))
+}
+
+// Who userID follows, same shape as the followers list
+func (cfg *apiConfig) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	cursor, err := queryCursor(r, newestFirst)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Otherwise this would list the follows of a user in another tenant
+	if cfg.tenants != nil && !cfg.requireUser(ctx, w, r, userID) {
+		return
+	}
+
+	viewerID, _ := cfg.optionalUser(r)
+	limit := queryLimit(r, "limit", 50, 100)
+
+	rows, err := cfg.databaseQueries.GetFollowing(ctx, database.GetFollowingParams{
+		ViewerID:   viewerID,
+		UserID:     userID,
+		CursorTime: cursor.Time,
+		CursorID:   cursor.ID,
+		PageLimit:  int32(limit + 1),
+	})
+
+	if err != nil {
+		log.Printf("GetFollowing failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	users := make([]followResponse, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, followResponse{
+			ID:          row.ID,
+			Handle:      row.Handle.String,
+			FollowedAt:  row.FollowedAt,
+			IsFollowing: row.IsFollowing,
+		})
+	}
+
+	respond(w, r, http.StatusOK, newFollowListResponse(users, limit))
+}
+
+// Trims the extra lookahead row and turns the last row on the page into the next cursor
+func newFollowListResponse(users []followResponse, limit int) followListResponse {
+	if len(users) <= limit {
+		return followListResponse{Users: users}
+	}
+
+	users = users[:limit]
+	last := users[len(users)-1]
+
+	return followListResponse{
+		Users:      users,
+		NextCursor: pageCursor{Time: last.FollowedAt, ID: last.ID}.String(),
+	}
+}
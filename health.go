@@ -0,0 +1,26 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// Readiness, unlike /api/healthz this fails while we're shutting down or can't reach the database,
+// so load balancers stop sending us traffic without restarting the process
+func (cfg *apiConfig) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if cfg.draining.Load() {
+		respondWithJson(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if err := cfg.db.PingContext(ctx); err != nil {
+		log.Printf("Readiness check failed: %v", err)
+		respondWithJson(w, http.StatusServiceUnavailable, map[string]string{"status": "database unavailable"})
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, map[string]string{"status": "ready"})
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/language"
+	"github.com/itsmandrew/server-go/internal/moderation"
+)
+
+const (
+	// Caps on a single upload, bigger archives have to be split
+	maxImportBytes   = 10 << 20
+	maxImportRecords = 10000
+	// Chirps per CreateChirps call, all of them share one transaction
+	importBatchSize = 500
+)
+
+// A line of the upload that's ready to insert, or the reason it can't be
+type importRecord struct {
+	Line      int
+	Body      string
+	CreatedAt time.Time
+	Err       error
+}
+
+type importLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type importResponse struct {
+	Imported int               `json:"imported"`
+	Errors   []importLineError `json:"errors"`
+}
+
+var errImportTooLarge = fmt.Errorf("imports are limited to %d chirps", maxImportRecords)
+
+// POST /api/chirps/import with an NDJSON or CSV body (the same formats /api/chirps/export produces).
+// Every record goes through the moderation pipeline and is attributed to the caller whatever user_id it carries.
+// Valid records are inserted in one transaction and the rest come back in a per-line report.
+// Imported chirps are history rather than new posts, so they don't fire ChirpCreated.
+func (cfg *apiConfig) importChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+	defer body.Close()
+
+	var records []importRecord
+	if importFormat(r) == "csv" {
+		records, err = parseCSVImport(body)
+	} else {
+		records, err = parseNDJSONImport(body)
+	}
+
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		respondWithError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("imports are limited to %d bytes", maxImportBytes))
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp := importResponse{Errors: []importLineError{}}
+	var params database.CreateChirpsParams
+	now := time.Now().UTC()
+
+	for _, rec := range records {
+		// Hash the body as written, same as createChirpHandler
+		hash := moderation.ContentHash(rec.Body)
+
+		if rec.Err == nil {
+			decision := cfg.moderation.Load().Run(r.Context(), moderation.Content{UserID: userID, Body: rec.Body})
+
+			switch decision.Verdict {
+			case moderation.Reject:
+				rec.Err = errors.New(decision.Violations[len(decision.Violations)-1].Reason)
+			case moderation.Flag:
+				log.Printf("Imported chirp by %s flagged: %v", userID, decision.Violations)
+			}
+
+			rec.Body = decision.Body
+		}
+
+		if rec.Err != nil {
+			resp.Errors = append(resp.Errors, importLineError{Line: rec.Line, Error: rec.Err.Error()})
+			continue
+		}
+
+		createdAt := rec.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+
+		params.Bodies = append(params.Bodies, rec.Body)
+		params.UserIds = append(params.UserIds, userID)
+		params.ContentHashes = append(params.ContentHashes, hash)
+		params.CreatedAts = append(params.CreatedAts, createdAt.UTC())
+		params.Languages = append(params.Languages, language.Detect(rec.Body))
+	}
+
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		for start := 0; start < len(params.Bodies); start += importBatchSize {
+			end := min(start+importBatchSize, len(params.Bodies))
+
+			chirps, err := qtx.CreateChirps(ctx, database.CreateChirpsParams{
+				TenantID:      tenantFromContext(r.Context()),
+				Bodies:        params.Bodies[start:end],
+				UserIds:       params.UserIds[start:end],
+				ContentHashes: params.ContentHashes[start:end],
+				CreatedAts:    params.CreatedAts[start:end],
+				Languages:     params.Languages[start:end],
+			})
+			if err != nil {
+				return err
+			}
+
+			resp.Imported += len(chirps)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Importing chirps failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("Imported %d chirps for %s, %d lines rejected", resp.Imported, userID, len(resp.Errors))
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// ?format= wins, otherwise the Content-Type decides and anything that isn't CSV is read as NDJSON
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		return "csv"
+	}
+
+	return "ndjson"
+}
+
+// One JSON object per line, blank lines are skipped. Lines that don't parse become per-line errors rather
+// than failing the whole upload.
+func parseNDJSONImport(body io.Reader) ([]importRecord, error) {
+	var records []importRecord
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportBytes)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		if len(records) == maxImportRecords {
+			return nil, errImportTooLarge
+		}
+
+		var raw struct {
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"created_at"`
+		}
+
+		rec := importRecord{Line: line}
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil {
+			rec.Err = fmt.Errorf("invalid JSON: %w", err)
+		} else if raw.Body == "" {
+			rec.Err = errors.New("body is required")
+		} else {
+			rec.Body, rec.CreatedAt = raw.Body, raw.CreatedAt
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, scanner.Err()
+}
+
+// Needs a header row with at least a body column, created_at is optional and anything else is ignored
+func parseCSVImport(body io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	bodyCol := slices.Index(header, "body")
+	createdCol := slices.Index(header, "created_at")
+	if bodyCol < 0 {
+		return nil, errors.New("CSV header has no body column")
+	}
+
+	var records []importRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader can carry on past a malformed row, so it's reported like any other bad line
+			records = append(records, importRecord{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(records) == maxImportRecords {
+			return nil, errImportTooLarge
+		}
+
+		line, _ := reader.FieldPos(0)
+		rec := importRecord{Line: line}
+
+		switch {
+		case bodyCol >= len(row) || row[bodyCol] == "":
+			rec.Err = errors.New("body is required")
+		case createdCol >= 0 && createdCol < len(row) && row[createdCol] != "":
+			rec.Body = row[bodyCol]
+			rec.CreatedAt, err = time.Parse(time.RFC3339Nano, row[createdCol])
+			if err != nil {
+				rec.Err = errors.New("created_at must be an RFC3339 timestamp")
+			}
+		default:
+			rec.Body = row[bodyCol]
+		}
+
+		records = append(records, rec)
+	}
+}
@@ -0,0 +1,180 @@
+// Package api holds the JSON (and XML) shapes the server sends to clients. Handlers map database rows into these
+// instead of marshalling sqlc structs directly, so adding a column doesn't silently change (or leak into) the API.
+package api
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// A user as they see themselves, never includes the password hash
+type User struct {
+	XMLName     xml.Name  `json:"-" xml:"user"`
+	ID          uuid.UUID `json:"id" xml:"id"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" xml:"updated_at"`
+	Email       string    `json:"email" xml:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red" xml:"is_chirpy_red"`
+}
+
+func NewUser(u database.User) User {
+	return User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+func NewCreatedUser(u database.CreateUserRow) User {
+	return User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+func NewUserFromRow(u database.GetUserByIDNoPasswordRow) User {
+	return User{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		UpdatedAt:   u.UpdatedAt,
+		Email:       u.Email,
+		IsChirpyRed: u.IsChirpyRed,
+	}
+}
+
+// A user as anyone else sees them, Email is only set when it's the requester's own profile
+type Profile struct {
+	XMLName     xml.Name  `json:"-" xml:"profile"`
+	ID          uuid.UUID `json:"id" xml:"id"`
+	Handle      string    `json:"handle,omitempty" xml:"handle,omitempty"`
+	Email       string    `json:"email,omitempty" xml:"email,omitempty"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+	IsChirpyRed bool      `json:"is_chirpy_red" xml:"is_chirpy_red"`
+	Protected   bool      `json:"protected" xml:"protected"`
+	PinnedChirp *Chirp    `json:"pinned_chirp" xml:"pinned_chirp>chirp"`
+	// Preferences, like Email only shown to the user themselves
+	Locale           string `json:"locale,omitempty" xml:"locale,omitempty"`
+	Timezone         string `json:"timezone,omitempty" xml:"timezone,omitempty"`
+	SensitiveContent string `json:"sensitive_content,omitempty" xml:"sensitive_content,omitempty"`
+}
+
+func NewProfile(u database.GetUserProfileRow, isSelf bool) Profile {
+	p := Profile{
+		ID:          u.ID,
+		Handle:      u.Handle.String,
+		CreatedAt:   u.CreatedAt,
+		IsChirpyRed: u.IsChirpyRed,
+		Protected:   u.Protected,
+	}
+
+	if isSelf {
+		p.Email = u.Email
+		p.Locale = u.Locale
+		p.Timezone = u.Timezone
+		p.SensitiveContent = u.SensitiveContent
+	}
+
+	return p
+}
+
+type Link struct {
+	URL         string `json:"url" xml:"url"`
+	Title       string `json:"title,omitempty" xml:"title,omitempty"`
+	Description string `json:"description,omitempty" xml:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty" xml:"image_url,omitempty"`
+}
+
+func NewLink(l database.ChirpLink) Link {
+	return Link{
+		URL:         l.Url,
+		Title:       l.Title.String,
+		Description: l.Description.String,
+		ImageURL:    l.ImageUrl.String,
+	}
+}
+
+// Just enough about a chirp's author to render it without another request
+type Author struct {
+	ID          uuid.UUID `json:"id" xml:"id"`
+	Handle      string    `json:"handle,omitempty" xml:"handle,omitempty"`
+	DisplayName string    `json:"display_name,omitempty" xml:"display_name,omitempty"`
+	AvatarURL   string    `json:"avatar_url,omitempty" xml:"avatar_url,omitempty"`
+}
+
+func NewAuthor(u database.GetChirpAuthorsRow) Author {
+	return Author{
+		ID:          u.ID,
+		Handle:      u.Handle.String,
+		DisplayName: u.DisplayName.String,
+		AvatarURL:   u.AvatarUrl.String,
+	}
+}
+
+type Chirp struct {
+	XMLName        xml.Name   `json:"-" xml:"chirp"`
+	ID             uuid.UUID  `json:"id" xml:"id"`
+	CreatedAt      time.Time  `json:"created_at" xml:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" xml:"updated_at"`
+	Body           string     `json:"body" xml:"body"`
+	UserID         uuid.UUID  `json:"user_id" xml:"user_id"`
+	Author         Author     `json:"author" xml:"author"`
+	ReplyToID      *uuid.UUID `json:"reply_to_id" xml:"reply_to_id"`
+	Sensitive      bool       `json:"sensitive" xml:"sensitive"`
+	ContentWarning string     `json:"content_warning,omitempty" xml:"content_warning,omitempty"`
+	Language       string     `json:"language,omitempty" xml:"language,omitempty"`
+	Visibility     string     `json:"visibility" xml:"visibility"`
+	Links          []Link     `json:"links" xml:"links>link"`
+	LikeCount      int64      `json:"like_count" xml:"like_count"`
+	ReplyCount     int64      `json:"reply_count" xml:"reply_count"`
+	RechirpCount   int64      `json:"rechirp_count" xml:"rechirp_count"`
+	ViewCount      int64      `json:"view_count" xml:"view_count"`
+	LikedByMe      bool       `json:"liked_by_me" xml:"liked_by_me"`
+}
+
+// Maps just the chirp row, links, counts and the rest of the author are left empty for the caller to fill in
+func NewChirp(c database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserID:    c.UserID,
+		Author:    Author{ID: c.UserID},
+		Links:     []Link{},
+
+		Sensitive:      c.Sensitive,
+		ContentWarning: c.ContentWarning.String,
+		Language:       c.Language.String,
+		Visibility:     c.Visibility,
+	}
+
+	if c.ReplyToID.Valid {
+		chirp.ReplyToID = &c.ReplyToID.UUID
+	}
+
+	return chirp
+}
+
+type Tokens struct {
+	XMLName      xml.Name  `json:"-" xml:"tokens"`
+	Token        string    `json:"token" xml:"token"`
+	ExpiresAt    time.Time `json:"expires_at" xml:"expires_at"`
+	RefreshToken string    `json:"refresh_token" xml:"refresh_token"`
+	// When the refresh token stops working, it's replaced with a new one every time it's used
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at" xml:"refresh_token_expires_at"`
+}
+
+// Login returns the user alongside their tokens in one flat object
+type Login struct {
+	XMLName xml.Name `json:"-" xml:"login"`
+	User
+	Tokens
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+const testHash = "$2a$10$abcdefghijklmnopqrstuuvwxyz0123456789ABCDEFGHIJKLMNOP"
+
+func TestUserResponsesNeverContainPasswordHash(t *testing.T) {
+	now := time.Now()
+	id := uuid.New()
+
+	user := database.User{
+		ID:             id,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Email:          "someone@example.com",
+		HashedPassword: testHash,
+		Handle:         sql.NullString{String: "someone", Valid: true},
+	}
+
+	responses := map[string]any{
+		"create": NewCreatedUser(database.CreateUserRow{ID: id, CreatedAt: now, UpdatedAt: now, Email: user.Email}),
+		"update": NewUserFromRow(database.GetUserByIDNoPasswordRow{ID: id, CreatedAt: now, UpdatedAt: now, Email: user.Email}),
+		"login":  Login{User: NewUser(user), Tokens: Tokens{Token: "access", RefreshToken: "refresh"}},
+		"profile": NewProfile(database.GetUserProfileRow{
+			ID:        id,
+			CreatedAt: now,
+			Email:     user.Email,
+			Handle:    user.Handle,
+		}, true),
+	}
+
+	for name, resp := range responses {
+		body, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if strings.Contains(string(body), testHash) || strings.Contains(string(body), "hashed_password") {
+			t.Errorf("%s response leaks the password hash: %s", name, body)
+		}
+	}
+}
+
+// Catches someone adding a password field to a response type, not just the mapping functions
+func TestResponseTypesHaveNoPasswordFields(t *testing.T) {
+	types := []reflect.Type{
+		reflect.TypeOf(User{}),
+		reflect.TypeOf(Profile{}),
+		reflect.TypeOf(Login{}),
+		reflect.TypeOf(Tokens{}),
+		reflect.TypeOf(Chirp{}),
+	}
+
+	for _, typ := range types {
+		for _, field := range reflect.VisibleFields(typ) {
+			tag := strings.ToLower(field.Tag.Get("json"))
+			if strings.Contains(tag, "password") || strings.Contains(strings.ToLower(field.Name), "password") {
+				t.Errorf("%s.%s looks like a password field", typ.Name(), field.Name)
+			}
+		}
+	}
+}
+
+func TestLoginIsUserPlusTokens(t *testing.T) {
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	login := Login{
+		User:   NewUser(database.User{ID: uuid.New(), Email: "someone@example.com", IsChirpyRed: true}),
+		Tokens: Tokens{Token: "access", ExpiresAt: expires, RefreshToken: "refresh", RefreshTokenExpiresAt: expires},
+	}
+
+	body, err := json.Marshal(login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`"email":"someone@example.com"`,
+		`"is_chirpy_red":true`,
+		`"expires_at":"2030-01-01T00:00:00Z"`,
+		`"refresh_token_expires_at":"2030-01-01T00:00:00Z"`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %s in the login response, got %s", want, body)
+		}
+	}
+}
+
+func TestProfileOnlyShowsEmailToSelf(t *testing.T) {
+	row := database.GetUserProfileRow{ID: uuid.New(), Email: "someone@example.com", Locale: "fr", Timezone: "Europe/Paris"}
+
+	if p := NewProfile(row, false); p.Email != "" || p.Locale != "" || p.Timezone != "" {
+		t.Error("expected email and preferences to be hidden from other users")
+	}
+
+	if p := NewProfile(row, true); p.Email != row.Email || p.Locale != row.Locale || p.Timezone != row.Timezone {
+		t.Error("expected email and preferences on own profile")
+	}
+}
+
+func TestChirpDropsInternalColumns(t *testing.T) {
+	body, err := json.Marshal(NewChirp(database.Chirp{ID: uuid.New(), Body: "hi", ContentHash: "deadbeef"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(body), "deadbeef") {
+		t.Errorf("chirp response leaks content_hash: %s", body)
+	}
+
+	if !strings.Contains(string(body), `"links":[]`) {
+		t.Errorf("expected empty links array, got %s", body)
+	}
+}
+
+func TestChirpAlwaysHasAuthorID(t *testing.T) {
+	userID := uuid.New()
+	body, err := json.Marshal(NewChirp(database.Chirp{ID: uuid.New(), UserID: userID}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(body), `"author":{"id":"`+userID.String()+`"}`) {
+		t.Errorf("expected author with just an id before it's been loaded, got %s", body)
+	}
+}
+
+func TestResponsesMarshalToXML(t *testing.T) {
+	id := uuid.New()
+	chirp := NewChirp(database.Chirp{ID: id, Body: "hi", UserID: id})
+	chirp.Links = []Link{{URL: "https://example.com"}}
+
+	profile := NewProfile(database.GetUserProfileRow{ID: id, Email: "someone@example.com"}, true)
+	profile.PinnedChirp = &chirp
+
+	responses := map[string]any{
+		"chirp":   chirp,
+		"profile": profile,
+		"login":   Login{User: User{ID: id, Email: "someone@example.com"}, Tokens: Tokens{Token: "access"}},
+	}
+
+	for name, resp := range responses {
+		body, err := xml.Marshal(resp)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if !strings.HasPrefix(string(body), "<"+name+">") {
+			t.Errorf("expected a <%s> root element, got %s", name, body)
+		}
+	}
+
+	body, _ := xml.Marshal(chirp)
+	for _, want := range []string{"<links><link><url>https://example.com</url></link></links>", "<author><id>" + id.String() + "</id></author>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %s in %s", want, body)
+		}
+	}
+}
+
+// A page of chirps the way the store hands them back, every optional column set so nothing is skipped
+func fakeChirps(n int) []database.Chirp {
+	now := time.Now()
+	userID := uuid.New()
+	chirps := make([]database.Chirp, n)
+
+	for i := range chirps {
+		chirps[i] = database.Chirp{
+			ID:             uuid.New(),
+			CreatedAt:      now.Add(-time.Duration(i) * time.Minute),
+			UpdatedAt:      now,
+			Body:           fmt.Sprintf("Chirp number %d, about as long as the ones people actually post", i),
+			UserID:         userID,
+			ReplyToID:      uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			Sensitive:      i%10 == 0,
+			ContentWarning: sql.NullString{String: "spoilers", Valid: i%10 == 0},
+			Language:       sql.NullString{String: "en", Valid: true},
+			Visibility:     "public",
+		}
+	}
+
+	return chirps
+}
+
+// Turning a large listing into JSON, all at once and one chirp at a time the way the streamed listings write it
+func BenchmarkEncodeChirpList(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		rows := fakeChirps(n)
+
+		b.Run(fmt.Sprintf("marshal/n=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				chirps := make([]Chirp, 0, len(rows))
+				for _, row := range rows {
+					chirps = append(chirps, NewChirp(row))
+				}
+
+				if err := json.NewEncoder(io.Discard).Encode(chirps); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("stream/n=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				enc := json.NewEncoder(io.Discard)
+				for _, row := range rows {
+					if err := enc.Encode(NewChirp(row)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -39,20 +40,57 @@ func CheckPasswordHash(hash, password string) error {
 	return nil
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+// Wrong signature, bad claims, unexpected algorithm... anything that makes a token unusable wraps this
+var ErrInvalidToken = errors.New("invalid token")
+
+const defaultIssuer = "chirpy"
+
+// Everything that goes into making and checking access tokens. Issuer defaults to "chirpy",
+// Audience is only set and checked when it's configured.
+type JWTConfig struct {
+	Secret   string
+	Issuer   string
+	Audience string
+	// Clock skew allowed on exp/nbf/iat between us and whoever else validates our tokens
+	Leeway time.Duration
+}
+
+func (c JWTConfig) issuer() string {
+	if c.Issuer == "" {
+		return defaultIssuer
+	}
+	return c.Issuer
+}
+
+// An access token's claims, Scope is space separated like OAuth's scope parameter (RFC 8693)
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope,omitempty"`
+}
+
+// Makes an access token limited to scopes. With none the scope claim is left out, which means every scope.
+func (c JWTConfig) Make(userID uuid.UUID, expiresIn time.Duration, scopes ...string) (string, error) {
 
 	now := time.Now().UTC()
 
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
-		Subject:   userID.String(),
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    c.issuer(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Scope: strings.Join(scopes, " "),
+	}
+
+	if c.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{c.Audience}
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	ss, err := token.SignedString([]byte(tokenSecret))
+	ss, err := token.SignedString([]byte(c.Secret))
 
 	if err != nil {
 		log.Println(err.Error())
@@ -62,29 +100,66 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 	return ss, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+// Checks the signature and that exp, nbf, iat, iss (and aud if configured) are all present and acceptable.
+// Only HS256 is accepted, so alg=none or an RS256 token signed with our secret as the "public key" are rejected.
+func (c JWTConfig) Validate(tokenString string) (uuid.UUID, error) {
+	userID, _, err := c.ValidateScopes(tokenString)
+	return userID, err
+}
 
-	token, err := jwt.ParseWithClaims(tokenString,
-		&jwt.RegisteredClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(tokenSecret), nil
-		},
+// Validate that also returns the token's scopes. Tokens without a scope claim get every scope.
+func (c JWTConfig) ValidateScopes(tokenString string) (uuid.UUID, []string, error) {
+
+	claims := &Claims{}
+
+	// Claims are checked by hand below, v4's built-in validation has no leeway and doesn't require nbf/iat
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithoutClaimsValidation(),
 	)
 
-	var nullID uuid.UUID
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(c.Secret), nil
+	})
 
 	if err != nil {
-		log.Fatal(err)
-		return nullID, err
+		return uuid.UUID{}, nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	now := time.Now()
+
+	switch {
+	case !claims.VerifyExpiresAt(now.Add(-c.Leeway), true):
+		return uuid.UUID{}, nil, fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	case !claims.VerifyNotBefore(now.Add(c.Leeway), true):
+		return uuid.UUID{}, nil, fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	case !claims.VerifyIssuedAt(now.Add(c.Leeway), true):
+		return uuid.UUID{}, nil, fmt.Errorf("%w: token was issued in the future", ErrInvalidToken)
+	case !claims.VerifyIssuer(c.issuer(), true):
+		return uuid.UUID{}, nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	case c.Audience != "" && !claims.VerifyAudience(c.Audience, true):
+		return uuid.UUID{}, nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
 	}
 
-	subject := token.Claims.(*jwt.RegisteredClaims).Subject
-	uid, err := uuid.Parse(subject)
+	uid, err := uuid.Parse(claims.Subject)
 
 	if err != nil {
-		return uuid.UUID{}, err
+		return uuid.UUID{}, nil, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+
+	if claims.Scope == "" {
+		return uid, Scopes, nil
 	}
-	return uid, nil
+	return uid, strings.Fields(claims.Scope), nil
+}
+
+// Shorthand for a JWTConfig with just a secret and the default issuer
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return JWTConfig{Secret: tokenSecret}.Make(userID, expiresIn)
+}
+
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return JWTConfig{Secret: tokenSecret}.Validate(tokenString)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -101,7 +176,9 @@ func GetBearerToken(headers http.Header) (string, error) {
 func MakeRefreshToken() (string, error) {
 
 	key := make([]byte, 32)
-	rand.Read(key)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
 
 	encodedStr := hex.EncodeToString(key)
 	return encodedStr, nil
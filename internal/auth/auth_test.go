@@ -1,12 +1,17 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHashedPasswordAndCheck(t *testing.T) {
@@ -94,3 +99,224 @@ func TestGetBearerTokenFail(t *testing.T) {
 		t.Fatalf("expected error for missing Authorization header, got token %q", token)
 	}
 }
+
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.RegisteredClaims) string {
+	t.Helper()
+
+	ss, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return ss
+}
+
+func TestValidateJWTRejectsBadClaims(t *testing.T) {
+	cfg := JWTConfig{Secret: "my-super-secret", Audience: "chirpy-api"}
+	now := time.Now()
+	userID := uuid.New()
+
+	valid := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Audience:  jwt.ClaimStrings{"chirpy-api"},
+		Subject:   userID.String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+
+	if _, err := cfg.Validate(signClaims(t, jwt.SigningMethodHS256, []byte(cfg.Secret), valid)); err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+
+	tests := map[string]func(c *jwt.RegisteredClaims){
+		"expired":       func(c *jwt.RegisteredClaims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-time.Minute)) },
+		"not yet valid": func(c *jwt.RegisteredClaims) { c.NotBefore = jwt.NewNumericDate(now.Add(time.Hour)) },
+		"missing nbf":   func(c *jwt.RegisteredClaims) { c.NotBefore = nil },
+		"future iat":    func(c *jwt.RegisteredClaims) { c.IssuedAt = jwt.NewNumericDate(now.Add(time.Hour)) },
+		"wrong issuer":  func(c *jwt.RegisteredClaims) { c.Issuer = "someone-else" },
+		"wrong aud":     func(c *jwt.RegisteredClaims) { c.Audience = jwt.ClaimStrings{"other-api"} },
+		"missing aud":   func(c *jwt.RegisteredClaims) { c.Audience = nil },
+	}
+
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := valid
+			mutate(&claims)
+
+			_, err := cfg.Validate(signClaims(t, jwt.SigningMethodHS256, []byte(cfg.Secret), claims))
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateJWTRejectsUnexpectedAlgorithms(t *testing.T) {
+	cfg := JWTConfig{Secret: "my-super-secret"}
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Subject:   uuid.NewString(),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	none := signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims)
+	if _, err := cfg.Validate(none); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected alg=none to be rejected, got %v", err)
+	}
+
+	hs512 := signClaims(t, jwt.SigningMethodHS512, []byte(cfg.Secret), claims)
+	if _, err := cfg.Validate(hs512); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected HS512 to be rejected, got %v", err)
+	}
+}
+
+func TestValidateJWTLeeway(t *testing.T) {
+	cfg := JWTConfig{Secret: "my-super-secret", Leeway: time.Minute}
+	now := time.Now()
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    "chirpy",
+		Subject:   uuid.NewString(),
+		IssuedAt:  jwt.NewNumericDate(now.Add(30 * time.Second)),
+		NotBefore: jwt.NewNumericDate(now.Add(30 * time.Second)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(-30 * time.Second)),
+	}
+
+	if _, err := cfg.Validate(signClaims(t, jwt.SigningMethodHS256, []byte(cfg.Secret), claims)); err != nil {
+		t.Errorf("expected skew within leeway to pass, got %v", err)
+	}
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("expected %q to be recognised as a personal access token", token)
+	}
+
+	accessToken, _ := MakeJWT(uuid.New(), "secret", time.Minute)
+	if IsPersonalAccessToken(accessToken) {
+		t.Error("expected a JWT not to look like a personal access token")
+	}
+
+	other, _ := MakePersonalAccessToken()
+	if token == other {
+		t.Error("expected every token to be different")
+	}
+
+	hash := HashPersonalAccessToken(token)
+	if hash != HashPersonalAccessToken(token) || hash == HashPersonalAccessToken(other) {
+		t.Error("expected hashes to be stable per token and differ between tokens")
+	}
+
+	if strings.Contains(hash, token) {
+		t.Error("expected the hash not to contain the token")
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	// The example from RFC 7636 appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if !ValidPKCEChallenge(challenge) {
+		t.Errorf("expected %q to be a valid challenge", challenge)
+	}
+
+	if !VerifyPKCE(verifier, challenge) {
+		t.Error("expected the RFC example to verify")
+	}
+
+	if VerifyPKCE(verifier+"x", challenge) {
+		t.Error("expected a different verifier to fail")
+	}
+
+	// plain method: the challenge is the verifier itself
+	if VerifyPKCE(verifier, verifier) {
+		t.Error("expected the plain method not to be accepted")
+	}
+
+	if VerifyPKCE("short", challenge) || ValidPKCEChallenge("short") {
+		t.Error("expected verifiers and challenges of the wrong length to be rejected")
+	}
+}
+
+func TestJWTScopes(t *testing.T) {
+	cfg := JWTConfig{Secret: "secret"}
+	userID := uuid.New()
+
+	limited, err := cfg.Make(userID, time.Minute, ScopeChirpsRead, ScopeUsersWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotID, scopes, err := cfg.ValidateScopes(limited)
+	if err != nil {
+		t.Fatalf("expected a valid token, got %v", err)
+	}
+
+	if gotID != userID {
+		t.Errorf("expected user %s, got %s", userID, gotID)
+	}
+
+	if !slices.Equal(scopes, []string{ScopeChirpsRead, ScopeUsersWrite}) {
+		t.Errorf("expected the scopes the token was made with, got %v", scopes)
+	}
+
+	// Tokens from before scopes existed carry no claim and keep full access
+	unscoped, _ := cfg.Make(userID, time.Minute)
+	if _, scopes, _ := cfg.ValidateScopes(unscoped); !slices.Equal(scopes, Scopes) {
+		t.Errorf("expected a token without a scope claim to get every scope, got %v", scopes)
+	}
+}
+
+// Every authenticated request validates its access token, so this is on the hot path
+func BenchmarkValidateScopes(b *testing.B) {
+	cfg := JWTConfig{Secret: "secret", Audience: "chirpy-api", Leeway: 30 * time.Second}
+	token, err := cfg.Make(uuid.New(), time.Hour, ScopeChirpsRead, ScopeChirpsWrite)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for b.Loop() {
+		if _, _, err := cfg.ValidateScopes(token); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMakeJWT(b *testing.B) {
+	cfg := JWTConfig{Secret: "secret"}
+	userID := uuid.New()
+
+	for b.Loop() {
+		if _, err := cfg.Make(userID, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// What a login costs at each bcrypt cost, HashedPassword uses bcrypt.DefaultCost. Each step up doubles it.
+func BenchmarkBcryptCost(b *testing.B) {
+	password := []byte("myS3cret!")
+
+	for _, cost := range []int{bcrypt.MinCost, 8, bcrypt.DefaultCost, 12} {
+		hash, err := bcrypt.GenerateFromPassword(password, cost)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("cost=%d", cost), func(b *testing.B) {
+			for b.Loop() {
+				if err := bcrypt.CompareHashAndPassword(hash, password); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "slices"
+
+// What a token is allowed to do
+const (
+	ScopeChirpsRead  = "chirps:read"
+	ScopeChirpsWrite = "chirps:write"
+	ScopeUsersWrite  = "users:write"
+	ScopeAdmin       = "admin"
+)
+
+// Every scope a token can be given
+var Scopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeUsersWrite, ScopeAdmin}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
@@ -0,0 +1,257 @@
+// Package breaker is a circuit breaker for groups of database calls. After enough consecutive failures a group's
+// circuit opens and its calls fail straight away instead of waiting on a database that isn't answering. Once the
+// cooldown has passed a single probe call is let through: the circuit closes if it works and reopens if it doesn't.
+package breaker
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Matches every error returned while a circuit is open, with errors.Is
+var ErrOpen = errors.New("circuit breaker open")
+
+// Returned instead of running a call while its group's circuit is open
+type OpenError struct {
+	Group string
+	// The failure that last opened the circuit
+	Cause error
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for %s, last failure: %v", e.Group, e.Cause)
+}
+
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	// Cooldown over, a probe call decides whether to close again
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// Beyond this many groups the rest share one circuit, so ad hoc SQL can't grow the map forever
+const maxGroups = 500
+
+const otherGroup = "(other)"
+
+type Options struct {
+	// Consecutive failures that open a group's circuit, 5 when 0
+	Threshold int
+	// How long an open circuit rejects calls before letting a probe through, 10s when 0
+	Cooldown time.Duration
+	// Whether err means the database is in trouble, as opposed to e.g. a constraint violation. nil counts every error.
+	IsFailure func(err error) bool
+	// The group a query belongs to, nil gives every distinct query its own
+	Group func(query string) string
+}
+
+// A group's circuit as of a Snapshot
+type Stats struct {
+	Group    string
+	State    State
+	Failures int
+	// Times the circuit has opened
+	Trips uint64
+	// Calls failed fast while it was open
+	Rejected uint64
+}
+
+type circuit struct {
+	state    State
+	failures int
+	openedAt time.Time
+	cause    error
+	// A half-open circuit's probe is in flight, everything else is rejected until it's done
+	probing  bool
+	trips    uint64
+	rejected uint64
+}
+
+// Circuits for every group, created as calls come in. Thread-safe.
+type Set struct {
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+func NewSet(opts Options) *Set {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 10 * time.Second
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(error) bool { return true }
+	}
+	if opts.Group == nil {
+		opts.Group = func(query string) string { return query }
+	}
+
+	return &Set{opts: opts, now: time.Now, circuits: make(map[string]*circuit)}
+}
+
+// Must be called with s.mu held
+func (s *Set) circuit(group string) (string, *circuit) {
+	c, ok := s.circuits[group]
+	if ok {
+		return group, c
+	}
+
+	if len(s.circuits) >= maxGroups {
+		group = otherGroup
+		if c, ok = s.circuits[group]; ok {
+			return group, c
+		}
+	}
+
+	c = &circuit{}
+	s.circuits[group] = c
+	return group, c
+}
+
+// Checks whether a call in group may go ahead, an *OpenError if not. Every nil return must be followed by Done.
+func (s *Set) Allow(group string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, c := s.circuit(group)
+
+	switch c.state {
+	case Open:
+		if s.now().Sub(c.openedAt) < s.opts.Cooldown {
+			c.rejected++
+			return &OpenError{Group: group, Cause: c.cause}
+		}
+		c.state = HalfOpen
+		c.probing = true
+	case HalfOpen:
+		if c.probing {
+			c.rejected++
+			return &OpenError{Group: group, Cause: c.cause}
+		}
+		c.probing = true
+	}
+
+	return nil
+}
+
+// Records how an allowed call in group went
+func (s *Set) Done(group string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, c := s.circuit(group)
+
+	// Neither says anything about the database: the driver passing on a call, or the caller giving up on it
+	if errors.Is(err, driver.ErrSkip) || errors.Is(err, context.Canceled) {
+		c.probing = false
+		return
+	}
+
+	switch {
+	case err == nil || !s.opts.IsFailure(err):
+		c.state, c.failures, c.probing = Closed, 0, false
+	case c.state == HalfOpen:
+		s.trip(c, err)
+	default:
+		c.failures++
+		if c.failures >= s.opts.Threshold {
+			s.trip(c, err)
+		}
+	}
+}
+
+func (s *Set) trip(c *circuit, cause error) {
+	c.state = Open
+	c.openedAt = s.now()
+	c.cause = cause
+	c.probing = false
+	c.trips++
+}
+
+// Returns every group's circuit, open ones first then by name
+func (s *Set) Snapshot() []Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Stats, 0, len(s.circuits))
+	for group, c := range s.circuits {
+		result = append(result, Stats{
+			Group:    group,
+			State:    c.state,
+			Failures: c.failures,
+			Trips:    c.trips,
+			Rejected: c.rejected,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if (result[i].State == Closed) != (result[j].State == Closed) {
+			return result[j].State == Closed
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+// Writes every circuit's state and counters in the Prometheus text exposition format
+func (s *Set) WritePrometheus(w io.Writer) error {
+	snapshot := s.Snapshot()
+	var b strings.Builder
+
+	b.WriteString("# HELP chirpy_db_breaker_open Whether a query group's circuit breaker is open (1) or half open (0.5).\n")
+	b.WriteString("# TYPE chirpy_db_breaker_open gauge\n")
+	for _, st := range snapshot {
+		value := 0.0
+		switch st.State {
+		case Open:
+			value = 1
+		case HalfOpen:
+			value = 0.5
+		}
+		fmt.Fprintf(&b, "chirpy_db_breaker_open{group=%q} %g\n", st.Group, value)
+	}
+
+	b.WriteString("# HELP chirpy_db_breaker_trips_total Times a query group's circuit breaker has opened.\n")
+	b.WriteString("# TYPE chirpy_db_breaker_trips_total counter\n")
+	for _, st := range snapshot {
+		fmt.Fprintf(&b, "chirpy_db_breaker_trips_total{group=%q} %d\n", st.Group, st.Trips)
+	}
+
+	b.WriteString("# HELP chirpy_db_breaker_rejected_total Calls failed fast by an open circuit breaker.\n")
+	b.WriteString("# TYPE chirpy_db_breaker_rejected_total counter\n")
+	for _, st := range snapshot {
+		fmt.Fprintf(&b, "chirpy_db_breaker_rejected_total{group=%q} %d\n", st.Group, st.Rejected)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,153 @@
+package breaker
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("connection refused")
+
+func newTestSet(now *time.Time) *Set {
+	s := NewSet(Options{Threshold: 3, Cooldown: time.Minute})
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestSetTripsAfterThreshold(t *testing.T) {
+	now := time.Now()
+	s := newTestSet(&now)
+
+	for range 3 {
+		if err := s.Allow("q"); err != nil {
+			t.Fatalf("expected the call through, got %v", err)
+		}
+		s.Done("q", errDown)
+	}
+
+	err := s.Allow("q")
+	if !errors.Is(err, ErrOpen) || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("expected an open circuit naming the cause, got %v", err)
+	}
+
+	// Other groups are unaffected
+	if err := s.Allow("other"); err != nil {
+		t.Errorf("expected another group through, got %v", err)
+	}
+
+	st := s.Snapshot()[0]
+	if st.Group != "q" || st.State != Open || st.Trips != 1 || st.Rejected != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestSetSuccessResetsFailures(t *testing.T) {
+	now := time.Now()
+	s := newTestSet(&now)
+
+	for _, err := range []error{errDown, errDown, nil, errDown, errDown} {
+		s.Allow("q")
+		s.Done("q", err)
+	}
+
+	if err := s.Allow("q"); err != nil {
+		t.Errorf("expected the circuit closed, got %v", err)
+	}
+}
+
+func TestSetHalfOpenProbe(t *testing.T) {
+	now := time.Now()
+	s := newTestSet(&now)
+
+	for range 3 {
+		s.Allow("q")
+		s.Done("q", errDown)
+	}
+
+	now = now.Add(time.Minute)
+
+	if err := s.Allow("q"); err != nil {
+		t.Fatalf("expected a probe through after the cooldown, got %v", err)
+	}
+
+	// Only one probe at a time
+	if err := s.Allow("q"); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected calls rejected while probing, got %v", err)
+	}
+
+	// A failed probe reopens straight away
+	s.Done("q", errDown)
+	if err := s.Allow("q"); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expected the circuit reopened, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	s.Allow("q")
+	s.Done("q", nil)
+
+	if err := s.Allow("q"); err != nil {
+		t.Errorf("expected a good probe to close the circuit, got %v", err)
+	}
+}
+
+func TestSetIgnoresNonFailures(t *testing.T) {
+	now := time.Now()
+	s := NewSet(Options{Threshold: 1, IsFailure: func(err error) bool { return errors.Is(err, errDown) }})
+	s.now = func() time.Time { return now }
+
+	for _, err := range []error{errors.New("duplicate key"), context.Canceled, driver.ErrSkip} {
+		s.Allow("q")
+		s.Done("q", err)
+	}
+
+	if err := s.Allow("q"); err != nil {
+		t.Errorf("expected the circuit closed, got %v", err)
+	}
+}
+
+type downConnector struct{ calls int }
+
+func (c *downConnector) Connect(context.Context) (driver.Conn, error) {
+	c.calls++
+	return nil, errDown
+}
+
+func (c *downConnector) Driver() driver.Driver { return nil }
+
+func TestWrapFailsFastWhenDown(t *testing.T) {
+	inner := &downConnector{}
+	db := sql.OpenDB(Wrap(inner, NewSet(Options{Threshold: 2, Cooldown: time.Minute})))
+	defer db.Close()
+
+	for range 5 {
+		db.PingContext(context.Background())
+	}
+
+	if inner.calls != 2 {
+		t.Errorf("expected connecting to stop after 2 failures, got %d attempts", inner.calls)
+	}
+
+	if err := db.PingContext(context.Background()); !errors.Is(err, ErrOpen) {
+		t.Errorf("expected the open circuit error, got %v", err)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	now := time.Now()
+	s := newTestSet(&now)
+	s.Allow("GetChirps")
+	s.Done("GetChirps", nil)
+
+	var b strings.Builder
+	if err := s.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), `chirpy_db_breaker_open{group="GetChirps"} 0`) {
+		t.Errorf("unexpected output:\n%s", b.String())
+	}
+}
@@ -0,0 +1,176 @@
+package breaker
+
+import (
+	"context"
+	"database/sql/driver"
+)
+
+// Group new connections are made under. While it's open no connections are attempted, which is what stops
+// requests waiting on a database that's gone away entirely.
+const ConnectGroup = "connect"
+
+// Wraps a driver's connector so connecting and every query go through the circuits in s. Use it with sql.OpenDB.
+func Wrap(c driver.Connector, s *Set) driver.Connector {
+	return &connector{Connector: c, set: s}
+}
+
+type connector struct {
+	driver.Connector
+	set *Set
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.set.Allow(ConnectGroup); err != nil {
+		return nil, err
+	}
+
+	inner, err := c.Connector.Connect(ctx)
+	c.set.Done(ConnectGroup, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{Conn: inner, set: c.set}, nil
+}
+
+// Runs fn if query's circuit allows it, recording the outcome
+func (s *Set) call(query string, fn func() error) error {
+	group := s.opts.Group(query)
+	if err := s.Allow(group); err != nil {
+		return err
+	}
+
+	err := fn()
+	s.Done(group, err)
+	return err
+}
+
+// Passes everything through to the driver's connection, guarding queries with their circuits.
+// Optional interfaces the driver doesn't implement fall back the way database/sql would.
+type conn struct {
+	driver.Conn
+	set *Set
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var s driver.Stmt
+	err := c.set.call(query, func() (err error) {
+		if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+			s, err = p.PrepareContext(ctx, query)
+		} else {
+			s, err = c.Conn.Prepare(query)
+		}
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{Stmt: s, query: query, set: c.set}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+
+	return c.Conn.Begin()
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	var rows driver.Rows
+	err := c.set.call(query, func() (err error) {
+		rows, err = q.QueryContext(ctx, query, args)
+		return err
+	})
+
+	return rows, err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	var result driver.Result
+	err := c.set.call(query, func() (err error) {
+		result, err = e.ExecContext(ctx, query, args)
+		return err
+	})
+
+	return result, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+type stmt struct {
+	driver.Stmt
+	query string
+	set   *Set
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	var result driver.Result
+	err := s.set.call(s.query, func() (err error) {
+		if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+			result, err = e.ExecContext(ctx, args)
+		} else {
+			result, err = s.Stmt.Exec(values(args))
+		}
+		return err
+	})
+
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	var rows driver.Rows
+	err := s.set.call(s.query, func() (err error) {
+		if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = q.QueryContext(ctx, args)
+		} else {
+			rows, err = s.Stmt.Query(values(args))
+		}
+		return err
+	})
+
+	return rows, err
+}
+
+func values(named []driver.NamedValue) []driver.Value {
+	args := make([]driver.Value, len(named))
+	for i, nv := range named {
+		args[i] = nv.Value
+	}
+	return args
+}
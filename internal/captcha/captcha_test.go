@@ -0,0 +1,59 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case r.PostForm.Get("secret") != "s3cret":
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-secret"]}`))
+		case r.PostForm.Get("response") == "good" && r.PostForm.Get("remoteip") == "203.0.113.7":
+			w.Write([]byte(`{"success": true}`))
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	v := &Verifier{URL: srv.URL, Secret: "s3cret", Client: srv.Client()}
+	ctx := context.Background()
+
+	if err := v.Verify(ctx, "good", "203.0.113.7"); err != nil {
+		t.Errorf("expected good token to pass, got %v", err)
+	}
+
+	for _, token := range []string{"bad", ""} {
+		if err := v.Verify(ctx, token, "203.0.113.7"); !errors.Is(err, ErrFailed) {
+			t.Errorf("expected ErrFailed for %q, got %v", token, err)
+		}
+	}
+
+	v.Secret = "wrong"
+	if err := v.Verify(ctx, "good", "203.0.113.7"); err == nil || errors.Is(err, ErrFailed) {
+		t.Errorf("expected a bad secret to be a provider error, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if v, err := New("turnstile", "s"); err != nil || v.URL != TurnstileURL {
+		t.Errorf("unexpected turnstile verifier %+v, %v", v, err)
+	}
+
+	if _, err := New("recaptcha", "s"); err == nil {
+		t.Error("expected unknown provider to fail")
+	}
+
+	if _, err := New("hcaptcha", ""); err == nil {
+		t.Error("expected empty secret to fail")
+	}
+}
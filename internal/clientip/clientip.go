@@ -0,0 +1,118 @@
+// Package clientip works out the real client address for requests that came through reverse proxies.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Only trusts forwarding headers when the request actually came from one of the configured proxies,
+// otherwise anyone could claim any IP by sending X-Forwarded-For themselves
+type Resolver struct {
+	trusted   []netip.Prefix
+	trustUnix bool
+}
+
+// Takes CIDRs ("10.0.0.0/8") or bare IPs, blank entries are skipped so a split empty env var is fine.
+// "unix" trusts whatever connected over a unix socket, where the peer has no IP to check.
+func NewResolver(proxies []string) (*Resolver, error) {
+	r := &Resolver{}
+
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if p == "unix" {
+			r.trustUnix = true
+			continue
+		}
+
+		if !strings.Contains(p, "/") {
+			addr, err := netip.ParseAddr(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the client IP for req. X-Forwarded-For is walked right to left, since each proxy appends the
+// address it got the request from, and the first hop that isn't one of ours is the client.
+// X-Real-IP is the fallback for proxies that only set that.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer := peerAddr(req)
+
+	addr, err := netip.ParseAddr(peer)
+	trusted := err == nil && r.isTrusted(addr)
+	if err != nil && r.trustUnix && isUnixPeer(peer) {
+		trusted = true
+	}
+
+	if !trusted {
+		return peer
+	}
+
+	if forwarded := req.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				// Garbage in the chain, don't trust anything further left of it
+				break
+			}
+
+			client = hop.Unmap().String()
+			if !r.isTrusted(hop) {
+				break
+			}
+		}
+
+		return client
+	}
+
+	if realIP, err := netip.ParseAddr(strings.TrimSpace(req.Header.Get("X-Real-IP"))); err == nil {
+		return realIP.Unmap().String()
+	}
+
+	return peer
+}
+
+// Unix socket peers are unnamed, net/http's RemoteAddr for them is "" or "@"
+func isUnixPeer(peer string) bool {
+	return peer == "" || peer == "@"
+}
+
+func peerAddr(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,74 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewResolver([]string{"10.0.0.0/8", "192.168.1.1", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		realIP     string
+		want       string
+	}{
+		{"no proxy", "203.0.113.7:1234", "", "", "203.0.113.7"},
+		{"untrusted peer can't spoof", "203.0.113.7:1234", "1.2.3.4", "", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.5:1234", "198.51.100.2", "", "198.51.100.2"},
+		{"client-supplied prefix ignored", "10.0.0.5:1234", "1.2.3.4, 198.51.100.2", "", "198.51.100.2"},
+		{"proxy chain", "10.0.0.5:1234", "198.51.100.2, 192.168.1.1, 10.1.1.1", "", "198.51.100.2"},
+		{"garbage hop", "10.0.0.5:1234", "198.51.100.2, nonsense", "", "10.0.0.5"},
+		{"real ip fallback", "10.0.0.5:1234", "", "198.51.100.9", "198.51.100.9"},
+		{"trusted with no headers", "10.0.0.5:1234", "", "", "10.0.0.5"},
+		{"ipv6 peer", "[2001:db8::1]:443", "1.2.3.4", "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := resolver.ClientIP(req); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnixSocketPeers(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "@"
+	req.Header.Set("X-Forwarded-For", "198.51.100.2")
+
+	untrusting, _ := NewResolver([]string{"10.0.0.0/8"})
+	if got := untrusting.ClientIP(req); got != "@" {
+		t.Errorf("expected unix peers to be untrusted by default, got %q", got)
+	}
+
+	trusting, _ := NewResolver([]string{"unix"})
+	if got := trusting.ClientIP(req); got != "198.51.100.2" {
+		t.Errorf("expected forwarded client behind a trusted unix socket, got %q", got)
+	}
+}
+
+func TestNewResolverRejectsBadEntries(t *testing.T) {
+	if _, err := NewResolver([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected error for invalid CIDR")
+	}
+
+	if _, err := NewResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("expected error for invalid IP")
+	}
+}
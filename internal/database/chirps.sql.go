@@ -7,25 +7,82 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countRecentChirpsByUser = `-- name: CountRecentChirpsByUser :one
+SELECT COUNT(*)
+FROM chirps
+WHERE user_id = $1 AND created_at >= $2
+`
+
+type CountRecentChirpsByUserParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (q *Queries) CountRecentChirpsByUser(ctx context.Context, arg CountRecentChirpsByUserParams) (int64, error) {
+	row := q.queryRow(ctx, q.countRecentChirpsByUserStmt, countRecentChirpsByUser, arg.UserID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countRecentDuplicateChirps = `-- name: CountRecentDuplicateChirps :one
+SELECT COUNT(*)
+FROM chirps
+WHERE user_id = $1 AND content_hash = $2 AND created_at >= $3
+`
+
+type CountRecentDuplicateChirpsParams struct {
+	UserID      uuid.UUID `json:"user_id"`
+	ContentHash string    `json:"content_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (q *Queries) CountRecentDuplicateChirps(ctx context.Context, arg CountRecentDuplicateChirpsParams) (int64, error) {
+	row := q.queryRow(ctx, q.countRecentDuplicateChirpsStmt, countRecentDuplicateChirps, arg.UserID, arg.ContentHash, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7, $8, $9
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
 `
 
 type CreateChirpParams struct {
-	Body   string    `json:"body"`
-	UserID uuid.UUID `json:"user_id"`
+	Body           string         `json:"body"`
+	UserID         uuid.UUID      `json:"user_id"`
+	ContentHash    string         `json:"content_hash"`
+	ReplyToID      uuid.NullUUID  `json:"reply_to_id"`
+	TenantID       uuid.UUID      `json:"tenant_id"`
+	Sensitive      bool           `json:"sensitive"`
+	ContentWarning sql.NullString `json:"content_warning"`
+	Language       sql.NullString `json:"language"`
+	Visibility     string         `json:"visibility"`
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.queryRow(ctx, q.createChirpStmt, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ContentHash,
+		arg.ReplyToID,
+		arg.TenantID,
+		arg.Sensitive,
+		arg.ContentWarning,
+		arg.Language,
+		arg.Visibility,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,10 +90,83 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ContentHash,
+		&i.ReplyToID,
+		&i.TenantID,
+		&i.Sensitive,
+		&i.ContentWarning,
+		&i.Language,
+		&i.Visibility,
 	)
 	return i, err
 }
 
+const createChirps = `-- name: CreateChirps :many
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, content_hash, tenant_id, language)
+SELECT gen_random_uuid(), c.created_at, c.created_at, c.body, c.user_id, c.content_hash, $1::uuid,
+    NULLIF(c.language, '')
+FROM unnest(
+    $2::text[],
+    $3::uuid[],
+    $4::text[],
+    $5::timestamp[],
+    $6::text[]
+) AS c(body, user_id, content_hash, created_at, language)
+RETURNING id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
+`
+
+type CreateChirpsParams struct {
+	TenantID      uuid.UUID   `json:"tenant_id"`
+	Bodies        []string    `json:"bodies"`
+	UserIds       []uuid.UUID `json:"user_ids"`
+	ContentHashes []string    `json:"content_hashes"`
+	CreatedAts    []time.Time `json:"created_ats"`
+	Languages     []string    `json:"languages"`
+}
+
+func (q *Queries) CreateChirps(ctx context.Context, arg CreateChirpsParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.createChirpsStmt, createChirps,
+		arg.TenantID,
+		pq.Array(arg.Bodies),
+		pq.Array(arg.UserIds),
+		pq.Array(arg.ContentHashes),
+		pq.Array(arg.CreatedAts),
+		pq.Array(arg.Languages),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ContentHash,
+			&i.ReplyToID,
+			&i.TenantID,
+			&i.Sensitive,
+			&i.ContentWarning,
+			&i.Language,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteChirpByID = `-- name: DeleteChirpByID :exec
 DELETE 
 FROM chirps 
@@ -44,18 +174,323 @@ WHERE id = $1
 `
 
 func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteChirpByID, id)
+	_, err := q.exec(ctx, q.deleteChirpByIDStmt, deleteChirpByID, id)
+	return err
+}
+
+const deleteChirps = `-- name: DeleteChirps :exec
+TRUNCATE TABLE chirps CASCADE
+`
+
+func (q *Queries) DeleteChirps(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteChirpsStmt, deleteChirps)
 	return err
 }
 
+const deleteChirpsForTenant = `-- name: DeleteChirpsForTenant :exec
+DELETE FROM chirps
+WHERE tenant_id = $1
+`
+
+func (q *Queries) DeleteChirpsForTenant(ctx context.Context, tenantID uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteChirpsForTenantStmt, deleteChirpsForTenant, tenantID)
+	return err
+}
+
+const getChirpAuthors = `-- name: GetChirpAuthors :many
+SELECT DISTINCT u.id, u.handle, u.display_name, u.avatar_url
+FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = ANY($1::uuid[])
+`
+
+type GetChirpAuthorsRow struct {
+	ID          uuid.UUID      `json:"id"`
+	Handle      sql.NullString `json:"handle"`
+	DisplayName sql.NullString `json:"display_name"`
+	AvatarUrl   sql.NullString `json:"avatar_url"`
+}
+
+func (q *Queries) GetChirpAuthors(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpAuthorsRow, error) {
+	rows, err := q.query(ctx, q.getChirpAuthorsStmt, getChirpAuthors, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAuthorsRow
+	for rows.Next() {
+		var i GetChirpAuthorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.DisplayName,
+			&i.AvatarUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
+FROM chirps
+WHERE tenant_id = $1
+    AND created_at >= $2::timestamp AND created_at < $3::timestamp
+    AND (created_at, id) > ($4::timestamp, $5::uuid)
+    AND (user_id = $6::uuid
+        OR user_id NOT IN (
+            SELECT id FROM users
+            WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+                OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+        ))
+    AND (user_id = $6::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = $6::uuid)))
+    AND ($7::text = '' OR language = $7::text)
+ORDER BY created_at ASC, id ASC
+LIMIT $8
+`
+
+type GetChirpsParams struct {
+	TenantID   uuid.UUID `json:"tenant_id"`
+	Since      time.Time `json:"since"`
+	Before     time.Time `json:"before"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   uuid.UUID `json:"cursor_id"`
+	ViewerID   uuid.UUID `json:"viewer_id"`
+	Language   string    `json:"language"`
+	PageLimit  int32     `json:"page_limit"`
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.getChirpsStmt, getChirps,
+		arg.TenantID,
+		arg.Since,
+		arg.Before,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.Language,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ContentHash,
+			&i.ReplyToID,
+			&i.TenantID,
+			&i.Sensitive,
+			&i.ContentWarning,
+			&i.Language,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
+FROM chirps
+WHERE id = ANY($1::uuid[]) AND tenant_id = $2
+    AND (user_id = $3::uuid
+        OR user_id NOT IN (
+            SELECT id FROM users
+            WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+                OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+        ))
+    AND (user_id = $3::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = $3::uuid)))
+`
+
+type GetChirpsByIDsParams struct {
+	Ids      []uuid.UUID `json:"ids"`
+	TenantID uuid.UUID   `json:"tenant_id"`
+	ViewerID uuid.UUID   `json:"viewer_id"`
+}
+
+// The chirps in ids that viewer_id can see, in no particular order
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.getChirpsByIDsStmt, getChirpsByIDs,
+		pq.Array(arg.Ids),
+		arg.TenantID,
+		arg.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ContentHash,
+			&i.ReplyToID,
+			&i.TenantID,
+			&i.Sensitive,
+			&i.ContentWarning,
+			&i.Language,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByUser = `-- name: GetChirpsByUser :many
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
+FROM chirps
+WHERE user_id = $1 AND tenant_id = $2
+    AND (created_at, id) < ($3::timestamp, $4::uuid)
+    AND (user_id = $5::uuid OR user_id NOT IN (
+        SELECT id FROM users
+        WHERE shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+    ))
+    AND ($6::text = '' OR language = $6::text)
+    AND (user_id = $5::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = $5::uuid)))
+ORDER BY created_at DESC, id DESC
+LIMIT $7
+`
+
+type GetChirpsByUserParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	TenantID   uuid.UUID `json:"tenant_id"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   uuid.UUID `json:"cursor_id"`
+	ViewerID   uuid.UUID `json:"viewer_id"`
+	Language   string    `json:"language"`
+	PageLimit  int32     `json:"page_limit"`
+}
+
+func (q *Queries) GetChirpsByUser(ctx context.Context, arg GetChirpsByUserParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.getChirpsByUserStmt, getChirpsByUser,
+		arg.UserID,
+		arg.TenantID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.Language,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ContentHash,
+			&i.ReplyToID,
+			&i.TenantID,
+			&i.Sensitive,
+			&i.ContentWarning,
+			&i.Language,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByUserAsc = `-- name: GetChirpsByUserAsc :many
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
 FROM chirps
-ORDER BY created_at ASC
+WHERE user_id = $1 AND tenant_id = $2
+    AND (created_at, id) > ($3::timestamp, $4::uuid)
+    AND (user_id = $5::uuid OR user_id NOT IN (
+        SELECT id FROM users
+        WHERE shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+    ))
+    AND ($6::text = '' OR language = $6::text)
+    AND (user_id = $5::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = $5::uuid)))
+ORDER BY created_at ASC, id ASC
+LIMIT $7
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsByUserAscParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	TenantID   uuid.UUID `json:"tenant_id"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   uuid.UUID `json:"cursor_id"`
+	ViewerID   uuid.UUID `json:"viewer_id"`
+	Language   string    `json:"language"`
+	PageLimit  int32     `json:"page_limit"`
+}
+
+func (q *Queries) GetChirpsByUserAsc(ctx context.Context, arg GetChirpsByUserAscParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.getChirpsByUserAscStmt, getChirpsByUserAsc,
+		arg.UserID,
+		arg.TenantID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.Language,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +504,70 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ContentHash,
+			&i.ReplyToID,
+			&i.TenantID,
+			&i.Sensitive,
+			&i.ContentWarning,
+			&i.Language,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPage = `-- name: GetChirpsPage :many
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
+FROM chirps
+WHERE tenant_id = $1
+    AND (created_at, id) > ($2::timestamp, $3::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type GetChirpsPageParams struct {
+	TenantID   uuid.UUID `json:"tenant_id"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   uuid.UUID `json:"cursor_id"`
+	PageLimit  int32     `json:"page_limit"`
+}
+
+func (q *Queries) GetChirpsPage(ctx context.Context, arg GetChirpsPageParams) ([]Chirp, error) {
+	rows, err := q.query(ctx, q.getChirpsPageStmt, getChirpsPage,
+		arg.TenantID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ContentHash,
+			&i.ReplyToID,
+			&i.TenantID,
+			&i.Sensitive,
+			&i.ContentWarning,
+			&i.Language,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -84,13 +583,57 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getIndividualChirp = `-- name: GetIndividualChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
 FROM chirps
-WHERE id = $1
+WHERE id = $1 AND tenant_id = $2
+    AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
 `
 
-func (q *Queries) GetIndividualChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getIndividualChirp, id)
+type GetIndividualChirpParams struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetIndividualChirp(ctx context.Context, arg GetIndividualChirpParams) (Chirp, error) {
+	row := q.queryRow(ctx, q.getIndividualChirpStmt, getIndividualChirp, arg.ID, arg.TenantID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ContentHash,
+		&i.ReplyToID,
+		&i.TenantID,
+		&i.Sensitive,
+		&i.ContentWarning,
+		&i.Language,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getVisibleChirp = `-- name: GetVisibleChirp :one
+SELECT id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility
+FROM chirps
+WHERE id = $1 AND tenant_id = $2
+    AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+    AND (user_id = $3::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = $3::uuid)))
+`
+
+type GetVisibleChirpParams struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+	ViewerID uuid.UUID `json:"viewer_id"`
+}
+
+// GetIndividualChirp for when viewer_id is asking, no rows when it isn't theirs to see
+func (q *Queries) GetVisibleChirp(ctx context.Context, arg GetVisibleChirpParams) (Chirp, error) {
+	row := q.queryRow(ctx, q.getVisibleChirpStmt, getVisibleChirp, arg.ID, arg.TenantID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -98,6 +641,13 @@ func (q *Queries) GetIndividualChirp(ctx context.Context, id uuid.UUID) (Chirp,
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ContentHash,
+		&i.ReplyToID,
+		&i.TenantID,
+		&i.Sensitive,
+		&i.ContentWarning,
+		&i.Language,
+		&i.Visibility,
 	)
 	return i, err
 }
@@ -7,6 +7,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 )
 
 type DBTX interface {
@@ -20,12 +21,1558 @@ func New(db DBTX) *Queries {
 	return &Queries{db: db}
 }
 
+func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
+	q := Queries{db: db}
+	var err error
+	if q.acceptAllFollowRequestsStmt, err = db.PrepareContext(ctx, acceptAllFollowRequests); err != nil {
+		return nil, fmt.Errorf("error preparing query AcceptAllFollowRequests: %w", err)
+	}
+	if q.addChirpViewsStmt, err = db.PrepareContext(ctx, addChirpViews); err != nil {
+		return nil, fmt.Errorf("error preparing query AddChirpViews: %w", err)
+	}
+	if q.addHandleHistoryStmt, err = db.PrepareContext(ctx, addHandleHistory); err != nil {
+		return nil, fmt.Errorf("error preparing query AddHandleHistory: %w", err)
+	}
+	if q.addMutedWordStmt, err = db.PrepareContext(ctx, addMutedWord); err != nil {
+		return nil, fmt.Errorf("error preparing query AddMutedWord: %w", err)
+	}
+	if q.banUserStmt, err = db.PrepareContext(ctx, banUser); err != nil {
+		return nil, fmt.Errorf("error preparing query BanUser: %w", err)
+	}
+	if q.claimJobStmt, err = db.PrepareContext(ctx, claimJob); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimJob: %w", err)
+	}
+	if q.clearPinnedChirpStmt, err = db.PrepareContext(ctx, clearPinnedChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query ClearPinnedChirp: %w", err)
+	}
+	if q.completeJobStmt, err = db.PrepareContext(ctx, completeJob); err != nil {
+		return nil, fmt.Errorf("error preparing query CompleteJob: %w", err)
+	}
+	if q.confirmDeviceStmt, err = db.PrepareContext(ctx, confirmDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query ConfirmDevice: %w", err)
+	}
+	if q.consumeEmailChangeStmt, err = db.PrepareContext(ctx, consumeEmailChange); err != nil {
+		return nil, fmt.Errorf("error preparing query ConsumeEmailChange: %w", err)
+	}
+	if q.consumeOAuthAuthorizationCodeStmt, err = db.PrepareContext(ctx, consumeOAuthAuthorizationCode); err != nil {
+		return nil, fmt.Errorf("error preparing query ConsumeOAuthAuthorizationCode: %w", err)
+	}
+	if q.countDevicesForUserStmt, err = db.PrepareContext(ctx, countDevicesForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CountDevicesForUser: %w", err)
+	}
+	if q.countHandleChangesSinceStmt, err = db.PrepareContext(ctx, countHandleChangesSince); err != nil {
+		return nil, fmt.Errorf("error preparing query CountHandleChangesSince: %w", err)
+	}
+	if q.countMutedWordsStmt, err = db.PrepareContext(ctx, countMutedWords); err != nil {
+		return nil, fmt.Errorf("error preparing query CountMutedWords: %w", err)
+	}
+	if q.countRecentChirpsByUserStmt, err = db.PrepareContext(ctx, countRecentChirpsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CountRecentChirpsByUser: %w", err)
+	}
+	if q.countRecentDuplicateChirpsStmt, err = db.PrepareContext(ctx, countRecentDuplicateChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query CountRecentDuplicateChirps: %w", err)
+	}
+	if q.countUnreadNotificationsStmt, err = db.PrepareContext(ctx, countUnreadNotifications); err != nil {
+		return nil, fmt.Errorf("error preparing query CountUnreadNotifications: %w", err)
+	}
+	if q.createAuditLogEntryStmt, err = db.PrepareContext(ctx, createAuditLogEntry); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateAuditLogEntry: %w", err)
+	}
+	if q.createBannedWordStmt, err = db.PrepareContext(ctx, createBannedWord); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBannedWord: %w", err)
+	}
+	if q.createChirpStmt, err = db.PrepareContext(ctx, createChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirp: %w", err)
+	}
+	if q.createChirpLinkStmt, err = db.PrepareContext(ctx, createChirpLink); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirpLink: %w", err)
+	}
+	if q.createChirpsStmt, err = db.PrepareContext(ctx, createChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateChirps: %w", err)
+	}
+	if q.createDeniedIPStmt, err = db.PrepareContext(ctx, createDeniedIP); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDeniedIP: %w", err)
+	}
+	if q.createDeviceStmt, err = db.PrepareContext(ctx, createDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDevice: %w", err)
+	}
+	if q.createEmailChangeStmt, err = db.PrepareContext(ctx, createEmailChange); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateEmailChange: %w", err)
+	}
+	if q.createFollowRequestStmt, err = db.PrepareContext(ctx, createFollowRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFollowRequest: %w", err)
+	}
+	if q.createNotificationStmt, err = db.PrepareContext(ctx, createNotification); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateNotification: %w", err)
+	}
+	if q.createOAuthAuthorizationCodeStmt, err = db.PrepareContext(ctx, createOAuthAuthorizationCode); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOAuthAuthorizationCode: %w", err)
+	}
+	if q.createOAuthClientStmt, err = db.PrepareContext(ctx, createOAuthClient); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOAuthClient: %w", err)
+	}
+	if q.createPersonalAccessTokenStmt, err = db.PrepareContext(ctx, createPersonalAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreatePersonalAccessToken: %w", err)
+	}
+	if q.createRefreshTokenStmt, err = db.PrepareContext(ctx, createRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateRefreshToken: %w", err)
+	}
+	if q.createUserStmt, err = db.PrepareContext(ctx, createUser); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
+	}
+	if q.createWebhookStmt, err = db.PrepareContext(ctx, createWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateWebhook: %w", err)
+	}
+	if q.deactivateUserStmt, err = db.PrepareContext(ctx, deactivateUser); err != nil {
+		return nil, fmt.Errorf("error preparing query DeactivateUser: %w", err)
+	}
+	if q.deleteBannedWordStmt, err = db.PrepareContext(ctx, deleteBannedWord); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteBannedWord: %w", err)
+	}
+	if q.deleteChirpByIDStmt, err = db.PrepareContext(ctx, deleteChirpByID); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirpByID: %w", err)
+	}
+	if q.deleteChirpsStmt, err = db.PrepareContext(ctx, deleteChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirps: %w", err)
+	}
+	if q.deleteChirpsForTenantStmt, err = db.PrepareContext(ctx, deleteChirpsForTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteChirpsForTenant: %w", err)
+	}
+	if q.deleteDeactivatedUsersStmt, err = db.PrepareContext(ctx, deleteDeactivatedUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteDeactivatedUsers: %w", err)
+	}
+	if q.deleteDeniedIPStmt, err = db.PrepareContext(ctx, deleteDeniedIP); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteDeniedIP: %w", err)
+	}
+	if q.deleteDeviceStmt, err = db.PrepareContext(ctx, deleteDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteDevice: %w", err)
+	}
+	if q.deleteExpiredEmailChangesStmt, err = db.PrepareContext(ctx, deleteExpiredEmailChanges); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredEmailChanges: %w", err)
+	}
+	if q.deleteExpiredOAuthAuthorizationCodesStmt, err = db.PrepareContext(ctx, deleteExpiredOAuthAuthorizationCodes); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredOAuthAuthorizationCodes: %w", err)
+	}
+	if q.deleteFinishedJobsStmt, err = db.PrepareContext(ctx, deleteFinishedJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFinishedJobs: %w", err)
+	}
+	if q.deleteFollowRequestStmt, err = db.PrepareContext(ctx, deleteFollowRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFollowRequest: %w", err)
+	}
+	if q.deleteMutedWordStmt, err = db.PrepareContext(ctx, deleteMutedWord); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMutedWord: %w", err)
+	}
+	if q.deleteOAuthClientStmt, err = db.PrepareContext(ctx, deleteOAuthClient); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOAuthClient: %w", err)
+	}
+	if q.deletePushSubscriptionStmt, err = db.PrepareContext(ctx, deletePushSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePushSubscription: %w", err)
+	}
+	if q.deletePushSubscriptionByIDStmt, err = db.PrepareContext(ctx, deletePushSubscriptionByID); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePushSubscriptionByID: %w", err)
+	}
+	if q.deleteRefreshTokensStmt, err = db.PrepareContext(ctx, deleteRefreshTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteRefreshTokens: %w", err)
+	}
+	if q.deleteStaleRefreshTokensStmt, err = db.PrepareContext(ctx, deleteStaleRefreshTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteStaleRefreshTokens: %w", err)
+	}
+	if q.deleteUsersStmt, err = db.PrepareContext(ctx, deleteUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUsers: %w", err)
+	}
+	if q.deleteUsersForTenantStmt, err = db.PrepareContext(ctx, deleteUsersForTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUsersForTenant: %w", err)
+	}
+	if q.deleteWebhookStmt, err = db.PrepareContext(ctx, deleteWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteWebhook: %w", err)
+	}
+	if q.enqueueJobStmt, err = db.PrepareContext(ctx, enqueueJob); err != nil {
+		return nil, fmt.Errorf("error preparing query EnqueueJob: %w", err)
+	}
+	if q.failJobStmt, err = db.PrepareContext(ctx, failJob); err != nil {
+		return nil, fmt.Errorf("error preparing query FailJob: %w", err)
+	}
+	if q.followUserStmt, err = db.PrepareContext(ctx, followUser); err != nil {
+		return nil, fmt.Errorf("error preparing query FollowUser: %w", err)
+	}
+	if q.getActiveUserCountsStmt, err = db.PrepareContext(ctx, getActiveUserCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query GetActiveUserCounts: %w", err)
+	}
+	if q.getAuditLogStmt, err = db.PrepareContext(ctx, getAuditLog); err != nil {
+		return nil, fmt.Errorf("error preparing query GetAuditLog: %w", err)
+	}
+	if q.getBannedWordsStmt, err = db.PrepareContext(ctx, getBannedWords); err != nil {
+		return nil, fmt.Errorf("error preparing query GetBannedWords: %w", err)
+	}
+	if q.getChirpAncestorsStmt, err = db.PrepareContext(ctx, getChirpAncestors); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpAncestors: %w", err)
+	}
+	if q.getChirpAuthorsStmt, err = db.PrepareContext(ctx, getChirpAuthors); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpAuthors: %w", err)
+	}
+	if q.getChirpCountsStmt, err = db.PrepareContext(ctx, getChirpCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpCounts: %w", err)
+	}
+	if q.getChirpEngagementStmt, err = db.PrepareContext(ctx, getChirpEngagement); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpEngagement: %w", err)
+	}
+	if q.getChirpLinkStmt, err = db.PrepareContext(ctx, getChirpLink); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpLink: %w", err)
+	}
+	if q.getChirpReplyTreeStmt, err = db.PrepareContext(ctx, getChirpReplyTree); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpReplyTree: %w", err)
+	}
+	if q.getChirpViewsByDayStmt, err = db.PrepareContext(ctx, getChirpViewsByDay); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpViewsByDay: %w", err)
+	}
+	if q.getChirpsStmt, err = db.PrepareContext(ctx, getChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirps: %w", err)
+	}
+	if q.getChirpsByIDsStmt, err = db.PrepareContext(ctx, getChirpsByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsByIDs: %w", err)
+	}
+	if q.getChirpsByUserStmt, err = db.PrepareContext(ctx, getChirpsByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsByUser: %w", err)
+	}
+	if q.getChirpsByUserAscStmt, err = db.PrepareContext(ctx, getChirpsByUserAsc); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsByUserAsc: %w", err)
+	}
+	if q.getChirpsPageStmt, err = db.PrepareContext(ctx, getChirpsPage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetChirpsPage: %w", err)
+	}
+	if q.getDailyActiveUsersStmt, err = db.PrepareContext(ctx, getDailyActiveUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDailyActiveUsers: %w", err)
+	}
+	if q.getDeniedIPsStmt, err = db.PrepareContext(ctx, getDeniedIPs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeniedIPs: %w", err)
+	}
+	if q.getDeviceByTokenStmt, err = db.PrepareContext(ctx, getDeviceByToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDeviceByToken: %w", err)
+	}
+	if q.getDevicesForUserStmt, err = db.PrepareContext(ctx, getDevicesForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDevicesForUser: %w", err)
+	}
+	if q.getFeedCandidatesStmt, err = db.PrepareContext(ctx, getFeedCandidates); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFeedCandidates: %w", err)
+	}
+	if q.getFollowRequestsStmt, err = db.PrepareContext(ctx, getFollowRequests); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowRequests: %w", err)
+	}
+	if q.getFollowersStmt, err = db.PrepareContext(ctx, getFollowers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowers: %w", err)
+	}
+	if q.getFollowingStmt, err = db.PrepareContext(ctx, getFollowing); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFollowing: %w", err)
+	}
+	if q.getIndividualChirpStmt, err = db.PrepareContext(ctx, getIndividualChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query GetIndividualChirp: %w", err)
+	}
+	if q.getLatestFeedStmt, err = db.PrepareContext(ctx, getLatestFeed); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLatestFeed: %w", err)
+	}
+	if q.getLinksForChirpsStmt, err = db.PrepareContext(ctx, getLinksForChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLinksForChirps: %w", err)
+	}
+	if q.getMetricStmt, err = db.PrepareContext(ctx, getMetric); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMetric: %w", err)
+	}
+	if q.getMutedWordsStmt, err = db.PrepareContext(ctx, getMutedWords); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMutedWords: %w", err)
+	}
+	if q.getNotificationSettingsStmt, err = db.PrepareContext(ctx, getNotificationSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNotificationSettings: %w", err)
+	}
+	if q.getNotificationsForUserStmt, err = db.PrepareContext(ctx, getNotificationsForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetNotificationsForUser: %w", err)
+	}
+	if q.getOAuthClientStmt, err = db.PrepareContext(ctx, getOAuthClient); err != nil {
+		return nil, fmt.Errorf("error preparing query GetOAuthClient: %w", err)
+	}
+	if q.getOAuthClientsForOwnerStmt, err = db.PrepareContext(ctx, getOAuthClientsForOwner); err != nil {
+		return nil, fmt.Errorf("error preparing query GetOAuthClientsForOwner: %w", err)
+	}
+	if q.getPersonalAccessTokenByHashStmt, err = db.PrepareContext(ctx, getPersonalAccessTokenByHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPersonalAccessTokenByHash: %w", err)
+	}
+	if q.getPersonalAccessTokensForUserStmt, err = db.PrepareContext(ctx, getPersonalAccessTokensForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPersonalAccessTokensForUser: %w", err)
+	}
+	if q.getPushSubscriptionStmt, err = db.PrepareContext(ctx, getPushSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPushSubscription: %w", err)
+	}
+	if q.getPushSubscriptionsForUserStmt, err = db.PrepareContext(ctx, getPushSubscriptionsForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPushSubscriptionsForUser: %w", err)
+	}
+	if q.getRefreshTokenForUpdateStmt, err = db.PrepareContext(ctx, getRefreshTokenForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetRefreshTokenForUpdate: %w", err)
+	}
+	if q.getSettingsStmt, err = db.PrepareContext(ctx, getSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSettings: %w", err)
+	}
+	if q.getSignupsPerDayStmt, err = db.PrepareContext(ctx, getSignupsPerDay); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSignupsPerDay: %w", err)
+	}
+	if q.getTableStatsStmt, err = db.PrepareContext(ctx, getTableStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTableStats: %w", err)
+	}
+	if q.getTenantsStmt, err = db.PrepareContext(ctx, getTenants); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTenants: %w", err)
+	}
+	if q.getTopChirpersStmt, err = db.PrepareContext(ctx, getTopChirpers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTopChirpers: %w", err)
+	}
+	if q.getTopLikedUsersStmt, err = db.PrepareContext(ctx, getTopLikedUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTopLikedUsers: %w", err)
+	}
+	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
+	}
+	if q.getUserByIDNoPasswordStmt, err = db.PrepareContext(ctx, getUserByIDNoPassword); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByIDNoPassword: %w", err)
+	}
+	if q.getUserByOldHandleStmt, err = db.PrepareContext(ctx, getUserByOldHandle); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserByOldHandle: %w", err)
+	}
+	if q.getUserFromRefreshTokenStmt, err = db.PrepareContext(ctx, getUserFromRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserFromRefreshToken: %w", err)
+	}
+	if q.getUserHandleForUpdateStmt, err = db.PrepareContext(ctx, getUserHandleForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserHandleForUpdate: %w", err)
+	}
+	if q.getUserIDByHandleStmt, err = db.PrepareContext(ctx, getUserIDByHandle); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserIDByHandle: %w", err)
+	}
+	if q.getUserIsAdminStmt, err = db.PrepareContext(ctx, getUserIsAdmin); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserIsAdmin: %w", err)
+	}
+	if q.getUserProfileStmt, err = db.PrepareContext(ctx, getUserProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserProfile: %w", err)
+	}
+	if q.getVisibleChirpStmt, err = db.PrepareContext(ctx, getVisibleChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query GetVisibleChirp: %w", err)
+	}
+	if q.getWebhookStmt, err = db.PrepareContext(ctx, getWebhook); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhook: %w", err)
+	}
+	if q.getWebhooksByUserStmt, err = db.PrepareContext(ctx, getWebhooksByUser); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhooksByUser: %w", err)
+	}
+	if q.getWebhooksForEventStmt, err = db.PrepareContext(ctx, getWebhooksForEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query GetWebhooksForEvent: %w", err)
+	}
+	if q.isChirpMutedStmt, err = db.PrepareContext(ctx, isChirpMuted); err != nil {
+		return nil, fmt.Errorf("error preparing query IsChirpMuted: %w", err)
+	}
+	if q.isFollowingStmt, err = db.PrepareContext(ctx, isFollowing); err != nil {
+		return nil, fmt.Errorf("error preparing query IsFollowing: %w", err)
+	}
+	if q.likeChirpStmt, err = db.PrepareContext(ctx, likeChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query LikeChirp: %w", err)
+	}
+	if q.markAllNotificationsReadStmt, err = db.PrepareContext(ctx, markAllNotificationsRead); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkAllNotificationsRead: %w", err)
+	}
+	if q.markNotificationReadStmt, err = db.PrepareContext(ctx, markNotificationRead); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkNotificationRead: %w", err)
+	}
+	if q.reactivateUserStmt, err = db.PrepareContext(ctx, reactivateUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ReactivateUser: %w", err)
+	}
+	if q.rechirpStmt, err = db.PrepareContext(ctx, rechirp); err != nil {
+		return nil, fmt.Errorf("error preparing query Rechirp: %w", err)
+	}
+	if q.recordUserActivityStmt, err = db.PrepareContext(ctx, recordUserActivity); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordUserActivity: %w", err)
+	}
+	if q.renameDeviceStmt, err = db.PrepareContext(ctx, renameDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query RenameDevice: %w", err)
+	}
+	if q.requeueStaleJobsStmt, err = db.PrepareContext(ctx, requeueStaleJobs); err != nil {
+		return nil, fmt.Errorf("error preparing query RequeueStaleJobs: %w", err)
+	}
+	if q.retryJobStmt, err = db.PrepareContext(ctx, retryJob); err != nil {
+		return nil, fmt.Errorf("error preparing query RetryJob: %w", err)
+	}
+	if q.revokePersonalAccessTokenStmt, err = db.PrepareContext(ctx, revokePersonalAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokePersonalAccessToken: %w", err)
+	}
+	if q.revokePersonalAccessTokensForUserStmt, err = db.PrepareContext(ctx, revokePersonalAccessTokensForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokePersonalAccessTokensForUser: %w", err)
+	}
+	if q.revokeRefreshTokenStmt, err = db.PrepareContext(ctx, revokeRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeRefreshToken: %w", err)
+	}
+	if q.revokeRefreshTokensForUserStmt, err = db.PrepareContext(ctx, revokeRefreshTokensForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeRefreshTokensForUser: %w", err)
+	}
+	if q.searchChirpsStmt, err = db.PrepareContext(ctx, searchChirps); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchChirps: %w", err)
+	}
+	if q.setDeviceConfirmTokenStmt, err = db.PrepareContext(ctx, setDeviceConfirmToken); err != nil {
+		return nil, fmt.Errorf("error preparing query SetDeviceConfirmToken: %w", err)
+	}
+	if q.setMetricStmt, err = db.PrepareContext(ctx, setMetric); err != nil {
+		return nil, fmt.Errorf("error preparing query SetMetric: %w", err)
+	}
+	if q.setPinnedChirpStmt, err = db.PrepareContext(ctx, setPinnedChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query SetPinnedChirp: %w", err)
+	}
+	if q.setUserHandleStmt, err = db.PrepareContext(ctx, setUserHandle); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserHandle: %w", err)
+	}
+	if q.setUserPreferencesStmt, err = db.PrepareContext(ctx, setUserPreferences); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserPreferences: %w", err)
+	}
+	if q.setUserProtectedStmt, err = db.PrepareContext(ctx, setUserProtected); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserProtected: %w", err)
+	}
+	if q.setUserShadowBanStmt, err = db.PrepareContext(ctx, setUserShadowBan); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserShadowBan: %w", err)
+	}
+	if q.touchDeviceStmt, err = db.PrepareContext(ctx, touchDevice); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchDevice: %w", err)
+	}
+	if q.touchPersonalAccessTokenStmt, err = db.PrepareContext(ctx, touchPersonalAccessToken); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchPersonalAccessToken: %w", err)
+	}
+	if q.unbanUserStmt, err = db.PrepareContext(ctx, unbanUser); err != nil {
+		return nil, fmt.Errorf("error preparing query UnbanUser: %w", err)
+	}
+	if q.undoRechirpStmt, err = db.PrepareContext(ctx, undoRechirp); err != nil {
+		return nil, fmt.Errorf("error preparing query UndoRechirp: %w", err)
+	}
+	if q.unfollowUserStmt, err = db.PrepareContext(ctx, unfollowUser); err != nil {
+		return nil, fmt.Errorf("error preparing query UnfollowUser: %w", err)
+	}
+	if q.unlikeChirpStmt, err = db.PrepareContext(ctx, unlikeChirp); err != nil {
+		return nil, fmt.Errorf("error preparing query UnlikeChirp: %w", err)
+	}
+	if q.updateChirpLinkPreviewStmt, err = db.PrepareContext(ctx, updateChirpLinkPreview); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateChirpLinkPreview: %w", err)
+	}
+	if q.updateIsChirpyRedByIDStmt, err = db.PrepareContext(ctx, updateIsChirpyRedByID); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateIsChirpyRedByID: %w", err)
+	}
+	if q.updateUserEmailStmt, err = db.PrepareContext(ctx, updateUserEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUserEmail: %w", err)
+	}
+	if q.updateUserPasswordStmt, err = db.PrepareContext(ctx, updateUserPassword); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUserPassword: %w", err)
+	}
+	if q.upsertMetricStmt, err = db.PrepareContext(ctx, upsertMetric); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertMetric: %w", err)
+	}
+	if q.upsertNotificationSettingsStmt, err = db.PrepareContext(ctx, upsertNotificationSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertNotificationSettings: %w", err)
+	}
+	if q.upsertPushSubscriptionStmt, err = db.PrepareContext(ctx, upsertPushSubscription); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertPushSubscription: %w", err)
+	}
+	if q.upsertSettingStmt, err = db.PrepareContext(ctx, upsertSetting); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertSetting: %w", err)
+	}
+	if q.userExistsStmt, err = db.PrepareContext(ctx, userExists); err != nil {
+		return nil, fmt.Errorf("error preparing query UserExists: %w", err)
+	}
+	return &q, nil
+}
+
+func (q *Queries) Close() error {
+	var err error
+	if q.acceptAllFollowRequestsStmt != nil {
+		if cerr := q.acceptAllFollowRequestsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing acceptAllFollowRequestsStmt: %w", cerr)
+		}
+	}
+	if q.addChirpViewsStmt != nil {
+		if cerr := q.addChirpViewsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addChirpViewsStmt: %w", cerr)
+		}
+	}
+	if q.addHandleHistoryStmt != nil {
+		if cerr := q.addHandleHistoryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addHandleHistoryStmt: %w", cerr)
+		}
+	}
+	if q.addMutedWordStmt != nil {
+		if cerr := q.addMutedWordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addMutedWordStmt: %w", cerr)
+		}
+	}
+	if q.banUserStmt != nil {
+		if cerr := q.banUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing banUserStmt: %w", cerr)
+		}
+	}
+	if q.claimJobStmt != nil {
+		if cerr := q.claimJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimJobStmt: %w", cerr)
+		}
+	}
+	if q.clearPinnedChirpStmt != nil {
+		if cerr := q.clearPinnedChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing clearPinnedChirpStmt: %w", cerr)
+		}
+	}
+	if q.completeJobStmt != nil {
+		if cerr := q.completeJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing completeJobStmt: %w", cerr)
+		}
+	}
+	if q.confirmDeviceStmt != nil {
+		if cerr := q.confirmDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing confirmDeviceStmt: %w", cerr)
+		}
+	}
+	if q.consumeEmailChangeStmt != nil {
+		if cerr := q.consumeEmailChangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing consumeEmailChangeStmt: %w", cerr)
+		}
+	}
+	if q.consumeOAuthAuthorizationCodeStmt != nil {
+		if cerr := q.consumeOAuthAuthorizationCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing consumeOAuthAuthorizationCodeStmt: %w", cerr)
+		}
+	}
+	if q.countDevicesForUserStmt != nil {
+		if cerr := q.countDevicesForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countDevicesForUserStmt: %w", cerr)
+		}
+	}
+	if q.countHandleChangesSinceStmt != nil {
+		if cerr := q.countHandleChangesSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countHandleChangesSinceStmt: %w", cerr)
+		}
+	}
+	if q.countMutedWordsStmt != nil {
+		if cerr := q.countMutedWordsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countMutedWordsStmt: %w", cerr)
+		}
+	}
+	if q.countRecentChirpsByUserStmt != nil {
+		if cerr := q.countRecentChirpsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countRecentChirpsByUserStmt: %w", cerr)
+		}
+	}
+	if q.countRecentDuplicateChirpsStmt != nil {
+		if cerr := q.countRecentDuplicateChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countRecentDuplicateChirpsStmt: %w", cerr)
+		}
+	}
+	if q.countUnreadNotificationsStmt != nil {
+		if cerr := q.countUnreadNotificationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countUnreadNotificationsStmt: %w", cerr)
+		}
+	}
+	if q.createAuditLogEntryStmt != nil {
+		if cerr := q.createAuditLogEntryStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createAuditLogEntryStmt: %w", cerr)
+		}
+	}
+	if q.createBannedWordStmt != nil {
+		if cerr := q.createBannedWordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBannedWordStmt: %w", cerr)
+		}
+	}
+	if q.createChirpStmt != nil {
+		if cerr := q.createChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpStmt: %w", cerr)
+		}
+	}
+	if q.createChirpLinkStmt != nil {
+		if cerr := q.createChirpLinkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpLinkStmt: %w", cerr)
+		}
+	}
+	if q.createChirpsStmt != nil {
+		if cerr := q.createChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createChirpsStmt: %w", cerr)
+		}
+	}
+	if q.createDeniedIPStmt != nil {
+		if cerr := q.createDeniedIPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDeniedIPStmt: %w", cerr)
+		}
+	}
+	if q.createDeviceStmt != nil {
+		if cerr := q.createDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDeviceStmt: %w", cerr)
+		}
+	}
+	if q.createEmailChangeStmt != nil {
+		if cerr := q.createEmailChangeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createEmailChangeStmt: %w", cerr)
+		}
+	}
+	if q.createFollowRequestStmt != nil {
+		if cerr := q.createFollowRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFollowRequestStmt: %w", cerr)
+		}
+	}
+	if q.createNotificationStmt != nil {
+		if cerr := q.createNotificationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createNotificationStmt: %w", cerr)
+		}
+	}
+	if q.createOAuthAuthorizationCodeStmt != nil {
+		if cerr := q.createOAuthAuthorizationCodeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOAuthAuthorizationCodeStmt: %w", cerr)
+		}
+	}
+	if q.createOAuthClientStmt != nil {
+		if cerr := q.createOAuthClientStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOAuthClientStmt: %w", cerr)
+		}
+	}
+	if q.createPersonalAccessTokenStmt != nil {
+		if cerr := q.createPersonalAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createPersonalAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.createRefreshTokenStmt != nil {
+		if cerr := q.createRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.createUserStmt != nil {
+		if cerr := q.createUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
+		}
+	}
+	if q.createWebhookStmt != nil {
+		if cerr := q.createWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createWebhookStmt: %w", cerr)
+		}
+	}
+	if q.deactivateUserStmt != nil {
+		if cerr := q.deactivateUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deactivateUserStmt: %w", cerr)
+		}
+	}
+	if q.deleteBannedWordStmt != nil {
+		if cerr := q.deleteBannedWordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteBannedWordStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpByIDStmt != nil {
+		if cerr := q.deleteChirpByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpByIDStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpsStmt != nil {
+		if cerr := q.deleteChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpsStmt: %w", cerr)
+		}
+	}
+	if q.deleteChirpsForTenantStmt != nil {
+		if cerr := q.deleteChirpsForTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteChirpsForTenantStmt: %w", cerr)
+		}
+	}
+	if q.deleteDeactivatedUsersStmt != nil {
+		if cerr := q.deleteDeactivatedUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteDeactivatedUsersStmt: %w", cerr)
+		}
+	}
+	if q.deleteDeniedIPStmt != nil {
+		if cerr := q.deleteDeniedIPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteDeniedIPStmt: %w", cerr)
+		}
+	}
+	if q.deleteDeviceStmt != nil {
+		if cerr := q.deleteDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteDeviceStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredEmailChangesStmt != nil {
+		if cerr := q.deleteExpiredEmailChangesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredEmailChangesStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredOAuthAuthorizationCodesStmt != nil {
+		if cerr := q.deleteExpiredOAuthAuthorizationCodesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredOAuthAuthorizationCodesStmt: %w", cerr)
+		}
+	}
+	if q.deleteFinishedJobsStmt != nil {
+		if cerr := q.deleteFinishedJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFinishedJobsStmt: %w", cerr)
+		}
+	}
+	if q.deleteFollowRequestStmt != nil {
+		if cerr := q.deleteFollowRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFollowRequestStmt: %w", cerr)
+		}
+	}
+	if q.deleteMutedWordStmt != nil {
+		if cerr := q.deleteMutedWordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMutedWordStmt: %w", cerr)
+		}
+	}
+	if q.deleteOAuthClientStmt != nil {
+		if cerr := q.deleteOAuthClientStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOAuthClientStmt: %w", cerr)
+		}
+	}
+	if q.deletePushSubscriptionStmt != nil {
+		if cerr := q.deletePushSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePushSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.deletePushSubscriptionByIDStmt != nil {
+		if cerr := q.deletePushSubscriptionByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePushSubscriptionByIDStmt: %w", cerr)
+		}
+	}
+	if q.deleteRefreshTokensStmt != nil {
+		if cerr := q.deleteRefreshTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteRefreshTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteStaleRefreshTokensStmt != nil {
+		if cerr := q.deleteStaleRefreshTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteStaleRefreshTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteUsersStmt != nil {
+		if cerr := q.deleteUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUsersStmt: %w", cerr)
+		}
+	}
+	if q.deleteUsersForTenantStmt != nil {
+		if cerr := q.deleteUsersForTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUsersForTenantStmt: %w", cerr)
+		}
+	}
+	if q.deleteWebhookStmt != nil {
+		if cerr := q.deleteWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteWebhookStmt: %w", cerr)
+		}
+	}
+	if q.enqueueJobStmt != nil {
+		if cerr := q.enqueueJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing enqueueJobStmt: %w", cerr)
+		}
+	}
+	if q.failJobStmt != nil {
+		if cerr := q.failJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing failJobStmt: %w", cerr)
+		}
+	}
+	if q.followUserStmt != nil {
+		if cerr := q.followUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing followUserStmt: %w", cerr)
+		}
+	}
+	if q.getActiveUserCountsStmt != nil {
+		if cerr := q.getActiveUserCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getActiveUserCountsStmt: %w", cerr)
+		}
+	}
+	if q.getAuditLogStmt != nil {
+		if cerr := q.getAuditLogStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getAuditLogStmt: %w", cerr)
+		}
+	}
+	if q.getBannedWordsStmt != nil {
+		if cerr := q.getBannedWordsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getBannedWordsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpAncestorsStmt != nil {
+		if cerr := q.getChirpAncestorsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpAncestorsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpAuthorsStmt != nil {
+		if cerr := q.getChirpAuthorsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpAuthorsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpCountsStmt != nil {
+		if cerr := q.getChirpCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpCountsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpEngagementStmt != nil {
+		if cerr := q.getChirpEngagementStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpEngagementStmt: %w", cerr)
+		}
+	}
+	if q.getChirpLinkStmt != nil {
+		if cerr := q.getChirpLinkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpLinkStmt: %w", cerr)
+		}
+	}
+	if q.getChirpReplyTreeStmt != nil {
+		if cerr := q.getChirpReplyTreeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpReplyTreeStmt: %w", cerr)
+		}
+	}
+	if q.getChirpViewsByDayStmt != nil {
+		if cerr := q.getChirpViewsByDayStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpViewsByDayStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsStmt != nil {
+		if cerr := q.getChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsByIDsStmt != nil {
+		if cerr := q.getChirpsByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsByIDsStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsByUserStmt != nil {
+		if cerr := q.getChirpsByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsByUserStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsByUserAscStmt != nil {
+		if cerr := q.getChirpsByUserAscStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsByUserAscStmt: %w", cerr)
+		}
+	}
+	if q.getChirpsPageStmt != nil {
+		if cerr := q.getChirpsPageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getChirpsPageStmt: %w", cerr)
+		}
+	}
+	if q.getDailyActiveUsersStmt != nil {
+		if cerr := q.getDailyActiveUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDailyActiveUsersStmt: %w", cerr)
+		}
+	}
+	if q.getDeniedIPsStmt != nil {
+		if cerr := q.getDeniedIPsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeniedIPsStmt: %w", cerr)
+		}
+	}
+	if q.getDeviceByTokenStmt != nil {
+		if cerr := q.getDeviceByTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDeviceByTokenStmt: %w", cerr)
+		}
+	}
+	if q.getDevicesForUserStmt != nil {
+		if cerr := q.getDevicesForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDevicesForUserStmt: %w", cerr)
+		}
+	}
+	if q.getFeedCandidatesStmt != nil {
+		if cerr := q.getFeedCandidatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFeedCandidatesStmt: %w", cerr)
+		}
+	}
+	if q.getFollowRequestsStmt != nil {
+		if cerr := q.getFollowRequestsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowRequestsStmt: %w", cerr)
+		}
+	}
+	if q.getFollowersStmt != nil {
+		if cerr := q.getFollowersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowersStmt: %w", cerr)
+		}
+	}
+	if q.getFollowingStmt != nil {
+		if cerr := q.getFollowingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFollowingStmt: %w", cerr)
+		}
+	}
+	if q.getIndividualChirpStmt != nil {
+		if cerr := q.getIndividualChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getIndividualChirpStmt: %w", cerr)
+		}
+	}
+	if q.getLatestFeedStmt != nil {
+		if cerr := q.getLatestFeedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLatestFeedStmt: %w", cerr)
+		}
+	}
+	if q.getLinksForChirpsStmt != nil {
+		if cerr := q.getLinksForChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLinksForChirpsStmt: %w", cerr)
+		}
+	}
+	if q.getMetricStmt != nil {
+		if cerr := q.getMetricStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMetricStmt: %w", cerr)
+		}
+	}
+	if q.getMutedWordsStmt != nil {
+		if cerr := q.getMutedWordsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMutedWordsStmt: %w", cerr)
+		}
+	}
+	if q.getNotificationSettingsStmt != nil {
+		if cerr := q.getNotificationSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNotificationSettingsStmt: %w", cerr)
+		}
+	}
+	if q.getNotificationsForUserStmt != nil {
+		if cerr := q.getNotificationsForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getNotificationsForUserStmt: %w", cerr)
+		}
+	}
+	if q.getOAuthClientStmt != nil {
+		if cerr := q.getOAuthClientStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getOAuthClientStmt: %w", cerr)
+		}
+	}
+	if q.getOAuthClientsForOwnerStmt != nil {
+		if cerr := q.getOAuthClientsForOwnerStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getOAuthClientsForOwnerStmt: %w", cerr)
+		}
+	}
+	if q.getPersonalAccessTokenByHashStmt != nil {
+		if cerr := q.getPersonalAccessTokenByHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPersonalAccessTokenByHashStmt: %w", cerr)
+		}
+	}
+	if q.getPersonalAccessTokensForUserStmt != nil {
+		if cerr := q.getPersonalAccessTokensForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPersonalAccessTokensForUserStmt: %w", cerr)
+		}
+	}
+	if q.getPushSubscriptionStmt != nil {
+		if cerr := q.getPushSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPushSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.getPushSubscriptionsForUserStmt != nil {
+		if cerr := q.getPushSubscriptionsForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPushSubscriptionsForUserStmt: %w", cerr)
+		}
+	}
+	if q.getRefreshTokenForUpdateStmt != nil {
+		if cerr := q.getRefreshTokenForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getRefreshTokenForUpdateStmt: %w", cerr)
+		}
+	}
+	if q.getSettingsStmt != nil {
+		if cerr := q.getSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSettingsStmt: %w", cerr)
+		}
+	}
+	if q.getSignupsPerDayStmt != nil {
+		if cerr := q.getSignupsPerDayStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSignupsPerDayStmt: %w", cerr)
+		}
+	}
+	if q.getTableStatsStmt != nil {
+		if cerr := q.getTableStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTableStatsStmt: %w", cerr)
+		}
+	}
+	if q.getTenantsStmt != nil {
+		if cerr := q.getTenantsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTenantsStmt: %w", cerr)
+		}
+	}
+	if q.getTopChirpersStmt != nil {
+		if cerr := q.getTopChirpersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTopChirpersStmt: %w", cerr)
+		}
+	}
+	if q.getTopLikedUsersStmt != nil {
+		if cerr := q.getTopLikedUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTopLikedUsersStmt: %w", cerr)
+		}
+	}
+	if q.getUserByEmailStmt != nil {
+		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
+		}
+	}
+	if q.getUserByIDNoPasswordStmt != nil {
+		if cerr := q.getUserByIDNoPasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByIDNoPasswordStmt: %w", cerr)
+		}
+	}
+	if q.getUserByOldHandleStmt != nil {
+		if cerr := q.getUserByOldHandleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserByOldHandleStmt: %w", cerr)
+		}
+	}
+	if q.getUserFromRefreshTokenStmt != nil {
+		if cerr := q.getUserFromRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserFromRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.getUserHandleForUpdateStmt != nil {
+		if cerr := q.getUserHandleForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserHandleForUpdateStmt: %w", cerr)
+		}
+	}
+	if q.getUserIDByHandleStmt != nil {
+		if cerr := q.getUserIDByHandleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserIDByHandleStmt: %w", cerr)
+		}
+	}
+	if q.getUserIsAdminStmt != nil {
+		if cerr := q.getUserIsAdminStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserIsAdminStmt: %w", cerr)
+		}
+	}
+	if q.getUserProfileStmt != nil {
+		if cerr := q.getUserProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserProfileStmt: %w", cerr)
+		}
+	}
+	if q.getVisibleChirpStmt != nil {
+		if cerr := q.getVisibleChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getVisibleChirpStmt: %w", cerr)
+		}
+	}
+	if q.getWebhookStmt != nil {
+		if cerr := q.getWebhookStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhookStmt: %w", cerr)
+		}
+	}
+	if q.getWebhooksByUserStmt != nil {
+		if cerr := q.getWebhooksByUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhooksByUserStmt: %w", cerr)
+		}
+	}
+	if q.getWebhooksForEventStmt != nil {
+		if cerr := q.getWebhooksForEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getWebhooksForEventStmt: %w", cerr)
+		}
+	}
+	if q.isChirpMutedStmt != nil {
+		if cerr := q.isChirpMutedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing isChirpMutedStmt: %w", cerr)
+		}
+	}
+	if q.isFollowingStmt != nil {
+		if cerr := q.isFollowingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing isFollowingStmt: %w", cerr)
+		}
+	}
+	if q.likeChirpStmt != nil {
+		if cerr := q.likeChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing likeChirpStmt: %w", cerr)
+		}
+	}
+	if q.markAllNotificationsReadStmt != nil {
+		if cerr := q.markAllNotificationsReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markAllNotificationsReadStmt: %w", cerr)
+		}
+	}
+	if q.markNotificationReadStmt != nil {
+		if cerr := q.markNotificationReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markNotificationReadStmt: %w", cerr)
+		}
+	}
+	if q.reactivateUserStmt != nil {
+		if cerr := q.reactivateUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing reactivateUserStmt: %w", cerr)
+		}
+	}
+	if q.rechirpStmt != nil {
+		if cerr := q.rechirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rechirpStmt: %w", cerr)
+		}
+	}
+	if q.recordUserActivityStmt != nil {
+		if cerr := q.recordUserActivityStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordUserActivityStmt: %w", cerr)
+		}
+	}
+	if q.renameDeviceStmt != nil {
+		if cerr := q.renameDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing renameDeviceStmt: %w", cerr)
+		}
+	}
+	if q.requeueStaleJobsStmt != nil {
+		if cerr := q.requeueStaleJobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing requeueStaleJobsStmt: %w", cerr)
+		}
+	}
+	if q.retryJobStmt != nil {
+		if cerr := q.retryJobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing retryJobStmt: %w", cerr)
+		}
+	}
+	if q.revokePersonalAccessTokenStmt != nil {
+		if cerr := q.revokePersonalAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokePersonalAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.revokePersonalAccessTokensForUserStmt != nil {
+		if cerr := q.revokePersonalAccessTokensForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokePersonalAccessTokensForUserStmt: %w", cerr)
+		}
+	}
+	if q.revokeRefreshTokenStmt != nil {
+		if cerr := q.revokeRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.revokeRefreshTokensForUserStmt != nil {
+		if cerr := q.revokeRefreshTokensForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeRefreshTokensForUserStmt: %w", cerr)
+		}
+	}
+	if q.searchChirpsStmt != nil {
+		if cerr := q.searchChirpsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchChirpsStmt: %w", cerr)
+		}
+	}
+	if q.setDeviceConfirmTokenStmt != nil {
+		if cerr := q.setDeviceConfirmTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setDeviceConfirmTokenStmt: %w", cerr)
+		}
+	}
+	if q.setMetricStmt != nil {
+		if cerr := q.setMetricStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setMetricStmt: %w", cerr)
+		}
+	}
+	if q.setPinnedChirpStmt != nil {
+		if cerr := q.setPinnedChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setPinnedChirpStmt: %w", cerr)
+		}
+	}
+	if q.setUserHandleStmt != nil {
+		if cerr := q.setUserHandleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserHandleStmt: %w", cerr)
+		}
+	}
+	if q.setUserPreferencesStmt != nil {
+		if cerr := q.setUserPreferencesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserPreferencesStmt: %w", cerr)
+		}
+	}
+	if q.setUserProtectedStmt != nil {
+		if cerr := q.setUserProtectedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserProtectedStmt: %w", cerr)
+		}
+	}
+	if q.setUserShadowBanStmt != nil {
+		if cerr := q.setUserShadowBanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserShadowBanStmt: %w", cerr)
+		}
+	}
+	if q.touchDeviceStmt != nil {
+		if cerr := q.touchDeviceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchDeviceStmt: %w", cerr)
+		}
+	}
+	if q.touchPersonalAccessTokenStmt != nil {
+		if cerr := q.touchPersonalAccessTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchPersonalAccessTokenStmt: %w", cerr)
+		}
+	}
+	if q.unbanUserStmt != nil {
+		if cerr := q.unbanUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unbanUserStmt: %w", cerr)
+		}
+	}
+	if q.undoRechirpStmt != nil {
+		if cerr := q.undoRechirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing undoRechirpStmt: %w", cerr)
+		}
+	}
+	if q.unfollowUserStmt != nil {
+		if cerr := q.unfollowUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unfollowUserStmt: %w", cerr)
+		}
+	}
+	if q.unlikeChirpStmt != nil {
+		if cerr := q.unlikeChirpStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing unlikeChirpStmt: %w", cerr)
+		}
+	}
+	if q.updateChirpLinkPreviewStmt != nil {
+		if cerr := q.updateChirpLinkPreviewStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateChirpLinkPreviewStmt: %w", cerr)
+		}
+	}
+	if q.updateIsChirpyRedByIDStmt != nil {
+		if cerr := q.updateIsChirpyRedByIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateIsChirpyRedByIDStmt: %w", cerr)
+		}
+	}
+	if q.updateUserEmailStmt != nil {
+		if cerr := q.updateUserEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserEmailStmt: %w", cerr)
+		}
+	}
+	if q.updateUserPasswordStmt != nil {
+		if cerr := q.updateUserPasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUserPasswordStmt: %w", cerr)
+		}
+	}
+	if q.upsertMetricStmt != nil {
+		if cerr := q.upsertMetricStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertMetricStmt: %w", cerr)
+		}
+	}
+	if q.upsertNotificationSettingsStmt != nil {
+		if cerr := q.upsertNotificationSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertNotificationSettingsStmt: %w", cerr)
+		}
+	}
+	if q.upsertPushSubscriptionStmt != nil {
+		if cerr := q.upsertPushSubscriptionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertPushSubscriptionStmt: %w", cerr)
+		}
+	}
+	if q.upsertSettingStmt != nil {
+		if cerr := q.upsertSettingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertSettingStmt: %w", cerr)
+		}
+	}
+	if q.userExistsStmt != nil {
+		if cerr := q.userExistsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing userExistsStmt: %w", cerr)
+		}
+	}
+	return err
+}
+
+func (q *Queries) exec(ctx context.Context, stmt *sql.Stmt, query string, args ...interface{}) (sql.Result, error) {
+	switch {
+	case stmt != nil && q.tx != nil:
+		return q.tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
+	case stmt != nil:
+		return stmt.ExecContext(ctx, args...)
+	default:
+		return q.db.ExecContext(ctx, query, args...)
+	}
+}
+
+func (q *Queries) query(ctx context.Context, stmt *sql.Stmt, query string, args ...interface{}) (*sql.Rows, error) {
+	switch {
+	case stmt != nil && q.tx != nil:
+		return q.tx.StmtContext(ctx, stmt).QueryContext(ctx, args...)
+	case stmt != nil:
+		return stmt.QueryContext(ctx, args...)
+	default:
+		return q.db.QueryContext(ctx, query, args...)
+	}
+}
+
+func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, args ...interface{}) *sql.Row {
+	switch {
+	case stmt != nil && q.tx != nil:
+		return q.tx.StmtContext(ctx, stmt).QueryRowContext(ctx, args...)
+	case stmt != nil:
+		return stmt.QueryRowContext(ctx, args...)
+	default:
+		return q.db.QueryRowContext(ctx, query, args...)
+	}
+}
+
 type Queries struct {
-	db DBTX
+	db                                       DBTX
+	tx                                       *sql.Tx
+	acceptAllFollowRequestsStmt              *sql.Stmt
+	addChirpViewsStmt                        *sql.Stmt
+	addHandleHistoryStmt                     *sql.Stmt
+	addMutedWordStmt                         *sql.Stmt
+	banUserStmt                              *sql.Stmt
+	claimJobStmt                             *sql.Stmt
+	clearPinnedChirpStmt                     *sql.Stmt
+	completeJobStmt                          *sql.Stmt
+	confirmDeviceStmt                        *sql.Stmt
+	consumeEmailChangeStmt                   *sql.Stmt
+	consumeOAuthAuthorizationCodeStmt        *sql.Stmt
+	countDevicesForUserStmt                  *sql.Stmt
+	countHandleChangesSinceStmt              *sql.Stmt
+	countMutedWordsStmt                      *sql.Stmt
+	countRecentChirpsByUserStmt              *sql.Stmt
+	countRecentDuplicateChirpsStmt           *sql.Stmt
+	countUnreadNotificationsStmt             *sql.Stmt
+	createAuditLogEntryStmt                  *sql.Stmt
+	createBannedWordStmt                     *sql.Stmt
+	createChirpStmt                          *sql.Stmt
+	createChirpLinkStmt                      *sql.Stmt
+	createChirpsStmt                         *sql.Stmt
+	createDeniedIPStmt                       *sql.Stmt
+	createDeviceStmt                         *sql.Stmt
+	createEmailChangeStmt                    *sql.Stmt
+	createFollowRequestStmt                  *sql.Stmt
+	createNotificationStmt                   *sql.Stmt
+	createOAuthAuthorizationCodeStmt         *sql.Stmt
+	createOAuthClientStmt                    *sql.Stmt
+	createPersonalAccessTokenStmt            *sql.Stmt
+	createRefreshTokenStmt                   *sql.Stmt
+	createUserStmt                           *sql.Stmt
+	createWebhookStmt                        *sql.Stmt
+	deactivateUserStmt                       *sql.Stmt
+	deleteBannedWordStmt                     *sql.Stmt
+	deleteChirpByIDStmt                      *sql.Stmt
+	deleteChirpsStmt                         *sql.Stmt
+	deleteChirpsForTenantStmt                *sql.Stmt
+	deleteDeactivatedUsersStmt               *sql.Stmt
+	deleteDeniedIPStmt                       *sql.Stmt
+	deleteDeviceStmt                         *sql.Stmt
+	deleteExpiredEmailChangesStmt            *sql.Stmt
+	deleteExpiredOAuthAuthorizationCodesStmt *sql.Stmt
+	deleteFinishedJobsStmt                   *sql.Stmt
+	deleteFollowRequestStmt                  *sql.Stmt
+	deleteMutedWordStmt                      *sql.Stmt
+	deleteOAuthClientStmt                    *sql.Stmt
+	deletePushSubscriptionStmt               *sql.Stmt
+	deletePushSubscriptionByIDStmt           *sql.Stmt
+	deleteRefreshTokensStmt                  *sql.Stmt
+	deleteStaleRefreshTokensStmt             *sql.Stmt
+	deleteUsersStmt                          *sql.Stmt
+	deleteUsersForTenantStmt                 *sql.Stmt
+	deleteWebhookStmt                        *sql.Stmt
+	enqueueJobStmt                           *sql.Stmt
+	failJobStmt                              *sql.Stmt
+	followUserStmt                           *sql.Stmt
+	getActiveUserCountsStmt                  *sql.Stmt
+	getAuditLogStmt                          *sql.Stmt
+	getBannedWordsStmt                       *sql.Stmt
+	getChirpAncestorsStmt                    *sql.Stmt
+	getChirpAuthorsStmt                      *sql.Stmt
+	getChirpCountsStmt                       *sql.Stmt
+	getChirpEngagementStmt                   *sql.Stmt
+	getChirpLinkStmt                         *sql.Stmt
+	getChirpReplyTreeStmt                    *sql.Stmt
+	getChirpViewsByDayStmt                   *sql.Stmt
+	getChirpsStmt                            *sql.Stmt
+	getChirpsByIDsStmt                       *sql.Stmt
+	getChirpsByUserStmt                      *sql.Stmt
+	getChirpsByUserAscStmt                   *sql.Stmt
+	getChirpsPageStmt                        *sql.Stmt
+	getDailyActiveUsersStmt                  *sql.Stmt
+	getDeniedIPsStmt                         *sql.Stmt
+	getDeviceByTokenStmt                     *sql.Stmt
+	getDevicesForUserStmt                    *sql.Stmt
+	getFeedCandidatesStmt                    *sql.Stmt
+	getFollowRequestsStmt                    *sql.Stmt
+	getFollowersStmt                         *sql.Stmt
+	getFollowingStmt                         *sql.Stmt
+	getIndividualChirpStmt                   *sql.Stmt
+	getLatestFeedStmt                        *sql.Stmt
+	getLinksForChirpsStmt                    *sql.Stmt
+	getMetricStmt                            *sql.Stmt
+	getMutedWordsStmt                        *sql.Stmt
+	getNotificationSettingsStmt              *sql.Stmt
+	getNotificationsForUserStmt              *sql.Stmt
+	getOAuthClientStmt                       *sql.Stmt
+	getOAuthClientsForOwnerStmt              *sql.Stmt
+	getPersonalAccessTokenByHashStmt         *sql.Stmt
+	getPersonalAccessTokensForUserStmt       *sql.Stmt
+	getPushSubscriptionStmt                  *sql.Stmt
+	getPushSubscriptionsForUserStmt          *sql.Stmt
+	getRefreshTokenForUpdateStmt             *sql.Stmt
+	getSettingsStmt                          *sql.Stmt
+	getSignupsPerDayStmt                     *sql.Stmt
+	getTableStatsStmt                        *sql.Stmt
+	getTenantsStmt                           *sql.Stmt
+	getTopChirpersStmt                       *sql.Stmt
+	getTopLikedUsersStmt                     *sql.Stmt
+	getUserByEmailStmt                       *sql.Stmt
+	getUserByIDNoPasswordStmt                *sql.Stmt
+	getUserByOldHandleStmt                   *sql.Stmt
+	getUserFromRefreshTokenStmt              *sql.Stmt
+	getUserHandleForUpdateStmt               *sql.Stmt
+	getUserIDByHandleStmt                    *sql.Stmt
+	getUserIsAdminStmt                       *sql.Stmt
+	getUserProfileStmt                       *sql.Stmt
+	getVisibleChirpStmt                      *sql.Stmt
+	getWebhookStmt                           *sql.Stmt
+	getWebhooksByUserStmt                    *sql.Stmt
+	getWebhooksForEventStmt                  *sql.Stmt
+	isChirpMutedStmt                         *sql.Stmt
+	isFollowingStmt                          *sql.Stmt
+	likeChirpStmt                            *sql.Stmt
+	markAllNotificationsReadStmt             *sql.Stmt
+	markNotificationReadStmt                 *sql.Stmt
+	reactivateUserStmt                       *sql.Stmt
+	rechirpStmt                              *sql.Stmt
+	recordUserActivityStmt                   *sql.Stmt
+	renameDeviceStmt                         *sql.Stmt
+	requeueStaleJobsStmt                     *sql.Stmt
+	retryJobStmt                             *sql.Stmt
+	revokePersonalAccessTokenStmt            *sql.Stmt
+	revokePersonalAccessTokensForUserStmt    *sql.Stmt
+	revokeRefreshTokenStmt                   *sql.Stmt
+	revokeRefreshTokensForUserStmt           *sql.Stmt
+	searchChirpsStmt                         *sql.Stmt
+	setDeviceConfirmTokenStmt                *sql.Stmt
+	setMetricStmt                            *sql.Stmt
+	setPinnedChirpStmt                       *sql.Stmt
+	setUserHandleStmt                        *sql.Stmt
+	setUserPreferencesStmt                   *sql.Stmt
+	setUserProtectedStmt                     *sql.Stmt
+	setUserShadowBanStmt                     *sql.Stmt
+	touchDeviceStmt                          *sql.Stmt
+	touchPersonalAccessTokenStmt             *sql.Stmt
+	unbanUserStmt                            *sql.Stmt
+	undoRechirpStmt                          *sql.Stmt
+	unfollowUserStmt                         *sql.Stmt
+	unlikeChirpStmt                          *sql.Stmt
+	updateChirpLinkPreviewStmt               *sql.Stmt
+	updateIsChirpyRedByIDStmt                *sql.Stmt
+	updateUserEmailStmt                      *sql.Stmt
+	updateUserPasswordStmt                   *sql.Stmt
+	upsertMetricStmt                         *sql.Stmt
+	upsertNotificationSettingsStmt           *sql.Stmt
+	upsertPushSubscriptionStmt               *sql.Stmt
+	upsertSettingStmt                        *sql.Stmt
+	userExistsStmt                           *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db: tx,
+		db:                                       tx,
+		tx:                                       tx,
+		acceptAllFollowRequestsStmt:              q.acceptAllFollowRequestsStmt,
+		addChirpViewsStmt:                        q.addChirpViewsStmt,
+		addHandleHistoryStmt:                     q.addHandleHistoryStmt,
+		addMutedWordStmt:                         q.addMutedWordStmt,
+		banUserStmt:                              q.banUserStmt,
+		claimJobStmt:                             q.claimJobStmt,
+		clearPinnedChirpStmt:                     q.clearPinnedChirpStmt,
+		completeJobStmt:                          q.completeJobStmt,
+		confirmDeviceStmt:                        q.confirmDeviceStmt,
+		consumeEmailChangeStmt:                   q.consumeEmailChangeStmt,
+		consumeOAuthAuthorizationCodeStmt:        q.consumeOAuthAuthorizationCodeStmt,
+		countDevicesForUserStmt:                  q.countDevicesForUserStmt,
+		countHandleChangesSinceStmt:              q.countHandleChangesSinceStmt,
+		countMutedWordsStmt:                      q.countMutedWordsStmt,
+		countRecentChirpsByUserStmt:              q.countRecentChirpsByUserStmt,
+		countRecentDuplicateChirpsStmt:           q.countRecentDuplicateChirpsStmt,
+		countUnreadNotificationsStmt:             q.countUnreadNotificationsStmt,
+		createAuditLogEntryStmt:                  q.createAuditLogEntryStmt,
+		createBannedWordStmt:                     q.createBannedWordStmt,
+		createChirpStmt:                          q.createChirpStmt,
+		createChirpLinkStmt:                      q.createChirpLinkStmt,
+		createChirpsStmt:                         q.createChirpsStmt,
+		createDeniedIPStmt:                       q.createDeniedIPStmt,
+		createDeviceStmt:                         q.createDeviceStmt,
+		createEmailChangeStmt:                    q.createEmailChangeStmt,
+		createFollowRequestStmt:                  q.createFollowRequestStmt,
+		createNotificationStmt:                   q.createNotificationStmt,
+		createOAuthAuthorizationCodeStmt:         q.createOAuthAuthorizationCodeStmt,
+		createOAuthClientStmt:                    q.createOAuthClientStmt,
+		createPersonalAccessTokenStmt:            q.createPersonalAccessTokenStmt,
+		createRefreshTokenStmt:                   q.createRefreshTokenStmt,
+		createUserStmt:                           q.createUserStmt,
+		createWebhookStmt:                        q.createWebhookStmt,
+		deactivateUserStmt:                       q.deactivateUserStmt,
+		deleteBannedWordStmt:                     q.deleteBannedWordStmt,
+		deleteChirpByIDStmt:                      q.deleteChirpByIDStmt,
+		deleteChirpsStmt:                         q.deleteChirpsStmt,
+		deleteChirpsForTenantStmt:                q.deleteChirpsForTenantStmt,
+		deleteDeactivatedUsersStmt:               q.deleteDeactivatedUsersStmt,
+		deleteDeniedIPStmt:                       q.deleteDeniedIPStmt,
+		deleteDeviceStmt:                         q.deleteDeviceStmt,
+		deleteExpiredEmailChangesStmt:            q.deleteExpiredEmailChangesStmt,
+		deleteExpiredOAuthAuthorizationCodesStmt: q.deleteExpiredOAuthAuthorizationCodesStmt,
+		deleteFinishedJobsStmt:                   q.deleteFinishedJobsStmt,
+		deleteFollowRequestStmt:                  q.deleteFollowRequestStmt,
+		deleteMutedWordStmt:                      q.deleteMutedWordStmt,
+		deleteOAuthClientStmt:                    q.deleteOAuthClientStmt,
+		deletePushSubscriptionStmt:               q.deletePushSubscriptionStmt,
+		deletePushSubscriptionByIDStmt:           q.deletePushSubscriptionByIDStmt,
+		deleteRefreshTokensStmt:                  q.deleteRefreshTokensStmt,
+		deleteStaleRefreshTokensStmt:             q.deleteStaleRefreshTokensStmt,
+		deleteUsersStmt:                          q.deleteUsersStmt,
+		deleteUsersForTenantStmt:                 q.deleteUsersForTenantStmt,
+		deleteWebhookStmt:                        q.deleteWebhookStmt,
+		enqueueJobStmt:                           q.enqueueJobStmt,
+		failJobStmt:                              q.failJobStmt,
+		followUserStmt:                           q.followUserStmt,
+		getActiveUserCountsStmt:                  q.getActiveUserCountsStmt,
+		getAuditLogStmt:                          q.getAuditLogStmt,
+		getBannedWordsStmt:                       q.getBannedWordsStmt,
+		getChirpAncestorsStmt:                    q.getChirpAncestorsStmt,
+		getChirpAuthorsStmt:                      q.getChirpAuthorsStmt,
+		getChirpCountsStmt:                       q.getChirpCountsStmt,
+		getChirpEngagementStmt:                   q.getChirpEngagementStmt,
+		getChirpLinkStmt:                         q.getChirpLinkStmt,
+		getChirpReplyTreeStmt:                    q.getChirpReplyTreeStmt,
+		getChirpViewsByDayStmt:                   q.getChirpViewsByDayStmt,
+		getChirpsStmt:                            q.getChirpsStmt,
+		getChirpsByIDsStmt:                       q.getChirpsByIDsStmt,
+		getChirpsByUserStmt:                      q.getChirpsByUserStmt,
+		getChirpsByUserAscStmt:                   q.getChirpsByUserAscStmt,
+		getChirpsPageStmt:                        q.getChirpsPageStmt,
+		getDailyActiveUsersStmt:                  q.getDailyActiveUsersStmt,
+		getDeniedIPsStmt:                         q.getDeniedIPsStmt,
+		getDeviceByTokenStmt:                     q.getDeviceByTokenStmt,
+		getDevicesForUserStmt:                    q.getDevicesForUserStmt,
+		getFeedCandidatesStmt:                    q.getFeedCandidatesStmt,
+		getFollowRequestsStmt:                    q.getFollowRequestsStmt,
+		getFollowersStmt:                         q.getFollowersStmt,
+		getFollowingStmt:                         q.getFollowingStmt,
+		getIndividualChirpStmt:                   q.getIndividualChirpStmt,
+		getLatestFeedStmt:                        q.getLatestFeedStmt,
+		getLinksForChirpsStmt:                    q.getLinksForChirpsStmt,
+		getMetricStmt:                            q.getMetricStmt,
+		getMutedWordsStmt:                        q.getMutedWordsStmt,
+		getNotificationSettingsStmt:              q.getNotificationSettingsStmt,
+		getNotificationsForUserStmt:              q.getNotificationsForUserStmt,
+		getOAuthClientStmt:                       q.getOAuthClientStmt,
+		getOAuthClientsForOwnerStmt:              q.getOAuthClientsForOwnerStmt,
+		getPersonalAccessTokenByHashStmt:         q.getPersonalAccessTokenByHashStmt,
+		getPersonalAccessTokensForUserStmt:       q.getPersonalAccessTokensForUserStmt,
+		getPushSubscriptionStmt:                  q.getPushSubscriptionStmt,
+		getPushSubscriptionsForUserStmt:          q.getPushSubscriptionsForUserStmt,
+		getRefreshTokenForUpdateStmt:             q.getRefreshTokenForUpdateStmt,
+		getSettingsStmt:                          q.getSettingsStmt,
+		getSignupsPerDayStmt:                     q.getSignupsPerDayStmt,
+		getTableStatsStmt:                        q.getTableStatsStmt,
+		getTenantsStmt:                           q.getTenantsStmt,
+		getTopChirpersStmt:                       q.getTopChirpersStmt,
+		getTopLikedUsersStmt:                     q.getTopLikedUsersStmt,
+		getUserByEmailStmt:                       q.getUserByEmailStmt,
+		getUserByIDNoPasswordStmt:                q.getUserByIDNoPasswordStmt,
+		getUserByOldHandleStmt:                   q.getUserByOldHandleStmt,
+		getUserFromRefreshTokenStmt:              q.getUserFromRefreshTokenStmt,
+		getUserHandleForUpdateStmt:               q.getUserHandleForUpdateStmt,
+		getUserIDByHandleStmt:                    q.getUserIDByHandleStmt,
+		getUserIsAdminStmt:                       q.getUserIsAdminStmt,
+		getUserProfileStmt:                       q.getUserProfileStmt,
+		getVisibleChirpStmt:                      q.getVisibleChirpStmt,
+		getWebhookStmt:                           q.getWebhookStmt,
+		getWebhooksByUserStmt:                    q.getWebhooksByUserStmt,
+		getWebhooksForEventStmt:                  q.getWebhooksForEventStmt,
+		isChirpMutedStmt:                         q.isChirpMutedStmt,
+		isFollowingStmt:                          q.isFollowingStmt,
+		likeChirpStmt:                            q.likeChirpStmt,
+		markAllNotificationsReadStmt:             q.markAllNotificationsReadStmt,
+		markNotificationReadStmt:                 q.markNotificationReadStmt,
+		reactivateUserStmt:                       q.reactivateUserStmt,
+		rechirpStmt:                              q.rechirpStmt,
+		recordUserActivityStmt:                   q.recordUserActivityStmt,
+		renameDeviceStmt:                         q.renameDeviceStmt,
+		requeueStaleJobsStmt:                     q.requeueStaleJobsStmt,
+		retryJobStmt:                             q.retryJobStmt,
+		revokePersonalAccessTokenStmt:            q.revokePersonalAccessTokenStmt,
+		revokePersonalAccessTokensForUserStmt:    q.revokePersonalAccessTokensForUserStmt,
+		revokeRefreshTokenStmt:                   q.revokeRefreshTokenStmt,
+		revokeRefreshTokensForUserStmt:           q.revokeRefreshTokensForUserStmt,
+		searchChirpsStmt:                         q.searchChirpsStmt,
+		setDeviceConfirmTokenStmt:                q.setDeviceConfirmTokenStmt,
+		setMetricStmt:                            q.setMetricStmt,
+		setPinnedChirpStmt:                       q.setPinnedChirpStmt,
+		setUserHandleStmt:                        q.setUserHandleStmt,
+		setUserPreferencesStmt:                   q.setUserPreferencesStmt,
+		setUserProtectedStmt:                     q.setUserProtectedStmt,
+		setUserShadowBanStmt:                     q.setUserShadowBanStmt,
+		touchDeviceStmt:                          q.touchDeviceStmt,
+		touchPersonalAccessTokenStmt:             q.touchPersonalAccessTokenStmt,
+		unbanUserStmt:                            q.unbanUserStmt,
+		undoRechirpStmt:                          q.undoRechirpStmt,
+		unfollowUserStmt:                         q.unfollowUserStmt,
+		unlikeChirpStmt:                          q.unlikeChirpStmt,
+		updateChirpLinkPreviewStmt:               q.updateChirpLinkPreviewStmt,
+		updateIsChirpyRedByIDStmt:                q.updateIsChirpyRedByIDStmt,
+		updateUserEmailStmt:                      q.updateUserEmailStmt,
+		updateUserPasswordStmt:                   q.updateUserPasswordStmt,
+		upsertMetricStmt:                         q.upsertMetricStmt,
+		upsertNotificationSettingsStmt:           q.upsertNotificationSettingsStmt,
+		upsertPushSubscriptionStmt:               q.upsertPushSubscriptionStmt,
+		upsertSettingStmt:                        q.upsertSettingStmt,
+		userExistsStmt:                           q.userExistsStmt,
 	}
 }
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// What callers usually need to tell apart about a failed query. Wrap a query's error to match them with errors.Is.
+var (
+	// No row matched, the query's sql.ErrNoRows
+	ErrNotFound = errors.New("not found")
+	// A UNIQUE constraint rejected the row, Postgres unique_violation
+	ErrDuplicate = errors.New("already exists")
+	// The row points at one that doesn't exist, Postgres foreign_key_violation
+	ErrForeignKey = errors.New("referenced row doesn't exist")
+)
+
+// A query error sorted into one of the errors above. The original error stays reachable with errors.Is/As.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Returns err wrapped in an *Error when it's one of ErrNotFound, ErrDuplicate or ErrForeignKey, anything else
+// (including nil and errors that are already wrapped) comes back unchanged
+func Wrap(err error) error {
+	var wrapped *Error
+	if err == nil || errors.As(err, &wrapped) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			return &Error{Kind: ErrDuplicate, Err: err}
+		case "23503":
+			return &Error{Kind: ErrForeignKey, Err: err}
+		}
+	}
+
+	return err
+}
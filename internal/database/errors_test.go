@@ -0,0 +1,44 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestWrap(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{fmt.Errorf("loading user: %w", sql.ErrNoRows), ErrNotFound},
+		{&pq.Error{Code: "23505"}, ErrDuplicate},
+		{&pq.Error{Code: "23503"}, ErrForeignKey},
+	}
+
+	for _, c := range cases {
+		err := Wrap(c.err)
+		if !errors.Is(err, c.kind) || !errors.Is(err, c.err) {
+			t.Errorf("Wrap(%v) = %v, expected it to match %v and the original", c.err, err, c.kind)
+		}
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(Wrap(&pq.Error{Code: "23505"}), &pqErr) {
+		t.Error("expected the *pq.Error to stay reachable")
+	}
+
+	other := &pq.Error{Code: "42P01"}
+	if Wrap(other) != other || Wrap(nil) != nil {
+		t.Error("expected other errors back unchanged")
+	}
+
+	once := Wrap(sql.ErrNoRows)
+	if Wrap(once) != once {
+		t.Error("expected wrapping twice to be a no-op")
+	}
+}
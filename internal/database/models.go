@@ -6,17 +6,206 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditLog struct {
+	ID        uuid.UUID       `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	TenantID  uuid.UUID       `json:"tenant_id"`
+	ActorID   uuid.NullUUID   `json:"actor_id"`
+	Action    string          `json:"action"`
+	TargetID  uuid.NullUUID   `json:"target_id"`
+	Details   json.RawMessage `json:"details"`
+}
+
+type BannedWord struct {
+	Word      string    `json:"word"`
+	CreatedAt time.Time `json:"created_at"`
+	IsPattern bool      `json:"is_pattern"`
+	Severity  string    `json:"severity"`
+}
+
 type Chirp struct {
+	ID             uuid.UUID      `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Body           string         `json:"body"`
+	UserID         uuid.UUID      `json:"user_id"`
+	ContentHash    string         `json:"content_hash"`
+	ReplyToID      uuid.NullUUID  `json:"reply_to_id"`
+	TenantID       uuid.UUID      `json:"tenant_id"`
+	Sensitive      bool           `json:"sensitive"`
+	ContentWarning sql.NullString `json:"content_warning"`
+	Language       sql.NullString `json:"language"`
+	Visibility     string         `json:"visibility"`
+}
+
+type ChirpLink struct {
+	ID          uuid.UUID      `json:"id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	ChirpID     uuid.UUID      `json:"chirp_id"`
+	Position    int32          `json:"position"`
+	Url         string         `json:"url"`
+	Title       sql.NullString `json:"title"`
+	Description sql.NullString `json:"description"`
+	ImageUrl    sql.NullString `json:"image_url"`
+	FetchedAt   sql.NullTime   `json:"fetched_at"`
+	FetchError  sql.NullString `json:"fetch_error"`
+}
+
+type ChirpView struct {
+	ChirpID uuid.UUID `json:"chirp_id"`
+	Day     time.Time `json:"day"`
+	Views   int64     `json:"views"`
+}
+
+type Device struct {
+	ID               uuid.UUID      `json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UserID           uuid.UUID      `json:"user_id"`
+	TokenHash        string         `json:"token_hash"`
+	Name             sql.NullString `json:"name"`
+	UserAgent        string         `json:"user_agent"`
+	LastIp           string         `json:"last_ip"`
+	LastSeenAt       time.Time      `json:"last_seen_at"`
+	ConfirmedAt      sql.NullTime   `json:"confirmed_at"`
+	ConfirmTokenHash sql.NullString `json:"confirm_token_hash"`
+	ConfirmExpiresAt sql.NullTime   `json:"confirm_expires_at"`
+}
+
+type EmailChange struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	NewEmail  string    `json:"new_email"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Follow struct {
+	FollowerID uuid.UUID `json:"follower_id"`
+	FolloweeID uuid.UUID `json:"followee_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FollowRequest struct {
+	RequesterID uuid.UUID `json:"requester_id"`
+	TargetID    uuid.UUID `json:"target_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type HandleHistory struct {
 	ID        uuid.UUID `json:"id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Handle    string    `json:"handle"`
+	UserID    uuid.UUID `json:"user_id"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type IpDenyList struct {
+	Cidr      string    `json:"cidr"`
 	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+}
+
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   sql.NullString  `json:"last_error"`
+}
+
+type Like struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Metric struct {
+	Name      string    `json:"name"`
+	Value     int64     `json:"value"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
+}
+
+type MutedWord struct {
 	UserID    uuid.UUID `json:"user_id"`
+	Phrase    string    `json:"phrase"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Notification struct {
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UserID    uuid.UUID     `json:"user_id"`
+	ActorID   uuid.NullUUID `json:"actor_id"`
+	Kind      string        `json:"kind"`
+	ChirpID   uuid.NullUUID `json:"chirp_id"`
+	ReadAt    sql.NullTime  `json:"read_at"`
+}
+
+type NotificationSetting struct {
+	UserID        uuid.UUID `json:"user_id"`
+	DisabledKinds []string  `json:"disabled_kinds"`
+	Email         bool      `json:"email"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string    `json:"code_hash"`
+	CreatedAt     time.Time `json:"created_at"`
+	ClientID      uuid.UUID `json:"client_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	RedirectUri   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes"`
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type OauthClient struct {
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	OwnerID      uuid.UUID      `json:"owner_id"`
+	Name         string         `json:"name"`
+	RedirectUris []string       `json:"redirect_uris"`
+	SecretHash   sql.NullString `json:"secret_hash"`
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID     `json:"id"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UserID     uuid.UUID     `json:"user_id"`
+	Name       string        `json:"name"`
+	TokenHash  string        `json:"token_hash"`
+	Scopes     []string      `json:"scopes"`
+	ExpiresAt  sql.NullTime  `json:"expires_at"`
+	LastUsedAt sql.NullTime  `json:"last_used_at"`
+	RevokedAt  sql.NullTime  `json:"revoked_at"`
+	ClientID   uuid.NullUUID `json:"client_id"`
+}
+
+type PushSubscription struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"p256dh"`
+	Auth      string    `json:"auth"`
+}
+
+type Rechirp struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type RefreshToken struct {
@@ -28,11 +217,57 @@ type RefreshToken struct {
 	RevokedAt sql.NullTime `json:"revoked_at"`
 }
 
+type Setting struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	UpdatedBy uuid.NullUUID   `json:"updated_by"`
+}
+
+type Tenant struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Slug      string         `json:"slug"`
+	Host      sql.NullString `json:"host"`
+	Name      string         `json:"name"`
+}
+
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
-	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	ID               uuid.UUID      `json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	Email            string         `json:"email"`
+	HashedPassword   string         `json:"hashed_password"`
+	IsChirpyRed      bool           `json:"is_chirpy_red"`
+	IsAdmin          bool           `json:"is_admin"`
+	PinnedChirpID    uuid.NullUUID  `json:"pinned_chirp_id"`
+	Handle           sql.NullString `json:"handle"`
+	DisplayName      sql.NullString `json:"display_name"`
+	AvatarUrl        sql.NullString `json:"avatar_url"`
+	TenantID         uuid.UUID      `json:"tenant_id"`
+	Locale           string         `json:"locale"`
+	Timezone         string         `json:"timezone"`
+	DeactivatedAt    sql.NullTime   `json:"deactivated_at"`
+	ShadowBannedAt   sql.NullTime   `json:"shadow_banned_at"`
+	BannedAt         sql.NullTime   `json:"banned_at"`
+	BannedUntil      sql.NullTime   `json:"banned_until"`
+	BanReason        sql.NullString `json:"ban_reason"`
+	SensitiveContent string         `json:"sensitive_content"`
+	Protected        bool           `json:"protected"`
+}
+
+type UserActivity struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Day      time.Time `json:"day"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+type Webhook struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"`
 }
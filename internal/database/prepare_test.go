@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// Runs against a real, migrated Postgres. Skipped unless TEST_DB_URL points at one, e.g.
+//
+//	TEST_DB_URL=postgres://... go test ./internal/database -bench . -run '^$'
+func benchDB(b *testing.B) *sql.DB {
+	b.Helper()
+
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		b.Skip("TEST_DB_URL not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// Runs fn once with plain Queries and once with prepared ones so the two show up side by side
+func benchPrepared(b *testing.B, fn func(b *testing.B, q *Queries)) {
+	db := benchDB(b)
+
+	b.Run("unprepared", func(b *testing.B) {
+		fn(b, New(db))
+	})
+
+	b.Run("prepared", func(b *testing.B) {
+		q, err := Prepare(context.Background(), db)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer q.Close()
+
+		fn(b, q)
+	})
+}
+
+func BenchmarkGetUserByEmail(b *testing.B) {
+	benchPrepared(b, func(b *testing.B, q *Queries) {
+		ctx := context.Background()
+		for b.Loop() {
+			if _, err := q.GetUserByEmail(ctx, GetUserByEmailParams{Email: "nobody@example.com"}); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGetChirps(b *testing.B) {
+	benchPrepared(b, func(b *testing.B, q *Queries) {
+		ctx := context.Background()
+		// An empty window keeps the benchmark about the round trip rather than however many rows are lying around
+		arg := GetChirpsParams{Since: time.Now(), Before: time.Now()}
+		for b.Loop() {
+			if _, err := q.GetChirps(ctx, arg); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -25,7 +25,51 @@ type CreateRefreshTokenParams struct {
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID)
+	row := q.queryRow(ctx, q.createRefreshTokenStmt, createRefreshToken, arg.Token, arg.UserID)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const deleteRefreshTokens = `-- name: DeleteRefreshTokens :exec
+TRUNCATE TABLE refresh_tokens
+`
+
+func (q *Queries) DeleteRefreshTokens(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteRefreshTokensStmt, deleteRefreshTokens)
+	return err
+}
+
+const deleteStaleRefreshTokens = `-- name: DeleteStaleRefreshTokens :execrows
+DELETE
+FROM refresh_tokens
+WHERE revoked_at IS NOT NULL OR expires_at < NOW()
+`
+
+func (q *Queries) DeleteStaleRefreshTokens(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteStaleRefreshTokensStmt, deleteStaleRefreshTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getRefreshTokenForUpdate = `-- name: GetRefreshTokenForUpdate :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at
+FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRefreshTokenForUpdate(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.queryRow(ctx, q.getRefreshTokenForUpdateStmt, getRefreshTokenForUpdate, token)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -45,7 +89,7 @@ WHERE token = $1
 `
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getUserFromRefreshToken, token)
+	row := q.queryRow(ctx, q.getUserFromRefreshTokenStmt, getUserFromRefreshToken, token)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -67,6 +111,19 @@ WHERE token = $1
 `
 
 func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeRefreshToken, token)
+	_, err := q.exec(ctx, q.revokeRefreshTokenStmt, revokeRefreshToken, token)
+	return err
+}
+
+const revokeRefreshTokensForUser = `-- name: RevokeRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokensForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.exec(ctx, q.revokeRefreshTokensForUserStmt, revokeRefreshTokensForUser, userID)
 	return err
 }
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Runs fn inside a transaction, committing if it returns nil and rolling back otherwise.
+// fn gets a copy of q bound to the transaction, so every query it makes is part of it. Errors come back through Wrap.
+func WithTx(ctx context.Context, db *sql.DB, q *Queries, fn func(qtx *Queries) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// No-op once Commit has succeeded
+	defer tx.Rollback()
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return Wrap(err)
+	}
+
+	return Wrap(tx.Commit())
+}
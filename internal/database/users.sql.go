@@ -7,60 +7,156 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const banUser = `-- name: BanUser :execrows
+UPDATE users
+    SET banned_at = NOW(),
+        banned_until = $2,
+        ban_reason = $3,
+        updated_at = NOW()
+WHERE id = $1 AND tenant_id = $4
+`
+
+type BanUserParams struct {
+	ID          uuid.UUID      `json:"id"`
+	BannedUntil sql.NullTime   `json:"banned_until"`
+	BanReason   sql.NullString `json:"ban_reason"`
+	TenantID    uuid.UUID      `json:"tenant_id"`
+}
+
+func (q *Queries) BanUser(ctx context.Context, arg BanUserParams) (int64, error) {
+	result, err := q.exec(ctx, q.banUserStmt, banUser,
+		arg.ID,
+		arg.BannedUntil,
+		arg.BanReason,
+		arg.TenantID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const clearPinnedChirp = `-- name: ClearPinnedChirp :execrows
+UPDATE users
+    SET pinned_chirp_id = NULL,
+        updated_at = NOW()
+WHERE id = $1 AND pinned_chirp_id = $2
+`
+
+type ClearPinnedChirpParams struct {
+	ID            uuid.UUID     `json:"id"`
+	PinnedChirpID uuid.NullUUID `json:"pinned_chirp_id"`
+}
+
+func (q *Queries) ClearPinnedChirp(ctx context.Context, arg ClearPinnedChirpParams) (int64, error) {
+	result, err := q.exec(ctx, q.clearPinnedChirpStmt, clearPinnedChirp, arg.ID, arg.PinnedChirpID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, tenant_id)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
 )
-RETURNING id, created_at, updated_at, email
+RETURNING id, created_at, updated_at, email, is_chirpy_red
 `
 
 type CreateUserParams struct {
-	Email          string `json:"email"`
-	HashedPassword string `json:"hashed_password"`
+	Email          string    `json:"email"`
+	HashedPassword string    `json:"hashed_password"`
+	TenantID       uuid.UUID `json:"tenant_id"`
 }
 
 type CreateUserRow struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.queryRow(ctx, q.createUserStmt, createUser, arg.Email, arg.HashedPassword, arg.TenantID)
 	var i CreateUserRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Email,
+		&i.IsChirpyRed,
 	)
 	return i, err
 }
 
+const deactivateUser = `-- name: DeactivateUser :execrows
+UPDATE users
+    SET deactivated_at = NOW(),
+        updated_at = NOW()
+WHERE id = $1 AND deactivated_at IS NULL
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.exec(ctx, q.deactivateUserStmt, deactivateUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteDeactivatedUsers = `-- name: DeleteDeactivatedUsers :execrows
+DELETE FROM users
+WHERE deactivated_at < $1
+`
+
+func (q *Queries) DeleteDeactivatedUsers(ctx context.Context, deactivatedAt sql.NullTime) (int64, error) {
+	result, err := q.exec(ctx, q.deleteDeactivatedUsersStmt, deleteDeactivatedUsers, deactivatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 TRUNCATE TABLE users CASCADE
 `
 
 func (q *Queries) DeleteUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteUsers)
+	_, err := q.exec(ctx, q.deleteUsersStmt, deleteUsers)
+	return err
+}
+
+const deleteUsersForTenant = `-- name: DeleteUsersForTenant :exec
+DELETE FROM users
+WHERE tenant_id = $1
+`
+
+func (q *Queries) DeleteUsersForTenant(ctx context.Context, tenantID uuid.UUID) error {
+	_, err := q.exec(ctx, q.deleteUsersForTenantStmt, deleteUsersForTenant, tenantID)
 	return err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, is_admin, pinned_chirp_id, handle, display_name, avatar_url, tenant_id, locale, timezone, deactivated_at, shadow_banned_at, banned_at, banned_until, ban_reason, sensitive_content, protected
 FROM users
-WHERE email = $1
+WHERE email = $1 AND tenant_id = $2
 `
 
-func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
-	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+type GetUserByEmailParams struct {
+	Email    string    `json:"email"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetUserByEmail(ctx context.Context, arg GetUserByEmailParams) (User, error) {
+	row := q.queryRow(ctx, q.getUserByEmailStmt, getUserByEmail, arg.Email, arg.TenantID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -69,35 +165,282 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.IsAdmin,
+		&i.PinnedChirpID,
+		&i.Handle,
+		&i.DisplayName,
+		&i.AvatarUrl,
+		&i.TenantID,
+		&i.Locale,
+		&i.Timezone,
+		&i.DeactivatedAt,
+		&i.ShadowBannedAt,
+		&i.BannedAt,
+		&i.BannedUntil,
+		&i.BanReason,
+		&i.SensitiveContent,
+		&i.Protected,
 	)
 	return i, err
 }
 
 const getUserByIDNoPassword = `-- name: GetUserByIDNoPassword :one
-SELECT id, created_at, updated_at, email
+SELECT id, created_at, updated_at, email, is_chirpy_red
 FROM users
 WHERE id = $1
 `
 
 type GetUserByIDNoPasswordRow struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Email       string    `json:"email"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
 }
 
 func (q *Queries) GetUserByIDNoPassword(ctx context.Context, id uuid.UUID) (GetUserByIDNoPasswordRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserByIDNoPassword, id)
+	row := q.queryRow(ctx, q.getUserByIDNoPasswordStmt, getUserByIDNoPassword, id)
 	var i GetUserByIDNoPasswordRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Email,
+		&i.IsChirpyRed,
 	)
 	return i, err
 }
 
+const getUserHandleForUpdate = `-- name: GetUserHandleForUpdate :one
+SELECT handle, tenant_id
+FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+type GetUserHandleForUpdateRow struct {
+	Handle   sql.NullString `json:"handle"`
+	TenantID uuid.UUID      `json:"tenant_id"`
+}
+
+func (q *Queries) GetUserHandleForUpdate(ctx context.Context, id uuid.UUID) (GetUserHandleForUpdateRow, error) {
+	row := q.queryRow(ctx, q.getUserHandleForUpdateStmt, getUserHandleForUpdate, id)
+	var i GetUserHandleForUpdateRow
+	err := row.Scan(&i.Handle, &i.TenantID)
+	return i, err
+}
+
+const getUserIDByHandle = `-- name: GetUserIDByHandle :one
+SELECT id
+FROM users
+WHERE handle = $1 AND tenant_id = $2 AND deactivated_at IS NULL
+`
+
+type GetUserIDByHandleParams struct {
+	Handle   sql.NullString `json:"handle"`
+	TenantID uuid.UUID      `json:"tenant_id"`
+}
+
+func (q *Queries) GetUserIDByHandle(ctx context.Context, arg GetUserIDByHandleParams) (uuid.UUID, error) {
+	row := q.queryRow(ctx, q.getUserIDByHandleStmt, getUserIDByHandle, arg.Handle, arg.TenantID)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getUserIsAdmin = `-- name: GetUserIsAdmin :one
+SELECT is_admin, tenant_id
+FROM users
+WHERE id = $1
+`
+
+type GetUserIsAdminRow struct {
+	IsAdmin  bool      `json:"is_admin"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetUserIsAdmin(ctx context.Context, id uuid.UUID) (GetUserIsAdminRow, error) {
+	row := q.queryRow(ctx, q.getUserIsAdminStmt, getUserIsAdmin, id)
+	var i GetUserIsAdminRow
+	err := row.Scan(&i.IsAdmin, &i.TenantID)
+	return i, err
+}
+
+const getUserProfile = `-- name: GetUserProfile :one
+SELECT id, created_at, email, is_chirpy_red, pinned_chirp_id, handle, locale, timezone, sensitive_content, protected
+FROM users
+WHERE id = $1 AND tenant_id = $2 AND deactivated_at IS NULL
+`
+
+type GetUserProfileParams struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+type GetUserProfileRow struct {
+	ID               uuid.UUID      `json:"id"`
+	CreatedAt        time.Time      `json:"created_at"`
+	Email            string         `json:"email"`
+	IsChirpyRed      bool           `json:"is_chirpy_red"`
+	PinnedChirpID    uuid.NullUUID  `json:"pinned_chirp_id"`
+	Handle           sql.NullString `json:"handle"`
+	Locale           string         `json:"locale"`
+	Timezone         string         `json:"timezone"`
+	SensitiveContent string         `json:"sensitive_content"`
+	Protected        bool           `json:"protected"`
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, arg GetUserProfileParams) (GetUserProfileRow, error) {
+	row := q.queryRow(ctx, q.getUserProfileStmt, getUserProfile, arg.ID, arg.TenantID)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Email,
+		&i.IsChirpyRed,
+		&i.PinnedChirpID,
+		&i.Handle,
+		&i.Locale,
+		&i.Timezone,
+		&i.SensitiveContent,
+		&i.Protected,
+	)
+	return i, err
+}
+
+const reactivateUser = `-- name: ReactivateUser :exec
+UPDATE users
+    SET deactivated_at = NULL,
+        updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.exec(ctx, q.reactivateUserStmt, reactivateUser, id)
+	return err
+}
+
+const setPinnedChirp = `-- name: SetPinnedChirp :exec
+UPDATE users
+    SET pinned_chirp_id = $2,
+        updated_at = NOW()
+WHERE id = $1
+`
+
+type SetPinnedChirpParams struct {
+	ID            uuid.UUID     `json:"id"`
+	PinnedChirpID uuid.NullUUID `json:"pinned_chirp_id"`
+}
+
+func (q *Queries) SetPinnedChirp(ctx context.Context, arg SetPinnedChirpParams) error {
+	_, err := q.exec(ctx, q.setPinnedChirpStmt, setPinnedChirp, arg.ID, arg.PinnedChirpID)
+	return err
+}
+
+const setUserHandle = `-- name: SetUserHandle :exec
+UPDATE users
+    SET handle = $2,
+        updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserHandleParams struct {
+	ID     uuid.UUID      `json:"id"`
+	Handle sql.NullString `json:"handle"`
+}
+
+func (q *Queries) SetUserHandle(ctx context.Context, arg SetUserHandleParams) error {
+	_, err := q.exec(ctx, q.setUserHandleStmt, setUserHandle, arg.ID, arg.Handle)
+	return err
+}
+
+const setUserPreferences = `-- name: SetUserPreferences :exec
+UPDATE users
+    SET locale = $2,
+        timezone = $3,
+        sensitive_content = COALESCE(NULLIF($4::text, ''), sensitive_content),
+        updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserPreferencesParams struct {
+	ID               uuid.UUID `json:"id"`
+	Locale           string    `json:"locale"`
+	Timezone         string    `json:"timezone"`
+	SensitiveContent string    `json:"sensitive_content"`
+}
+
+// An empty sensitive_content leaves the current setting alone
+func (q *Queries) SetUserPreferences(ctx context.Context, arg SetUserPreferencesParams) error {
+	_, err := q.exec(ctx, q.setUserPreferencesStmt, setUserPreferences,
+		arg.ID,
+		arg.Locale,
+		arg.Timezone,
+		arg.SensitiveContent,
+	)
+	return err
+}
+
+const setUserProtected = `-- name: SetUserProtected :exec
+UPDATE users
+    SET protected = $2,
+        updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserProtectedParams struct {
+	ID        uuid.UUID `json:"id"`
+	Protected bool      `json:"protected"`
+}
+
+func (q *Queries) SetUserProtected(ctx context.Context, arg SetUserProtectedParams) error {
+	_, err := q.exec(ctx, q.setUserProtectedStmt, setUserProtected, arg.ID, arg.Protected)
+	return err
+}
+
+const setUserShadowBan = `-- name: SetUserShadowBan :execrows
+UPDATE users
+    SET shadow_banned_at = $2,
+        updated_at = NOW()
+WHERE id = $1 AND tenant_id = $3
+`
+
+type SetUserShadowBanParams struct {
+	ID             uuid.UUID    `json:"id"`
+	ShadowBannedAt sql.NullTime `json:"shadow_banned_at"`
+	TenantID       uuid.UUID    `json:"tenant_id"`
+}
+
+func (q *Queries) SetUserShadowBan(ctx context.Context, arg SetUserShadowBanParams) (int64, error) {
+	result, err := q.exec(ctx, q.setUserShadowBanStmt, setUserShadowBan, arg.ID, arg.ShadowBannedAt, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const unbanUser = `-- name: UnbanUser :execrows
+UPDATE users
+    SET banned_at = NULL,
+        banned_until = NULL,
+        ban_reason = NULL,
+        updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2
+`
+
+type UnbanUserParams struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) UnbanUser(ctx context.Context, arg UnbanUserParams) (int64, error) {
+	result, err := q.exec(ctx, q.unbanUserStmt, unbanUser, arg.ID, arg.TenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const updateIsChirpyRedByID = `-- name: UpdateIsChirpyRedByID :exec
 UPDATE users
     SET is_chirpy_red = false,
@@ -106,25 +449,58 @@ WHERE id = $1
 `
 
 func (q *Queries) UpdateIsChirpyRedByID(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, updateIsChirpyRedByID, id)
+	_, err := q.exec(ctx, q.updateIsChirpyRedByIDStmt, updateIsChirpyRedByID, id)
+	return err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :exec
+UPDATE users
+    SET email = $2,
+        updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserEmailParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) error {
+	_, err := q.exec(ctx, q.updateUserEmailStmt, updateUserEmail, arg.ID, arg.Email)
 	return err
 }
 
 const updateUserPassword = `-- name: UpdateUserPassword :exec
 UPDATE users
     SET hashed_password = $1,
-        email = $2,
         updated_at = NOW()
-WHERE id = $3
+WHERE id = $2
 `
 
 type UpdateUserPasswordParams struct {
 	HashedPassword string    `json:"hashed_password"`
-	Email          string    `json:"email"`
 	ID             uuid.UUID `json:"id"`
 }
 
 func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
-	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.HashedPassword, arg.Email, arg.ID)
+	_, err := q.exec(ctx, q.updateUserPasswordStmt, updateUserPassword, arg.HashedPassword, arg.ID)
 	return err
 }
+
+const userExists = `-- name: UserExists :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2 AND deactivated_at IS NULL
+)
+`
+
+type UserExistsParams struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) UserExists(ctx context.Context, arg UserExistsParams) (bool, error) {
+	row := q.queryRow(ctx, q.userExistsStmt, userExists, arg.ID, arg.TenantID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
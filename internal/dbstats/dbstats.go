@@ -0,0 +1,126 @@
+// Package dbstats times every query that goes through a database/sql pool, by wrapping the driver's connector so
+// prepared statements and transactions are covered as well as one-off queries.
+package dbstats
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Beyond this many distinct queries the rest are counted together, so ad hoc SQL can't grow the map forever
+const maxQueries = 500
+
+// Name everything past maxQueries is recorded under
+const otherQueries = "(other)"
+
+// Timings for one query, named by its sqlc "-- name:" comment when it has one
+type QueryStats struct {
+	Name  string
+	Calls uint64
+	// Calls that took at least the recorder's threshold
+	Slow   uint64
+	Errors uint64
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Thread-safe per-query counters
+type Recorder struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	queries map[string]*QueryStats
+}
+
+// A recorder counting calls that take threshold or longer as slow, 0 counts none as slow
+func NewRecorder(threshold time.Duration) *Recorder {
+	return &Recorder{
+		threshold: threshold,
+		queries:   make(map[string]*QueryStats),
+	}
+}
+
+func (rec *Recorder) Threshold() time.Duration {
+	return rec.threshold
+}
+
+// Records one call of query. For queries returning rows this covers running it, not reading the rows.
+func (rec *Recorder) Observe(query string, duration time.Duration, err error) {
+	name := Name(query)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	stats, ok := rec.queries[name]
+	if !ok {
+		if len(rec.queries) >= maxQueries {
+			name = otherQueries
+			stats = rec.queries[name]
+		}
+
+		if stats == nil {
+			stats = &QueryStats{Name: name}
+			rec.queries[name] = stats
+		}
+	}
+
+	stats.Calls++
+	stats.Total += duration
+	stats.Max = max(stats.Max, duration)
+	if rec.threshold > 0 && duration >= rec.threshold {
+		stats.Slow++
+	}
+	if err != nil {
+		stats.Errors++
+	}
+}
+
+// Returns a copy of every query's stats, slowest in total first
+func (rec *Recorder) Snapshot() []QueryStats {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	result := make([]QueryStats, 0, len(rec.queries))
+	for _, stats := range rec.queries {
+		result = append(result, *stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// Drops everything recorded so far
+func (rec *Recorder) Reset() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.queries = make(map[string]*QueryStats)
+}
+
+var (
+	sqlcName   = regexp.MustCompile(`^\s*-- name: (\w+)`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// What a query is recorded as: its sqlc name, otherwise the start of its SQL on one line
+func Name(query string) string {
+	if m := sqlcName.FindStringSubmatch(query); m != nil {
+		return m[1]
+	}
+
+	name := strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+	if len(name) > 60 {
+		name = name[:60] + "…"
+	}
+
+	return name
+}
@@ -0,0 +1,121 @@
+package dbstats
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestName(t *testing.T) {
+	cases := map[string]string{
+		"-- name: GetChirps :many\nSELECT * FROM chirps": "GetChirps",
+		"SELECT   1\n\tFROM users":                       "SELECT 1 FROM users",
+	}
+
+	for query, want := range cases {
+		if got := Name(query); got != want {
+			t.Errorf("Name(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestRecorderObserve(t *testing.T) {
+	rec := NewRecorder(100 * time.Millisecond)
+
+	rec.Observe("-- name: A :one\nSELECT 1", 10*time.Millisecond, nil)
+	rec.Observe("-- name: A :one\nSELECT 1", 150*time.Millisecond, errors.New("boom"))
+	rec.Observe("-- name: B :one\nSELECT 2", time.Millisecond, nil)
+
+	got := rec.Snapshot()
+	if len(got) != 2 || got[0].Name != "A" {
+		t.Fatalf("unexpected snapshot %+v", got)
+	}
+
+	a := got[0]
+	if a.Calls != 2 || a.Slow != 1 || a.Errors != 1 || a.Max != 150*time.Millisecond || a.Total != 160*time.Millisecond {
+		t.Errorf("unexpected stats %+v", a)
+	}
+}
+
+func TestRecorderCapsQueries(t *testing.T) {
+	rec := NewRecorder(0)
+	for i := range maxQueries + 10 {
+		rec.Observe(time.Duration(i).String(), time.Millisecond, nil)
+	}
+
+	got := rec.Snapshot()
+	if len(got) != maxQueries+1 {
+		t.Fatalf("expected %d entries, got %d", maxQueries+1, len(got))
+	}
+
+	for _, s := range got {
+		if s.Name == otherQueries && s.Calls != 10 {
+			t.Errorf("expected the overflow under %s, got %+v", otherQueries, s)
+		}
+	}
+}
+
+// A driver with only the required methods, so database/sql goes through Prepare for everything
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(1), nil }
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string         { return []string{"n"} }
+func (fakeRows) Close() error              { return nil }
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
+
+func TestWrapTimesQueries(t *testing.T) {
+	rec := NewRecorder(0)
+	db := sql.OpenDB(Wrap(fakeConnector{}, rec))
+	defer db.Close()
+
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "-- name: ListThings :many\nSELECT n FROM things")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+
+	stmt, err := db.PrepareContext(ctx, "-- name: AddThing :exec\nINSERT INTO things VALUES ($1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	for range 3 {
+		if _, err := stmt.ExecContext(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := map[string]uint64{}
+	for _, s := range rec.Snapshot() {
+		calls[s.Name] = s.Calls
+	}
+
+	// The one-off query falls back to a prepared statement and must only count once
+	if calls["ListThings"] != 1 || calls["AddThing"] != 3 || len(calls) != 2 {
+		t.Errorf("unexpected calls %v", calls)
+	}
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// Anything published on the bus, Type is what subscribers register against
+type Event interface {
+	Type() string
+}
+
+const (
+	TypeChirpCreated = "chirp.created"
+	TypeChirpDeleted = "chirp.deleted"
+	TypeChirpLiked   = "chirp.liked"
+	TypeUserCreated  = "user.created"
+	TypeUserFollowed = "user.followed"
+	// Someone asked to follow a protected account
+	TypeFollowRequested = "user.follow_requested"
+)
+
+type ChirpCreated struct {
+	Chirp database.Chirp
+}
+
+func (ChirpCreated) Type() string { return TypeChirpCreated }
+
+type ChirpDeleted struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (ChirpDeleted) Type() string { return TypeChirpDeleted }
+
+type ChirpLiked struct {
+	ChirpID  uuid.UUID
+	AuthorID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (ChirpLiked) Type() string { return TypeChirpLiked }
+
+type UserCreated struct {
+	User database.CreateUserRow
+}
+
+func (UserCreated) Type() string { return TypeUserCreated }
+
+type UserFollowed struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (UserFollowed) Type() string { return TypeUserFollowed }
+
+type FollowRequested struct {
+	RequesterID uuid.UUID
+	TargetID    uuid.UUID
+}
+
+func (FollowRequested) Type() string { return TypeFollowRequested }
+
+type Handler func(ctx context.Context, event Event)
+
+// Publisher/subscriber contract, handlers only ever see this so the in-process bus can be swapped for a broker-backed one
+type Bus interface {
+	Publish(ctx context.Context, event Event)
+	Subscribe(eventType string, handler Handler)
+}
+
+// In-process Bus, every subscriber runs in its own goroutine so a slow one can't hold up the publisher or the others
+type LocalBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	wg       sync.WaitGroup
+}
+
+func NewLocalBus() *LocalBus {
+	return &LocalBus{handlers: make(map[string][]Handler)}
+}
+
+func (b *LocalBus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Hands event to every subscriber of its type. The subscribers get ctx's values (request ID etc.)
+// but not its cancellation, since the request that published usually finishes first.
+func (b *LocalBus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type()]
+	b.mu.RUnlock()
+
+	detached := context.WithoutCancel(ctx)
+
+	for _, handler := range handlers {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			defer func() {
+				if err := recover(); err != nil {
+					log.Printf("panic in %s subscriber: %v\n%s", event.Type(), err, debug.Stack())
+				}
+			}()
+
+			handler(detached, event)
+		}()
+	}
+}
+
+// Blocks until every subscriber that's been started has returned, used on shutdown
+func (b *LocalBus) Wait() {
+	b.wg.Wait()
+}
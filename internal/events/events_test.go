@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublishReachesSubscribersOfType(t *testing.T) {
+	bus := NewLocalBus()
+
+	var created, deleted atomic.Int32
+	bus.Subscribe(TypeChirpCreated, func(ctx context.Context, event Event) { created.Add(1) })
+	bus.Subscribe(TypeChirpCreated, func(ctx context.Context, event Event) { created.Add(1) })
+	bus.Subscribe(TypeChirpDeleted, func(ctx context.Context, event Event) { deleted.Add(1) })
+
+	bus.Publish(context.Background(), ChirpCreated{})
+	bus.Wait()
+
+	if created.Load() != 2 {
+		t.Errorf("expected both chirp.created subscribers to run, got %d", created.Load())
+	}
+
+	if deleted.Load() != 0 {
+		t.Errorf("expected chirp.deleted subscriber not to run, got %d", deleted.Load())
+	}
+}
+
+func TestSubscriberSurvivesCancelledContext(t *testing.T) {
+	bus := NewLocalBus()
+	id := uuid.New()
+
+	var got uuid.UUID
+	var ctxErr error
+	bus.Subscribe(TypeChirpDeleted, func(ctx context.Context, event Event) {
+		got = event.(ChirpDeleted).ChirpID
+		ctxErr = ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bus.Publish(ctx, ChirpDeleted{ChirpID: id})
+	bus.Wait()
+
+	if got != id {
+		t.Errorf("expected chirp ID %v, got %v", id, got)
+	}
+
+	if ctxErr != nil {
+		t.Errorf("expected subscriber context to be detached, got %v", ctxErr)
+	}
+}
+
+func TestPanickingSubscriberDoesNotStopOthers(t *testing.T) {
+	bus := NewLocalBus()
+
+	var ran atomic.Bool
+	bus.Subscribe(TypeUserCreated, func(ctx context.Context, event Event) { panic("boom") })
+	bus.Subscribe(TypeUserCreated, func(ctx context.Context, event Event) { ran.Store(true) })
+
+	bus.Publish(context.Background(), UserCreated{})
+	bus.Wait()
+
+	if !ran.Load() {
+		t.Error("expected the second subscriber to run")
+	}
+}
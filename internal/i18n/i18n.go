@@ -0,0 +1,110 @@
+// Package i18n translates user-facing messages using catalogs embedded in the binary. Messages are looked up
+// by their English text, which maps to a stable code (the key in locales/en.json) that clients can match on
+// whatever language the text comes back in. Text with no catalog entry is passed through untranslated.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Language used when the client doesn't ask for one we have, and the catalog every other one is checked against
+const Default = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	// language -> code -> message
+	catalogs = map[string]map[string]string{}
+	// English message -> code
+	codes = map[string]string{}
+)
+
+func init() {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range entries {
+		raw, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		messages := map[string]string{}
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			panic("i18n: " + entry.Name() + ": " + err.Error())
+		}
+
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+
+	for code, msg := range catalogs[Default] {
+		codes[msg] = code
+	}
+}
+
+// Every language with a catalog, sorted
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Stable code for an English message, false when the catalog doesn't have it
+func Code(msg string) (string, bool) {
+	code, ok := codes[msg]
+	return code, ok
+}
+
+// The message for code in lang, falling back to English when lang is unknown or hasn't translated it yet
+func Message(lang, code string) string {
+	if msg, ok := catalogs[lang][code]; ok {
+		return msg
+	}
+	return catalogs[Default][code]
+}
+
+// Translates an English message into lang, returning it unchanged when it isn't in the catalog
+func Translate(lang, msg string) string {
+	code, ok := Code(msg)
+	if !ok {
+		return msg
+	}
+	return Message(lang, code)
+}
+
+// Picks the language from an Accept-Language header we have the best catalog for. Region subtags are ignored
+// (es-MX gets es), ties go to whichever the client listed first, and Default is used when nothing matches.
+func Match(acceptLanguage string) string {
+	best, bestQ := Default, 0.0
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := catalogs[lang]; ok && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+
+	return best
+}
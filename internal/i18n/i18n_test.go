@@ -0,0 +1,60 @@
+package i18n
+
+import "testing"
+
+func TestCatalogsCoverEveryCode(t *testing.T) {
+	for _, lang := range Languages() {
+		for code := range catalogs[Default] {
+			if _, ok := catalogs[lang][code]; !ok {
+				t.Errorf("%s is missing %s", lang, code)
+			}
+		}
+
+		for code := range catalogs[lang] {
+			if _, ok := catalogs[Default][code]; !ok {
+				t.Errorf("%s has %s, which isn't in the %s catalog", lang, code, Default)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"es", "es"},
+		{"es-MX,es;q=0.9,en;q=0.8", "es"},
+		{"de-DE,fr;q=0.7,en;q=0.5", "fr"},
+		{"en;q=0.5,fr;q=0.8", "fr"},
+		{"fr;q=0,es;q=0.1", "es"},
+		{"de", "en"},
+		{"FR-ca", "fr"},
+		{"fr;q=abc", "en"},
+	}
+
+	for _, tt := range tests {
+		if got := Match(tt.header); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := Translate("es", "User not found"); got != "Usuario no encontrado" {
+		t.Errorf("expected a Spanish message, got %q", got)
+	}
+
+	if got := Translate("de", "User not found"); got != "User not found" {
+		t.Errorf("expected unknown languages to fall back to English, got %q", got)
+	}
+
+	if got := Translate("fr", "something dynamic"); got != "something dynamic" {
+		t.Errorf("expected messages outside the catalog to pass through, got %q", got)
+	}
+
+	if code, ok := Code("User not found"); !ok || code != "user_not_found" {
+		t.Errorf("expected user_not_found, got %q %v", code, ok)
+	}
+}
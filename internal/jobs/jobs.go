@@ -0,0 +1,207 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// The subset of database.Queries the queue needs, *database.Queries satisfies it
+type Store interface {
+	EnqueueJob(ctx context.Context, arg database.EnqueueJobParams) (database.Job, error)
+	ClaimJob(ctx context.Context) (database.Job, error)
+	CompleteJob(ctx context.Context, id uuid.UUID) error
+	RetryJob(ctx context.Context, arg database.RetryJobParams) error
+	FailJob(ctx context.Context, arg database.FailJobParams) error
+}
+
+// Does the work for one kind of job, returning an error schedules a retry
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+// DB-backed job queue, any number of processes can run workers against the same table
+type Queue struct {
+	store        Store
+	handlers     map[string]Handler
+	maxAttempts  int32
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	jobTimeout   time.Duration
+	wg           sync.WaitGroup
+}
+
+type Options struct {
+	// Attempts before a job is moved to the dead state
+	MaxAttempts int32
+	// Delay before the first retry, doubled on each attempt up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// How long an idle worker sleeps before checking for new jobs
+	PollInterval time.Duration
+	// Deadline for a single handler run
+	JobTimeout time.Duration
+}
+
+func NewQueue(store Store, opts Options) *Queue {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Hour
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.JobTimeout <= 0 {
+		opts.JobTimeout = 30 * time.Second
+	}
+
+	return &Queue{
+		store:        store,
+		handlers:     make(map[string]Handler),
+		maxAttempts:  opts.MaxAttempts,
+		backoff:      opts.Backoff,
+		maxBackoff:   opts.MaxBackoff,
+		pollInterval: opts.PollInterval,
+		jobTimeout:   opts.JobTimeout,
+	}
+}
+
+// Registers the handler for a job kind, call before Start
+func (q *Queue) Handle(kind string, handler Handler) {
+	q.handlers[kind] = handler
+}
+
+// Stores a job to run as soon as a worker is free
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any) error {
+	return q.EnqueueAt(ctx, kind, payload, time.Now().UTC())
+}
+
+// Stores a job that won't be picked up before runAt
+func (q *Queue) EnqueueAt(ctx context.Context, kind string, payload any, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.store.EnqueueJob(ctx, database.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       runAt,
+	})
+	return err
+}
+
+// Starts the workers. They stop claiming new jobs once ctx is cancelled but finish
+// the one they're on, Wait blocks until they've all drained.
+func (q *Queue) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			q.work(ctx)
+		}()
+	}
+}
+
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		ran, err := q.RunOne(ctx)
+		if err != nil {
+			log.Printf("Job queue error: %v", err)
+		}
+
+		if !ran || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.pollInterval):
+			}
+		}
+	}
+}
+
+// Claims and runs a single job, reporting whether there was one to run
+func (q *Queue) RunOne(ctx context.Context) (bool, error) {
+	// Bookkeeping outlives shutdown so a job that finishes while we drain still gets marked
+	storeCtx := context.WithoutCancel(ctx)
+
+	job, err := q.store.ClaimJob(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	runErr := q.run(storeCtx, job)
+	if runErr == nil {
+		return true, q.store.CompleteJob(storeCtx, job.ID)
+	}
+
+	lastError := sql.NullString{String: runErr.Error(), Valid: true}
+
+	if job.Attempts >= job.MaxAttempts {
+		log.Printf("Job %s (%s) is dead after %d attempts: %v", job.ID, job.Kind, job.Attempts, runErr)
+		return true, q.store.FailJob(storeCtx, database.FailJobParams{ID: job.ID, LastError: lastError})
+	}
+
+	return true, q.store.RetryJob(storeCtx, database.RetryJobParams{
+		ID:        job.ID,
+		RunAt:     time.Now().UTC().Add(q.Backoff(job.Attempts)),
+		LastError: lastError,
+	})
+}
+
+func (q *Queue) run(ctx context.Context, job database.Job) (err error) {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+
+	// A panicking handler fails the attempt instead of killing the worker
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("panic in %s job %s: %v\n%s", job.Kind, job.ID, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, q.jobTimeout)
+	defer cancel()
+
+	return handler(ctx, job.Payload)
+}
+
+// Delay before retrying a job that has failed `attempts` times
+func (q *Queue) Backoff(attempts int32) time.Duration {
+	delay := q.backoff
+	for i := int32(1); i < attempts; i++ {
+		delay *= 2
+		if delay >= q.maxBackoff {
+			return q.maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,163 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// In-memory Store that mimics the SQL state transitions
+type fakeStore struct {
+	mu   sync.Mutex
+	jobs []*database.Job
+}
+
+func (s *fakeStore) EnqueueJob(ctx context.Context, arg database.EnqueueJobParams) (database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := &database.Job{
+		ID:          uuid.New(),
+		Kind:        arg.Kind,
+		Payload:     arg.Payload,
+		Status:      "pending",
+		MaxAttempts: arg.MaxAttempts,
+		RunAt:       arg.RunAt,
+	}
+	s.jobs = append(s.jobs, job)
+	return *job, nil
+}
+
+func (s *fakeStore) ClaimJob(ctx context.Context) (database.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Status == "pending" {
+			job.Status = "running"
+			job.Attempts++
+			return *job, nil
+		}
+	}
+	return database.Job{}, sql.ErrNoRows
+}
+
+func (s *fakeStore) find(id uuid.UUID) *database.Job {
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) CompleteJob(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.find(id).Status = "done"
+	return nil
+}
+
+func (s *fakeStore) RetryJob(ctx context.Context, arg database.RetryJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(arg.ID)
+	job.Status = "pending"
+	job.RunAt = arg.RunAt
+	job.LastError = arg.LastError
+	return nil
+}
+
+func (s *fakeStore) FailJob(ctx context.Context, arg database.FailJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := s.find(arg.ID)
+	job.Status = "dead"
+	job.LastError = arg.LastError
+	return nil
+}
+
+func TestRunOneCompletesJob(t *testing.T) {
+	store := &fakeStore{}
+	queue := NewQueue(store, Options{})
+
+	var got string
+	queue.Handle("greet", func(ctx context.Context, payload json.RawMessage) error {
+		return json.Unmarshal(payload, &got)
+	})
+
+	if err := queue.Enqueue(context.Background(), "greet", "hello"); err != nil {
+		t.Fatalf("Enqueue returned an unexpected error: %v", err)
+	}
+
+	ran, err := queue.RunOne(context.Background())
+	if err != nil || !ran {
+		t.Fatalf("expected a job to run, got ran=%v err=%v", ran, err)
+	}
+
+	if got != "hello" {
+		t.Errorf("expected payload %q, got %q", "hello", got)
+	}
+
+	if store.jobs[0].Status != "done" {
+		t.Errorf("expected job to be done, got %s", store.jobs[0].Status)
+	}
+}
+
+func TestFailingJobRetriesThenDies(t *testing.T) {
+	store := &fakeStore{}
+	queue := NewQueue(store, Options{MaxAttempts: 2})
+
+	queue.Handle("flaky", func(ctx context.Context, payload json.RawMessage) error {
+		return errors.New("nope")
+	})
+
+	queue.Enqueue(context.Background(), "flaky", nil)
+
+	queue.RunOne(context.Background())
+	if store.jobs[0].Status != "pending" || store.jobs[0].LastError.String != "nope" {
+		t.Fatalf("expected job to be pending retry, got %s (%q)", store.jobs[0].Status, store.jobs[0].LastError.String)
+	}
+
+	if !store.jobs[0].RunAt.After(time.Now()) {
+		t.Error("expected retry to be scheduled in the future")
+	}
+
+	queue.RunOne(context.Background())
+	if store.jobs[0].Status != "dead" {
+		t.Errorf("expected job to be dead after max attempts, got %s", store.jobs[0].Status)
+	}
+}
+
+func TestUnknownKindIsRetried(t *testing.T) {
+	store := &fakeStore{}
+	queue := NewQueue(store, Options{})
+
+	queue.Enqueue(context.Background(), "mystery", nil)
+	queue.RunOne(context.Background())
+
+	if store.jobs[0].Status != "pending" || !store.jobs[0].LastError.Valid {
+		t.Errorf("expected unknown kind to be retried with an error, got %s", store.jobs[0].Status)
+	}
+}
+
+func TestBackoffDoublesAndCaps(t *testing.T) {
+	queue := NewQueue(&fakeStore{}, Options{Backoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := queue.Backoff(int32(i + 1)); got != want {
+			t.Errorf("Backoff(%d) = %v, expected %v", i+1, got, want)
+		}
+	}
+}
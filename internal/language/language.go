@@ -0,0 +1,121 @@
+// Package language guesses which language a chirp is written in. Chirps are short, so rather than n-gram models
+// it looks at the writing system and, for Latin script, counts common function words.
+package language
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Function words that are frequent in one language and rare in the others. Words several of them share
+// ("a", "de", "la", "que", "con", "mais") are left out, they'd only blur the scores.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "you", "with", "this", "that", "have", "for", "not", "it's", "i'm", "what", "just", "my", "of", "to", "be"},
+	"es": {"el", "los", "las", "y", "es", "por", "para", "pero", "muy", "esto", "yo", "del", "como", "más", "hoy", "qué", "lo"},
+	"fr": {"le", "les", "et", "est", "avec", "pour", "une", "très", "c'est", "je", "ce", "des", "du", "pas", "sur", "qui", "dans", "nous", "vous"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ich", "ein", "eine", "auf", "für", "auch", "sich", "wir", "sie", "heute", "sehr", "zu", "den"},
+	"pt": {"os", "as", "é", "com", "não", "uma", "mas", "muito", "isso", "eu", "do", "da", "em", "você", "hoje", "nos", "ao"},
+	"it": {"il", "gli", "è", "per", "non", "ma", "molto", "questo", "io", "di", "che", "sono", "oggi", "anche", "della", "nel", "ho"},
+	"nl": {"het", "een", "en", "niet", "met", "ik", "van", "op", "voor", "zijn", "ook", "dat", "maar", "wij", "jij", "vandaag", "heel", "naar", "er"},
+}
+
+// Below this many matched function words a Latin script guess isn't worth making
+const minStopwordHits = 2
+
+var wordPattern = regexp.MustCompile(`[\p{L}']+`)
+
+// Codes Detect can return
+func Supported() []string {
+	return []string{"ar", "de", "el", "en", "es", "fr", "he", "it", "ja", "ko", "nl", "pt", "ru", "zh"}
+}
+
+// Reports whether code looks like an ISO 639-1 code: two lowercase letters. Clients can tag chirps with languages
+// Detect doesn't know, so this doesn't check against Supported.
+func Valid(code string) bool {
+	return len(code) == 2 && code[0] >= 'a' && code[0] <= 'z' && code[1] >= 'a' && code[1] <= 'z'
+}
+
+// The ISO 639-1 code of the language text is most likely in, or "" when there isn't enough to go on
+func Detect(text string) string {
+	if code := detectScript(text); code != "" {
+		return code
+	}
+
+	scores := map[string]int{}
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		for code, words := range stopwords {
+			for _, w := range words {
+				if w == word {
+					scores[code]++
+				}
+			}
+		}
+	}
+
+	best, bestScore, tied := "", 0, false
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = code, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+
+	if bestScore < minStopwordHits || tied {
+		return ""
+	}
+
+	return best
+}
+
+// Picks the language from the writing system when most letters are in one that only a single supported language
+// uses. Kana means Japanese even among kanji, Han on its own means Chinese.
+func detectScript(text string) string {
+	counts := map[string]int{}
+	letters := 0
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		}
+	}
+
+	if letters == 0 {
+		return ""
+	}
+
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["han"]
+		counts["han"] = 0
+	}
+	counts["zh"] = counts["han"]
+	delete(counts, "han")
+
+	for code, n := range counts {
+		if n*2 > letters {
+			return code
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,66 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"I think the weather is nice and you should come with us", "en"},
+		{"Hoy el día está muy bonito y vamos a la playa con los amigos", "es"},
+		{"Je pense que c'est une très belle journée pour nous", "fr"},
+		{"Ich bin heute sehr müde und habe keine Lust auf die Arbeit", "de"},
+		{"Eu não sei se você vai hoje, mas isso é muito bom", "pt"},
+		{"Oggi sono molto stanco e non ho voglia di lavorare", "it"},
+		{"Ik ben vandaag heel moe en het is niet leuk", "nl"},
+		{"Сегодня хорошая погода", "ru"},
+		{"今日はいい天気ですね", "ja"},
+		{"今天天气很好", "zh"},
+		{"오늘 날씨가 좋네요", "ko"},
+		{"Καλημέρα σε όλους", "el"},
+		{"مرحبا بالعالم", "ar"},
+		{"שלום עולם", "he"},
+	}
+
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectNotEnoughToGoOn(t *testing.T) {
+	for _, text := range []string{"", "lol", "https://example.com", "👍👍👍", "kerfuffle sharbert", "1234"} {
+		if got := Detect(text); got != "" {
+			t.Errorf("Detect(%q) = %q, want no guess", text, got)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, code := range []string{"en", "fr", "xx"} {
+		if !Valid(code) {
+			t.Errorf("Valid(%q) = false, want true", code)
+		}
+	}
+
+	for _, code := range []string{"", "e", "EN", "eng", "e1", "en-US"} {
+		if Valid(code) {
+			t.Errorf("Valid(%q) = true, want false", code)
+		}
+	}
+}
+
+func TestDetectOnlyReturnsSupported(t *testing.T) {
+	supported := map[string]bool{}
+	for _, code := range Supported() {
+		supported[code] = true
+	}
+
+	for code := range stopwords {
+		if !supported[code] {
+			t.Errorf("stopwords has %q which Supported doesn't list", code)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package linkpreview
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/publicnet"
+)
+
+// Returned when a URL resolves to an address we refuse to fetch from (loopback, private ranges, metadata services...)
+var ErrForbiddenAddress = publicnet.ErrForbiddenAddress
+
+// Most links we care about per chirp, anything after is ignored
+const MaxLinksPerChirp = 5
+
+// Only read this much of a page looking for meta tags
+const maxBodyBytes = 512 * 1024
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// Finds http(s) URLs in a chirp body, dropping trailing punctuation and duplicates
+func ExtractURLs(body string) []string {
+	seen := map[string]bool{}
+	var result []string
+
+	for _, match := range urlPattern.FindAllString(body, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}")
+
+		u, err := url.Parse(match)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+		result = append(result, match)
+
+		if len(result) == MaxLinksPerChirp {
+			break
+		}
+	}
+
+	return result
+}
+
+// OpenGraph data for a page, any field can be empty
+type Preview struct {
+	Title       string
+	Description string
+	ImageURL    string
+}
+
+// Fetches pages for previews. Connections to non-public IPs are refused at dial time,
+// which also covers redirects and DNS answers that change between lookups.
+type Fetcher struct {
+	client *http.Client
+}
+
+func NewFetcher(timeout time.Duration) *Fetcher {
+	return &Fetcher{client: publicnet.NewClient(timeout)}
+}
+
+// Downloads rawURL and pulls the OpenGraph tags out of it
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return Preview{}, fmt.Errorf("unsupported URL %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", "ChirpyBot/1.0 (+link previews)")
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/html" {
+		return Preview{}, fmt.Errorf("not an HTML page (%s)", mediaType)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return Preview{}, err
+	}
+
+	preview := Parse(string(page))
+
+	// Relative og:image paths are resolved against the final page URL
+	if preview.ImageURL != "" {
+		if img, err := resp.Request.URL.Parse(preview.ImageURL); err == nil {
+			preview.ImageURL = img.String()
+		}
+	}
+
+	return preview, nil
+}
+
+var (
+	metaPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern  = regexp.MustCompile(`(?is)([a-z:_-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// Extracts og:title/og:description/og:image from an HTML document,
+// falling back to <title> and the description meta tag
+func Parse(page string) Preview {
+	var preview Preview
+	var fallbackDescription string
+
+	for _, tag := range metaPattern.FindAllString(page, -1) {
+		attrs := map[string]string{}
+		for _, m := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2] + m[3]
+		}
+
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+		content := strings.TrimSpace(html.UnescapeString(attrs["content"]))
+
+		switch key {
+		case "og:title":
+			preview.Title = content
+		case "og:description":
+			preview.Description = content
+		case "og:image":
+			preview.ImageURL = content
+		case "description":
+			fallbackDescription = content
+		}
+	}
+
+	if preview.Title == "" {
+		if m := titlePattern.FindStringSubmatch(page); m != nil {
+			preview.Title = strings.TrimSpace(html.UnescapeString(m[1]))
+		}
+	}
+
+	if preview.Description == "" {
+		preview.Description = fallbackDescription
+	}
+
+	return preview
+}
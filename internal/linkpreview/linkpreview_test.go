@@ -0,0 +1,65 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtractURLs(t *testing.T) {
+	body := "check https://example.com/a, and (http://foo.org/x?y=1) or https://example.com/a again"
+
+	got := ExtractURLs(body)
+	expected := []string{"https://example.com/a", "http://foo.org/x?y=1"}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestExtractURLsIgnoresOtherSchemes(t *testing.T) {
+	if got := ExtractURLs("ftp://example.com and javascript:alert(1)"); len(got) != 0 {
+		t.Errorf("expected no URLs, got %v", got)
+	}
+}
+
+func TestParseOpenGraph(t *testing.T) {
+	page := `<html><head>
+		<title>Fallback</title>
+		<meta property="og:title" content="Big &amp; News">
+		<meta content='A story' property='og:description'>
+		<meta property="og:image" content="/img.png">
+	</head></html>`
+
+	got := Parse(page)
+	expected := Preview{Title: "Big & News", Description: "A story", ImageURL: "/img.png"}
+
+	if got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestParseFallsBackToTitleAndDescription(t *testing.T) {
+	page := `<title> Plain page </title><meta name="description" content="Just a page">`
+
+	got := Parse(page)
+	if got.Title != "Plain page" || got.Description != "Just a page" {
+		t.Errorf("unexpected fallback preview %+v", got)
+	}
+}
+
+func TestFetchRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should never reach a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher(time.Second).Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
@@ -0,0 +1,397 @@
+// Package loadgen drives a running Chirpy server with fake users: it signs them up, has them follow each other,
+// then posts and reads chirps at a steady rate and reports latency percentiles per kind of request. The rate is
+// kept whatever the server does (open loop), so a slow server shows up as latency rather than as fewer requests.
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of request in the load, what the report is broken down by
+const (
+	OpPostChirp  = "post_chirp"
+	OpGetChirp   = "get_chirp"
+	OpUserChirps = "user_chirps"
+	OpFeed       = "feed"
+)
+
+// How many others each fake user follows, so feeds have something in them
+const followsPerUser = 5
+
+type Options struct {
+	// Base URL of the server, like http://localhost:8080
+	Target string
+	// Fake users to sign up and spread the load over
+	Users int
+	// Requests per second once they're set up
+	RPS float64
+	// How long to keep up the load
+	Duration time.Duration
+	// Share of requests that post a chirp, from 0 to 1. The rest are reads.
+	WriteRatio float64
+	// Most requests in flight at once. A tick that would go over is counted as dropped instead of waiting, which
+	// would slow the rate down.
+	MaxInFlight int
+	Client      *http.Client
+}
+
+// What happened to one kind of request
+type OpStats struct {
+	Count  int
+	Errors int
+	// Responses by status code, 0 for requests that didn't get one
+	Statuses map[int]int
+	P50      time.Duration
+	P90      time.Duration
+	P99      time.Duration
+	Max      time.Duration
+}
+
+type Report struct {
+	Elapsed time.Duration
+	// Ticks skipped because MaxInFlight requests were already running
+	Dropped int
+	Ops     map[string]OpStats
+}
+
+type user struct {
+	id    string
+	token string
+}
+
+type runner struct {
+	opts   Options
+	client *http.Client
+	users  []user
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	statuses  map[string]map[int]int
+	errors    map[string]int
+	// Chirps posted so far, what get_chirp reads
+	chirps []string
+}
+
+// Sets up opts.Users users then runs the load until opts.Duration is up or ctx is cancelled, whichever is first.
+// Setting up fails on the first error, the server's rate limits (RATE_LIMIT_AUTH) usually need raising for it.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.Users < 1 || opts.RPS <= 0 || opts.Duration <= 0 {
+		return Report{}, errors.New("loadgen needs at least one user, a positive rate and a duration")
+	}
+
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = 100
+	}
+
+	r := &runner{
+		opts:      opts,
+		client:    opts.Client,
+		latencies: map[string][]time.Duration{},
+		statuses:  map[string]map[int]int{},
+		errors:    map[string]int{},
+	}
+
+	if r.client == nil {
+		r.client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	if err := r.setUp(ctx); err != nil {
+		return Report{}, err
+	}
+
+	return r.run(ctx), nil
+}
+
+// Signs up and logs in every user, then has each follow a few of the others
+func (r *runner) setUp(ctx context.Context) error {
+	runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	for i := range r.opts.Users {
+		creds := map[string]string{
+			"email":    fmt.Sprintf("loadgen-%s-%d@example.com", runID, i),
+			"password": "loadgen-" + runID,
+		}
+
+		var created struct {
+			ID string `json:"id"`
+		}
+		if _, err := r.do(ctx, http.MethodPost, "/api/users", "", creds, http.StatusCreated, &created); err != nil {
+			return fmt.Errorf("signing up user %d: %w", i, err)
+		}
+
+		var login struct {
+			Token string `json:"token"`
+		}
+		if _, err := r.do(ctx, http.MethodPost, "/api/login", "", creds, http.StatusOK, &login); err != nil {
+			return fmt.Errorf("logging in user %d: %w", i, err)
+		}
+
+		r.users = append(r.users, user{id: created.ID, token: login.Token})
+	}
+
+	for i, u := range r.users {
+		for j := 1; j <= min(followsPerUser, len(r.users)-1); j++ {
+			followee := r.users[(i+j)%len(r.users)]
+			if _, err := r.do(ctx, http.MethodPost, "/api/users/"+followee.id+"/follow", u.token, nil, 0, nil); err != nil {
+				return fmt.Errorf("following: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *runner) run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Duration)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / r.opts.RPS))
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, r.opts.MaxInFlight)
+	var wg sync.WaitGroup
+	report := Report{}
+	start := time.Now()
+
+loop:
+	for n := 0; ; n++ {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			report.Dropped++
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			// In flight requests get to finish even once the run is over, they were sent inside it
+			r.step(context.WithoutCancel(ctx), r.users[n%len(r.users)])
+		}()
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	report.Ops = r.stats()
+	return report
+}
+
+// Sends one request as u, a post or one of the reads
+func (r *runner) step(ctx context.Context, u user) {
+	if rand.Float64() < r.opts.WriteRatio {
+		body := map[string]string{"body": fmt.Sprintf("Load test chirp %d from %s", rand.Int64(), u.id)}
+
+		var chirp struct {
+			ID string `json:"id"`
+		}
+		if r.timed(ctx, OpPostChirp, http.MethodPost, "/api/chirps", u.token, body, http.StatusCreated, &chirp) {
+			r.mu.Lock()
+			r.chirps = append(r.chirps, chirp.ID)
+			r.mu.Unlock()
+		}
+		return
+	}
+
+	switch rand.IntN(3) {
+	case 0:
+		r.mu.Lock()
+		var id string
+		if len(r.chirps) > 0 {
+			id = r.chirps[rand.IntN(len(r.chirps))]
+		}
+		r.mu.Unlock()
+
+		if id != "" {
+			r.timed(ctx, OpGetChirp, http.MethodGet, "/api/chirps/"+id, u.token, nil, http.StatusOK, nil)
+			return
+		}
+		fallthrough
+
+	case 1:
+		r.pages(ctx, OpUserChirps, "/api/users/"+r.users[rand.IntN(len(r.users))].id+"/chirps", u.token)
+
+	case 2:
+		r.pages(ctx, OpFeed, "/api/feed", u.token)
+	}
+}
+
+// Reads a listing a page at a time, following next_cursor for up to 3 pages like a client scrolling would
+func (r *runner) pages(ctx context.Context, op, path, token string) {
+	cursor := ""
+	for range 3 {
+		q := url.Values{"limit": {"20"}}
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+
+		var page struct {
+			NextCursor string `json:"next_cursor"`
+		}
+		if !r.timed(ctx, op, http.MethodGet, path+"?"+q.Encode(), token, nil, http.StatusOK, &page) || page.NextCursor == "" {
+			return
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// Sends a request and records how long it took under op. Reports whether it got the want status.
+func (r *runner) timed(ctx context.Context, op, method, path, token string, body any, want int, dst any) bool {
+	start := time.Now()
+	status, err := r.do(ctx, method, path, token, body, want, dst)
+	took := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies[op] = append(r.latencies[op], took)
+	if r.statuses[op] == nil {
+		r.statuses[op] = map[int]int{}
+	}
+	r.statuses[op][status]++
+	if err != nil {
+		r.errors[op]++
+	}
+
+	return err == nil
+}
+
+// Sends a JSON request and decodes the response into dst. A status other than want (any 2xx when want is 0)
+// is an error, and so is a 2xx body that dst can't hold.
+func (r *runner) do(ctx context.Context, method, path, token string, body any, want int, dst any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.opts.Target, "/")+path, reader)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if (want != 0 && resp.StatusCode != want) || (want == 0 && resp.StatusCode/100 != 2) {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s %s: %s %s", method, path, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if dst != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+			return resp.StatusCode, err
+		}
+	} else {
+		io.Copy(io.Discard, resp.Body)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (r *runner) stats() map[string]OpStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make(map[string]OpStats, len(r.latencies))
+	for op, latencies := range r.latencies {
+		slices.Sort(latencies)
+		ops[op] = OpStats{
+			Count:    len(latencies),
+			Errors:   r.errors[op],
+			Statuses: r.statuses[op],
+			P50:      percentile(latencies, 50),
+			P90:      percentile(latencies, 90),
+			P99:      percentile(latencies, 99),
+			Max:      latencies[len(latencies)-1],
+		}
+	}
+
+	return ops
+}
+
+// The nearest-rank pth percentile of sorted
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// Writes the report as a table, one line per kind of request
+func (rep Report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	total := 0
+	for _, s := range rep.Ops {
+		total += s.Count
+	}
+
+	fmt.Fprintf(&b, "%d requests in %s (%.1f/s), %d dropped\n\n", total, rep.Elapsed.Round(time.Millisecond),
+		float64(total)/rep.Elapsed.Seconds(), rep.Dropped)
+	fmt.Fprintf(&b, "%-12s %8s %8s %10s %10s %10s %10s  %s\n", "op", "count", "errors", "p50", "p90", "p99", "max", "statuses")
+
+	ops := make([]string, 0, len(rep.Ops))
+	for op := range rep.Ops {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+
+	for _, op := range ops {
+		s := rep.Ops[op]
+
+		codes := make([]int, 0, len(s.Statuses))
+		for code := range s.Statuses {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+
+		statuses := make([]string, 0, len(codes))
+		for _, code := range codes {
+			statuses = append(statuses, fmt.Sprintf("%d:%d", code, s.Statuses[code]))
+		}
+
+		fmt.Fprintf(&b, "%-12s %8d %8d %10s %10s %10s %10s  %s\n", op, s.Count, s.Errors,
+			s.P50.Round(time.Microsecond), s.P90.Round(time.Microsecond), s.P99.Round(time.Microsecond),
+			s.Max.Round(time.Microsecond), strings.Join(statuses, " "))
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
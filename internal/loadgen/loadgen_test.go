@@ -0,0 +1,110 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Just enough of the API for a run: signups, logins, follows, posts and empty listings
+func fakeServer(t *testing.T, posts *atomic.Int64) *httptest.Server {
+	var users atomic.Int64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": "00000000-0000-0000-0000-%012d"}`, users.Add(1))
+	})
+	mux.HandleFunc("POST /api/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token": "t"}`))
+	})
+	mux.HandleFunc("POST /api/users/{userID}/follow", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /api/chirps", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t" {
+			t.Errorf("expected the user's token, got %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": "c%d"}`, posts.Add(1))
+	})
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"chirps": []}`))
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestRun(t *testing.T) {
+	var posts atomic.Int64
+	srv := fakeServer(t, &posts)
+	defer srv.Close()
+
+	report, err := Run(context.Background(), Options{
+		Target:     srv.URL,
+		Users:      3,
+		RPS:        200,
+		Duration:   300 * time.Millisecond,
+		WriteRatio: 0.5,
+		Client:     srv.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := report.Ops[OpPostChirp]
+	if post.Count == 0 || int64(post.Count) != posts.Load() || post.Errors != 0 || post.Statuses[http.StatusCreated] != post.Count {
+		t.Errorf("unexpected post stats %+v with %d posted", post, posts.Load())
+	}
+
+	reads := report.Ops[OpGetChirp].Count + report.Ops[OpUserChirps].Count + report.Ops[OpFeed].Count
+	if reads == 0 {
+		t.Errorf("expected some reads, got %+v", report.Ops)
+	}
+
+	for op, s := range report.Ops {
+		if s.P50 > s.P90 || s.P90 > s.P99 || s.P99 > s.Max {
+			t.Errorf("%s: percentiles out of order %+v", op, s)
+		}
+	}
+
+	var b strings.Builder
+	report.WriteTo(&b)
+	if !strings.Contains(b.String(), "post_chirp") {
+		t.Errorf("expected post_chirp in the report:\n%s", b.String())
+	}
+}
+
+func TestRunFailsSetUp(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "Too many requests"}`, http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	_, err := Run(context.Background(), Options{Target: srv.URL, Users: 1, RPS: 1, Duration: time.Second})
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("expected the signup's 429, got %v", err)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	for p, want := range map[int]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("p%d: got %s, want %s", p, got, want)
+		}
+	}
+
+	if got := percentile(sorted[:1], 99); got != time.Millisecond {
+		t.Errorf("expected a single sample to be every percentile, got %s", got)
+	}
+}
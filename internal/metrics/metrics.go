@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Latency buckets in seconds, same shape as the Prometheus client defaults
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Counters and latency histogram for a single method + route pair
+type RouteStats struct {
+	Method string
+	Route  string
+	Count  uint64
+	Errors uint64
+	// Total latency in seconds, used for the histogram _sum and averages
+	Sum float64
+	// Cumulative counts, BucketCounts[i] is the number of requests that took <= Buckets[i]
+	Buckets      []float64
+	BucketCounts []uint64
+}
+
+type routeKey struct {
+	method string
+	route  string
+}
+
+// Thread-safe store of per-route request metrics
+type Registry struct {
+	mu      sync.Mutex
+	buckets []float64
+	routes  map[routeKey]*RouteStats
+}
+
+func NewRegistry(buckets []float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Registry{
+		buckets: sorted,
+		routes:  make(map[routeKey]*RouteStats),
+	}
+}
+
+// Records one finished request, any 5xx status counts as an error
+func (reg *Registry) Observe(method, route string, status int, duration time.Duration) {
+	seconds := duration.Seconds()
+	key := routeKey{method: method, route: route}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	stats, ok := reg.routes[key]
+	if !ok {
+		stats = &RouteStats{
+			Method:       method,
+			Route:        route,
+			Buckets:      reg.buckets,
+			BucketCounts: make([]uint64, len(reg.buckets)),
+		}
+		reg.routes[key] = stats
+	}
+
+	stats.Count++
+	stats.Sum += seconds
+	if status >= 500 {
+		stats.Errors++
+	}
+
+	for i, upper := range reg.buckets {
+		if seconds <= upper {
+			stats.BucketCounts[i]++
+		}
+	}
+}
+
+// Returns a copy of every route's stats sorted by route then method
+func (reg *Registry) Snapshot() []RouteStats {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	result := make([]RouteStats, 0, len(reg.routes))
+	for _, stats := range reg.routes {
+		copied := *stats
+		copied.BucketCounts = append([]uint64(nil), stats.BucketCounts...)
+		result = append(result, copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Route != result[j].Route {
+			return result[i].Route < result[j].Route
+		}
+		return result[i].Method < result[j].Method
+	})
+
+	return result
+}
+
+// Drops everything recorded so far
+func (reg *Registry) Reset() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.routes = make(map[routeKey]*RouteStats)
+}
+
+// Writes the stats in the Prometheus text exposition format
+func (reg *Registry) WritePrometheus(w io.Writer) error {
+	snapshot := reg.Snapshot()
+	var b strings.Builder
+
+	b.WriteString("# HELP chirpy_http_requests_total Total HTTP requests by method and route.\n")
+	b.WriteString("# TYPE chirpy_http_requests_total counter\n")
+	for _, s := range snapshot {
+		fmt.Fprintf(&b, "chirpy_http_requests_total{%s} %d\n", labels(s), s.Count)
+	}
+
+	b.WriteString("# HELP chirpy_http_request_errors_total HTTP requests that returned a 5xx status.\n")
+	b.WriteString("# TYPE chirpy_http_request_errors_total counter\n")
+	for _, s := range snapshot {
+		fmt.Fprintf(&b, "chirpy_http_request_errors_total{%s} %d\n", labels(s), s.Errors)
+	}
+
+	b.WriteString("# HELP chirpy_http_request_duration_seconds HTTP request latency.\n")
+	b.WriteString("# TYPE chirpy_http_request_duration_seconds histogram\n")
+	for _, s := range snapshot {
+		for i, upper := range s.Buckets {
+			le := strconv.FormatFloat(upper, 'g', -1, 64)
+			fmt.Fprintf(&b, "chirpy_http_request_duration_seconds_bucket{%s,le=%q} %d\n", labels(s), le, s.BucketCounts[i])
+		}
+		fmt.Fprintf(&b, "chirpy_http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels(s), s.Count)
+		fmt.Fprintf(&b, "chirpy_http_request_duration_seconds_sum{%s} %g\n", labels(s), s.Sum)
+		fmt.Fprintf(&b, "chirpy_http_request_duration_seconds_count{%s} %d\n", labels(s), s.Count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func labels(s RouteStats) string {
+	return fmt.Sprintf("method=%q,route=%q", s.Method, s.Route)
+}
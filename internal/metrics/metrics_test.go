@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserveCountsAndBuckets(t *testing.T) {
+	reg := NewRegistry([]float64{0.1, 1})
+
+	reg.Observe("GET", "/api/chirps", 200, 50*time.Millisecond)
+	reg.Observe("GET", "/api/chirps", 500, 500*time.Millisecond)
+	reg.Observe("GET", "/api/chirps", 200, 2*time.Second)
+
+	snapshot := reg.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("expected 1 route, got %d", len(snapshot))
+	}
+
+	stats := snapshot[0]
+	if stats.Count != 3 {
+		t.Errorf("expected count 3, got %d", stats.Count)
+	}
+
+	if stats.Errors != 1 {
+		t.Errorf("expected 1 error, got %d", stats.Errors)
+	}
+
+	if stats.BucketCounts[0] != 1 || stats.BucketCounts[1] != 2 {
+		t.Errorf("unexpected bucket counts %v", stats.BucketCounts)
+	}
+}
+
+func TestSnapshotSeparatesMethods(t *testing.T) {
+	reg := NewRegistry(nil)
+
+	reg.Observe("GET", "/api/users", 200, time.Millisecond)
+	reg.Observe("POST", "/api/users", 201, time.Millisecond)
+
+	snapshot := reg.Snapshot()
+	if len(snapshot) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(snapshot))
+	}
+
+	if snapshot[0].Method != "GET" || snapshot[1].Method != "POST" {
+		t.Errorf("expected sorted methods, got %s and %s", snapshot[0].Method, snapshot[1].Method)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	reg := NewRegistry([]float64{1})
+	reg.Observe("GET", "/api/healthz", 200, time.Millisecond)
+
+	var b strings.Builder
+	if err := reg.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus returned an unexpected error: %v", err)
+	}
+
+	out := b.String()
+	expected := []string{
+		`chirpy_http_requests_total{method="GET",route="/api/healthz"} 1`,
+		`chirpy_http_request_duration_seconds_bucket{method="GET",route="/api/healthz",le="1"} 1`,
+		`chirpy_http_request_duration_seconds_bucket{method="GET",route="/api/healthz",le="+Inf"} 1`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Outcome of a check, ordered so the strictest one wins when combining
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Flag
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Flag:
+		return "flag"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+func (v Verdict) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// What's being moderated, checks may rewrite Body (e.g. censoring)
+type Content struct {
+	UserID uuid.UUID
+	Body   string
+}
+
+type Result struct {
+	Check   string  `json:"check"`
+	Verdict Verdict `json:"verdict"`
+	Reason  string  `json:"reason"`
+}
+
+// A single moderation rule, add new ones by implementing this and passing them to NewPipeline
+type Check interface {
+	Name() string
+	Check(ctx context.Context, content *Content) Result
+}
+
+// Combined result of every check that ran
+type Decision struct {
+	Verdict Verdict
+	Body    string
+	// Every non-allow result, in check order
+	Violations []Result
+}
+
+// Runs checks in order, stopping at the first rejection
+type Pipeline struct {
+	checks []Check
+}
+
+func NewPipeline(checks ...Check) *Pipeline {
+	return &Pipeline{checks: checks}
+}
+
+func (p *Pipeline) Run(ctx context.Context, content Content) Decision {
+	decision := Decision{Verdict: Allow}
+
+	for _, check := range p.checks {
+		result := check.Check(ctx, &content)
+		result.Check = check.Name()
+
+		if result.Verdict == Allow {
+			continue
+		}
+
+		decision.Violations = append(decision.Violations, result)
+		if result.Verdict > decision.Verdict {
+			decision.Verdict = result.Verdict
+		}
+
+		if result.Verdict == Reject {
+			break
+		}
+	}
+
+	decision.Body = content.Body
+	return decision
+}
+
+// Rejects chirps longer than Max characters
+type LengthCheck struct {
+	Max int
+}
+
+func (LengthCheck) Name() string { return "length" }
+
+func (c LengthCheck) Check(ctx context.Context, content *Content) Result {
+	if utf8.RuneCountInString(content.Body) > c.Max {
+		return Result{Verdict: Reject, Reason: "Chirp is too long"}
+	}
+
+	if strings.TrimSpace(content.Body) == "" {
+		return Result{Verdict: Reject, Reason: "Chirp is empty"}
+	}
+
+	return Result{Verdict: Allow}
+}
+
+// Asks an external HTTP classifier for a verdict. The service gets {"body": "..."} and answers
+// {"verdict": "allow|flag|reject", "reason": "..."}. If it's down we fail open so posting keeps working.
+type ClassifierCheck struct {
+	URL    string
+	Client *http.Client
+}
+
+func (ClassifierCheck) Name() string { return "classifier" }
+
+func (c ClassifierCheck) Check(ctx context.Context, content *Content) Result {
+	verdict, reason, err := c.classify(ctx, content.Body)
+	if err != nil {
+		return Result{Verdict: Allow, Reason: err.Error()}
+	}
+
+	switch verdict {
+	case "reject":
+		return Result{Verdict: Reject, Reason: reason}
+	case "flag":
+		return Result{Verdict: Flag, Reason: reason}
+	}
+
+	return Result{Verdict: Allow}
+}
+
+func (c ClassifierCheck) classify(ctx context.Context, body string) (string, string, error) {
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Second}
+	}
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("classifier returned %d", resp.StatusCode)
+	}
+
+	var decoded struct {
+		Verdict string `json:"verdict"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return "", "", err
+	}
+
+	return decoded.Verdict, decoded.Reason, nil
+}
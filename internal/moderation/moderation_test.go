@@ -0,0 +1,302 @@
+package moderation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func bannedWords() *Filter {
+	f, err := NewFilter([]Term{{Text: "kerfuffle"}, {Text: "sharbert"}, {Text: "fornax"}})
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func TestPipelineAllowsCleanChirp(t *testing.T) {
+	p := NewPipeline(LengthCheck{Max: 140}, ProfanityCheck{Filter: bannedWords})
+
+	decision := p.Run(context.Background(), Content{Body: "hello world"})
+
+	if decision.Verdict != Allow || len(decision.Violations) != 0 {
+		t.Errorf("expected allow with no violations, got %v %v", decision.Verdict, decision.Violations)
+	}
+
+	if decision.Body != "hello world" {
+		t.Errorf("expected body to be unchanged, got %q", decision.Body)
+	}
+}
+
+func TestPipelineCensorsAndFlags(t *testing.T) {
+	p := NewPipeline(LengthCheck{Max: 140}, ProfanityCheck{Filter: bannedWords})
+
+	decision := p.Run(context.Background(), Content{Body: "what a Kerfuffle today"})
+
+	if decision.Verdict != Flag {
+		t.Errorf("expected flag, got %v", decision.Verdict)
+	}
+
+	if decision.Body != "what a **** today" {
+		t.Errorf("expected censored body, got %q", decision.Body)
+	}
+}
+
+func TestPipelineStopsAtReject(t *testing.T) {
+	p := NewPipeline(LengthCheck{Max: 10}, ProfanityCheck{Filter: bannedWords})
+
+	decision := p.Run(context.Background(), Content{Body: strings.Repeat("fornax ", 5)})
+
+	if decision.Verdict != Reject {
+		t.Fatalf("expected reject, got %v", decision.Verdict)
+	}
+
+	if len(decision.Violations) != 1 || decision.Violations[0].Check != "length" {
+		t.Errorf("expected only the length violation, got %v", decision.Violations)
+	}
+}
+
+func TestClassifierCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"verdict":"reject","reason":"spam"}`))
+	}))
+	defer server.Close()
+
+	check := ClassifierCheck{URL: server.URL, Client: server.Client()}
+	result := check.Check(context.Background(), &Content{Body: "buy now"})
+
+	if result.Verdict != Reject || result.Reason != "spam" {
+		t.Errorf("expected reject for spam, got %v %q", result.Verdict, result.Reason)
+	}
+}
+
+func TestClassifierCheckFailsOpen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	check := ClassifierCheck{URL: server.URL, Client: server.Client()}
+	if result := check.Check(context.Background(), &Content{Body: "hi"}); result.Verdict != Allow {
+		t.Errorf("expected allow when the classifier is down, got %v", result.Verdict)
+	}
+}
+
+type fakeHistory struct {
+	duplicates int64
+	recent     int64
+	gotHash    string
+}
+
+func (h *fakeHistory) CountRecentDuplicates(ctx context.Context, userID uuid.UUID, contentHash string, since time.Time) (int64, error) {
+	h.gotHash = contentHash
+	return h.duplicates, nil
+}
+
+func (h *fakeHistory) CountRecent(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	return h.recent, nil
+}
+
+func TestContentHashIgnoresCaseAndSpacing(t *testing.T) {
+	if ContentHash("Hello   World") != ContentHash("hello world") {
+		t.Error("expected hashes to match for case/whitespace variants")
+	}
+
+	if ContentHash("hello world") == ContentHash("hello there") {
+		t.Error("expected different chirps to hash differently")
+	}
+}
+
+func TestDuplicateCheck(t *testing.T) {
+	history := &fakeHistory{duplicates: 1}
+	check := DuplicateCheck{History: history, Window: time.Minute, Verdict: Reject}
+
+	result := check.Check(context.Background(), &Content{Body: "same again"})
+	if result.Verdict != Reject {
+		t.Errorf("expected duplicate to be rejected, got %v", result.Verdict)
+	}
+
+	if history.gotHash != ContentHash("same again") {
+		t.Errorf("expected lookup by content hash, got %q", history.gotHash)
+	}
+
+	history.duplicates = 0
+	if result := check.Check(context.Background(), &Content{Body: "new"}); result.Verdict != Allow {
+		t.Errorf("expected new chirp to be allowed, got %v", result.Verdict)
+	}
+}
+
+func TestBurstCheck(t *testing.T) {
+	history := &fakeHistory{recent: 5}
+	check := BurstCheck{History: history, Window: time.Minute, Max: 5}
+
+	if result := check.Check(context.Background(), &Content{Body: "x"}); result.Verdict != Reject {
+		t.Errorf("expected burst to be rejected, got %v", result.Verdict)
+	}
+
+	history.recent = 4
+	if result := check.Check(context.Background(), &Content{Body: "x"}); result.Verdict != Allow {
+		t.Errorf("expected post under the limit to be allowed, got %v", result.Verdict)
+	}
+}
+
+func TestCensor(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		want     string
+		censored int
+	}{
+		{"what a Kerfuffle today", "what a **** today", 1},
+		{"kerfuffle! (Sharbert), \"fornax\"...", "****! (****), \"****\"...", 3},
+		{"first line\n\nkerfuffle  here\tand there ", "first line\n\n****  here\tand there ", 1},
+		{"kerfuffles and fornax-like words stay", "kerfuffles and fornax-like words stay", 0},
+		{"k3rfuffl3 5harb3rt f0rn4x", "**** **** ****", 3},
+		{"KERFUFF1E, sh@rbert!", "****, ****!", 2},
+		{"s.h.a.r.b.e.r.t. and for-nax and k_e_r_f_u_f_f_l_e", "****. and **** and ****", 3},
+		{"!!! ... ? ****", "!!! ... ? ****", 0},
+		{"", "", 0},
+	} {
+		got, verdict, censored := bannedWords().Censor(tc.input)
+		if got != tc.want || censored != tc.censored {
+			t.Errorf("Censor(%q) = %q, %d, want %q, %d", tc.input, got, censored, tc.want, tc.censored)
+		}
+
+		if (censored > 0) != (verdict == Flag) {
+			t.Errorf("Censor(%q): expected flag only when something was censored, got %v", tc.input, verdict)
+		}
+	}
+}
+
+func TestCensorMatchesWordsWithPunctuation(t *testing.T) {
+	f, _ := NewFilter([]Term{{Text: "don't"}})
+
+	got, _, censored := f.Censor("don't, Don't! dont d0n't")
+	if got != "****, ****! **** ****" || censored != 4 {
+		t.Errorf("expected every spelling to be censored, got %q, %d", got, censored)
+	}
+}
+
+func TestCensorStandInsAtTheEnds(t *testing.T) {
+	f, _ := NewFilter([]Term{{Text: "ass"}})
+
+	got, _, _ := f.Censor("@ss, a$$! class")
+	if got != "****, ****! class" {
+		t.Errorf("expected stand-ins at the ends to count as letters, got %q", got)
+	}
+}
+
+func TestCensorPatterns(t *testing.T) {
+	f, err := NewFilter([]Term{
+		{Text: "fornax"},
+		{Text: `kerf+uf+le`, Pattern: true},
+		{Text: `\bshar\w*`, Pattern: true},
+		{Text: `\d{3}-\d{4}`, Pattern: true, Verdict: Reject},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, verdict, censored := f.Censor("KERFFUFFLE and Sharberts, fornax")
+	if got != "**** and ****, ****" || verdict != Flag || censored != 3 {
+		t.Errorf("unexpected censoring %q %v %d", got, verdict, censored)
+	}
+
+	// Patterns aren't limited to whole words
+	if got, _, _ := f.Censor("xkerfuflex"); got != "x****x" {
+		t.Errorf("expected the pattern to match inside a word, got %q", got)
+	}
+
+	if _, verdict, _ := f.Censor("call 555-1234 now"); verdict != Reject {
+		t.Errorf("expected reject, got %v", verdict)
+	}
+}
+
+func TestNewFilterSkipsBadPatterns(t *testing.T) {
+	f, err := NewFilter([]Term{
+		{Text: "fornax"},
+		{Text: "kerf(uffle", Pattern: true},
+		{Text: "x*", Pattern: true},
+		{Text: "sharbert", Pattern: true},
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "kerf(uffle") || !strings.Contains(err.Error(), "empty text") {
+		t.Errorf("expected both bad patterns to be reported, got %v", err)
+	}
+
+	if got, _, _ := f.Censor("fornax sharbert kerfuffle"); got != "**** **** kerfuffle" {
+		t.Errorf("expected the good terms to still apply, got %q", got)
+	}
+}
+
+func TestCompilePatternCaches(t *testing.T) {
+	a, err := CompilePattern(`fo+rnax`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := CompilePattern(`fo+rnax`)
+	if a != b {
+		t.Error("expected the same compiled pattern back")
+	}
+}
+
+func TestNilFilterCensorsNothing(t *testing.T) {
+	var f *Filter
+	if got, verdict, censored := f.Censor("kerfuffle"); got != "kerfuffle" || verdict != Allow || censored != 0 {
+		t.Errorf("expected nothing censored, got %q %v %d", got, verdict, censored)
+	}
+}
+
+func TestPipelineRejectsSevereWords(t *testing.T) {
+	f, _ := NewFilter([]Term{{Text: "kerfuffle"}, {Text: "fornax", Verdict: Reject}})
+	p := NewPipeline(ProfanityCheck{Filter: func() *Filter { return f }})
+
+	decision := p.Run(context.Background(), Content{Body: "kerfuffle f0rnax"})
+
+	if decision.Verdict != Reject || decision.Violations[0].Check != "profanity" {
+		t.Errorf("expected a profanity reject, got %v %v", decision.Verdict, decision.Violations)
+	}
+}
+
+// A chirp-sized body and one much longer than any chirp, to show how Censor grows with the input
+func BenchmarkCensor(b *testing.B) {
+	f := bannedWords()
+	short := "This is a kerfuffle opinion I need to share with the world, what a sharbert of a day"
+
+	for _, tc := range []struct {
+		name string
+		body string
+	}{
+		{"chirp", short},
+		{"long", strings.Repeat(short+" ", 100)},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			for b.Loop() {
+				f.Censor(tc.body)
+			}
+		})
+	}
+}
+
+// The whole pipeline a new chirp goes through, with the history queries answered by a fake store
+func BenchmarkPipeline(b *testing.B) {
+	history := &fakeHistory{}
+	p := NewPipeline(
+		LengthCheck{Max: 140},
+		DuplicateCheck{History: history, Window: 10 * time.Minute, Verdict: Reject},
+		BurstCheck{History: history, Window: time.Minute, Max: 10},
+		ProfanityCheck{Filter: bannedWords},
+	)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	for b.Loop() {
+		p.Run(ctx, Content{UserID: userID, Body: "This is a kerfuffle opinion I need to share with the world"})
+	}
+}
@@ -0,0 +1,228 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Something to keep out of chirps. A word is matched ignoring case, the punctuation around it and common
+// obfuscations (k3rfuffle, s.h.a.r.b.e.r.t), a pattern is a regular expression matched anywhere, ignoring case.
+type Term struct {
+	Text    string
+	Pattern bool
+	// Flag censors what matched, Reject refuses the whole chirp. Allow is taken as Flag.
+	Verdict Verdict
+}
+
+// Banned words and patterns ready to match, safe to share between goroutines. Build a new one with NewFilter
+// whenever the list changes.
+type Filter struct {
+	// Canonical form of each word -> verdict
+	words    map[string]Verdict
+	patterns []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	verdict Verdict
+}
+
+// Characters swapped in for letters to dodge filters and the letter each stands for. i and l look enough alike
+// that they're treated as the same letter.
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '!': 'i', '|': 'i', 'l': 'i', '3': 'e', '4': 'a', '@': 'a',
+	'5': 's', '$': 's', '7': 't', '+': 't', '8': 'b', '9': 'g',
+}
+
+// Characters put between letters to break a word up
+const separators = ".-_*'"
+
+// Compiled patterns by source. Filters are rebuilt on every banned word reload, this keeps the patterns from being
+// compiled again each time. It only grows with the patterns admins add.
+var patternCache sync.Map
+
+// Compiles src the way a pattern Term uses it: case-insensitive, and refusing a pattern that matches empty text
+// since that would match between every character
+func CompilePattern(src string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(src); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile("(?i)" + src)
+	if err != nil {
+		return nil, err
+	}
+
+	if re.MatchString("") {
+		return nil, errors.New("pattern matches empty text")
+	}
+
+	patternCache.Store(src, re)
+	return re, nil
+}
+
+// Builds a filter out of terms. Patterns that don't compile are left out and reported in the error, the filter
+// returned is usable either way.
+func NewFilter(terms []Term) (*Filter, error) {
+	f := &Filter{words: map[string]Verdict{}}
+	var errs []error
+
+	for _, t := range terms {
+		verdict := max(t.Verdict, Flag)
+
+		if t.Pattern {
+			re, err := CompilePattern(t.Text)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pattern %q: %w", t.Text, err))
+				continue
+			}
+			f.patterns = append(f.patterns, pattern{re: re, verdict: verdict})
+			continue
+		}
+
+		if key := canonical(t.Text); key != "" {
+			f.words[key] = max(f.words[key], verdict)
+		}
+	}
+
+	return f, errors.Join(errs...)
+}
+
+// Lowercases word, undoes the leetspeak and drops separators, so every spelling of a word comes out the same
+func canonical(word string) string {
+	var b strings.Builder
+	b.Grow(len(word))
+
+	for _, r := range word {
+		if strings.ContainsRune(separators, r) {
+			continue
+		}
+
+		r = unicode.ToLower(r)
+		if letter, ok := leet[r]; ok {
+			r = letter
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// Replaces every banned word and pattern match in input with ****. Returns the result, how many were replaced and
+// the strictest verdict among them (Allow when nothing was). Spacing and everything else is kept as it was.
+func (f *Filter) Censor(input string) (string, Verdict, int) {
+	if f == nil {
+		return input, Allow, 0
+	}
+
+	var b strings.Builder
+	b.Grow(len(input))
+	verdict := Allow
+	censored := 0
+
+	for rest := input; len(rest) > 0; {
+		// Copy the whitespace before the next word as is
+		start := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsSpace(r) })
+		if start < 0 {
+			b.WriteString(rest)
+			break
+		}
+		b.WriteString(rest[:start])
+		rest = rest[start:]
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		word := rest[:end]
+		rest = rest[end:]
+
+		i, j, v, ok := f.matchWord(word)
+		if !ok {
+			b.WriteString(word)
+			continue
+		}
+
+		b.WriteString(word[:i])
+		b.WriteString("****")
+		b.WriteString(word[j:])
+		verdict = max(verdict, v)
+		censored++
+	}
+
+	out := b.String()
+	for _, p := range f.patterns {
+		out = p.re.ReplaceAllStringFunc(out, func(m string) string {
+			// Patterns like \b can still match nothing
+			if m == "" {
+				return m
+			}
+
+			verdict = max(verdict, p.verdict)
+			censored++
+			return "****"
+		})
+	}
+
+	return out, verdict, censored
+}
+
+// Where the banned word in word is, if there's one. It's looked for without the punctuation around it first,
+// then keeping punctuation that stands in for a letter, like the @ in @ss.
+func (f *Filter) matchWord(word string) (int, int, Verdict, bool) {
+	if len(f.words) == 0 {
+		return 0, 0, Allow, false
+	}
+
+	tried := ""
+	for _, trim := range []func(rune) bool{unicode.IsPunct, isPlainPunct} {
+		core := strings.TrimLeftFunc(word, trim)
+		start := len(word) - len(core)
+		core = strings.TrimRightFunc(core, trim)
+
+		// Most words have no punctuation at all, so both trims give the same thing
+		if len(core) == len(tried) {
+			continue
+		}
+		tried = core
+
+		if v, ok := f.words[canonical(core)]; ok {
+			return start, start + len(core), v, true
+		}
+	}
+
+	return 0, 0, Allow, false
+}
+
+// Punctuation that isn't standing in for a letter
+func isPlainPunct(r rune) bool {
+	_, ok := leet[r]
+	return !ok && unicode.IsPunct(r)
+}
+
+// Censors banned words in place and rejects chirps with one whose verdict is Reject. Filter is called per check
+// so it can read from a live cache.
+type ProfanityCheck struct {
+	Filter func() *Filter
+}
+
+func (ProfanityCheck) Name() string { return "profanity" }
+
+func (c ProfanityCheck) Check(ctx context.Context, content *Content) Result {
+	cleaned, verdict, censored := c.Filter().Censor(content.Body)
+	if censored == 0 {
+		return Result{Verdict: Allow}
+	}
+
+	if verdict == Reject {
+		return Result{Verdict: Reject, Reason: "Chirp contains language that isn't allowed"}
+	}
+
+	content.Body = cleaned
+	return Result{Verdict: Flag, Reason: "Chirp contained banned words"}
+}
@@ -0,0 +1,84 @@
+package moderation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Where the spam checks look up a user's recent posts, backed by the chirps table in production
+type ChirpHistory interface {
+	CountRecentDuplicates(ctx context.Context, userID uuid.UUID, contentHash string, since time.Time) (int64, error)
+	CountRecent(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error)
+}
+
+// Hash used to spot repeated chirps, case and whitespace differences don't count as different
+func ContentHash(body string) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(body)), " ")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// Catches the same user posting the same thing again within Window
+type DuplicateCheck struct {
+	History ChirpHistory
+	Window  time.Duration
+	// What to do with a duplicate, Reject or Flag
+	Verdict Verdict
+	Now     func() time.Time
+}
+
+func (DuplicateCheck) Name() string { return "duplicate" }
+
+func (c DuplicateCheck) Check(ctx context.Context, content *Content) Result {
+	since := now(c.Now).Add(-c.Window)
+
+	count, err := c.History.CountRecentDuplicates(ctx, content.UserID, ContentHash(content.Body), since)
+	if err != nil {
+		// Don't block posting because the lookup failed
+		return Result{Verdict: Allow, Reason: err.Error()}
+	}
+
+	if count > 0 {
+		return Result{Verdict: c.Verdict, Reason: "Duplicate chirp"}
+	}
+
+	return Result{Verdict: Allow}
+}
+
+// Rejects a user posting more than Max chirps within the sliding Window
+type BurstCheck struct {
+	History ChirpHistory
+	Window  time.Duration
+	Max     int64
+	Now     func() time.Time
+}
+
+func (BurstCheck) Name() string { return "burst" }
+
+func (c BurstCheck) Check(ctx context.Context, content *Content) Result {
+	since := now(c.Now).Add(-c.Window)
+
+	count, err := c.History.CountRecent(ctx, content.UserID, since)
+	if err != nil {
+		return Result{Verdict: Allow, Reason: err.Error()}
+	}
+
+	if count >= c.Max {
+		return Result{Verdict: Reject, Reason: fmt.Sprintf("Posting too fast, at most %d chirps per %v", c.Max, c.Window)}
+	}
+
+	return Result{Verdict: Allow}
+}
+
+func now(clock func() time.Time) time.Time {
+	if clock == nil {
+		return time.Now().UTC()
+	}
+	return clock()
+}
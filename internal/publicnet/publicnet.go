@@ -0,0 +1,134 @@
+// Package publicnet makes outbound HTTP requests to URLs users hand us (link previews, webhooks, push endpoints)
+// without letting them reach the server's own network: loopback, private ranges and link-local addresses like the
+// 169.254.169.254 metadata service are refused.
+package publicnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// Returned when a URL resolves to an address we refuse to connect to
+var ErrForbiddenAddress = errors.New("address not allowed")
+
+// Most redirects a client follows
+const maxRedirects = 3
+
+// Ranges the net.IP methods don't cover that still aren't somewhere on the internet
+var reservedNets = mustParseCIDRs(
+	"0.0.0.0/8",     // "this network", 0.x.x.x reaches the local host on Linux
+	"100.64.0.0/10", // carrier-grade NAT, often the cloud provider's own network
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved, including 255.255.255.255
+	"64:ff9b::/96",  // NAT64, which would translate to any IPv4 address behind it
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// An http.Client that only connects to public IPs. The check runs at dial time on the resolved address, which also
+// covers redirects and DNS answers that change between lookups.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			ip := net.ParseIP(host)
+			if ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+		// Never go through a proxy, the dial check only sees the proxy's address
+		Proxy: nil,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return errors.New("redirect to non-http URL")
+			}
+			// Names are caught when dialing, an address can be turned down before that
+			if ip := net.ParseIP(req.URL.Hostname()); ip != nil && !IsPublicIP(ip) {
+				return fmt.Errorf("redirect to %w: %s", ErrForbiddenAddress, ip)
+			}
+			return nil
+		},
+	}
+}
+
+// Checks an absolute http(s) URL resolves only to public addresses. For when a URL is saved, so the user hears
+// about it then rather than from failed deliveries later. The client still checks on every connection.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("unsupported URL %q", rawURL)
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if !IsPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, u.Hostname(), addr.IP)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package publicnet
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"127.0.0.1":          false,
+		"::1":                false,
+		"10.1.2.3":           false,
+		"192.168.0.10":       false,
+		"172.16.5.4":         false,
+		"169.254.169.254":    false,
+		"0.0.0.0":            false,
+		"fd00::1":            false,
+		"0.1.2.3":            false,
+		"100.64.0.1":         false,
+		"100.127.255.254":    false,
+		"198.18.0.1":         false,
+		"198.19.255.255":     false,
+		"240.0.0.1":          false,
+		"255.255.255.255":    false,
+		"64:ff9b::a9fe:a9fe": false,
+		"::ffff:100.64.0.1":  false,
+		"100.128.0.1":        true,
+		"198.20.0.1":         true,
+		"93.184.216.34":      true,
+		"2606:4700::1111":    true,
+	} {
+		if got := IsPublicIP(net.ParseIP(ip)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request shouldn't have reached the server")
+	}))
+	defer server.Close()
+
+	_, err := NewClient(time.Second).Get(server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestClientRefusesRedirectToPrivateAddress(t *testing.T) {
+	client := NewClient(time.Second)
+	req, _ := http.NewRequest(http.MethodGet, "http://169.254.169.254/latest/meta-data/", nil)
+
+	if err := client.CheckRedirect(req, []*http.Request{{}}); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for _, raw := range []string{
+		"http://127.0.0.1:8080/hook",
+		"https://10.0.0.5/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"http://localhost/",
+		"ftp://example.com/",
+		"not a url",
+	} {
+		if err := CheckURL(context.Background(), raw); err == nil {
+			t.Errorf("CheckURL(%q): expected an error", raw)
+		}
+	}
+
+	if err := CheckURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("expected a public address to pass, got %v", err)
+	}
+}
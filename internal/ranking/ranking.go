@@ -0,0 +1,89 @@
+// Package ranking orders feed candidates. The feed gathers candidates and hands them to a Ranker, so trying a
+// different ordering only means writing another Ranker.
+package ranking
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// What a ranker gets to look at for one chirp
+type Candidate struct {
+	CreatedAt time.Time
+	Likes     int64
+	Replies   int64
+	Rechirps  int64
+	// Whether the viewer follows the author (or is the author)
+	Followed bool
+}
+
+// Scores candidates, higher ranks first
+type Ranker interface {
+	Name() string
+	Score(c Candidate, now time.Time) float64
+}
+
+// Newest first, what a chronological feed would show
+type Latest struct{}
+
+func (Latest) Name() string { return "latest" }
+
+func (Latest) Score(c Candidate, now time.Time) float64 {
+	return -now.Sub(c.CreatedAt).Seconds()
+}
+
+// Engagement decayed by age: a chirp's score halves every HalfLife, and ones from followed authors are
+// multiplied by FollowBoost so they hold their own against popular chirps from strangers
+type Top struct {
+	HalfLife    time.Duration
+	FollowBoost float64
+}
+
+func (Top) Name() string { return "top" }
+
+func (t Top) Score(c Candidate, now time.Time) float64 {
+	// Rechirps spread a chirp furthest and replies take more effort than a like, the 1 keeps fresh chirps
+	// with no engagement yet ordered by age rather than all tied at 0
+	engagement := 1 + float64(c.Likes) + 2*float64(c.Rechirps) + 1.5*float64(c.Replies)
+
+	if c.Followed && t.FollowBoost > 0 {
+		engagement *= t.FollowBoost
+	}
+
+	age := max(now.Sub(c.CreatedAt), 0)
+	if t.HalfLife <= 0 {
+		return engagement
+	}
+
+	return engagement * math.Exp2(-age.Hours()/t.HalfLife.Hours())
+}
+
+// Sorts items best first by r's score for each one's candidate. Ties go to the newer chirp.
+func Rank[T any](items []T, candidate func(T) Candidate, r Ranker, now time.Time) {
+	type scored struct {
+		item      T
+		score     float64
+		createdAt time.Time
+	}
+
+	s := make([]scored, len(items))
+	for i, item := range items {
+		c := candidate(item)
+		s[i] = scored{item: item, score: r.Score(c, now), createdAt: c.CreatedAt}
+	}
+
+	slices.SortStableFunc(s, func(a, b scored) int {
+		switch {
+		case a.score > b.score:
+			return -1
+		case a.score < b.score:
+			return 1
+		}
+		return b.createdAt.Compare(a.createdAt)
+	})
+
+	for i := range s {
+		items[i] = s[i].item
+	}
+}
@@ -0,0 +1,79 @@
+package ranking
+
+import (
+	"testing"
+	"time"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func rankNames(t *testing.T, r Ranker, items map[string]Candidate) []string {
+	t.Helper()
+
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+
+	Rank(names, func(name string) Candidate { return items[name] }, r, now)
+	return names
+}
+
+func TestLatestOrdersNewestFirst(t *testing.T) {
+	got := rankNames(t, Latest{}, map[string]Candidate{
+		"old":    {CreatedAt: now.Add(-3 * time.Hour), Likes: 100},
+		"new":    {CreatedAt: now.Add(-time.Minute)},
+		"middle": {CreatedAt: now.Add(-time.Hour)},
+	})
+
+	want := []string{"new", "middle", "old"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestTopDecaysWithAge(t *testing.T) {
+	top := Top{HalfLife: 6 * time.Hour}
+
+	fresh := top.Score(Candidate{CreatedAt: now, Likes: 9}, now)
+	halfLife := top.Score(Candidate{CreatedAt: now.Add(-6 * time.Hour), Likes: 9}, now)
+
+	if fresh != 10 {
+		t.Errorf("expected a fresh chirp with 9 likes to score 10, got %v", fresh)
+	}
+
+	if halfLife != 5 {
+		t.Errorf("expected the score to halve after one half-life, got %v", halfLife)
+	}
+}
+
+func TestTopPrefersEngagementAndFollows(t *testing.T) {
+	top := Top{HalfLife: 6 * time.Hour, FollowBoost: 2}
+
+	got := rankNames(t, top, map[string]Candidate{
+		"quiet":    {CreatedAt: now.Add(-time.Hour)},
+		"popular":  {CreatedAt: now.Add(-2 * time.Hour), Likes: 20, Rechirps: 5},
+		"followed": {CreatedAt: now.Add(-time.Hour), Likes: 3, Followed: true},
+		"stale":    {CreatedAt: now.Add(-72 * time.Hour), Likes: 500},
+	})
+
+	want := []string{"popular", "followed", "quiet", "stale"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestRankBreaksTiesByRecency(t *testing.T) {
+	got := rankNames(t, Top{}, map[string]Candidate{
+		"older": {CreatedAt: now.Add(-2 * time.Hour), Likes: 1},
+		"newer": {CreatedAt: now.Add(-time.Hour), Likes: 1},
+	})
+
+	if got[0] != "newer" {
+		t.Errorf("expected the newer chirp first on a tie, got %v", got)
+	}
+}
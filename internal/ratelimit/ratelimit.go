@@ -0,0 +1,91 @@
+// Package ratelimit counts requests per caller in fixed windows. Callers are identified by whatever key
+// the server picks (client IP, user ID...), and state is in memory so each instance limits on its own.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Outcome of one Allow call, with everything the standard rate limit headers need
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// When the current window ends and the count starts again
+	Reset time.Time
+}
+
+type counter struct {
+	start time.Time
+	count int
+}
+
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*counter
+}
+
+// Allows limit requests per key in every window
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, now: time.Now, windows: map[string]*counter{}}
+}
+
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Changes how many requests a key gets per window. Windows already under way keep their count, so lowering the
+// limit can cut a key off straight away.
+func (l *Limiter) SetLimit(limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+}
+
+func (l *Limiter) Window() time.Duration {
+	return l.window
+}
+
+// Counts a request for key. Rejected requests still count, so hammering a limit doesn't let up early.
+func (l *Limiter) Allow(key string) Result {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &counter{start: now}
+		l.windows[key] = w
+	}
+
+	w.count++
+
+	return Result{
+		Allowed:   w.count <= l.limit,
+		Limit:     l.limit,
+		Remaining: max(l.limit-w.count, 0),
+		Reset:     w.start.Add(l.window),
+	}
+}
+
+// Forgets windows that have ended, otherwise every key ever seen stays in memory
+func (l *Limiter) Prune() {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
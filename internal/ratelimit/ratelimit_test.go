@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowCountsPerKeyAndResets(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i, want := range []int{1, 0} {
+		res := l.Allow("a")
+		if !res.Allowed || res.Remaining != want {
+			t.Fatalf("request %d: got %+v, want allowed with %d remaining", i+1, res, want)
+		}
+	}
+
+	if res := l.Allow("a"); res.Allowed || res.Remaining != 0 {
+		t.Errorf("expected third request to be limited, got %+v", res)
+	}
+
+	if res := l.Allow("b"); !res.Allowed {
+		t.Error("expected other keys to have their own quota")
+	}
+
+	res := l.Allow("a")
+	if want := now.Add(time.Minute); !res.Reset.Equal(want) {
+		t.Errorf("expected reset at %v, got %v", want, res.Reset)
+	}
+
+	now = now.Add(time.Minute)
+	if res := l.Allow("a"); !res.Allowed || res.Remaining != 1 {
+		t.Errorf("expected a fresh window after reset, got %+v", res)
+	}
+}
+
+func TestPruneDropsEndedWindows(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(1, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("old")
+	now = now.Add(30 * time.Second)
+	l.Allow("new")
+	now = now.Add(45 * time.Second)
+	l.Prune()
+
+	if _, ok := l.windows["old"]; ok {
+		t.Error("expected ended window to be pruned")
+	}
+	if _, ok := l.windows["new"]; !ok {
+		t.Error("expected current window to be kept")
+	}
+}
+
+func TestSetLimitKeepsCurrentWindows(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("a")
+	l.SetLimit(2)
+
+	if res := l.Allow("a"); res.Allowed || res.Limit != 2 {
+		t.Errorf("expected the lower limit to apply to the current window, got %+v", res)
+	}
+
+	l.SetLimit(10)
+	if res := l.Allow("a"); !res.Allowed || res.Remaining != 6 {
+		t.Errorf("expected a raised limit to let the key back in, got %+v", res)
+	}
+}
@@ -0,0 +1,265 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Biggest response body read from the cluster, search responses only carry IDs and timestamps
+const maxResponseBytes = 10 << 20
+
+// The cluster answered with something other than 2xx
+type ResponseError struct {
+	Status int
+	Body   string
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("search cluster answered %d: %s", e.Status, e.Body)
+}
+
+// Backend on an Elasticsearch or OpenSearch cluster, talking to its REST API directly
+type Elasticsearch struct {
+	URL       string
+	IndexName string
+	// Basic auth when Username is set, otherwise an API key when that is
+	Username string
+	Password string
+	APIKey   string
+	Client   *http.Client
+}
+
+// A backend for the cluster at rawURL, storing chirps in index ("chirps" when empty)
+func NewElasticsearch(rawURL, index string) (*Elasticsearch, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("search URL %q must be an http(s) URL", rawURL)
+	}
+
+	if index == "" {
+		index = "chirps"
+	}
+
+	return &Elasticsearch{URL: strings.TrimSuffix(rawURL, "/"), IndexName: index}, nil
+}
+
+var indexMapping = map[string]any{
+	"mappings": map[string]any{
+		"properties": map[string]any{
+			"id":         map[string]string{"type": "keyword"},
+			"tenant_id":  map[string]string{"type": "keyword"},
+			"user_id":    map[string]string{"type": "keyword"},
+			"language":   map[string]string{"type": "keyword"},
+			"body":       map[string]string{"type": "text"},
+			"created_at": map[string]string{"type": "date"},
+		},
+	},
+}
+
+// Creates the index with its mapping, an index that already exists is left alone
+func (e *Elasticsearch) EnsureIndex(ctx context.Context) error {
+	body, err := json.Marshal(indexMapping)
+	if err != nil {
+		return err
+	}
+
+	err = e.request(ctx, http.MethodPut, "/"+url.PathEscape(e.IndexName), "application/json", body, nil)
+
+	var respErr *ResponseError
+	if errors.As(err, &respErr) && respErr.Status == http.StatusBadRequest &&
+		strings.Contains(respErr.Body, "resource_already_exists_exception") {
+		return nil
+	}
+
+	return err
+}
+
+type indexedChirp struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	UserID    string    `json:"user_id"`
+	Language  string    `json:"language,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Adds or replaces docs with a single bulk request
+func (e *Elasticsearch) Index(ctx context.Context, docs ...Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, doc := range docs {
+		action := map[string]any{"index": map[string]string{"_index": e.IndexName, "_id": doc.ID.String()}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+
+		err := enc.Encode(indexedChirp{
+			ID:        doc.ID.String(),
+			TenantID:  doc.TenantID.String(),
+			UserID:    doc.UserID.String(),
+			Language:  doc.Language,
+			Body:      doc.Body,
+			CreatedAt: doc.CreatedAt.UTC(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+
+	if err := e.request(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", buf.Bytes(), &resp); err != nil {
+		return err
+	}
+
+	// The bulk request as a whole succeeds even when some documents don't
+	if resp.Errors {
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if len(result.Error) > 0 {
+					return &ResponseError{Status: result.Status, Body: string(result.Error)}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Removes a chirp from the index, one that isn't there is fine
+func (e *Elasticsearch) Delete(ctx context.Context, id uuid.UUID) error {
+	err := e.request(ctx, http.MethodDelete, "/"+url.PathEscape(e.IndexName)+"/_doc/"+id.String(), "", nil, nil)
+
+	var respErr *ResponseError
+	if errors.As(err, &respErr) && respErr.Status == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+func (e *Elasticsearch) Search(ctx context.Context, q Query) ([]Hit, error) {
+	filters := []any{
+		map[string]any{"term": map[string]string{"tenant_id": q.TenantID.String()}},
+	}
+
+	if q.Language != "" {
+		filters = append(filters, map[string]any{"term": map[string]string{"language": q.Language}})
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"size": q.Limit,
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": map[string]any{
+					"simple_query_string": map[string]any{
+						"query":            q.Text,
+						"fields":           []string{"body"},
+						"default_operator": "and",
+					},
+				},
+				"filter": filters,
+			},
+		},
+		// Newest first like every other chirp list, search_after is the paging cursor
+		"sort":         []any{map[string]string{"created_at": "desc"}, map[string]string{"id": "desc"}},
+		"search_after": []any{q.Before.UnixMilli(), q.BeforeID.String()},
+		"_source":      []string{"created_at"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				ID     string `json:"_id"`
+				Source struct {
+					CreatedAt time.Time `json:"created_at"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	if err := e.request(ctx, http.MethodPost, "/"+url.PathEscape(e.IndexName)+"/_search", "application/json", body, &resp); err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		id, err := uuid.Parse(h.ID)
+		if err != nil {
+			return nil, fmt.Errorf("search hit has a bad ID %q", h.ID)
+		}
+
+		hits = append(hits, Hit{ID: id, CreatedAt: h.Source.CreatedAt})
+	}
+
+	return hits, nil
+}
+
+// Sends a request to the cluster and decodes a 2xx response's JSON into out, when it isn't nil
+func (e *Elasticsearch) request(ctx context.Context, method, path, contentType string, body []byte, out any) error {
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	switch {
+	case e.Username != "":
+		req.SetBasicAuth(e.Username, e.Password)
+	case e.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.APIKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		return &ResponseError{Status: resp.StatusCode, Body: string(data[:min(len(data), 500)])}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
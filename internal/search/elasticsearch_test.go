@@ -0,0 +1,157 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewElasticsearch(t *testing.T) {
+	es, err := NewElasticsearch("http://localhost:9200/", "")
+	if err != nil || es.URL != "http://localhost:9200" || es.IndexName != "chirps" {
+		t.Errorf("unexpected backend %+v, %v", es, err)
+	}
+
+	for _, bad := range []string{"", "localhost:9200", "ftp://localhost", "http://"} {
+		if _, err := NewElasticsearch(bad, "chirps"); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestElasticsearchIndex(t *testing.T) {
+	var lines []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/_bulk" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		if user, pass, _ := r.BasicAuth(); user != "chirpy" || pass != "s3cret" {
+			t.Errorf("expected basic auth, got %q %q", user, pass)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		lines = strings.Split(strings.TrimSpace(string(body)), "\n")
+		w.Write([]byte(`{"errors": false, "items": []}`))
+	}))
+	defer srv.Close()
+
+	es := &Elasticsearch{URL: srv.URL, IndexName: "chirps", Username: "chirpy", Password: "s3cret", Client: srv.Client()}
+
+	id := uuid.New()
+	err := es.Index(context.Background(), Document{ID: id, Body: "hello world", Language: "en", CreatedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("expected an action and a document line, got %q", lines)
+	}
+
+	if !strings.Contains(lines[0], `"_id":"`+id.String()+`"`) || !strings.Contains(lines[1], `"body":"hello world"`) {
+		t.Errorf("unexpected bulk body %q", lines)
+	}
+}
+
+func TestElasticsearchIndexItemError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors": true, "items": [{"index": {"status": 400, "error": {"type": "mapper_parsing_exception"}}}]}`))
+	}))
+	defer srv.Close()
+
+	es := &Elasticsearch{URL: srv.URL, IndexName: "chirps", Client: srv.Client()}
+
+	err := es.Index(context.Background(), Document{ID: uuid.New()})
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("expected the item error, got %v", err)
+	}
+}
+
+func TestElasticsearchSearch(t *testing.T) {
+	tenantID, hitID := uuid.New(), uuid.New()
+	created := time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chirps/_search" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		if r.Header.Get("Authorization") != "ApiKey k3y" {
+			t.Errorf("expected API key auth, got %q", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, _ := json.Marshal(body["query"])
+		for _, want := range []string{tenantID.String(), `"language":"fr"`, `"query":"bonjour"`} {
+			if !strings.Contains(string(raw), want) {
+				t.Errorf("expected query to contain %s, got %s", want, raw)
+			}
+		}
+
+		if body["size"] != float64(5) {
+			t.Errorf("expected size 5, got %v", body["size"])
+		}
+
+		w.Write([]byte(`{"hits": {"hits": [{"_id": "` + hitID.String() + `", "_source": {"created_at": "2026-03-01T12:00:00.123456Z"}}]}}`))
+	}))
+	defer srv.Close()
+
+	es := &Elasticsearch{URL: srv.URL, IndexName: "chirps", APIKey: "k3y", Client: srv.Client()}
+
+	hits, err := es.Search(context.Background(), Query{
+		TenantID: tenantID,
+		Text:     "bonjour",
+		Language: "fr",
+		Before:   time.Now(),
+		Limit:    5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hits) != 1 || hits[0].ID != hitID || !hits[0].CreatedAt.Equal(created) {
+		t.Errorf("unexpected hits %+v", hits)
+	}
+}
+
+func TestElasticsearchEnsureIndexExisting(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"type": "resource_already_exists_exception"}, "status": 400}`))
+	}))
+	defer srv.Close()
+
+	es := &Elasticsearch{URL: srv.URL, IndexName: "chirps", Client: srv.Client()}
+
+	if err := es.EnsureIndex(context.Background()); err != nil {
+		t.Errorf("expected an existing index to be fine, got %v", err)
+	}
+}
+
+func TestElasticsearchDeleteMissing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"result": "not_found"}`))
+	}))
+	defer srv.Close()
+
+	es := &Elasticsearch{URL: srv.URL, IndexName: "chirps", Client: srv.Client()}
+
+	if err := es.Delete(context.Background(), uuid.New()); err != nil {
+		t.Errorf("expected deleting a missing chirp to be fine, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+// Package search finds chirps by their text. Postgres full-text search over the chirps table is the default,
+// Elasticsearch (or OpenSearch, which speaks the same API) can take over once that gets too slow.
+package search
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A chirp as the search index sees it
+type Document struct {
+	ID        uuid.UUID
+	TenantID  uuid.UUID
+	UserID    uuid.UUID
+	Body      string
+	Language  string
+	CreatedAt time.Time
+}
+
+type Query struct {
+	TenantID uuid.UUID
+	// Words to look for, all of them have to match. "Quoted phrases", -excluded words and OR work too.
+	Text string
+	// ISO 639-1 code, empty for any language
+	Language string
+	// Only chirps older than Before, or as old with a lower ID, for paging
+	Before   time.Time
+	BeforeID uuid.UUID
+	Limit    int
+}
+
+// A matching chirp, enough to page on. The caller loads the chirp itself.
+type Hit struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+}
+
+// Where chirps get searched. Index and Delete keep an external index in sync with the chirps table, Search
+// returns hits newest first.
+type Backend interface {
+	Index(ctx context.Context, docs ...Document) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	Search(ctx context.Context, q Query) ([]Hit, error)
+}
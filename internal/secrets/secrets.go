@@ -0,0 +1,129 @@
+// Package secrets resolves configuration secrets from the environment, from files (docker/k8s secrets style)
+// or from a HashiCorp Vault KV engine, so they don't have to sit in plaintext in .env.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Looks a secret up by name, in order:
+//  1. the NAME env var
+//  2. the file named by NAME_FILE
+//  3. the NAME key of the Vault secret, when Vault is configured
+//
+// A missing secret is "", not an error, callers decide what's required.
+type Loader struct {
+	vault map[string]string
+}
+
+// Vault settings, Addr empty means Vault isn't used
+type VaultConfig struct {
+	Addr  string
+	Token string
+	// Full API path of the secret, e.g. "secret/data/chirpy" for KV v2
+	Path   string
+	Client *http.Client
+}
+
+func VaultConfigFromEnv() VaultConfig {
+	return VaultConfig{
+		Addr:  os.Getenv("VAULT_ADDR"),
+		Token: os.Getenv("VAULT_TOKEN"),
+		Path:  os.Getenv("VAULT_SECRET_PATH"),
+	}
+}
+
+// Reads the Vault secret once up front, so a Vault outage after startup doesn't matter
+func NewLoader(ctx context.Context, vault VaultConfig) (*Loader, error) {
+	l := &Loader{}
+
+	if vault.Addr == "" {
+		return l, nil
+	}
+
+	data, err := readVault(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	l.vault = data
+	return l, nil
+}
+
+func (l *Loader) Get(name string) (string, error) {
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading %s_FILE: %w", name, err)
+		}
+		// Secret files nearly always end in a newline that isn't part of the secret
+		return strings.TrimRight(string(raw), "\r\n"), nil
+	}
+
+	return l.vault[name], nil
+}
+
+func readVault(ctx context.Context, cfg VaultConfig) (map[string]string, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("VAULT_ADDR is set but VAULT_SECRET_PATH isn't")
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	url := strings.TrimRight(cfg.Addr, "/") + "/v1/" + strings.TrimLeft(cfg.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", cfg.Token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("reading vault secret: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reading vault secret: status %d", resp.StatusCode)
+	}
+
+	// KV v2 nests the values under data.data, v1 has them straight under data
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding vault secret: %w", err)
+	}
+
+	values := body.Data
+	if nested, ok := body.Data["data"]; ok {
+		if err := json.Unmarshal(nested, &values); err != nil {
+			return nil, fmt.Errorf("decoding vault secret: %w", err)
+		}
+	}
+
+	result := make(map[string]string, len(values))
+	for k, raw := range values {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			// Only string values make sense as secrets
+			continue
+		}
+		result[k] = s
+	}
+
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetPrefersEnvThenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jwt")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TEST_SECRET", "")
+	t.Setenv("TEST_SECRET_FILE", path)
+
+	l, err := NewLoader(context.Background(), VaultConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := l.Get("TEST_SECRET"); got != "from-file" {
+		t.Errorf("expected file value without trailing newline, got %q", got)
+	}
+
+	t.Setenv("TEST_SECRET", "from-env")
+	if got, _ := l.Get("TEST_SECRET"); got != "from-env" {
+		t.Errorf("expected env to win, got %q", got)
+	}
+}
+
+func TestGetMissingFileErrors(t *testing.T) {
+	t.Setenv("TEST_SECRET", "")
+	t.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "nope"))
+
+	l, _ := NewLoader(context.Background(), VaultConfig{})
+	if _, err := l.Get("TEST_SECRET"); err == nil {
+		t.Error("expected error for missing secret file")
+	}
+}
+
+func TestVaultKV2(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/chirpy" || r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"data":{"JWT_SECRET":"from-vault","PORT":8080},"metadata":{"version":3}}}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SECRET_FILE", "")
+
+	l, err := NewLoader(context.Background(), VaultConfig{Addr: srv.URL, Token: "root", Path: "secret/data/chirpy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := l.Get("JWT_SECRET"); got != "from-vault" {
+		t.Errorf("expected vault value, got %q", got)
+	}
+
+	if got, _ := l.Get("PORT"); got != "" {
+		t.Errorf("expected non-string values to be skipped, got %q", got)
+	}
+}
+
+func TestVaultErrorsFailStartup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	if _, err := NewLoader(context.Background(), VaultConfig{Addr: srv.URL, Token: "bad", Path: "secret/data/chirpy"}); err == nil {
+		t.Error("expected error when vault rejects the token")
+	}
+}
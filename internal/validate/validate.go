@@ -0,0 +1,178 @@
+// Package validate checks decoded request structs against `validate` struct tags, so handlers can report
+// every bad field at once instead of bailing on the first. Fields are named by their json tag, nested
+// structs as "keys.auth", which is what clients sent and what they'll look for in the response.
+//
+// Rules, comma separated:
+//
+//	required  non-zero value (non-empty string or slice)
+//	email     a bare address like a@b.co, no display name
+//	url       absolute http or https URL
+//	https     absolute https URL
+//	min=N     strings and slices at least N long, numbers at least N
+//	max=N     strings and slices at most N long, numbers at most N
+//
+// Only required applies to zero values, so optional fields can still carry format rules.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field name to what's wrong with it, nil when everything passed
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for field, msg := range e {
+		parts = append(parts, field+" "+msg)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// Checks every tagged field of v, which must be a struct or a pointer to one. Only the first failing rule
+// of each field is reported.
+func Struct(v any) Errors {
+	errs := Errors{}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() == reflect.Struct {
+		check(rv, "", errs)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func check(rv reflect.Value, prefix string, errs Errors) {
+	rt := rv.Type()
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		name = prefix + name
+
+		value := rv.Field(i)
+		if msg := checkField(value, field.Tag.Get("validate")); msg != "" {
+			errs[name] = msg
+			continue
+		}
+
+		if value.Kind() == reflect.Struct {
+			check(value, name+".", errs)
+		}
+	}
+}
+
+// The json name, falling back to the Go name for untagged fields like encoding/json does
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func checkField(value reflect.Value, tag string) string {
+	if tag == "" {
+		return ""
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		if name == "required" {
+			if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+				return "is required"
+			}
+			continue
+		}
+
+		// Format rules say nothing about missing values, that's required's job
+		if value.IsZero() {
+			return ""
+		}
+
+		if msg := checkRule(value, name, arg); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func checkRule(value reflect.Value, name, arg string) string {
+	switch name {
+	case "email":
+		s := value.String()
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email"
+		}
+
+	case "url":
+		u, err := url.Parse(value.String())
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return "must be an absolute http or https URL"
+		}
+
+	case "https":
+		u, err := url.Parse(value.String())
+		if err != nil || u.Host == "" || u.Scheme != "https" {
+			return "must be an https URL"
+		}
+
+	case "min", "max":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("validate: bad %s argument %q", name, arg))
+		}
+		return checkBound(value, name, n)
+
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q", name))
+	}
+
+	return ""
+}
+
+func checkBound(value reflect.Value, name string, n int) string {
+	var size int
+	unit := ""
+
+	switch value.Kind() {
+	case reflect.String:
+		size, unit = utf8.RuneCountInString(value.String()), " characters"
+	case reflect.Slice, reflect.Map:
+		size, unit = value.Len(), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = int(value.Int())
+	default:
+		panic(fmt.Sprintf("validate: %s can't apply to %s", name, value.Kind()))
+	}
+
+	if name == "min" && size < n {
+		return fmt.Sprintf("must be at least %d%s", n, unit)
+	}
+
+	if name == "max" && size > n {
+		return fmt.Sprintf("must be at most %d%s", n, unit)
+	}
+
+	return ""
+}
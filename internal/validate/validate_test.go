@@ -0,0 +1,82 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+type keys struct {
+	Auth string `json:"auth" validate:"required"`
+}
+
+type request struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Password string   `json:"password" validate:"required"`
+	Bio      string   `json:"bio,omitempty" validate:"max=5"`
+	Endpoint string   `json:"endpoint" validate:"https"`
+	Events   []string `json:"events" validate:"required"`
+	Retry    int      `json:"retry" validate:"min=0,max=60"`
+	Keys     keys     `json:"keys"`
+	Untagged string
+}
+
+func TestStructReportsEveryBadField(t *testing.T) {
+	errs := Struct(&request{
+		Email:    "Bob <bob@example.com>",
+		Bio:      "héllo!",
+		Endpoint: "http://push.example.com",
+		Retry:    61,
+	})
+
+	want := Errors{
+		"email":     "must be a valid email",
+		"password":  "is required",
+		"bio":       "must be at most 5 characters",
+		"endpoint":  "must be an https URL",
+		"events":    "is required",
+		"retry":     "must be at most 60",
+		"keys.auth": "is required",
+	}
+
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", errs, want)
+	}
+}
+
+func TestStructPassesValidRequest(t *testing.T) {
+	errs := Struct(request{
+		Email:    "bob@example.com",
+		Password: "hunter2",
+		Bio:      "héllo",
+		Events:   []string{"chirp.created"},
+		Retry:    30,
+		Keys:     keys{Auth: "x"},
+	})
+
+	if errs != nil {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestFormatRulesSkipEmptyValues(t *testing.T) {
+	var v struct {
+		Email string `json:"email" validate:"email"`
+		URL   string `json:"url" validate:"url"`
+	}
+
+	if errs := Struct(v); errs != nil {
+		t.Errorf("expected optional fields to pass when empty, got %v", errs)
+	}
+}
+
+func TestUnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a typo'd rule to panic")
+		}
+	}()
+
+	Struct(struct {
+		Name string `validate:"requird"`
+	}{Name: "x"})
+}
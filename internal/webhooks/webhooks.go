@@ -0,0 +1,120 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/publicnet"
+)
+
+// Event types subscribers can ask for
+const (
+	EventChirpCreated = "chirp.created"
+	EventChirpDeleted = "chirp.deleted"
+	EventUserCreated  = "user.created"
+)
+
+// Reports whether eventType is one we know how to send
+func ValidEvent(eventType string) bool {
+	switch eventType {
+	case EventChirpCreated, EventChirpDeleted, EventUserCreated:
+		return true
+	}
+	return false
+}
+
+// Only absolute http(s) URLs can be subscribed. This is just the shape, CheckTarget also looks at where it points.
+func ValidURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Refuses a URL that isn't valid or that resolves to a loopback, private or link-local address, so webhooks can't be
+// used to make the server call into its own network
+func CheckTarget(ctx context.Context, raw string) error {
+	if !ValidURL(raw) {
+		return fmt.Errorf("unsupported URL %q", raw)
+	}
+	return publicnet.CheckURL(ctx, raw)
+}
+
+// JSON body posted to every subscriber
+type Payload struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Where a payload goes and the secret it's signed with
+type Target struct {
+	URL    string
+	Secret string
+}
+
+// Hex HMAC-SHA256 of body, sent as "sha256=<hex>" in X-Chirpy-Signature so receivers can verify it's from us
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Posts signed payloads to subscribers, retrying is left to the job queue
+type Dispatcher struct {
+	client *http.Client
+}
+
+// A nil client gets one that only connects to public addresses, whatever a subscriber's URL resolves to by the time
+// it's delivered to
+func NewDispatcher(client *http.Client) *Dispatcher {
+	if client == nil {
+		client = publicnet.NewClient(10 * time.Second)
+	}
+
+	return &Dispatcher{client: client}
+}
+
+// Sends a single signed POST, any non-2xx response counts as a failure
+func (d *Dispatcher) Deliver(ctx context.Context, target Target, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set("X-Chirpy-Event", payload.Type)
+	req.Header.Set("X-Chirpy-Delivery", payload.ID.String())
+	req.Header.Set("X-Chirpy-Signature", "sha256="+Sign(target.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/publicnet"
+)
+
+func TestDeliverSignsPayload(t *testing.T) {
+	secret := "shh"
+	var gotSignature, gotEvent string
+	var gotBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-Chirpy-Signature")
+		gotEvent = r.Header.Get("X-Chirpy-Event")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(server.Client())
+	payload := Payload{ID: uuid.New(), Type: EventChirpCreated, CreatedAt: time.Now(), Data: map[string]string{"body": "hi"}}
+
+	if err := d.Deliver(context.Background(), Target{URL: server.URL, Secret: secret}, payload); err != nil {
+		t.Fatalf("Deliver returned an unexpected error: %v", err)
+	}
+
+	if gotEvent != EventChirpCreated {
+		t.Errorf("expected event header %q, got %q", EventChirpCreated, gotEvent)
+	}
+
+	if expected := "sha256=" + Sign(secret, gotBody); gotSignature != expected {
+		t.Errorf("expected signature %q, got %q", expected, gotSignature)
+	}
+
+	var decoded Payload
+	if err := json.Unmarshal(gotBody, &decoded); err != nil || decoded.ID != payload.ID {
+		t.Errorf("body did not round trip: %s", gotBody)
+	}
+}
+
+func TestDeliverFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(server.Client())
+	err := d.Deliver(context.Background(), Target{URL: server.URL, Secret: "s"}, Payload{ID: uuid.New(), Type: EventUserCreated})
+
+	if err == nil {
+		t.Error("expected Deliver to return an error for a 502 response, got nil")
+	}
+}
+
+func TestDeliverRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the webhook shouldn't have been delivered")
+	}))
+	defer server.Close()
+
+	// The default client, the one the server delivers with
+	d := NewDispatcher(nil)
+	err := d.Deliver(context.Background(), Target{URL: server.URL, Secret: "shh"}, Payload{ID: uuid.New(), Type: EventChirpCreated})
+	if !errors.Is(err, publicnet.ErrForbiddenAddress) {
+		t.Errorf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	for _, raw := range []string{"http://127.0.0.1:9000/hook", "http://localhost:9000", "http://169.254.169.254/", "ftp://example.com"} {
+		if err := CheckTarget(context.Background(), raw); err == nil {
+			t.Errorf("CheckTarget(%q): expected an error", raw)
+		}
+	}
+
+	if err := CheckTarget(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("expected a public address to pass, got %v", err)
+	}
+}
+
+func TestValidURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://example.com/hook": true,
+		"http://localhost:9000":    true,
+		"ftp://example.com":        false,
+		"/relative":                false,
+		"not a url":                false,
+	}
+
+	for raw, expected := range cases {
+		if got := ValidURL(raw); got != expected {
+			t.Errorf("ValidURL(%q) = %v, expected %v", raw, got, expected)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/itsmandrew/server-go/internal/publicnet"
+)
+
+// Returned by Send when the push service says the subscription no longer exists (404/410), callers should delete it
+var ErrSubscriptionGone = errors.New("push subscription expired or unsubscribed")
+
+// Record size advertised in the aes128gcm header, we only ever send a single record
+const recordSize = 4096
+
+// A browser's PushSubscription, keys are base64url as the browser hands them over
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Application server identity used to sign VAPID headers
+type VAPID struct {
+	PublicKey  string
+	privateKey *ecdsa.PrivateKey
+	Subject    string
+}
+
+// Builds the VAPID identity from base64url keys (65 byte uncompressed public point, 32 byte private scalar)
+func NewVAPID(publicKey, privateKey, subject string) (*VAPID, error) {
+	rawPriv, err := decodeBase64(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key: %w", err)
+	}
+
+	priv, err := ecdh.P256().NewPrivateKey(rawPriv)
+	if err != nil {
+		return nil, fmt.Errorf("vapid private key: %w", err)
+	}
+
+	pub := priv.PublicKey().Bytes()
+	if base64.RawURLEncoding.EncodeToString(pub) != publicKey {
+		return nil, errors.New("vapid public key does not match private key")
+	}
+
+	ecdsaKey := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(rawPriv),
+	}
+
+	return &VAPID{PublicKey: publicKey, privateKey: ecdsaKey, Subject: subject}, nil
+}
+
+// Makes a fresh key pair, for generating config with
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	priv, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(priv.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(priv.Bytes()), nil
+}
+
+// Authorization header value for a push to endpoint
+func (v *VAPID) authorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.RegisteredClaims{
+		Audience:  jwt.ClaimStrings{u.Scheme + "://" + u.Host},
+		ExpiresAt: jwt.NewNumericDate(now.Add(12 * time.Hour)),
+		Subject:   v.Subject,
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(v.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return "vapid t=" + token + ", k=" + v.PublicKey, nil
+}
+
+// Encrypts plaintext for sub using the aes128gcm content coding from RFC 8291
+func Encrypt(sub Subscription, plaintext []byte) ([]byte, error) {
+	uaPublicRaw, err := decodeBase64(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+
+	authSecret, err := decodeBase64(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicRaw)
+	if err != nil {
+		return nil, fmt.Errorf("p256dh: %w", err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return encrypt(asPrivate, uaPublic, authSecret, salt, plaintext)
+}
+
+func encrypt(asPrivate *ecdh.PrivateKey, uaPublic *ecdh.PublicKey, authSecret, salt, plaintext []byte) ([]byte, error) {
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	asPublic := asPrivate.PublicKey().Bytes()
+	cek, nonce, err := deriveKeys(ecdhSecret, authSecret, salt, uaPublic.Bytes(), asPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last (and only) record
+	padded := append(append([]byte(nil), plaintext...), 0x02)
+	if len(padded)+gcm.Overhead() > recordSize {
+		return nil, errors.New("push payload too large")
+	}
+
+	var header bytes.Buffer
+	header.Write(salt)
+	binary.Write(&header, binary.BigEndian, uint32(recordSize))
+	header.WriteByte(byte(len(asPublic)))
+	header.Write(asPublic)
+
+	return gcm.Seal(header.Bytes(), nonce, padded, nil), nil
+}
+
+// Key schedule from RFC 8291 section 3.4
+func deriveKeys(ecdhSecret, authSecret, salt, uaPublic, asPublic []byte) (cek, nonce []byte, err error) {
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cek, nonce, nil
+}
+
+// Refuses a subscription endpoint that isn't https or that resolves to a loopback, private or link-local address.
+// Browsers only hand out endpoints on their push service, anything else is someone aiming pushes at our network.
+func CheckEndpoint(ctx context.Context, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("push endpoint %q isn't an https URL", endpoint)
+	}
+	return publicnet.CheckURL(ctx, endpoint)
+}
+
+// Sends encrypted pushes to browser push services
+type Client struct {
+	http  *http.Client
+	vapid *VAPID
+	ttl   time.Duration
+}
+
+// A nil httpClient gets one that only connects to public addresses, endpoints come from users
+func NewClient(httpClient *http.Client, vapid *VAPID, ttl time.Duration) *Client {
+	if httpClient == nil {
+		httpClient = publicnet.NewClient(10 * time.Second)
+	}
+
+	return &Client{http: httpClient, vapid: vapid, ttl: ttl}
+}
+
+func (c *Client) PublicKey() string {
+	return c.vapid.PublicKey
+}
+
+// Encrypts and delivers payload to sub, ErrSubscriptionGone means the subscription should be pruned
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte) error {
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := c.vapid.authorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(c.ttl.Seconds())))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrSubscriptionGone
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("push service returned %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Browsers hand out unpadded base64url but be lenient about padding
+func decodeBase64(s string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
@@ -0,0 +1,177 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/publicnet"
+)
+
+// Plays the browser side of RFC 8291 to check Encrypt produces something a user agent can read
+func decryptAsUserAgent(t *testing.T, uaPrivate *ecdh.PrivateKey, authSecret, body []byte) []byte {
+	t.Helper()
+
+	salt := body[:16]
+	idLen := int(body[20])
+	asPublicRaw := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicRaw)
+	if err != nil {
+		t.Fatalf("bad server public key in header: %v", err)
+	}
+
+	ecdhSecret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatalf("ECDH failed: %v", err)
+	}
+
+	cek, nonce, err := deriveKeys(ecdhSecret, authSecret, salt, uaPrivate.PublicKey().Bytes(), asPublicRaw)
+	if err != nil {
+		t.Fatalf("deriveKeys failed: %v", err)
+	}
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+
+	padded, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+
+	if padded[len(padded)-1] != 0x02 {
+		t.Fatalf("expected last-record delimiter, got %x", padded[len(padded)-1])
+	}
+
+	return padded[:len(padded)-1]
+}
+
+func newTestSubscription(t *testing.T, endpoint string) (Subscription, *ecdh.PrivateKey, []byte) {
+	t.Helper()
+
+	uaPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+
+	sub := Subscription{
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(authSecret),
+	}
+
+	return sub, uaPrivate, authSecret
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	sub, uaPrivate, authSecret := newTestSubscription(t, "https://push.example.com/abc")
+	message := []byte(`{"kind":"reply"}`)
+
+	body, err := Encrypt(sub, message)
+	if err != nil {
+		t.Fatalf("Encrypt returned an unexpected error: %v", err)
+	}
+
+	if got := decryptAsUserAgent(t, uaPrivate, authSecret, body); string(got) != string(message) {
+		t.Errorf("expected %q, got %q", message, got)
+	}
+}
+
+func TestNewVAPIDRejectsMismatchedKeys(t *testing.T) {
+	pub1, _, _ := GenerateVAPIDKeys()
+	_, priv2, _ := GenerateVAPIDKeys()
+
+	if _, err := NewVAPID(pub1, priv2, "mailto:admin@example.com"); err == nil {
+		t.Error("expected NewVAPID to reject a public key that doesn't match the private key")
+	}
+}
+
+func TestSendSetsHeadersAndDetectsGone(t *testing.T) {
+	status := http.StatusCreated
+	var gotAuth, gotEncoding string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotEncoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	pub, priv, _ := GenerateVAPIDKeys()
+	vapid, err := NewVAPID(pub, priv, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatalf("NewVAPID returned an unexpected error: %v", err)
+	}
+
+	client := NewClient(server.Client(), vapid, time.Hour)
+	sub, _, _ := newTestSubscription(t, server.URL+"/push/1")
+
+	if err := client.Send(context.Background(), sub, []byte("hi")); err != nil {
+		t.Fatalf("Send returned an unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(gotAuth, "vapid t=") || !strings.HasSuffix(gotAuth, ", k="+pub) {
+		t.Errorf("unexpected Authorization header %q", gotAuth)
+	}
+
+	if gotEncoding != "aes128gcm" {
+		t.Errorf("expected aes128gcm content encoding, got %q", gotEncoding)
+	}
+
+	status = http.StatusGone
+	if err := client.Send(context.Background(), sub, []byte("hi")); !errors.Is(err, ErrSubscriptionGone) {
+		t.Errorf("expected ErrSubscriptionGone, got %v", err)
+	}
+}
+
+func TestSendRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the push shouldn't have been sent")
+	}))
+	defer server.Close()
+
+	pub, priv, _ := GenerateVAPIDKeys()
+	vapid, err := NewVAPID(pub, priv, "mailto:admin@example.com")
+	if err != nil {
+		t.Fatalf("NewVAPID returned an unexpected error: %v", err)
+	}
+
+	// The default client, the one the server sends with
+	client := NewClient(nil, vapid, time.Hour)
+	sub, _, _ := newTestSubscription(t, server.URL+"/push/1")
+
+	if err := client.Send(context.Background(), sub, []byte("hi")); !errors.Is(err, publicnet.ErrForbiddenAddress) {
+		t.Errorf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	for _, endpoint := range []string{
+		"https://127.0.0.1/push/1",
+		"https://localhost/push/1",
+		"https://10.0.0.1/push/1",
+		"https://169.254.169.254/latest/meta-data/",
+		"http://93.184.216.34/push/1",
+	} {
+		if err := CheckEndpoint(context.Background(), endpoint); err == nil {
+			t.Errorf("CheckEndpoint(%q): expected an error", endpoint)
+		}
+	}
+
+	if err := CheckEndpoint(context.Background(), "https://93.184.216.34/push/1"); err != nil {
+		t.Errorf("expected a public https endpoint to pass, got %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+var errIPDenied = errors.New("Requests from your network are blocked")
+
+// In-memory copy of the ip_deny_list table, it's checked on every request so it mustn't hit the database
+type ipDenyList struct {
+	mu       sync.RWMutex
+	prefixes []netip.Prefix
+}
+
+func newIPDenyList() *ipDenyList {
+	return &ipDenyList{}
+}
+
+// Whether ip falls in any denied network. Anything that doesn't parse as an address is let through,
+// there's nothing to match it against.
+func (l *ipDenyList) denied(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.WithZone("").Unmap()
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, prefix := range l.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the list with whatever is in the database right now. Rows that don't parse are skipped and logged
+// rather than failing the whole reload.
+func (l *ipDenyList) reload(ctx context.Context, q *database.Queries) error {
+	rows, err := q.GetDeniedIPs(ctx)
+	if err != nil {
+		return err
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(rows))
+	for _, row := range rows {
+		prefix, err := netip.ParsePrefix(row.Cidr)
+		if err != nil {
+			log.Printf("Skipping invalid IP deny list entry %q: %v", row.Cidr, err)
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	l.mu.Lock()
+	l.prefixes = prefixes
+	l.mu.Unlock()
+	return nil
+}
+
+// Parses a single address or a CIDR block into the canonical form stored in the table: host bits cleared,
+// IPv4-mapped IPv6 addresses unmapped, and a lone address as a /32 or /128
+func parseDeniedNetwork(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.WithZone("").Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+
+	return prefix.Masked(), nil
+}
+
+// Answers 403 for clients on the deny list before anything else looks at the request. It has to sit inside
+// middlewareClientIP so it sees the address behind any trusted proxies.
+func (cfg *apiConfig) middlewareIPDenyList(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.ipDenyList.denied(remoteIP(r)) {
+			respondWithError(w, http.StatusForbidden, errIPDenied.Error())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Periodically reloads so edits made through another instance show up here too
+func (cfg *apiConfig) runIPDenyListReloader(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloadCtx, cancel := cfg.dbContext(ctx)
+			if err := cfg.ipDenyList.reload(reloadCtx, cfg.databaseQueries); err != nil {
+				log.Printf("Reloading IP deny list failed: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func (cfg *apiConfig) getIPDenyListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	entries, err := cfg.databaseQueries.GetDeniedIPs(ctx)
+	if err != nil {
+		log.Printf("GetDeniedIPs failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if entries == nil {
+		entries = []database.IpDenyList{}
+	}
+
+	respondWithJson(w, http.StatusOK, entries)
+}
+
+// POST /admin/ip_deny_list, blocks an address or CIDR block. Adding one that's already there updates its reason.
+func (cfg *apiConfig) createIPDenyListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		CIDR   string `json:"cidr" validate:"required"`
+		Reason string `json:"reason" validate:"max=500"`
+	}
+
+	adminID, ok := cfg.requireGlobalAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	prefix, err := parseDeniedNetwork(params.CIDR)
+	if err != nil {
+		respondWithFieldErrors(w, validate.Errors{"cidr": "must be an IP address or CIDR block"})
+		return
+	}
+
+	// Easy to do by accident with a wide block, and the admin couldn't undo it from where they are
+	if addr, err := netip.ParseAddr(remoteIP(r)); err == nil && prefix.Contains(addr.WithZone("").Unmap()) {
+		respondWithError(w, http.StatusBadRequest, "That would block your own IP address")
+		return
+	}
+
+	var entry database.IpDenyList
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		var err error
+		entry, err = qtx.CreateDeniedIP(ctx, database.CreateDeniedIPParams{
+			Cidr:   prefix.String(),
+			Reason: strings.TrimSpace(params.Reason),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, qtx, adminID, auditIPDenied, uuid.Nil, map[string]any{
+			"cidr":   entry.Cidr,
+			"reason": entry.Reason,
+		})
+	})
+
+	if err != nil {
+		log.Printf("Adding %s to the IP deny list failed: %v", prefix, err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := cfg.ipDenyList.reload(ctx, cfg.databaseQueries); err != nil {
+		log.Printf("Reloading IP deny list failed: %v", err)
+	}
+
+	log.Printf("%s added to the IP deny list by %s", entry.Cidr, adminID)
+	respondWithJson(w, http.StatusCreated, entry)
+}
+
+// DELETE /admin/ip_deny_list/{cidr...}, the slash in a CIDR block is part of the path so 10.0.0.0/8 works as is
+func (cfg *apiConfig) deleteIPDenyListHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireGlobalAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	prefix, err := parseDeniedNetwork(r.PathValue("cidr"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid IP address or CIDR block")
+		return
+	}
+
+	var deleted int64
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		var err error
+		deleted, err = qtx.DeleteDeniedIP(ctx, prefix.String())
+		if err != nil || deleted == 0 {
+			return err
+		}
+
+		return recordAudit(ctx, qtx, adminID, auditIPAllowed, uuid.Nil, map[string]any{"cidr": prefix.String()})
+	})
+
+	if err != nil {
+		log.Printf("Removing %s from the IP deny list failed: %v", prefix, err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "IP deny list entry not found")
+		return
+	}
+
+	if err := cfg.ipDenyList.reload(ctx, cfg.databaseQueries); err != nil {
+		log.Printf("Reloading IP deny list failed: %v", err)
+	}
+
+	log.Printf("%s removed from the IP deny list by %s", prefix, adminID)
+	w.WriteHeader(http.StatusNoContent)
+}
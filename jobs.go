@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/jobs"
+)
+
+// Job kinds handled by the worker pool
+const (
+	jobWebhookDelivery = "webhook.deliver"
+	jobTokenCleanup    = "tokens.cleanup"
+	jobPushDelivery    = "push.deliver"
+	jobLinkPreview     = "link.preview"
+	jobMailDelivery    = "mail.deliver"
+	jobUserPurge       = "users.purge"
+	jobSearchIndex     = "search.index"
+	jobSearchReindex   = "search.reindex"
+	jobChirpPublish    = "chirps.publish"
+)
+
+// Hooks every job kind up to its handler
+func (cfg *apiConfig) registerJobHandlers(queue *jobs.Queue) {
+	queue.Handle(jobWebhookDelivery, cfg.runWebhookJob)
+	queue.Handle(jobTokenCleanup, cfg.runTokenCleanupJob)
+	queue.Handle(jobPushDelivery, cfg.runPushJob)
+	queue.Handle(jobLinkPreview, cfg.runLinkPreviewJob)
+	queue.Handle(jobMailDelivery, cfg.runMailJob)
+	queue.Handle(jobUserPurge, cfg.runUserPurgeJob)
+	queue.Handle(jobSearchIndex, cfg.runSearchIndexJob)
+	queue.Handle(jobSearchReindex, cfg.runSearchReindexJob)
+	queue.Handle(jobChirpPublish, cfg.runChirpPublishJob)
+}
+
+// Deletes refresh tokens, OAuth authorization codes and email change links that can never be used again, then
+// requeues interrupted jobs and prunes finished ones
+func (cfg *apiConfig) runTokenCleanupJob(ctx context.Context, _ json.RawMessage) error {
+	deleted, err := cfg.databaseQueries.DeleteStaleRefreshTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	codes, err := cfg.databaseQueries.DeleteExpiredOAuthAuthorizationCodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.databaseQueries.DeleteExpiredEmailChanges(ctx); err != nil {
+		return err
+	}
+
+	log.Printf("Token cleanup removed %d refresh tokens and %d authorization codes", deleted, codes)
+
+	if err := cfg.requeueStaleJobs(ctx); err != nil {
+		return err
+	}
+
+	if cfg.jobRetention > 0 {
+		pruned, err := cfg.databaseQueries.DeleteFinishedJobs(ctx, time.Now().UTC().Add(-cfg.jobRetention))
+		if err != nil {
+			return err
+		}
+		log.Printf("Job cleanup removed %d finished jobs", pruned)
+	}
+
+	return nil
+}
+
+// Puts jobs back in the queue that have been running longer than the lease. Workers give up on a job after its
+// timeout, so one still running by then was claimed by an instance that crashed or was killed. Jobs other
+// instances are in the middle of are left alone.
+func (cfg *apiConfig) requeueStaleJobs(ctx context.Context) error {
+	n, err := cfg.databaseQueries.RequeueStaleJobs(ctx, time.Now().UTC().Add(-cfg.jobLease))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.Printf("Requeued %d interrupted jobs", n)
+	}
+	return nil
+}
+
+// Queues a job of kind every interval until ctx is cancelled, for housekeeping like token cleanup
+func (cfg *apiConfig) scheduleJob(ctx context.Context, kind string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			enqueueCtx, cancel := cfg.dbContext(ctx)
+			if err := cfg.jobs.Enqueue(enqueueCtx, kind, nil); err != nil {
+				log.Printf("Queueing %s failed: %v", kind, err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// The cache always holds this many entries per leaderboard, ?limit= just takes a prefix
+const leaderboardSize = 100
+
+var (
+	errLeaderboardBy     = errors.New("by must be chirps or likes")
+	errLeaderboardWindow = errors.New("window must be 24h, 7d, 30d or all")
+)
+
+// The windows a leaderboard can cover. Keeping it to a few fixed ones keeps the cache small and always warm.
+var leaderboardWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"all": 0,
+}
+
+type leaderboardEntry struct {
+	Rank   int        `json:"rank"`
+	Author api.Author `json:"author"`
+	Score  int64      `json:"score"`
+}
+
+type leaderboardResponse struct {
+	By         string             `json:"by"`
+	Window     string             `json:"window"`
+	ComputedAt time.Time          `json:"computed_at"`
+	Entries    []leaderboardEntry `json:"entries"`
+}
+
+type leaderboardKey struct {
+	tenantID uuid.UUID
+	by       string
+	window   string
+}
+
+// Leaderboards are an aggregate over a lot of rows, so each one is computed at most once per ttl and shared
+type leaderboardCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[leaderboardKey]leaderboardResponse
+}
+
+func newLeaderboardCache(ttl time.Duration) *leaderboardCache {
+	return &leaderboardCache{ttl: ttl, entries: map[leaderboardKey]leaderboardResponse{}}
+}
+
+// The cached leaderboard for key, computing it with load when it's missing or older than the ttl
+func (c *leaderboardCache) get(key leaderboardKey, load func() (leaderboardResponse, error)) (leaderboardResponse, error) {
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && time.Since(cached.ComputedAt) < c.ttl {
+		return cached, nil
+	}
+
+	fresh, err := load()
+	if err != nil {
+		return leaderboardResponse{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = fresh
+	c.mu.Unlock()
+	return fresh, nil
+}
+
+func (cfg *apiConfig) computeLeaderboard(ctx context.Context, key leaderboardKey) (leaderboardResponse, error) {
+	var since time.Time
+	if span := leaderboardWindows[key.window]; span > 0 {
+		since = time.Now().Add(-span)
+	}
+
+	resp := leaderboardResponse{
+		By:         key.by,
+		Window:     key.window,
+		ComputedAt: time.Now(),
+		Entries:    []leaderboardEntry{},
+	}
+
+	add := func(id uuid.UUID, handle, displayName, avatarURL string, score int64) {
+		resp.Entries = append(resp.Entries, leaderboardEntry{
+			Rank:   len(resp.Entries) + 1,
+			Author: api.Author{ID: id, Handle: handle, DisplayName: displayName, AvatarURL: avatarURL},
+			Score:  score,
+		})
+	}
+
+	if key.by == "likes" {
+		rows, err := cfg.databaseQueries.GetTopLikedUsers(ctx, database.GetTopLikedUsersParams{
+			TenantID:  key.tenantID,
+			Since:     since,
+			PageLimit: leaderboardSize,
+		})
+		if err != nil {
+			return leaderboardResponse{}, err
+		}
+
+		for _, row := range rows {
+			add(row.ID, row.Handle.String, row.DisplayName.String, row.AvatarUrl.String, row.Score)
+		}
+
+		return resp, nil
+	}
+
+	rows, err := cfg.databaseQueries.GetTopChirpers(ctx, database.GetTopChirpersParams{
+		TenantID:  key.tenantID,
+		Since:     since,
+		PageLimit: leaderboardSize,
+	})
+	if err != nil {
+		return leaderboardResponse{}, err
+	}
+
+	for _, row := range rows {
+		add(row.ID, row.Handle.String, row.DisplayName.String, row.AvatarUrl.String, row.Score)
+	}
+
+	return resp, nil
+}
+
+// GET /api/leaderboard?by=chirps&window=7d&limit=10, the users who posted the most chirps (or got the most likes
+// from others) in the window. Results are cached for LEADERBOARD_CACHE_TTL, computed_at says how fresh they are.
+func (cfg *apiConfig) leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	query := r.URL.Query()
+
+	key := leaderboardKey{
+		tenantID: tenantFromContext(r.Context()),
+		by:       query.Get("by"),
+		window:   query.Get("window"),
+	}
+
+	if key.by == "" {
+		key.by = "chirps"
+	}
+	if key.by != "chirps" && key.by != "likes" {
+		respondWithError(w, http.StatusBadRequest, errLeaderboardBy.Error())
+		return
+	}
+
+	if key.window == "" {
+		key.window = "7d"
+	}
+	if _, ok := leaderboardWindows[key.window]; !ok {
+		respondWithError(w, http.StatusBadRequest, errLeaderboardWindow.Error())
+		return
+	}
+
+	board, err := cfg.leaderboards.get(key, func() (leaderboardResponse, error) {
+		return cfg.computeLeaderboard(ctx, key)
+	})
+	if err != nil {
+		log.Printf("Computing %s leaderboard failed: %v", key.by, err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	limit := queryLimit(r, "limit", 10, leaderboardSize)
+	board.Entries = board.Entries[:min(limit, len(board.Entries))]
+
+	respondWithJson(w, http.StatusOK, board)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+	"github.com/itsmandrew/server-go/internal/linkpreview"
+)
+
+type linkPreviewJob struct {
+	LinkID uuid.UUID `json:"link_id"`
+}
+
+// Loads the link previews for a batch of chirps with a single query, keyed by chirp ID
+func (cfg *apiConfig) linksForChirps(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]api.Link, error) {
+	links, err := cfg.databaseQueries.GetLinksForChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byChirp := make(map[uuid.UUID][]api.Link)
+	for _, link := range links {
+		byChirp[link.ChirpID] = append(byChirp[link.ChirpID], api.NewLink(link))
+	}
+
+	return byChirp, nil
+}
+
+// Stores the links found in new chirps and queues a preview fetch for each
+func (cfg *apiConfig) subscribeLinkPreviews(bus events.Bus) {
+	bus.Subscribe(events.TypeChirpCreated, func(ctx context.Context, event events.Event) {
+		chirp := event.(events.ChirpCreated).Chirp
+
+		urls := linkpreview.ExtractURLs(chirp.Body)
+		if len(urls) == 0 {
+			return
+		}
+
+		ctx, cancel := cfg.dbContext(ctx)
+		defer cancel()
+
+		for i, url := range urls {
+			link, err := cfg.databaseQueries.CreateChirpLink(ctx, database.CreateChirpLinkParams{
+				ChirpID:  chirp.ID,
+				Position: int32(i),
+				Url:      url,
+			})
+
+			if err != nil {
+				log.Printf("CreateChirpLink failed: %v", err)
+				return
+			}
+
+			if err := cfg.jobs.Enqueue(ctx, jobLinkPreview, linkPreviewJob{LinkID: link.ID}); err != nil {
+				log.Printf("Queueing link preview failed: %v", err)
+			}
+		}
+	})
+}
+
+// Job handler that fetches one link's OpenGraph data. Fetch failures are recorded on the link rather than
+// retried, most of them (404s, non-HTML, blocked addresses) won't get better.
+func (cfg *apiConfig) runLinkPreviewJob(ctx context.Context, raw json.RawMessage) error {
+	var job linkPreviewJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return err
+	}
+
+	link, err := cfg.databaseQueries.GetChirpLink(ctx, job.LinkID)
+	err = database.Wrap(err)
+
+	// Chirp was deleted before we got to it
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	preview, fetchErr := cfg.linkFetcher.Fetch(ctx, link.Url)
+
+	params := database.UpdateChirpLinkPreviewParams{
+		ID:          link.ID,
+		Title:       nullString(preview.Title),
+		Description: nullString(preview.Description),
+		ImageUrl:    nullString(preview.ImageURL),
+	}
+
+	if fetchErr != nil {
+		log.Printf("Fetching preview for %s failed: %v", link.Url, fetchErr)
+		params.FetchError = nullString(fetchErr.Error())
+	}
+
+	return cfg.databaseQueries.UpdateChirpLinkPreview(ctx, params)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
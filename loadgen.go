@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/loadgen"
+)
+
+// `chirpy loadgen -target http://localhost:8080 -rps 50 -duration 1m`, puts a running server under synthetic load
+// and prints latency percentiles. It doesn't need any of the server's env. Returns the exit code.
+func runLoadgen(args []string) int {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8080", "base URL of the server to load")
+	users := fs.Int("users", 10, "fake users to sign up and spread the load over")
+	rps := fs.Float64("rps", 20, "requests per second")
+	duration := fs.Duration("duration", 30*time.Second, "how long to keep the load up")
+	writeRatio := fs.Float64("write-ratio", 0.2, "share of requests that post a chirp, the rest read")
+	maxInFlight := fs.Int("max-in-flight", 100, "most requests running at once, ticks over it are dropped")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Ctrl-C stops the load early but still prints what it got
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Signing up %d users on %s...\n", *users, *target)
+
+	report, err := loadgen.Run(ctx, loadgen.Options{
+		Target:      *target,
+		Users:       *users,
+		RPS:         *rps,
+		Duration:    *duration,
+		WriteRatio:  *writeRatio,
+		MaxInFlight: *maxInFlight,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loadgen: %v\n", err)
+		return 1
+	}
+
+	report.WriteTo(os.Stdout)
+	return 0
+}
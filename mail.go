@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/itsmandrew/server-go/internal/mail"
+)
+
+// Queues an email, the request doesn't wait on the SMTP server and failed sends are retried
+func (cfg *apiConfig) sendMail(ctx context.Context, m mail.Message) error {
+	return cfg.jobs.Enqueue(ctx, jobMailDelivery, m)
+}
+
+// Job handler that sends one email. Without SMTP_URL the message is logged instead, which is enough for development.
+func (cfg *apiConfig) runMailJob(ctx context.Context, raw json.RawMessage) error {
+	var m mail.Message
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return err
+	}
+
+	if cfg.mail == nil {
+		log.Printf("SMTP_URL isn't set, not sending mail to %s: %s\n%s", m.To, m.Subject, m.Body)
+		return nil
+	}
+
+	return cfg.mail.Send(ctx, m)
+}
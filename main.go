@@ -1,21 +1,53 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"html"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"time"
+	// Timezone preferences are validated with time.LoadLocation, which needs this on hosts without tzdata
+	_ "time/tzdata"
 
 	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
 	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/breaker"
+	"github.com/itsmandrew/server-go/internal/captcha"
+	"github.com/itsmandrew/server-go/internal/clientip"
 	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/dbstats"
+	"github.com/itsmandrew/server-go/internal/events"
+	"github.com/itsmandrew/server-go/internal/i18n"
+	"github.com/itsmandrew/server-go/internal/jobs"
+	"github.com/itsmandrew/server-go/internal/language"
+	"github.com/itsmandrew/server-go/internal/linkpreview"
+	"github.com/itsmandrew/server-go/internal/mail"
+	"github.com/itsmandrew/server-go/internal/metrics"
+	"github.com/itsmandrew/server-go/internal/moderation"
+	"github.com/itsmandrew/server-go/internal/ranking"
+	"github.com/itsmandrew/server-go/internal/search"
+	"github.com/itsmandrew/server-go/internal/secrets"
+	"github.com/itsmandrew/server-go/internal/validate"
+	"github.com/itsmandrew/server-go/internal/webhooks"
+	"github.com/itsmandrew/server-go/internal/webpush"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 func respondWithJson(w http.ResponseWriter, code int, payload interface{}) error {
@@ -32,202 +64,502 @@ func respondWithJson(w http.ResponseWriter, code int, payload interface{}) error
 	return nil
 }
 
+// Successor to respondWithJson for chirp and user resources, which also come as XML (from their xml struct tags)
+// when the Accept header prefers it. JSON stays the default.
+func respond(w http.ResponseWriter, r *http.Request, code int, payload any) error {
+	w.Header().Add("Vary", "Accept")
+
+	if contentType := negotiate(r, "application/json", "application/xml", "text/xml"); contentType == "application/json" {
+		return respondWithJson(w, code, payload)
+	}
+
+	response, err := xml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(code)
+	io.WriteString(w, xml.Header)
+	w.Write(response)
+
+	return nil
+}
+
+// Body of every error response. Code is only set for messages in the i18n catalog, and unlike Error it's the
+// same whatever language the client asked for.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// msg is always written in English, it's translated here into the language middlewareLocale picked
 func respondWithError(w http.ResponseWriter, code int, msg string) error {
-	return respondWithJson(w, code, map[string]string{"error": msg})
+	resp := errorResponse{Error: msg}
+
+	if id, ok := i18n.Code(msg); ok {
+		resp.Code = id
+		resp.Error = i18n.Message(w.Header().Get("Content-Language"), id)
+	}
+
+	return respondWithJson(w, code, resp)
 }
 
 // Adjustable struct that allows for state
 type apiConfig struct {
-	fileserverHits  atomic.Int32
+	fileserverHits atomic.Int64
+	// Hits counted since the last flush, which adds them to the stored total
+	unflushedHits   atomic.Int64
+	chirpsCreated   atomic.Int64
+	db              *sql.DB
+	requestMetrics  *metrics.Registry
+	accessLog       *slog.Logger
+	logLevel        slog.LevelVar
 	databaseQueries *database.Queries
 	platform        string
-	jwtSecret       string
+	jwt             auth.JWTConfig
+	dbTimeout       time.Duration
+	// Timings of every query run on db, for /admin/db
+	queryStats *dbstats.Recorder
+	// nil when DB_BREAKER_THRESHOLD=0
+	dbBreakers *breaker.Set
+
+	// Side effects (webhooks etc.) hang off events published here instead of living in the handlers
+	events            events.Bus
+	jobs              *jobs.Queue
+	webhookDispatcher *webhooks.Dispatcher
+	// nil when VAPID keys aren't configured, which turns Web Push off
+	push        *webpush.Client
+	bannedWords *bannedWordCache
+	ipDenyList  *ipDenyList
+	chirpViews  *chirpViewCounter
+	activity    *activityTracker
+	// Cached for LEADERBOARD_CACHE_TTL
+	leaderboards *leaderboardCache
+	// The ?ranking= modes of /api/feed other than latest, and how far back they look for chirps
+	feedRankers map[string]ranking.Ranker
+	feedWindow  time.Duration
+	// Swapped wholesale on config reload, so always Load() it
+	moderation  atomic.Pointer[moderation.Pipeline]
+	linkFetcher *linkpreview.Fetcher
+	startedAt   time.Time
+	// How often /admin/metrics/stream sends an update
+	metricsStreamInterval time.Duration
+	// How long access tokens handed to OAuth clients last
+	oauthTokenTTL time.Duration
+	// nil when SMTP_URL isn't set, mail is logged instead of sent
+	mail *mail.Client
+	// Where the server is reachable from outside, for links in emails
+	publicURL string
+	// Logins from a device the user hasn't used before wait for an emailed confirmation
+	requireDeviceConfirmation bool
+	// How many times a user can change their handle in handleChangeWindow, 0 for no limit
+	handleChangeLimit  int
+	handleChangeWindow time.Duration
+	// How long a deactivated account is kept before it's deleted, 0 keeps them forever
+	deactivatedUserRetention time.Duration
+	// A job still marked running this long after it was claimed belongs to a worker that's gone
+	jobLease time.Duration
+	// How long finished jobs are kept, 0 keeps them forever
+	jobRetention time.Duration
+	// nil unless CAPTCHA_PROVIDER is set, signup and login then need a captcha_token
+	captcha *captcha.Verifier
+	// Postgres full-text search unless SEARCH_BACKEND picks a search cluster
+	search search.Backend
+	// Set on shutdown so /api/readyz fails while load balancers drain us
+	draining atomic.Bool
+	// nil unless an admin has switched maintenance mode on
+	maintenance atomic.Pointer[maintenanceState]
+	// Limits for the rate-limited routes, which admins can change at /admin/rate_limits
+	rateLimits []*rateLimitPolicy
+	// Longest chirp the moderation pipeline lets through, the max_chirp_length setting
+	maxChirpLength atomic.Int64
+	// Set when an admin closes signups with the signups_open setting
+	signupsClosed atomic.Bool
+	// Latency and errors to inject, set at /admin/faults when PLATFORM=dev
+	faults faultInjector
+	// nil unless MULTI_TENANT=on, in which case every request is scoped to the tenant it resolves to
+	tenants *tenantRegistry
 }
 
 // Wrapper around my other handlers, increments my struct var per request (goroutine) and then handles wrapped handler (using ServeHTTP)
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cfg.fileserverHits.Add(1)
+		cfg.unflushedHits.Add(1)
 		next.ServeHTTP(w, r)
 	})
 }
 
 // Handler for my metrics endpoint, writes the Content-Type for the heaader and also writes to the body the current "Hits"
+// plus a table of the per-route request metrics
 func (cfg *apiConfig) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	if negotiate(r, "text/html", "application/json") == "application/json" {
+		cfg.metricsJSONHandler(w, r)
+		return
+	}
+
+	var rows strings.Builder
+	for _, s := range cfg.requestMetrics.Snapshot() {
+		avgMs := 0.0
+		if s.Count > 0 {
+			avgMs = s.Sum / float64(s.Count) * 1000
+		}
+		fmt.Fprintf(&rows, "\n\t\t\t<tr><td>%s</td><td>%s</td><td>%d</td><td>%d</td><td>%.2f</td></tr>",
+			html.EscapeString(s.Method), html.EscapeString(s.Route), s.Count, s.Errors, avgMs)
+	}
+
 	w.Header().Add("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(200)
 	fmt.Fprintf(w, `
 		<html>
 	<body>
 		<h1>Welcome, Chirpy Admin</h1>
-		<p>Chirpy has been visited %d times!</p>
+		<p id="hits">Chirpy has been visited %d times!</p>
+		<p>Live: <span id="new-chirps">0</span> new chirps, <span id="errors-per-min">0.0</span> errors/min</p>
+		<table>
+			<tr><th>Method</th><th>Route</th><th>Requests</th><th>Errors</th><th>Avg ms</th></tr>%s
+		</table>
+		<script>
+			let newChirps = 0;
+			new EventSource("/admin/metrics/stream").addEventListener("metrics", (e) => {
+				const d = JSON.parse(e.data);
+				newChirps += d.new_chirps;
+				document.getElementById("hits").textContent = "Chirpy has been visited " + d.total_hits + " times!";
+				document.getElementById("new-chirps").textContent = newChirps;
+				document.getElementById("errors-per-min").textContent = d.errors_per_min.toFixed(1);
+			});
+		</script>
 	</body>
-	</html>`, cfg.fileserverHits.Load())
+	</html>`, cfg.fileserverHits.Load(), rows.String())
 }
 
-// Handler for my reset endpoint, resets the state of our apiConfig, 'hits' to 0
-func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
+// Handler for the Prometheus scrape endpoint
+func (cfg *apiConfig) prometheusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
 
-	type message struct {
-		Msg string `json:"msg"`
-	}
+	fmt.Fprintf(w, "# HELP chirpy_fileserver_hits Requests served by the /app file server.\n")
+	fmt.Fprintf(w, "# TYPE chirpy_fileserver_hits counter\n")
+	fmt.Fprintf(w, "chirpy_fileserver_hits %d\n", cfg.fileserverHits.Load())
 
-	if cfg.platform != "dev" {
-		w.WriteHeader(http.StatusForbidden)
-		return
+	if err := cfg.requestMetrics.WritePrometheus(w); err != nil {
+		log.Printf("Writing prometheus metrics failed: %v", err)
 	}
 
-	// Resetting stuff
-	cfg.fileserverHits.Store(0)
-	err := cfg.databaseQueries.DeleteUsers(r.Context())
-
-	if err != nil {
-		log.Printf("DeleteUsers failed: %v", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
+	if cfg.dbBreakers != nil {
+		if err := cfg.dbBreakers.WritePrometheus(w); err != nil {
+			log.Printf("Writing prometheus metrics failed: %v", err)
+		}
 	}
-
-	msg := message{Msg: "Metrics and users table were reset"}
-	respondWithJson(w, http.StatusOK, msg)
-	log.Println("Metrics and table reset")
 }
 
 // Handler for creating a user
 func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
 	type parameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email        string `json:"email" validate:"required,email"`
+		Password     string `json:"password" validate:"required,max=72"`
+		CaptchaToken string `json:"captcha_token"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	if !cfg.checkCaptcha(ctx, w, r, params.CaptchaToken) {
+		return
+	}
 
-	defer r.Body.Close()
+	if cfg.signupsClosed.Load() {
+		respondWithError(w, http.StatusForbidden, "Signups are closed")
+		return
+	}
 
-	err := decoder.Decode(&params)
+	encryptedPass, err := auth.HashedPassword(params.Password)
 
 	// Decoding error print out
 	if err != nil {
-		log.Printf("Error decoding")
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+		log.Printf("Error with encrypting the password")
+		respondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	encryptedPass, err := auth.HashedPassword(params.Password)
-
 	passByParam := database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: encryptedPass,
+		TenantID:       tenantFromContext(r.Context()),
 	}
 
-	// Decoding error print out
-	if err != nil {
-		log.Printf("Error with encrypting the password")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-	}
+	user, err := cfg.databaseQueries.CreateUser(ctx, passByParam)
+	err = database.Wrap(err)
 
-	user, err := cfg.databaseQueries.CreateUser(r.Context(), passByParam)
+	// Email is the only unique column set on signup
+	if errors.Is(err, database.ErrDuplicate) {
+		respondWithError(w, http.StatusConflict, errEmailTaken.Error())
+		return
+	}
 
 	if err != nil {
 		log.Printf("CreateUser failed: %v", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	log.Printf("Created user: %v\n", user)
-	respondWithJson(w, http.StatusCreated, user)
+	cfg.events.Publish(r.Context(), events.UserCreated{User: user})
+	respond(w, r, http.StatusCreated, api.NewCreatedUser(user))
 }
 
 func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type chirpParameters struct {
+		Body      string        `json:"body" validate:"required"`
+		ReplyToID uuid.NullUUID `json:"reply_to_id"`
+		// A warning marks the chirp sensitive on its own, sensitive alone gets no label
+		Sensitive      bool   `json:"sensitive"`
+		ContentWarning string `json:"content_warning" validate:"max=100"`
+		// ISO 639-1, detected from the body when left out
+		Language string `json:"language"`
+		// public when left out, followers or private
+		Visibility string `json:"visibility"`
+		// Posts the chirp later instead of now
+		PublishAt *time.Time `json:"publish_at"`
+	}
 
-	var parameters database.CreateChirpParams
-
-	// 1.  Reads the Header for a Bearer Token
-	token, err := auth.GetBearerToken(r.Header)
+	userID := userIDFromContext(r.Context())
 
-	if err != nil {
-		log.Println("No Bearer token")
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+	params := chirpParameters{}
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
-	// Checks to see if the token is a AccessToken vs RefreshToken (accessToken has 3 dots) -> Sanity Check
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		log.Printf("Token does not have three segments (likely not a JWT): %q\n", token)
-		respondWithError(w, http.StatusUnauthorized, "Invalid token format")
+	if params.Language != "" && !language.Valid(params.Language) {
+		respondWithFieldErrors(w, validate.Errors{"language": "must be a two letter ISO 639-1 code like en"})
 		return
 	}
 
-	// 2. Decode the params into our struct
-	decoder := json.NewDecoder(r.Body)
-	defer r.Body.Close()
-	err = decoder.Decode(&parameters)
+	if params.Language == "" {
+		params.Language = language.Detect(params.Body)
+	}
 
-	// Handling decoding error
-	if err != nil {
-		log.Printf("Error decoding")
-		respondWithError(w, 500, "Something went wrong")
-		return
+	if params.Visibility == "" {
+		params.Visibility = visibilityPublic
 	}
 
-	// 3. Validate our Access Token
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if !slices.Contains(chirpVisibilities, params.Visibility) {
+		respondWithFieldErrors(w, validate.Errors{"visibility": "must be public, followers or private"})
+		return
+	}
 
-	if err != nil {
-		log.Println("JWT token is invalid")
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+	if params.PublishAt != nil && !validPublishAt(*params.PublishAt) {
+		respondWithFieldErrors(w, validate.Errors{"publish_at": "must be in the future and at most a year away"})
 		return
 	}
 
-	var nullID uuid.UUID
-	if userID == nullID {
-		log.Println("Something wrong, no id value")
-		respondWithError(w, http.StatusUnauthorized, "ID is null")
+	warning := strings.TrimSpace(params.ContentWarning)
+
+	parameters := database.CreateChirpParams{
+		Body:           params.Body,
+		UserID:         userID,
+		ReplyToID:      params.ReplyToID,
+		TenantID:       tenantFromContext(r.Context()),
+		Sensitive:      params.Sensitive || warning != "",
+		ContentWarning: sql.NullString{String: warning, Valid: warning != ""},
+		Language:       sql.NullString{String: params.Language, Valid: params.Language != ""},
+		Visibility:     params.Visibility,
+	}
+
+	// Hash the body as written, before censoring, so repeats are caught however they get cleaned up
+	parameters.ContentHash = moderation.ContentHash(parameters.Body)
+
+	decision := cfg.moderation.Load().Run(ctx, moderation.Content{UserID: userID, Body: parameters.Body})
+
+	if decision.Verdict == moderation.Reject {
+		reason := decision.Violations[len(decision.Violations)-1].Reason
+		log.Printf("Chirp rejected: %s", reason)
+		respondWithError(w, 400, reason)
 		return
 	}
 
-	parameters.UserID = userID
+	if decision.Verdict == moderation.Flag {
+		log.Printf("Chirp by %s flagged: %v", userID, decision.Violations)
+	}
+
+	parameters.Body = decision.Body
+
+	// The foreign key only proves the parent exists in some tenant, replies mustn't reach into another one or to a
+	// chirp the author can't see
+	if parameters.ReplyToID.Valid {
+		_, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+			ID:       parameters.ReplyToID.UUID,
+			TenantID: parameters.TenantID,
+			ViewerID: userID,
+		})
+		err = database.Wrap(err)
+
+		if errors.Is(err, database.ErrNotFound) {
+			respondWithError(w, http.StatusNotFound, "Chirp being replied to doesn't exist")
+			return
+		}
 
-	ok, cleanBody := validateChirp(parameters.Body)
+		if err != nil {
+			log.Printf("GetVisibleChirp failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
 
-	if !ok {
-		log.Printf("Chirp is too long")
-		respondWithError(w, 400, "Chirp is too long")
+	if params.PublishAt != nil {
+		cfg.scheduleChirp(ctx, w, parameters, *params.PublishAt)
 		return
 	}
 
-	parameters.Body = cleanBody
+	chirp, err := cfg.databaseQueries.CreateChirp(ctx, parameters)
+	err = database.Wrap(err)
 
-	chirp, err := cfg.databaseQueries.CreateChirp(r.Context(), parameters)
+	if errors.Is(err, database.ErrForeignKey) {
+		respondWithError(w, http.StatusNotFound, "Chirp being replied to doesn't exist")
+		return
+	}
 
 	if err != nil {
 		log.Printf("CreateChirp failed: %v", err)
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	log.Printf("Created chirp: %v\n", chirp)
-	respondWithJson(w, http.StatusCreated, chirp)
+	cfg.events.Publish(r.Context(), events.ChirpCreated{Chirp: chirp})
+
+	// Previews are fetched in the background and nobody's liked it yet, so there's nothing to load
+	respond(w, r, http.StatusCreated, api.NewChirp(chirp))
 
 }
 
+// How many chirps getChirpsHandler reads and turns into responses at once while streaming
+const chirpStreamBatch = 500
+
 func (cfg *apiConfig) getChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	// since is inclusive and before exclusive, so a poller can pass its last fetch time straight back as since
+	since, err := queryTime(r, "since", oldestFirst.Time)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	chirps, err := cfg.databaseQueries.GetChirps(r.Context())
+	before, err := queryTime(r, "before", newestFirst.Time)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
+	lang, err := queryLanguage(r)
 	if err != nil {
-		log.Println("Something went wrong with the query")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Shadow-banned users still see their own chirps
+	viewerID, _ := cfg.optionalUser(r)
+
+	hidden, err := cfg.hiddenChirps(ctx, viewerID)
+	if err != nil {
+		log.Printf("Loading hidden chirp filters failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Chirps are read a page at a time so only one page and its links/engagement is ever in memory. next is the
+	// cursor after the page, taken before hidden chirps are dropped, and the zero cursor once there's nothing left.
+	load := func(ctx context.Context, cursor pageCursor) (response []api.Chirp, next pageCursor, err error) {
+		page, err := cfg.databaseQueries.GetChirps(ctx, database.GetChirpsParams{
+			TenantID:   tenantFromContext(r.Context()),
+			Since:      since,
+			Before:     before,
+			CursorTime: cursor.Time,
+			CursorID:   cursor.ID,
+			ViewerID:   viewerID,
+			Language:   lang,
+			PageLimit:  chirpStreamBatch,
+		})
+		if err != nil {
+			return nil, pageCursor{}, err
+		}
+
+		if len(page) == chirpStreamBatch {
+			last := page[len(page)-1]
+			next = pageCursor{Time: last.CreatedAt, ID: last.ID}
+		}
+
+		page = slices.DeleteFunc(page, hidden)
+		cfg.chirpViews.record(page, viewerID)
+		response, err = cfg.chirpResponses(ctx, page, viewerID)
+		return response, next, err
+	}
+
+	// The first page is loaded before the headers go out, so the common failure still gets a proper status
+	response, next, err := load(ctx, oldestFirst)
+	cancel()
+
+	if err != nil {
+		log.Printf("Loading chirps failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	stream, err := newListStream(w, r, "chirps")
+	if err != nil {
+		log.Printf("Streaming chirps failed: %v", err)
 		return
 	}
 
-	log.Printf("Retrieving chirps: %v\n", chirps)
-	respondWithJson(w, http.StatusOK, chirps)
+	count := 0
+	for {
+		for _, chirp := range response {
+			if err := stream.Write(chirp); err != nil {
+				log.Printf("Streaming chirps failed: %v", err)
+				return
+			}
+		}
+		count += len(response)
+
+		if next == (pageCursor{}) {
+			break
+		}
+
+		// Each page gets its own DB timeout, like the export
+		pageCtx, cancelPage := cfg.dbContext(r.Context())
+		response, next, err = load(pageCtx, next)
+		cancelPage()
+
+		if err != nil {
+			// Too late for an error status, the client sees a truncated body instead
+			log.Printf("Loading chirps failed mid-stream: %v", err)
+			return
+		}
+	}
+
+	log.Printf("Retrieving %d chirps\n", count)
+
+	if err := stream.Close(); err != nil {
+		log.Printf("Streaming chirps failed: %v", err)
+	}
 }
 
 func (cfg *apiConfig) getIndividualChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
 	userID := r.PathValue("chirpID")
 	log.Println(userID)
@@ -246,56 +578,79 @@ func (cfg *apiConfig) getIndividualChirpHandler(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	chirp, err := cfg.databaseQueries.GetIndividualChirp(r.Context(), parsedID)
+	viewerID, _ := cfg.optionalUser(r)
+
+	// Someone who can't see it gets the same 404 as for a chirp that doesn't exist
+	chirp, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       parsedID,
+		TenantID: tenantFromContext(r.Context()),
+		ViewerID: viewerID,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetVisibleChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	cfg.chirpViews.record([]database.Chirp{chirp}, viewerID)
+	response, err := cfg.chirpResponses(ctx, []database.Chirp{chirp}, viewerID)
 
 	if err != nil {
-		log.Println("Something went wrong with the query")
-		respondWithError(w, http.StatusNotFound, err.Error())
+		log.Printf("Loading chirp links/engagement failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	respondWithJson(w, http.StatusOK, chirp)
+	respond(w, r, http.StatusOK, response[0])
 
 }
 
 func (cfg *apiConfig) loginUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
 	type parameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
-
-	type validResponse struct {
-		ID           uuid.UUID `json:"id"`
-		Email        string    `json:"email"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required"`
+		// Tokens go in httpOnly cookies instead of the body, for the web app
+		Session      bool   `json:"session"`
+		CaptchaToken string `json:"captcha_token"`
 	}
 
 	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
 
-	// Decoding logic
-	decoder := json.NewDecoder(r.Body)
-	defer r.Body.Close()
-	err := decoder.Decode(&params)
-
-	if err != nil {
-		log.Printf("Error decoding")
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	if !cfg.checkCaptcha(ctx, w, r, params.CaptchaToken) {
 		return
 	}
 
 	log.Println(params)
 
 	// Get user query (call to database)
-	user, err := cfg.databaseQueries.GetUserByEmail(r.Context(), params.Email)
+	user, err := cfg.databaseQueries.GetUserByEmail(ctx, database.GetUserByEmailParams{
+		Email:    params.Email,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	// Same answer as a wrong password, so logins can't be used to find out who has an account
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusUnauthorized, "Email or password is incorrect")
+		return
+	}
 
-	// Error handling for if the datebase query goes wrong
 	if err != nil {
-		log.Println("Something went wrong with the query")
-		respondWithError(w, http.StatusInternalServerError, "Email does not exist")
+		log.Printf("GetUserByEmail failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -309,8 +664,23 @@ func (cfg *apiConfig) loginUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.DeactivatedAt.Valid {
+		respondWithError(w, http.StatusForbidden, errAccountDeactivated.Error())
+		return
+	}
+
+	if userBanned(user) {
+		respondWithError(w, http.StatusForbidden, errAccountBanned.Error())
+		return
+	}
+
+	if !cfg.checkDevice(ctx, w, r, user) {
+		return
+	}
+
 	// Create a JWT token for our user that logins in (access token)
-	jwtToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Duration(3600)*time.Second)
+	jwtExpiresAt := time.Now().Add(accessTokenTTL)
+	jwtToken, err := cfg.tokenConfig(r.Context()).Make(user.ID, accessTokenTTL, auth.Scopes...)
 
 	// Error handling if creation of token fucks up
 	if err != nil {
@@ -320,80 +690,173 @@ func (cfg *apiConfig) loginUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create a refresh token (string form)
-	refreshToken, _ := auth.MakeRefreshToken()
+	refreshToken, err := auth.MakeRefreshToken()
+
+	if err != nil {
+		log.Println("Something went wrong with creating refresh token")
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	refreshTokenParams := database.CreateRefreshTokenParams{
 		Token:  refreshToken,
 		UserID: user.ID,
 	}
 
-	// Insert refresh token into database
-	createdRToken, err := cfg.databaseQueries.CreateRefreshToken(r.Context(), refreshTokenParams)
+	// Insert refresh token into database, inside a transaction so the whole token issuance commits or nothing does
+	var createdRToken database.RefreshToken
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		var err error
+		createdRToken, err = qtx.CreateRefreshToken(ctx, refreshTokenParams)
+		return err
+	})
 
 	// Error handling for insert refresh_token into database
 	if err != nil {
 		log.Println("Something went wrong with inserting refresh token into database")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
 	log.Printf("Refresh token created for %v\n", user.Email)
 
+	if params.Session {
+		cfg.setSessionCookies(w, jwtToken, createdRToken.Token)
+		respond(w, r, http.StatusOK, api.NewUser(user))
+		return
+	}
+
 	// Everything works
-	safeResponse := validResponse{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Token:        jwtToken,
-		RefreshToken: createdRToken.Token,
+	safeResponse := api.Login{
+		User: api.NewUser(user),
+		Tokens: api.Tokens{
+			Token:                 jwtToken,
+			ExpiresAt:             jwtExpiresAt,
+			RefreshToken:          createdRToken.Token,
+			RefreshTokenExpiresAt: createdRToken.ExpiresAt,
+		},
 	}
 
-	respondWithJson(w, http.StatusOK, safeResponse)
+	respond(w, r, http.StatusOK, safeResponse)
 }
 
-func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
+// How long access tokens from /api/login and /api/refresh last
+const accessTokenTTL = time.Hour
 
-	type validResponse struct {
-		AccessToken string `json:"token"`
-	}
+var (
+	errRefreshRevoked = errors.New("refresh token revoked")
+	errRefreshExpired = errors.New("refresh token expired")
+	errRefreshMissing = errors.New("refresh token missing")
+)
 
-	// Check header for the refresh token
-	refreshToken, err := auth.GetBearerToken(r.Header)
+// Revokes refreshToken and issues its replacement in one transaction, returning the new one
+func (cfg *apiConfig) rotateRefreshToken(ctx context.Context, r *http.Request, refreshToken string) (database.RefreshToken, error) {
+	newRefreshToken, err := auth.MakeRefreshToken()
 
-	// Handling error for missing Authorization token
 	if err != nil {
-		log.Println("No bearer token")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
+		log.Println("Error in creating new refresh token")
+		return database.RefreshToken{}, err
 	}
 
-	// Getting the token vals from the database
-	dbToken, err := cfg.databaseQueries.GetUserFromRefreshToken(r.Context(), refreshToken)
+	var rotated database.RefreshToken
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		// Getting the token vals from the database, locked so two refreshes can't both rotate the same token
+		dbToken, err := qtx.GetRefreshTokenForUpdate(ctx, refreshToken)
+		err = database.Wrap(err)
+		if errors.Is(err, database.ErrNotFound) {
+			return errRefreshMissing
+		}
+		if err != nil {
+			return err
+		}
+
+		var nullValue sql.NullTime
+		if dbToken.RevokedAt != nullValue {
+			return errRefreshRevoked
+		}
 
-	// Handling query error (call to database)
-	if err != nil {
-		log.Println("Error in getting refresh token in database")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
+		// Tokens get 60 days at creation, after that you have to log in again
+		if time.Now().After(dbToken.ExpiresAt) {
+			return errRefreshExpired
+		}
+
+		// A refresh token from another tenant is as good as no token here
+		if cfg.tenants != nil {
+			exists, err := qtx.UserExists(ctx, database.UserExistsParams{
+				ID:       dbToken.UserID,
+				TenantID: tenantFromContext(r.Context()),
+			})
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return errRefreshMissing
+			}
+		}
+
+		if err := qtx.RevokeRefreshToken(ctx, refreshToken); err != nil {
+			return err
+		}
+
+		rotated, err = qtx.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+			Token:  newRefreshToken,
+			UserID: dbToken.UserID,
+		})
+		return err
+	})
 
-	var nullValue sql.NullTime
-	if dbToken.RevokedAt != nullValue {
+	return rotated, err
+}
+
+// Error responses for rotateRefreshToken failures
+func respondWithRefreshError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errRefreshRevoked) {
 		log.Println("Refresh token expired")
 		respondWithError(w, http.StatusUnauthorized, "Fuck ur refresh token")
 		return
 	}
 
-	// Handling value not found in database (null return)
-	var nullToken database.RefreshToken
-	if dbToken == nullToken {
+	if errors.Is(err, errRefreshExpired) {
+		log.Println("Refresh token past its expiry")
+		respondWithError(w, http.StatusUnauthorized, "Refresh token expired")
+		return
+	}
+
+	if errors.Is(err, errRefreshMissing) {
 		log.Println("Refresh token not found in the database")
 		respondWithError(w, http.StatusNotFound, "Refresh token not in database")
 		return
 	}
 
+	// Handling query error (call to database)
+	log.Println("Error in rotating refresh token in database")
+	respondWithDBError(w, http.StatusInternalServerError, err)
+}
+
+// Swaps a refresh token for a new access token, rotating the refresh token: the old one is revoked and a new one issued in the same transaction
+func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	// Check header for the refresh token
+	refreshToken, err := auth.GetBearerToken(r.Header)
+
+	// Handling error for missing Authorization token
+	if err != nil {
+		log.Println("No bearer token")
+		respondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rotated, err := cfg.rotateRefreshToken(ctx, r, refreshToken)
+	if err != nil {
+		respondWithRefreshError(w, err)
+		return
+	}
+
 	// Creating new access token
-	newAccessToken, err := auth.MakeJWT(dbToken.UserID, cfg.jwtSecret, time.Duration(3600)*time.Second)
+	newAccessExpiresAt := time.Now().Add(accessTokenTTL)
+	newAccessToken, err := cfg.tokenConfig(r.Context()).Make(rotated.UserID, accessTokenTTL, auth.Scopes...)
 
 	// Handling error for creation of access token
 	if err != nil {
@@ -403,8 +866,11 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Setting up response
-	resp := validResponse{
-		AccessToken: newAccessToken,
+	resp := api.Tokens{
+		Token:                 newAccessToken,
+		ExpiresAt:             newAccessExpiresAt,
+		RefreshToken:          rotated.Token,
+		RefreshTokenExpiresAt: rotated.ExpiresAt,
 	}
 
 	// Writing response
@@ -413,6 +879,8 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (cfg *apiConfig) revokeUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
 	refreshToken, err := auth.GetBearerToken(r.Header)
 
@@ -423,11 +891,11 @@ func (cfg *apiConfig) revokeUpdateHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = cfg.databaseQueries.RevokeRefreshToken(r.Context(), refreshToken)
+	err = cfg.databaseQueries.RevokeRefreshToken(ctx, refreshToken)
 
 	if err != nil {
 		fmt.Println("Error in the update query for RevokeRefreshToken")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -436,85 +904,116 @@ func (cfg *apiConfig) revokeUpdateHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
+	// A different email isn't set straight away, it starts the same confirmation as POST /api/users/email
 	type paramaters struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		Password string `json:"password" validate:"required,max=72"`
+		Email    string `json:"email" validate:"email"`
 	}
 
-	// 1.  Reads the Header for a Bearer Token
-	token, err := auth.GetBearerToken(r.Header)
+	userID := userIDFromContext(r.Context())
 
-	if err != nil {
-		log.Println("No Bearer token")
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+	params := paramaters{}
+	// 2. Decode the body
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
-	// Checks to see if the token is a AccessToken vs RefreshToken (accessToken has 3 dots) -> Sanity Check
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		log.Printf("Token does not have three segments (likely not a JWT): %q\n", token)
-		respondWithError(w, http.StatusUnauthorized, "Invalid token format")
-		return
-	}
+	cfg.updateUser(ctx, w, r, userID, params.Email, params.Password)
+}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+// PATCH /api/users, changes only the fields sent. Like PUT, a new email waits on the confirmation link and answers 202.
+func (cfg *apiConfig) patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
-	if err != nil {
-		log.Println("JWT not valid")
-		respondWithError(w, http.StatusUnauthorized, err.Error())
-		return
+	type parameters struct {
+		Email string `json:"email" validate:"email"`
+		// bcrypt ignores anything past 72 bytes
+		Password string `json:"password" validate:"max=72"`
 	}
 
-	params := paramaters{}
-	// 2. Decode the body
-
-	decoder := json.NewDecoder(r.Body)
-	defer r.Body.Close()
-	err = decoder.Decode(&params)
+	userID := userIDFromContext(r.Context())
 
-	if err != nil {
-		log.Printf("Error decoding")
-		respondWithError(w, http.StatusInternalServerError, "Something went wrong")
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
-	// 3. Hash the password
-	hashedPassword, err := auth.HashedPassword(params.Password)
-
-	if err != nil {
-		log.Println("Error in hashing password")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	if params.Email == "" && params.Password == "" {
+		respondWithError(w, http.StatusBadRequest, "Send an email or password to change")
 		return
 	}
 
-	newArguments := database.UpdateUserPasswordParams{
-		HashedPassword: hashedPassword,
-		Email:          params.Email,
-		ID:             userID,
+	cfg.updateUser(ctx, w, r, userID, params.Email, params.Password)
+}
+
+// Sets a new password and starts an email change for PUT and PATCH /api/users, either left empty stays as it is
+func (cfg *apiConfig) updateUser(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uuid.UUID, email, password string) {
+	var hashedPassword string
+	if password != "" {
+		var err error
+		hashedPassword, err = auth.HashedPassword(password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	}
-	err = cfg.databaseQueries.UpdateUserPassword(r.Context(), newArguments)
 
-	if err != nil {
-		log.Println("Error in UPDATE query execution")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	// Both fields change together or not at all, a taken email doesn't leave the new password behind
+	var (
+		user   database.GetUserByIDNoPasswordRow
+		change database.EmailChange
+		token  string
+	)
+	err := database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		if hashedPassword != "" {
+			err := qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+				HashedPassword: hashedPassword,
+				ID:             userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		user, err = qtx.GetUserByIDNoPassword(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if email != "" && email != user.Email {
+			change, token, err = createEmailChange(ctx, qtx, r, userID, email)
+		}
+		return err
+	})
+
+	if errors.Is(err, errEmailTaken) {
+		respondWithError(w, http.StatusConflict, err.Error())
 		return
 	}
 
-	// Return 200 and getUser
-	user, err := cfg.databaseQueries.GetUserByIDNoPassword(r.Context(), userID)
 	if err != nil {
-		log.Println("Error in GET user by email")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		log.Printf("Updating user failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	respondWithJson(w, http.StatusOK, user)
+	if token != "" {
+		err = cfg.sendEmailChangeMail(ctx, r, user, change, token)
+		respondWithEmailChange(w, r, change, err)
+		return
+	}
 
+	respond(w, r, http.StatusOK, api.NewUserFromRow(user))
 }
 
 func (cfg *apiConfig) deleteChirpFromID(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
 
 	chirpID := r.PathValue("chirp_id")
 	log.Println(chirpID)
@@ -528,104 +1027,49 @@ func (cfg *apiConfig) deleteChirpFromID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// 1.  Reads the Header for a Bearer Token
-	token, err := auth.GetBearerToken(r.Header)
+	userID := userIDFromContext(r.Context())
+
+	// DeleteTheChirp, check if our userID is the author of the chirp
+	chirp, err := cfg.databaseQueries.GetIndividualChirp(ctx, database.GetIndividualChirpParams{
+		ID:       newChirpID,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		fmt.Println("No chirp found by the provided ID")
+		respondWithError(w, http.StatusNotFound, "Lol no chirps existing with this ID")
+		return
+	}
 
 	if err != nil {
-		log.Println("No Bearer token")
-		respondWithError(w, http.StatusUnauthorized, err.Error())
+		fmt.Println("Error in GETTING sql query / individual chirp")
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
-	// Checks to see if the token is a AccessToken vs RefreshToken (accessToken has 3 dots) -> Sanity Check
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		log.Printf("Token does not have three segments (likely not a JWT): %q\n", token)
-		respondWithError(w, http.StatusUnauthorized, "Invalid token format")
+	if chirp.UserID != userID {
+		log.Println("User is not the author of this chirp dummy")
+		respondWithError(w, http.StatusForbidden, "User not the author of the chirp")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-
-	if err != nil {
-		log.Println("Error in validating JWT")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// DeleteTheChirp, check if our userID is the author of the chirp
-	chirp, err := cfg.databaseQueries.GetIndividualChirp(r.Context(), newChirpID)
-
-	if err != nil {
-		fmt.Println("Error in GETTING sql query / individual chirp")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	var nullChirp database.Chirp
-	if chirp == nullChirp {
-		fmt.Println("No chirp found by the provided ID")
-		respondWithError(w, http.StatusNotFound, "Lol no chirps existing with this ID")
-		return
-	}
-
-	if chirp.UserID != userID {
-		log.Println("User is not the author of this chirp dummy")
-		respondWithError(w, http.StatusForbidden, "User not the author of the chirp")
-		return
-	}
-
-	err = cfg.databaseQueries.DeleteChirpByID(r.Context(), newChirpID)
+	err = cfg.databaseQueries.DeleteChirpByID(ctx, newChirpID)
 
 	if err != nil {
 		log.Println("Error in executing DeleteChirpByID")
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithDBError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	cfg.events.Publish(r.Context(), events.ChirpDeleted{ChirpID: chirp.ID, UserID: chirp.UserID})
+
 	w.WriteHeader(http.StatusNoContent)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	// Return 204 if success
 
 }
 
-func simpleCensor(input string, badWords map[string]struct{}) string {
-	// Cleaning up the body now...
-	words := strings.Fields(input)
-	result := ""
-
-	for i := range words {
-		_, ok := badWords[strings.ToLower(words[i])]
-		currString := words[i]
-
-		if ok {
-			currString = "****"
-		}
-
-		result += currString + " "
-	}
-
-	result = strings.TrimSpace(result)
-	return result
-}
-
-func validateChirp(body string) (bool, string) {
-
-	bannedWords := map[string]struct{}{
-		"kerfuffle": {},
-		"sharbert":  {},
-		"fornax":    {},
-	}
-
-	if len(body) > 140 {
-		log.Printf("Chirp is too long")
-		return false, ""
-	}
-
-	result := simpleCensor(body, bannedWords)
-	return true, result
-}
-
 func init() {
 	// loads .env into the process’s env vars; logs but does not exit if .env is missing
 	if err := godotenv.Load(); err != nil {
@@ -634,20 +1078,79 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "loadgen" {
+		os.Exit(runLoadgen(os.Args[2:]))
+	}
+
+	// Secrets can come from the env, NAME_FILE files or Vault, see internal/secrets
+	secretsCtx, cancelSecrets := context.WithTimeout(context.Background(), 15*time.Second)
+	secretLoader, err := secrets.NewLoader(secretsCtx, secrets.VaultConfigFromEnv())
+	cancelSecrets()
+	if err != nil {
+		log.Fatalf("Loading secrets failed: %v", err)
+	}
+
+	mustSecret := func(name string) string {
+		v, err := secretLoader.Get(name)
+		if err != nil {
+			log.Fatalf("Loading %s failed: %v", name, err)
+		}
+		return v
+	}
 
 	// Getenv gets the EXPORTED variables, doesn't export
-	dbURL := os.Getenv("DB_URL")
+	dbURL := mustSecret("DB_URL")
 	platform := os.Getenv("PLATFORM")
-	jwtSecret := os.Getenv("JWT_SECRET")
+	jwtSecret := mustSecret("JWT_SECRET")
 
-	db, err := sql.Open("postgres", dbURL)
+	connector, err := pq.NewConnector(dbURL)
 
 	if err != nil {
 		fmt.Println("Cannot connect to db")
 		return
 	}
 
+	// Every query is timed through the connector, calls taking DB_SLOW_QUERY or longer are counted as slow
+	queryStats := dbstats.NewRecorder(envDuration("DB_SLOW_QUERY", 200*time.Millisecond))
+	var dbConnector driver.Connector = dbstats.Wrap(connector, queryStats)
+
+	// Each query (and connecting) gets a circuit breaker that opens after DB_BREAKER_THRESHOLD failures in a row,
+	// so requests get a quick 503 rather than all waiting out DB_TIMEOUT on a database that's down
+	var dbBreakers *breaker.Set
+	if threshold := envInt("DB_BREAKER_THRESHOLD", 5); threshold > 0 {
+		dbBreakers = breaker.NewSet(breaker.Options{
+			Threshold: threshold,
+			Cooldown:  envDuration("DB_BREAKER_COOLDOWN", 10*time.Second),
+			IsFailure: isTransientDBError,
+			Group:     dbstats.Name,
+		})
+		dbConnector = breaker.Wrap(dbConnector, dbBreakers)
+	}
+
+	db := sql.OpenDB(dbConnector)
+
+	// DB_CONNECT_TIMEOUT=0 skips waiting, for running without a database at all
+	if timeout := envDuration("DB_CONNECT_TIMEOUT", 30*time.Second); timeout > 0 {
+		if err := waitForDB(db, timeout); err != nil {
+			log.Fatalf("Connecting to the database failed: %v", err)
+		}
+	}
+
+	// Prepared statements save Postgres re-parsing the hot queries on every call. DB_PREPARE=off is for poolers
+	// like PgBouncer in transaction mode, where a statement prepared on one connection isn't there on the next.
 	dbQueries := database.New(db)
+	if os.Getenv("DB_PREPARE") != "off" {
+		prepareCtx, cancelPrepare := context.WithTimeout(context.Background(), 10*time.Second)
+		prepared, err := database.Prepare(prepareCtx, db)
+		cancelPrepare()
+
+		if err != nil {
+			log.Printf("Preparing statements failed, running unprepared: %v", err)
+		} else {
+			dbQueries = prepared
+			defer dbQueries.Close()
+		}
+	}
 
 	// Gives a blank, thread-safe routing table. Ready to attach paths
 	// to handler functions, and plug directly into an HTTP server
@@ -655,25 +1158,212 @@ func main() {
 	mux := http.NewServeMux()
 
 	apiCfg := apiConfig{
+		db:              db,
 		databaseQueries: dbQueries,
+		queryStats:      queryStats,
+		dbBreakers:      dbBreakers,
+		requestMetrics:  metrics.NewRegistry(nil),
 		platform:        platform,
-		jwtSecret:       jwtSecret,
+		jwt: auth.JWTConfig{
+			Secret:   jwtSecret,
+			Issuer:   os.Getenv("JWT_ISSUER"),
+			Audience: os.Getenv("JWT_AUDIENCE"),
+			Leeway:   envDuration("JWT_LEEWAY", 30*time.Second),
+		},
+		dbTimeout:             envDuration("DB_TIMEOUT", 3*time.Second),
+		startedAt:             time.Now(),
+		bannedWords:           newBannedWordCache(),
+		ipDenyList:            newIPDenyList(),
+		chirpViews:            newChirpViewCounter(),
+		activity:              newActivityTracker(),
+		leaderboards:          newLeaderboardCache(envDuration("LEADERBOARD_CACHE_TTL", 5*time.Minute)),
+		feedWindow:            envDuration("FEED_TOP_WINDOW", 72*time.Hour),
+		metricsStreamInterval: envDuration("METRICS_STREAM_INTERVAL", 2*time.Second),
+		oauthTokenTTL:         envDuration("OAUTH_TOKEN_TTL", 30*24*time.Hour),
+		handleChangeLimit:     envInt("HANDLE_CHANGE_LIMIT", 3),
+		handleChangeWindow:    envDuration("HANDLE_CHANGE_WINDOW", 30*24*time.Hour),
+		publicURL:             strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
+
+		requireDeviceConfirmation: os.Getenv("REQUIRE_DEVICE_CONFIRMATION") == "on",
+		deactivatedUserRetention:  envDuration("DEACTIVATED_USER_RETENTION", 30*24*time.Hour),
+		jobLease:                  envDuration("JOB_LEASE", 5*time.Minute),
+		jobRetention:              envDuration("JOB_RETENTION", 7*24*time.Hour),
+
+		feedRankers: map[string]ranking.Ranker{
+			"top": ranking.Top{HalfLife: envDuration("FEED_TOP_HALF_LIFE", 6*time.Hour), FollowBoost: 2},
+		},
+	}
+
+	if apiCfg.publicURL == "" {
+		apiCfg.publicURL = "http://localhost:8080"
+	}
+
+	// Access logs go to stdout as JSON, ACCESS_LOG=off turns them off (handy for tests)
+	if os.Getenv("ACCESS_LOG") != "off" {
+		apiCfg.logLevel.Set(parseLogLevel(os.Getenv("LOG_LEVEL")))
+		apiCfg.accessLog = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &apiCfg.logLevel}))
+	}
+
+	// Pick up the hit counter from the last run
+	loadCtx, cancelLoad := apiCfg.dbContext(context.Background())
+	if err := apiCfg.loadMetrics(loadCtx); err != nil {
+		log.Printf("Loading metrics failed, starting from 0: %v", err)
+	}
+	cancelLoad()
+
+	loadCtx, cancelLoad = apiCfg.dbContext(context.Background())
+	if err := apiCfg.bannedWords.reload(loadCtx, dbQueries); err != nil {
+		log.Printf("Loading banned words failed: %v", err)
+	}
+	cancelLoad()
+
+	loadCtx, cancelLoad = apiCfg.dbContext(context.Background())
+	if err := apiCfg.ipDenyList.reload(loadCtx, dbQueries); err != nil {
+		log.Printf("Loading IP deny list failed: %v", err)
+	}
+	cancelLoad()
+
+	// Without its tenants a multi-tenant server would put everyone in the default one, so this one is fatal
+	if os.Getenv("MULTI_TENANT") == "on" {
+		apiCfg.tenants = newTenantRegistry()
+
+		loadCtx, cancelLoad = apiCfg.dbContext(context.Background())
+		if err := apiCfg.tenants.reload(loadCtx, dbQueries); err != nil {
+			log.Fatalf("Loading tenants failed: %v", err)
+		}
+		cancelLoad()
+	}
+
+	// Cancelled on SIGINT/SIGTERM so we can shut down cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background work (jobs, flushers) gets its own context that's only cancelled once the server has drained,
+	// so requests still being served during shutdown get their side effects
+	background, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	flushInterval := envDuration("METRICS_FLUSH_INTERVAL", 30*time.Second)
+
+	if vapidPublic := os.Getenv("VAPID_PUBLIC_KEY"); vapidPublic != "" {
+		vapid, err := webpush.NewVAPID(vapidPublic, mustSecret("VAPID_PRIVATE_KEY"), os.Getenv("VAPID_SUBJECT"))
+		if err != nil {
+			log.Fatalf("Invalid VAPID keys: %v", err)
+		}
+		apiCfg.push = webpush.NewClient(nil, vapid, envDuration("PUSH_TTL", 24*time.Hour))
+	}
+
+	if smtpURL := mustSecret("SMTP_URL"); smtpURL != "" {
+		from := os.Getenv("MAIL_FROM")
+		if from == "" {
+			from = "chirpy@localhost"
+		}
+
+		apiCfg.mail, err = mail.NewClient(smtpURL, from)
+		if err != nil {
+			log.Fatalf("Invalid SMTP_URL: %v", err)
+		}
 	}
 
+	if provider := os.Getenv("CAPTCHA_PROVIDER"); provider != "" {
+		apiCfg.captcha, err = captcha.New(provider, mustSecret("CAPTCHA_SECRET"))
+		if err != nil {
+			log.Fatalf("Invalid CAPTCHA settings: %v", err)
+		}
+	}
+
+	apiCfg.search = postgresSearch{q: dbQueries}
+	var searchCluster *search.Elasticsearch
+	switch backend := os.Getenv("SEARCH_BACKEND"); backend {
+	case "", "postgres":
+	case "elasticsearch", "opensearch":
+		searchCluster, err = search.NewElasticsearch(os.Getenv("SEARCH_URL"), os.Getenv("SEARCH_INDEX"))
+		if err != nil {
+			log.Fatalf("Invalid search settings: %v", err)
+		}
+
+		searchCluster.Username = os.Getenv("SEARCH_USERNAME")
+		searchCluster.Password = mustSecret("SEARCH_PASSWORD")
+		searchCluster.APIKey = mustSecret("SEARCH_API_KEY")
+		apiCfg.search = searchCluster
+
+		// Not fatal, the cluster may just be slower to start than us. Index requests retry through the job queue.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := searchCluster.EnsureIndex(ctx); err != nil {
+			log.Printf("Creating the search index failed: %v", err)
+		}
+		cancel()
+	default:
+		log.Fatalf("Invalid search settings: unknown SEARCH_BACKEND %q", backend)
+	}
+
+	apiCfg.maxChirpLength.Store(defaultMaxChirpLength)
+	apiCfg.moderation.Store(apiCfg.newModerationPipeline())
+
+	// Signup/login/refresh get a tight limit to slow down credential stuffing
+	authLimits := newRateLimitPolicy("auth", "RATE_LIMIT_AUTH", 10)
+	writeLimits := newRateLimitPolicy("write", "RATE_LIMIT_WRITE", 60)
+	apiCfg.rateLimits = []*rateLimitPolicy{authLimits, writeLimits}
+
+	// Whatever admins changed at /admin/settings wins over the env
+	loadCtx, cancelLoad = apiCfg.dbContext(context.Background())
+	if err := apiCfg.loadSettings(loadCtx); err != nil {
+		log.Printf("Loading settings failed: %v", err)
+	}
+	cancelLoad()
+
+	bus := events.NewLocalBus()
+	apiCfg.events = bus
+
+	apiCfg.webhookDispatcher = webhooks.NewDispatcher(nil)
+	apiCfg.subscribeWebhooks(bus)
+
+	apiCfg.linkFetcher = linkpreview.NewFetcher(envDuration("LINK_PREVIEW_TIMEOUT", 5*time.Second))
+	apiCfg.subscribeLinkPreviews(bus)
+	apiCfg.subscribeNotifications(bus)
+	apiCfg.subscribeDashboard(bus)
+	if searchCluster != nil {
+		apiCfg.subscribeSearchIndex(bus)
+	}
+
+	// Background jobs (webhook delivery, cleanup), workers drain their current job when ctx is cancelled on shutdown
+	apiCfg.jobs = jobs.NewQueue(dbQueries, jobs.Options{
+		MaxAttempts:  5,
+		Backoff:      envDuration("JOB_BACKOFF", 5*time.Second),
+		PollInterval: envDuration("JOB_POLL_INTERVAL", time.Second),
+	})
+	apiCfg.registerJobHandlers(apiCfg.jobs)
+
+	// Jobs left running by a crashed instance, the cleanup job keeps catching them after this
+	requeueCtx, cancelRequeue := apiCfg.dbContext(context.Background())
+	if err := apiCfg.requeueStaleJobs(requeueCtx); err != nil {
+		log.Printf("Requeueing interrupted jobs failed: %v", err)
+	}
+	cancelRequeue()
+
+	apiCfg.jobs.Start(background, 4)
+	go apiCfg.scheduleJob(background, jobTokenCleanup, envDuration("TOKEN_CLEANUP_INTERVAL", time.Hour))
+
+	if apiCfg.deactivatedUserRetention > 0 {
+		go apiCfg.scheduleJob(background, jobUserPurge, time.Hour)
+	}
+	go apiCfg.runBannedWordReloader(background, envDuration("BANNED_WORDS_RELOAD_INTERVAL", time.Minute))
+	go apiCfg.runIPDenyListReloader(background, envDuration("IP_DENY_LIST_RELOAD_INTERVAL", time.Minute))
+	go apiCfg.runConfigReloader(background)
+
+	flusherDone := make(chan struct{})
+	go func() {
+		apiCfg.runMetricsFlusher(background, flushInterval)
+		close(flusherDone)
+	}()
+
 	// Serving static stuff
+	// Also covers /app/assets/, which lives under static/ with the page
 	mux.Handle(
 		"/app/",
 		http.StripPrefix(
 			"/app/",
-			apiCfg.middlewareMetricsInc(http.FileServer(http.Dir(".")))),
-	)
-
-	mux.Handle(
-		"/app/assets/",
-		http.StripPrefix(
-			"/app/assets/",
-			apiCfg.middlewareMetricsInc(http.FileServer(http.Dir("./assets"))),
-		),
+			apiCfg.middlewareMetricsInc(http.FileServer(frontendFS(platform)))),
 	)
 
 	// Custom response for Health endpoint
@@ -683,48 +1373,197 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	mux.HandleFunc(
+		"GET /api/readyz",
+		apiCfg.readyzHandler,
+	)
+
 	// Check increments endpoint
 	mux.HandleFunc(
 		"GET /admin/metrics",
 		apiCfg.metricsHandler,
 	)
 
+	// Live updates for the dashboard above
+	mux.HandleFunc(
+		"GET /admin/metrics/stream",
+		apiCfg.metricsStreamHandler,
+	)
+
+	// Prometheus scrape endpoint
+	mux.HandleFunc(
+		"GET /admin/metrics/prometheus",
+		apiCfg.prometheusHandler,
+	)
+
 	// Reset metrics
 	mux.HandleFunc(
 		"POST /admin/reset",
 		apiCfg.resetHandler,
 	)
 
-	// Create users
+	mux.Handle(
+		"POST /admin/search/reindex",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.searchReindexHandler),
+	)
+
+	mux.Handle(
+		"POST /admin/reload",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.reloadConfigHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/maintenance",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.getMaintenanceHandler),
+	)
+
+	mux.Handle(
+		"PUT /admin/maintenance",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.setMaintenanceHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/settings",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.getSettingsHandler),
+	)
+
+	mux.Handle(
+		"PUT /admin/settings",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.putSettingsHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/rate_limits",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.getRateLimitsHandler),
+	)
+
+	mux.Handle(
+		"PUT /admin/rate_limits",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.setRateLimitsHandler),
+	)
+
+	mux.HandleFunc(
+		"POST /admin/reset/metrics",
+		apiCfg.resetMetricsHandler,
+	)
+
+	mux.HandleFunc(
+		"POST /admin/reset/database",
+		apiCfg.resetDatabaseHandler,
+	)
+
 	mux.HandleFunc(
+		"GET /admin/faults",
+		apiCfg.getFaultsHandler,
+	)
+
+	mux.HandleFunc(
+		"PUT /admin/faults",
+		apiCfg.setFaultsHandler,
+	)
+
+	go runRateLimitPruner(background, apiCfg.rateLimits...)
+
+	// Create users
+	mux.Handle(
 		"POST /api/users",
-		apiCfg.createUserHandler,
+		apiCfg.rateLimited(authLimits, apiCfg.createUserHandler),
 	)
 
 	// Create chirps
-	mux.HandleFunc(
+	mux.Handle(
 		"POST /api/chirps",
-		apiCfg.createChirpHandler,
+		apiCfg.rateLimited(writeLimits, apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.requireAuth(apiCfg.createChirpHandler))),
 	)
 
-	mux.HandleFunc(
+	mux.Handle(
 		"GET /api/chirps",
-		apiCfg.getChirpsHandler,
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getChirpsHandler),
+	)
+
+	mux.Handle(
+		"GET /api/chirps/export",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.exportChirpsHandler),
+	)
+
+	mux.Handle(
+		"POST /api/chirps/import",
+		apiCfg.rateLimited(writeLimits, apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.importChirpsHandler)),
 	)
 
 	mux.HandleFunc(
+		"POST /api/validate_chirp",
+		apiCfg.validateChirpHandler,
+	)
+
+	mux.Handle(
 		"GET /api/chirps/{chirpID}",
-		apiCfg.getIndividualChirpHandler,
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getIndividualChirpHandler),
+	)
+
+	mux.Handle(
+		"GET /api/chirps/{chirpID}/conversation",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getConversationHandler),
 	)
 
 	mux.HandleFunc(
-		"POST /api/login",
-		apiCfg.loginUserHandler,
+		"GET /api/oembed",
+		apiCfg.oembedHandler,
 	)
 
 	mux.HandleFunc(
+		"GET /embed/chirps/{chirpID}",
+		apiCfg.embedChirpHandler,
+	)
+
+	mux.Handle(
+		"GET /api/search",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.searchHandler),
+	)
+
+	mux.Handle(
+		"GET /api/feed",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getFeedHandler),
+	)
+
+	mux.Handle(
+		"GET /api/leaderboard",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.leaderboardHandler),
+	)
+
+	mux.Handle(
+		"GET /api/stats/chirps",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.chirpStatsHandler),
+	)
+
+	mux.Handle(
+		"GET /api/chirps/{chirpID}/analytics",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.requireAuth(apiCfg.chirpAnalyticsHandler)),
+	)
+
+	mux.Handle(
+		"POST /api/login",
+		apiCfg.rateLimited(authLimits, apiCfg.loginUserHandler),
+	)
+
+	mux.Handle(
 		"POST /api/refresh",
-		apiCfg.refreshHandler,
+		apiCfg.rateLimited(authLimits, apiCfg.refreshHandler),
+	)
+
+	mux.HandleFunc(
+		"GET /api/csrf",
+		apiCfg.csrfTokenHandler,
+	)
+
+	mux.Handle(
+		"POST /api/session/refresh",
+		apiCfg.rateLimited(authLimits, apiCfg.sessionRefreshHandler),
+	)
+
+	mux.HandleFunc(
+		"POST /api/session/logout",
+		apiCfg.sessionLogoutHandler,
 	)
 
 	mux.HandleFunc(
@@ -732,27 +1571,435 @@ func main() {
 		apiCfg.revokeUpdateHandler,
 	)
 
-	mux.HandleFunc(
+	mux.Handle(
+		"PATCH /api/users",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.requireAuth(apiCfg.patchUserHandler)),
+	)
+
+	mux.Handle(
 		"PUT /api/users",
-		apiCfg.updateUserHandler,
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.requireAuth(apiCfg.updateUserHandler)),
+	)
+
+	mux.Handle(
+		"POST /api/users/deactivate",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.deactivateUserHandler),
+	)
+
+	mux.Handle(
+		"POST /api/users/reactivate",
+		apiCfg.rateLimited(authLimits, apiCfg.reactivateUserHandler),
+	)
+
+	mux.Handle(
+		"POST /api/users/email",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.requestEmailChangeHandler),
 	)
 
 	mux.HandleFunc(
+		"GET /api/users/email/confirm",
+		apiCfg.confirmEmailChangeHandler,
+	)
+
+	mux.Handle(
 		"DELETE /api/chirps/{chirp_id}",
-		apiCfg.deleteChirpFromID,
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.requireAuth(apiCfg.deleteChirpFromID)),
 	)
 
-	// Server settings for our http server
-	server := &http.Server{
-		Handler: mux,
-		Addr:    ":8080",
+	mux.Handle(
+		"GET /api/users/{userID}",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getUserProfileHandler),
+	)
+
+	mux.Handle(
+		"GET /api/users/by_handle/{handle}",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getUserByHandleHandler),
+	)
+
+	mux.Handle(
+		"PUT /api/users/handle",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.setHandleHandler),
+	)
+
+	mux.Handle(
+		"PUT /api/users/preferences",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.setPreferencesHandler),
+	)
+
+	mux.Handle(
+		"PUT /api/users/privacy",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.setPrivacyHandler),
+	)
+
+	mux.Handle(
+		"GET /api/me",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getMeHandler),
+	)
+
+	mux.Handle(
+		"GET /api/me/muted_words",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getMutedWordsHandler),
+	)
+
+	mux.Handle(
+		"POST /api/me/muted_words",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.addMutedWordHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/me/muted_words/{phrase}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.deleteMutedWordHandler),
+	)
+
+	mux.Handle(
+		"GET /api/me/notification_settings",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getNotificationSettingsHandler),
+	)
+
+	mux.Handle(
+		"PUT /api/me/notification_settings",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.putNotificationSettingsHandler),
+	)
+
+	mux.Handle(
+		"POST /api/chirps/{chirpID}/pin",
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.pinChirpHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/chirps/{chirpID}/pin",
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.unpinChirpHandler),
+	)
+
+	mux.Handle(
+		"POST /api/users/{userID}/follow",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.followHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/users/{userID}/follow",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.unfollowHandler),
+	)
+
+	mux.Handle(
+		"GET /api/follow_requests",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getFollowRequestsHandler),
+	)
+
+	mux.Handle(
+		"POST /api/follow_requests/{userID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.approveFollowRequestHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/follow_requests/{userID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.denyFollowRequestHandler),
+	)
+
+	// Covers /followers, /following and /chirps, see userListHandler for why they share a pattern
+	mux.Handle(
+		"GET /api/users/{userID}/{list}",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.userListHandler),
+	)
+
+	mux.Handle(
+		"POST /api/chirps/{chirpID}/like",
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.requireAuth(apiCfg.likeChirpHandler)),
+	)
+
+	mux.Handle(
+		"DELETE /api/chirps/{chirpID}/like",
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.requireAuth(apiCfg.unlikeChirpHandler)),
+	)
+
+	mux.Handle(
+		"POST /api/chirps/{chirpID}/rechirp",
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.requireAuth(apiCfg.rechirpHandler)),
+	)
+
+	mux.Handle(
+		"DELETE /api/chirps/{chirpID}/rechirp",
+		apiCfg.requireScope(auth.ScopeChirpsWrite, apiCfg.requireAuth(apiCfg.undoRechirpHandler)),
+	)
+
+	mux.Handle(
+		"POST /api/webhooks",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.createWebhookHandler),
+	)
+
+	mux.Handle(
+		"GET /api/webhooks",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getWebhooksHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/webhooks/{webhookID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.deleteWebhookHandler),
+	)
+
+	mux.Handle(
+		"POST /api/tokens",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.createPersonalAccessTokenHandler),
+	)
+
+	mux.Handle(
+		"GET /api/tokens",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getPersonalAccessTokensHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/tokens/{tokenID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.revokePersonalAccessTokenHandler),
+	)
+
+	mux.Handle(
+		"POST /api/oauth/clients",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.createOAuthClientHandler),
+	)
+
+	mux.Handle(
+		"GET /api/oauth/clients",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getOAuthClientsHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/oauth/clients/{clientID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.deleteOAuthClientHandler),
+	)
+
+	mux.HandleFunc(
+		"GET /oauth/authorize",
+		apiCfg.oauthAuthorizeHandler,
+	)
+
+	// Signs the user in, so it shares the login limit
+	mux.Handle(
+		"POST /oauth/authorize",
+		apiCfg.rateLimited(authLimits, apiCfg.oauthApproveHandler),
+	)
+
+	mux.Handle(
+		"POST /oauth/token",
+		apiCfg.rateLimited(authLimits, apiCfg.oauthTokenHandler),
+	)
+
+	mux.Handle(
+		"GET /api/devices",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.getDevicesHandler),
+	)
+
+	mux.HandleFunc(
+		"GET /api/devices/confirm",
+		apiCfg.confirmDeviceHandler,
+	)
+
+	mux.Handle(
+		"PUT /api/devices/{deviceID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.renameDeviceHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/devices/{deviceID}",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.deleteDeviceHandler),
+	)
+
+	mux.Handle(
+		"GET /api/notifications",
+		apiCfg.requireScope(auth.ScopeChirpsRead, apiCfg.getNotificationsHandler),
+	)
+
+	mux.Handle(
+		"POST /api/notifications/{notificationID}/read",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.readNotificationHandler),
+	)
+
+	mux.Handle(
+		"POST /api/notifications/read_all",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.readAllNotificationsHandler),
+	)
+
+	mux.HandleFunc(
+		"GET /api/push/vapid_public_key",
+		apiCfg.vapidPublicKeyHandler,
+	)
+
+	mux.Handle(
+		"POST /api/push/subscriptions",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.createPushSubscriptionHandler),
+	)
+
+	mux.Handle(
+		"DELETE /api/push/subscriptions",
+		apiCfg.requireScope(auth.ScopeUsersWrite, apiCfg.deletePushSubscriptionHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/banned_words",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.getBannedWordsHandler),
+	)
+
+	mux.Handle(
+		"POST /admin/banned_words",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.createBannedWordHandler),
+	)
+
+	mux.Handle(
+		"DELETE /admin/banned_words/{word}",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.deleteBannedWordHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/ip_deny_list",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.getIPDenyListHandler),
+	)
+
+	mux.Handle(
+		"POST /admin/ip_deny_list",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.createIPDenyListHandler),
+	)
+
+	mux.Handle(
+		"DELETE /admin/ip_deny_list/{cidr...}",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.deleteIPDenyListHandler),
+	)
+
+	mux.Handle(
+		"PUT /admin/users/{userID}/shadow_ban",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.shadowBanUserHandler),
+	)
+
+	mux.Handle(
+		"DELETE /admin/users/{userID}/shadow_ban",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.liftShadowBanHandler),
+	)
+
+	mux.Handle(
+		"POST /admin/users/{userID}/ban",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.banUserHandler),
+	)
+
+	mux.Handle(
+		"DELETE /admin/users/{userID}/ban",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.unbanUserHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/audit_log",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.getAuditLogHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/analytics",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.analyticsHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/db",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.dbStatsHandler),
+	)
+
+	mux.Handle(
+		"GET /admin/debug/runtime",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.debugRuntimeHandler),
+	)
+
+	// The index, and heap, goroutine, allocs etc. by name under it
+	mux.Handle(
+		"GET /admin/debug/pprof/",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.pprofHandler(pprof.Index)),
+	)
+
+	mux.Handle(
+		"GET /admin/debug/pprof/cmdline",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.pprofHandler(pprof.Cmdline)),
+	)
+
+	mux.Handle(
+		"GET /admin/debug/pprof/profile",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.pprofHandler(pprof.Profile)),
+	)
+
+	mux.Handle(
+		"GET /admin/debug/pprof/symbol",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.pprofHandler(pprof.Symbol)),
+	)
+
+	mux.Handle(
+		"POST /admin/debug/pprof/symbol",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.pprofHandler(pprof.Symbol)),
+	)
+
+	mux.Handle(
+		"GET /admin/debug/pprof/trace",
+		apiCfg.requireScope(auth.ScopeAdmin, apiCfg.pprofHandler(pprof.Trace)),
+	)
+
+	// Set to listen on a unix socket instead of :8080, for a reverse proxy on the same host
+	socketPath := os.Getenv("LISTEN_SOCKET")
+
+	// Comma separated CIDRs/IPs of reverse proxies whose X-Forwarded-For we believe
+	trustedProxies := strings.Split(os.Getenv("TRUSTED_PROXIES"), ",")
+	if socketPath != "" {
+		// Only processes the socket's permissions let in can connect, and that's the proxy
+		trustedProxies = append(trustedProxies, "unix")
 	}
 
-	// print on startup:
-	log.Printf("Starting server on port %s…", "8080")
-	err = server.ListenAndServe()
+	proxies, err := clientip.NewResolver(trustedProxies)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
+	handler := apiCfg.serverHandler(mux, proxies, envDuration("HANDLER_TIMEOUT", 10*time.Second))
+
+	// Server settings for our http server, the timeouts stop slow clients from pinning connections
+	server := &http.Server{
+		Handler:           handler,
+		Addr:              ":8080",
+		ReadHeaderTimeout: envDuration("READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       envDuration("READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      envDuration("WRITE_TIMEOUT", 15*time.Second),
+		IdleTimeout:       envDuration("IDLE_TIMEOUT", 60*time.Second),
+	}
+
+	ln, err := listen(server.Addr, socketPath, envFileMode("LISTEN_SOCKET_MODE", 0o660))
 	if err != nil {
-		os.Exit(0)
+		log.Fatalf("Listen failed: %v", err)
 	}
+
+	go func() {
+		// print on startup:
+		log.Printf("Starting server on %s…", ln.Addr())
+		err := server.Serve(ln)
+
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("Server error: %v", err)
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	// Put signal handling back to normal so a second Ctrl-C kills us straight away instead of waiting out the drain
+	stop()
+	log.Println("Shutting down…")
+
+	// Fail /api/readyz first and give load balancers time to notice before we stop accepting connections
+	apiCfg.draining.Store(true)
+	if delay := envDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second); delay > 0 {
+		log.Printf("Draining for %v", delay)
+		time.Sleep(delay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown error: %v", err)
+	}
+
+	stopBackground()
+
+	// Wait for the final metrics flush, event subscribers and any running jobs before exiting
+	<-flusherDone
+	bus.Wait()
+	apiCfg.jobs.Wait()
 }
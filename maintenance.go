@@ -0,0 +1,141 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// What clients get told while maintenance mode is on
+type maintenanceState struct {
+	Message    string
+	RetryAfter time.Duration
+	Since      time.Time
+}
+
+type maintenanceResponse struct {
+	Enabled           bool       `json:"enabled"`
+	Message           string     `json:"message,omitempty"`
+	RetryAfterSeconds int        `json:"retry_after_seconds,omitempty"`
+	Since             *time.Time `json:"since,omitempty"`
+}
+
+const defaultMaintenanceMessage = "Chirpy is down for maintenance, reads still work and writes will be back shortly"
+
+// Where tokens are issued. An admin whose access token runs out during maintenance needs one to turn it back off,
+// and the setting outlives restarts, so these stay open.
+var maintenanceSignInPaths = map[string]bool{
+	"/api/login":           true,
+	"/api/refresh":         true,
+	"/api/session/refresh": true,
+	"/oauth/token":         true,
+}
+
+// Rejects writes with a 503 while maintenance mode is on. Reads keep working, and /admin/ and signing in are let
+// through so maintenance mode can be turned back off.
+func (cfg *apiConfig) middlewareMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := cfg.maintenance.Load()
+		if state == nil || isReadMethod(r.Method) || strings.HasPrefix(r.URL.Path, "/admin/") ||
+			maintenanceSignInPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(state.RetryAfter.Seconds())))
+		respondWithJson(w, http.StatusServiceUnavailable, map[string]any{
+			"error":       state.Message,
+			"maintenance": true,
+		})
+	})
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// What an admin sends to turn maintenance mode on or off
+type maintenanceParams struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message" validate:"max=500"`
+	RetryAfterSeconds int    `json:"retry_after_seconds" validate:"min=0,max=86400"`
+}
+
+// The state params asks for, with the defaults filled in. nil when it turns maintenance mode off.
+func newMaintenanceState(params maintenanceParams) *maintenanceState {
+	if !params.Enabled {
+		return nil
+	}
+
+	state := &maintenanceState{
+		Message:    params.Message,
+		RetryAfter: time.Duration(params.RetryAfterSeconds) * time.Second,
+		Since:      time.Now(),
+	}
+
+	if state.Message == "" {
+		state.Message = defaultMaintenanceMessage
+	}
+	if state.RetryAfter <= 0 {
+		state.RetryAfter = 5 * time.Minute
+	}
+
+	return state
+}
+
+func newMaintenanceResponse(state *maintenanceState) maintenanceResponse {
+	if state == nil {
+		return maintenanceResponse{Enabled: false}
+	}
+
+	return maintenanceResponse{
+		Enabled:           true,
+		Message:           state.Message,
+		RetryAfterSeconds: int(state.RetryAfter.Seconds()),
+		Since:             &state.Since,
+	}
+}
+
+func (cfg *apiConfig) getMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, newMaintenanceResponse(cfg.maintenance.Load()))
+}
+
+// Turns maintenance mode on or off. It's saved as a setting, so other instances follow after a reload.
+func (cfg *apiConfig) setMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireGlobalAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var params maintenanceParams
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	state := newMaintenanceState(params)
+	if err := cfg.saveSettings(ctx, adminID, map[string]any{settingMaintenance: newMaintenanceResponse(state)}); err != nil {
+		log.Printf("Saving maintenance mode failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if state == nil {
+		log.Printf("Maintenance mode turned off by %s", adminID)
+	} else {
+		log.Printf("Maintenance mode turned on by %s", adminID)
+	}
+
+	respondWithJson(w, http.StatusOK, newMaintenanceResponse(cfg.maintenance.Load()))
+}
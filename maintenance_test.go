@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenanceLetsSignInThrough(t *testing.T) {
+	cfg := &apiConfig{}
+	cfg.maintenance.Store(newMaintenanceState(maintenanceParams{Enabled: true}))
+
+	h := cfg.middlewareMaintenance(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, path string
+		status       int
+	}{
+		{http.MethodGet, "/api/chirps", http.StatusNoContent},
+		{http.MethodPost, "/api/chirps", http.StatusServiceUnavailable},
+		{http.MethodPost, "/api/users", http.StatusServiceUnavailable},
+		{http.MethodPut, "/admin/maintenance", http.StatusNoContent},
+		{http.MethodPost, "/api/login", http.StatusNoContent},
+		{http.MethodPost, "/api/refresh", http.StatusNoContent},
+		{http.MethodPost, "/api/session/refresh", http.StatusNoContent},
+		{http.MethodPost, "/oauth/token", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.status, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// Name of the row in the metrics table that holds the fileserver hit counter
+const fileserverHitsMetric = "fileserver_hits"
+
+// Loads the persisted hit counter into memory, a missing row just means we start from 0
+func (cfg *apiConfig) loadMetrics(ctx context.Context) error {
+	hits, err := cfg.databaseQueries.GetMetric(ctx, fileserverHitsMetric)
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cfg.fileserverHits.Store(hits)
+	return nil
+}
+
+// Adds the hits counted since the last flush to the database and starts counting afresh. If the write fails
+// they're put back for the next flush.
+func (cfg *apiConfig) flushMetrics(ctx context.Context) error {
+	hits := cfg.unflushedHits.Swap(0)
+	if hits == 0 {
+		return nil
+	}
+
+	err := cfg.databaseQueries.UpsertMetric(ctx, database.UpsertMetricParams{
+		Name:  fileserverHitsMetric,
+		Value: hits,
+	})
+	if err != nil {
+		cfg.unflushedHits.Add(hits)
+	}
+	return err
+}
+
+// Writes out everything counted in memory: the hit counter, chirp views and user activity. A failure is logged
+// and doesn't stop the rest.
+func (cfg *apiConfig) flushCounters(ctx context.Context) {
+	if err := cfg.flushMetrics(ctx); err != nil {
+		log.Printf("Flushing metrics failed: %v", err)
+	}
+
+	if err := cfg.chirpViews.flush(ctx, cfg.databaseQueries); err != nil {
+		log.Printf("Flushing chirp views failed: %v", err)
+	}
+
+	if err := cfg.activity.flush(ctx, cfg.databaseQueries); err != nil {
+		log.Printf("Flushing user activity failed: %v", err)
+	}
+}
+
+// Flushes the counters every interval until ctx is cancelled, then does one last flush so nothing is lost on shutdown
+func (cfg *apiConfig) runMetricsFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			flushCtx, cancel := cfg.dbContext(ctx)
+			cfg.flushCounters(flushCtx)
+			cancel()
+		case <-ctx.Done():
+			// ctx is already cancelled, so give the final flush its own deadline
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			cfg.flushCounters(flushCtx)
+			cancel()
+			return
+		}
+	}
+}
+
+type routeMetricsResponse struct {
+	Method   string  `json:"method"`
+	Route    string  `json:"route"`
+	Requests uint64  `json:"requests"`
+	Errors   uint64  `json:"errors"`
+	AvgMs    float64 `json:"avg_ms"`
+}
+
+type metricsResponse struct {
+	FileserverHits int64                  `json:"fileserver_hits"`
+	StartedAt      time.Time              `json:"started_at"`
+	UptimeSeconds  int64                  `json:"uptime_seconds"`
+	Routes         []routeMetricsResponse `json:"routes"`
+}
+
+// JSON version of the admin metrics page, for scripts that send Accept: application/json
+func (cfg *apiConfig) metricsJSONHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot := cfg.requestMetrics.Snapshot()
+
+	resp := metricsResponse{
+		FileserverHits: cfg.fileserverHits.Load(),
+		StartedAt:      cfg.startedAt,
+		UptimeSeconds:  int64(time.Since(cfg.startedAt).Seconds()),
+		Routes:         make([]routeMetricsResponse, 0, len(snapshot)),
+	}
+
+	for _, s := range snapshot {
+		avgMs := 0.0
+		if s.Count > 0 {
+			avgMs = s.Sum / float64(s.Count) * 1000
+		}
+
+		resp.Routes = append(resp.Routes, routeMetricsResponse{
+			Method:   s.Method,
+			Route:    s.Route,
+			Requests: s.Count,
+			Errors:   s.Errors,
+			AvgMs:    avgMs,
+		})
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// Each flush adds only what was counted since the last one, so instances sharing the table don't undo each other
+func TestFlushMetricsSendsDeltas(t *testing.T) {
+	fake := newFakeDB(nil)
+	cfg := newFakeConfig(t, fake)
+
+	cfg.fileserverHits.Store(10)
+	cfg.unflushedHits.Store(3)
+
+	if err := cfg.flushMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.wasSaved("UpsertMetric") {
+		t.Error("expected the hits to be written")
+	}
+	if n := cfg.unflushedHits.Load(); n != 0 {
+		t.Errorf("expected the unflushed count to be reset, got %d", n)
+	}
+	if n := cfg.fileserverHits.Load(); n != 10 {
+		t.Errorf("expected the running total to stay at 10, got %d", n)
+	}
+
+	fake.saved = nil
+	if err := cfg.flushMetrics(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.wasSaved("UpsertMetric") {
+		t.Error("expected nothing to be written without new hits")
+	}
+}
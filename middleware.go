@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/clientip"
+	"github.com/itsmandrew/server-go/internal/i18n"
+)
+
+// Keys for values the middleware stores on the request context
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	clientIPKey
+	tenantKey
+	routePatternKey
+)
+
+// Wraps a handler, like the middleware* functions. The ones that take settings go through a closure.
+type middleware func(http.Handler) http.Handler
+
+// Wraps h in mws with the first listed outermost, so chain(h, a, b) is a(b(h)) and requests pass through a first
+func chain(h http.Handler, mws ...middleware) http.Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		h = mws[i](h)
+	}
+	return h
+}
+
+// Everything a request goes through, from the request ID down to the mux
+func (cfg *apiConfig) serverHandler(mux *http.ServeMux, proxies *clientip.Resolver, timeout time.Duration) http.Handler {
+	// Every handler gets a deadline on its context so a hung query can't hold the request forever
+	handler := middlewareTimeout(timeout, cfg.middlewareMaintenance(recordRoutePattern(withRoutingErrors(mux))))
+
+	// Development servers can be told to misbehave, after the tenant so rules match the path without its prefix
+	if cfg.platform == "dev" {
+		handler = cfg.middlewareFaults(handler)
+	}
+
+	// Resolved (and any /t/{slug} prefix stripped) before anything looks at the path
+	handler = middlewareTenant(cfg.tenants, handler)
+
+	securityHeaders := securityHeadersFromEnv(cfg.platform)
+	return chain(handler,
+		middlewareRequestID,
+		func(next http.Handler) http.Handler { return middlewareSecurityHeaders(securityHeaders, next) },
+		middlewareLocale,
+		func(next http.Handler) http.Handler { return middlewareClientIP(proxies, next) },
+		cfg.middlewareAccessLog,
+		cfg.middlewareMetrics,
+		cfg.middlewareIPDenyList,
+		// Innermost, so a handler's panic still comes out as a 500 that's logged and counted
+		middlewareRecover,
+	)
+}
+
+// Returns the request ID set by middlewareRequestID, or "" outside of a request
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Tags every request with an ID (reusing the client's X-Request-ID if it sent one) and echoes it back in the response
+func middlewareRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Picks the language for error messages from Accept-Language. It's recorded as the response's Content-Language
+// up front, which is where respondWithError reads it back from since it only has the ResponseWriter.
+func middlewareLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", i18n.Match(r.Header.Get("Accept-Language")))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Turns a panic in any handler into a logged stack trace and a 500 with the usual error body
+func middlewareRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			// Let net/http deal with deliberate connection aborts
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			log.Printf("panic serving %s %s (request_id=%s): %v\n%s",
+				r.Method, r.URL.Path, requestIDFromContext(r.Context()), err, debug.Stack())
+			respondWithError(w, http.StatusInternalServerError, "Internal server error")
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Wraps a ResponseWriter so middleware can see the status code and how many bytes were written
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Lets http.ResponseController reach the real writer (for Flush etc.)
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// Route label for a ServeMux pattern, so /api/chirps/{chirpID} is one series instead of one per ID
+func routeLabel(pattern string) string {
+	if pattern == "" {
+		return "unmatched"
+	}
+
+	// Patterns look like "GET /api/chirps", the method is tracked separately
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		return path
+	}
+
+	return pattern
+}
+
+// Hands the pattern the mux matched back to middlewareMetrics. It has to sit right around the mux: the middleware
+// in between pass the mux copies of the request (WithContext, Clone), and the mux only sets Pattern on its copy.
+func recordRoutePattern(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Deferred so a handler that panics is still counted under its route
+		defer func() {
+			if pattern, ok := r.Context().Value(routePatternKey).(*string); ok {
+				*pattern = r.Pattern
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Records count, errors and latency for every request, keyed by route and method
+func (cfg *apiConfig) middlewareMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+
+		// Filled in by recordRoutePattern once the mux has routed the request
+		pattern := new(string)
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routePatternKey, pattern)))
+
+		cfg.requestMetrics.Observe(r.Method, routeLabel(*pattern), rec.status, time.Since(start))
+	})
+}
+
+// Host part of RemoteAddr, falls back to the raw value if it has no port
+// Resolves the real client IP once per request (honouring X-Forwarded-For from trusted proxies) so everything
+// downstream that calls remoteIP agrees on it
+func middlewareClientIP(resolver *clientip.Resolver, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey, resolver.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Client IP set by middlewareClientIP, falling back to the TCP peer
+func remoteIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Writes one structured log line per request, skipped entirely when the access logger is disabled
+func (cfg *apiConfig) middlewareAccessLog(next http.Handler) http.Handler {
+	if cfg.accessLog == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		cfg.accessLog.Info("request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("user_agent", r.UserAgent()),
+			slog.String("remote_ip", remoteIP(r)),
+			slog.String("request_id", requestIDFromContext(r.Context())),
+		)
+	})
+}
+
+// Headers telling browsers to lock down how they treat our responses, empty fields aren't sent
+type securityHeaders struct {
+	// Strict-Transport-Security max-age, 0 leaves it out
+	hstsMaxAge time.Duration
+	// CSP frame-ancestors sources, 'none' and 'self' also get the matching X-Frame-Options for older browsers
+	frameAncestors string
+	referrerPolicy string
+}
+
+// Reads SECURITY_HEADERS, HSTS_MAX_AGE, FRAME_ANCESTORS and REFERRER_POLICY. HSTS is left off in dev, which is
+// served over plain HTTP on localhost.
+func securityHeadersFromEnv(platform string) *securityHeaders {
+	if os.Getenv("SECURITY_HEADERS") == "off" {
+		return nil
+	}
+
+	h := &securityHeaders{
+		hstsMaxAge:     envDuration("HSTS_MAX_AGE", 365*24*time.Hour),
+		frameAncestors: "'none'",
+		referrerPolicy: "strict-origin-when-cross-origin",
+	}
+
+	if platform == "dev" {
+		h.hstsMaxAge = 0
+	}
+
+	if v := os.Getenv("FRAME_ANCESTORS"); v != "" {
+		h.frameAncestors = v
+	}
+
+	if v := os.Getenv("REFERRER_POLICY"); v != "" {
+		h.referrerPolicy = v
+	}
+
+	return h
+}
+
+// Sets the security headers on every response, the static frontend and admin pages included. They go on before
+// the handler runs so one that needs something different, like an embeddable page, can still overwrite them.
+func middlewareSecurityHeaders(h *securityHeaders, next http.Handler) http.Handler {
+	if h == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+
+		if h.hstsMaxAge > 0 {
+			header.Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(h.hstsMaxAge.Seconds())))
+		}
+
+		if h.frameAncestors != "" {
+			header.Set("Content-Security-Policy", "frame-ancestors "+h.frameAncestors)
+
+			switch h.frameAncestors {
+			case "'none'":
+				header.Set("X-Frame-Options", "DENY")
+			case "'self'":
+				header.Set("X-Frame-Options", "SAMEORIGIN")
+			}
+		}
+
+		if h.referrerPolicy != "" {
+			header.Set("Referrer-Policy", h.referrerPolicy)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Puts a deadline on the request context, anything that honours ctx (DB calls, outbound requests) gives up once it passes.
+// Server-sent event streams are left alone, they're meant to stay open, and so is pprof, which profiles for as long as
+// it's asked to. Chirp exports stream for as long as the export takes and time each page query themselves.
+func middlewareTimeout(timeout time.Duration, next http.Handler) http.Handler {
+	if timeout <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" || strings.HasPrefix(r.URL.Path, "/admin/debug/pprof/") ||
+			r.URL.Path == "/api/chirps/export" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
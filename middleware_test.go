@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/clientip"
+	"github.com/itsmandrew/server-go/internal/metrics"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { order = append(order, "handler") }),
+		mark("a"), mark("b"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if len(order) != 3 || order[0] != "a" || order[1] != "b" || order[2] != "handler" {
+		t.Errorf("expected a, b, handler, got %v", order)
+	}
+}
+
+// The metrics middleware sits outside the ones that copy the request, it still has to see the route the mux matched
+func TestMetricsRecordRoutePattern(t *testing.T) {
+	tenants := newTenantRegistry()
+	tenants.bySlug["acme"] = uuid.New()
+
+	cfg := &apiConfig{
+		requestMetrics: metrics.NewRegistry(nil),
+		tenants:        tenants,
+		ipDenyList:     newIPDenyList(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /api/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	proxies, err := clientip.NewResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := cfg.serverHandler(mux, proxies, time.Second)
+	for _, path := range []string{"/api/chirps/1", "/t/acme/api/chirps/2", "/api/panic", "/api/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	counts := map[string]uint64{}
+	for _, s := range cfg.requestMetrics.Snapshot() {
+		counts[s.Route] += s.Count
+	}
+
+	if counts["/api/chirps/{chirpID}"] != 2 || counts["/api/panic"] != 1 || counts["unmatched"] != 1 {
+		t.Errorf("unexpected route counts %v", counts)
+	}
+}
+
+func TestTimeoutSkipsExport(t *testing.T) {
+	h := middlewareTimeout(time.Second, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			w.Header().Set("X-Deadline", "set")
+		}
+	}))
+
+	for path, want := range map[string]string{"/api/chirps": "set", "/api/chirps/export": ""} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if got := rec.Header().Get("X-Deadline"); got != want {
+			t.Errorf("%s: expected deadline %q, got %q", path, want, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/moderation"
+)
+
+// Max chirp length in characters, unless an admin changes the max_chirp_length setting
+const defaultMaxChirpLength = 140
+
+// Builds the chain of checks every chirp goes through, the external classifier is only used when MODERATION_CLASSIFIER_URL is set
+func (cfg *apiConfig) newModerationPipeline() *moderation.Pipeline {
+	history := chirpHistory{q: cfg.databaseQueries}
+
+	duplicateVerdict := moderation.Reject
+	if os.Getenv("DUPLICATE_CHIRP_ACTION") == "flag" {
+		duplicateVerdict = moderation.Flag
+	}
+
+	// Spam checks run before profanity so they see the body as the user wrote it
+	checks := []moderation.Check{
+		moderation.LengthCheck{Max: int(cfg.maxChirpLength.Load())},
+		moderation.DuplicateCheck{
+			History: history,
+			Window:  envDuration("DUPLICATE_CHIRP_WINDOW", 10*time.Minute),
+			Verdict: duplicateVerdict,
+		},
+		moderation.BurstCheck{
+			History: history,
+			Window:  envDuration("CHIRP_BURST_WINDOW", time.Minute),
+			Max:     int64(envInt("CHIRP_BURST_MAX", 10)),
+		},
+		moderation.ProfanityCheck{Filter: cfg.bannedWords.snapshot},
+	}
+
+	if classifierURL := os.Getenv("MODERATION_CLASSIFIER_URL"); classifierURL != "" {
+		log.Printf("Using moderation classifier at %s", classifierURL)
+		checks = append(checks, moderation.ClassifierCheck{
+			URL:    classifierURL,
+			Client: &http.Client{Timeout: envDuration("MODERATION_CLASSIFIER_TIMEOUT", 2*time.Second)},
+		})
+	}
+
+	return moderation.NewPipeline(checks...)
+}
+
+type validateChirpResponse struct {
+	Valid       bool                `json:"valid"`
+	Verdict     moderation.Verdict  `json:"verdict"`
+	CleanedBody string              `json:"cleaned_body"`
+	Violations  []moderation.Result `json:"violations"`
+}
+
+// Runs a chirp through the same pipeline as createChirpHandler without saving it, so clients can warn before posting.
+// Auth is required since the duplicate/burst checks are per user.
+func (cfg *apiConfig) validateChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var params struct {
+		Body string `json:"body" validate:"required"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	decision := cfg.moderation.Load().Run(ctx, moderation.Content{UserID: userID, Body: params.Body})
+
+	violations := decision.Violations
+	if violations == nil {
+		violations = []moderation.Result{}
+	}
+
+	respondWithJson(w, http.StatusOK, validateChirpResponse{
+		Valid:       decision.Verdict != moderation.Reject,
+		Verdict:     decision.Verdict,
+		CleanedBody: decision.Body,
+		Violations:  violations,
+	})
+}
+
+// Adapts the chirp queries to moderation.ChirpHistory
+type chirpHistory struct {
+	q *database.Queries
+}
+
+func (h chirpHistory) CountRecentDuplicates(ctx context.Context, userID uuid.UUID, contentHash string, since time.Time) (int64, error) {
+	return h.q.CountRecentDuplicateChirps(ctx, database.CountRecentDuplicateChirpsParams{
+		UserID:      userID,
+		ContentHash: contentHash,
+		CreatedAt:   since,
+	})
+}
+
+func (h chirpHistory) CountRecent(ctx context.Context, userID uuid.UUID, since time.Time) (int64, error) {
+	return h.q.CountRecentChirpsByUser(ctx, database.CountRecentChirpsByUserParams{
+		UserID:    userID,
+		CreatedAt: since,
+	})
+}
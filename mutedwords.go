@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Most muted words a user can have, every timeline request checks each chirp against all of them
+const mutedWordsLimit = 200
+
+type mutedWordResponse struct {
+	Phrase    string    `json:"phrase"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Lowercases and collapses runs of whitespace, so "Game  Of Thrones" and "game of thrones" are the same mute
+func normalizeMutedPhrase(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Reports whether a chirp contains one of viewerID's muted words, matching anywhere in the text regardless of case
+// the same way the notification queries do. Their own chirps are never muted, and nothing is when logged out.
+func (cfg *apiConfig) mutedChirps(ctx context.Context, viewerID uuid.UUID) (func(database.Chirp) bool, error) {
+	if viewerID == uuid.Nil {
+		return func(database.Chirp) bool { return false }, nil
+	}
+
+	rows, err := cfg.databaseQueries.GetMutedWords(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(chirp database.Chirp) bool {
+		if len(rows) == 0 || chirp.UserID == viewerID {
+			return false
+		}
+
+		body := strings.ToLower(chirp.Body)
+		for _, row := range rows {
+			if strings.Contains(body, row.Phrase) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func (cfg *apiConfig) getMutedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	rows, err := cfg.databaseQueries.GetMutedWords(ctx, userID)
+	if err != nil {
+		log.Printf("GetMutedWords failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := make([]mutedWordResponse, 0, len(rows))
+	for _, row := range rows {
+		resp = append(resp, mutedWordResponse{Phrase: row.Phrase, CreatedAt: row.CreatedAt})
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// POST /api/me/muted_words, {"phrase": "spoilers"}. Muting something already muted is a no-op.
+func (cfg *apiConfig) addMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Phrase string `json:"phrase" validate:"required,max=100"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	phrase := normalizeMutedPhrase(params.Phrase)
+	if phrase == "" {
+		respondWithFieldErrors(w, validate.Errors{"phrase": "is required"})
+		return
+	}
+
+	count, err := cfg.databaseQueries.CountMutedWords(ctx, userID)
+	if err != nil {
+		log.Printf("CountMutedWords failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if count >= mutedWordsLimit {
+		respondWithError(w, http.StatusConflict, "You've reached the limit of 200 muted words")
+		return
+	}
+
+	row, err := cfg.databaseQueries.AddMutedWord(ctx, database.AddMutedWordParams{
+		UserID: userID,
+		Phrase: phrase,
+	})
+
+	if err != nil {
+		log.Printf("AddMutedWord failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusCreated, mutedWordResponse{Phrase: row.Phrase, CreatedAt: row.CreatedAt})
+}
+
+// DELETE /api/me/muted_words/{phrase}, the phrase is matched the same way it was stored so case doesn't matter
+func (cfg *apiConfig) deleteMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	deleted, err := cfg.databaseQueries.DeleteMutedWord(ctx, database.DeleteMutedWordParams{
+		UserID: userID,
+		Phrase: normalizeMutedPhrase(r.PathValue("phrase")),
+	})
+
+	if err != nil {
+		log.Printf("DeleteMutedWord failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Muted word not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+)
+
+// What caused a notification
+const (
+	notificationLike     = "like"
+	notificationReply    = "reply"
+	notificationMention  = "mention"
+	notificationFollower = "follow"
+	// Someone wants to follow a protected account, answered at /api/follow_requests
+	notificationFollowRequest = "follow_request"
+)
+
+type notificationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Kind      string     `json:"kind"`
+	ActorID   *uuid.UUID `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+func newNotificationResponse(n database.Notification) notificationResponse {
+	resp := notificationResponse{
+		ID:        n.ID,
+		CreatedAt: n.CreatedAt,
+		Kind:      n.Kind,
+		Read:      n.ReadAt.Valid,
+	}
+
+	if n.ActorID.Valid {
+		resp.ActorID = &n.ActorID.UUID
+	}
+	if n.ChirpID.Valid {
+		resp.ChirpID = &n.ChirpID.UUID
+	}
+	if n.ReadAt.Valid {
+		resp.ReadAt = &n.ReadAt.Time
+	}
+
+	return resp
+}
+
+// Stores a notification for recipient, called by event subscribers. Acting on your own stuff never notifies you,
+// and kinds the recipient turned off in their notification settings are dropped.
+func (cfg *apiConfig) notify(ctx context.Context, recipient, actor uuid.UUID, kind string, chirpID uuid.NullUUID) {
+	if recipient == actor {
+		return
+	}
+
+	ctx, cancel := cfg.dbContext(ctx)
+	defer cancel()
+
+	settings, err := cfg.notificationSettings(ctx, recipient)
+	if err != nil {
+		log.Printf("GetNotificationSettings failed: %v", err)
+	} else if slices.Contains(settings.DisabledKinds, kind) {
+		return
+	}
+
+	// A reply the recipient would have muted in their timeline shouldn't reach them this way either
+	if chirpID.Valid {
+		muted, err := cfg.databaseQueries.IsChirpMuted(ctx, database.IsChirpMutedParams{
+			UserID:  recipient,
+			ChirpID: chirpID.UUID,
+		})
+
+		if err != nil {
+			log.Printf("IsChirpMuted failed: %v", err)
+		} else if muted {
+			return
+		}
+	}
+
+	notification, err := cfg.databaseQueries.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  recipient,
+		ActorID: uuid.NullUUID{UUID: actor, Valid: actor != uuid.Nil},
+		Kind:    kind,
+		ChirpID: chirpID,
+	})
+
+	if err != nil {
+		log.Printf("CreateNotification failed: %v", err)
+		return
+	}
+
+	if pushNotificationKinds[kind] {
+		cfg.queuePush(ctx, recipient, newNotificationResponse(notification))
+	}
+
+	if settings.Email {
+		cfg.emailNotification(ctx, recipient, notification)
+	}
+}
+
+// Turns bus events into notifications
+func (cfg *apiConfig) subscribeNotifications(bus events.Bus) {
+	bus.Subscribe(events.TypeUserFollowed, func(ctx context.Context, event events.Event) {
+		e := event.(events.UserFollowed)
+		cfg.notify(ctx, e.FolloweeID, e.FollowerID, notificationFollower, uuid.NullUUID{})
+	})
+
+	bus.Subscribe(events.TypeFollowRequested, func(ctx context.Context, event events.Event) {
+		e := event.(events.FollowRequested)
+		cfg.notify(ctx, e.TargetID, e.RequesterID, notificationFollowRequest, uuid.NullUUID{})
+	})
+
+	bus.Subscribe(events.TypeChirpLiked, func(ctx context.Context, event events.Event) {
+		e := event.(events.ChirpLiked)
+		cfg.notify(ctx, e.AuthorID, e.UserID, notificationLike, uuid.NullUUID{UUID: e.ChirpID, Valid: true})
+	})
+
+	bus.Subscribe(events.TypeChirpCreated, func(ctx context.Context, event events.Event) {
+		chirp := event.(events.ChirpCreated).Chirp
+		if !chirp.ReplyToID.Valid {
+			return
+		}
+
+		dbCtx, cancel := cfg.dbContext(ctx)
+		parent, err := cfg.databaseQueries.GetIndividualChirp(dbCtx, database.GetIndividualChirpParams{
+			ID:       chirp.ReplyToID.UUID,
+			TenantID: chirp.TenantID,
+		})
+		cancel()
+
+		if err != nil {
+			log.Printf("Looking up replied-to chirp failed: %v", err)
+			return
+		}
+
+		// No point telling them about a reply they aren't allowed to read
+		if parent.UserID != chirp.UserID {
+			dbCtx, cancel := cfg.dbContext(ctx)
+			_, err := cfg.databaseQueries.GetVisibleChirp(dbCtx, database.GetVisibleChirpParams{
+				ID:       chirp.ID,
+				TenantID: chirp.TenantID,
+				ViewerID: parent.UserID,
+			})
+			cancel()
+			err = database.Wrap(err)
+
+			if err != nil {
+				if !errors.Is(err, database.ErrNotFound) {
+					log.Printf("Checking the reply's visibility failed: %v", err)
+				}
+				return
+			}
+		}
+
+		cfg.notify(ctx, parent.UserID, chirp.UserID, notificationReply, uuid.NullUUID{UUID: chirp.ID, Valid: true})
+	})
+}
+
+func (cfg *apiConfig) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type validResponse struct {
+		UnreadCount   int64                  `json:"unread_count"`
+		Notifications []notificationResponse `json:"notifications"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated notifications request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	notifications, err := cfg.databaseQueries.GetNotificationsForUser(ctx, database.GetNotificationsForUserParams{
+		UserID: userID,
+		Limit:  int32(queryLimit(r, "limit", 50, 100)),
+	})
+
+	if err != nil {
+		log.Printf("GetNotificationsForUser failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	unread, err := cfg.databaseQueries.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		log.Printf("CountUnreadNotifications failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := validResponse{
+		UnreadCount:   unread,
+		Notifications: make([]notificationResponse, 0, len(notifications)),
+	}
+	for _, n := range notifications {
+		resp.Notifications = append(resp.Notifications, newNotificationResponse(n))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) readNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated notifications request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid notification ID")
+		return
+	}
+
+	updated, err := cfg.databaseQueries.MarkNotificationRead(ctx, database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		log.Printf("MarkNotificationRead failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if updated == 0 {
+		respondWithError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) readAllNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type validResponse struct {
+		Updated int64 `json:"updated"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated notifications request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	updated, err := cfg.databaseQueries.MarkAllNotificationsRead(ctx, userID)
+	if err != nil {
+		log.Printf("MarkAllNotificationsRead failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, validResponse{Updated: updated})
+}
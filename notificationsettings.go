@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/mail"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Every kind of notification a user can turn off, in the order settings list them
+var notificationKinds = []string{
+	notificationLike,
+	notificationReply,
+	notificationMention,
+	notificationFollower,
+	notificationFollowRequest,
+}
+
+// Subject lines for notification emails
+var notificationEmailSubjects = map[string]string{
+	notificationLike:          "Someone liked your chirp",
+	notificationReply:         "Someone replied to your chirp",
+	notificationMention:       "Someone mentioned you",
+	notificationFollower:      "You have a new follower",
+	notificationFollowRequest: "Someone asked to follow you",
+}
+
+type notificationSettingsResponse struct {
+	// Whether each kind of notification is on
+	Kinds map[string]bool `json:"kinds"`
+	Email bool            `json:"email"`
+}
+
+// userID's notification settings. Users who never changed them have a default row: everything on, no email.
+func (cfg *apiConfig) notificationSettings(ctx context.Context, userID uuid.UUID) (database.NotificationSetting, error) {
+	settings, err := cfg.databaseQueries.GetNotificationSettings(ctx, userID)
+	if err = database.Wrap(err); errors.Is(err, database.ErrNotFound) {
+		return database.NotificationSetting{UserID: userID}, nil
+	}
+
+	return settings, err
+}
+
+func newNotificationSettingsResponse(settings database.NotificationSetting) notificationSettingsResponse {
+	resp := notificationSettingsResponse{
+		Kinds: make(map[string]bool, len(notificationKinds)),
+		Email: settings.Email,
+	}
+
+	for _, kind := range notificationKinds {
+		resp.Kinds[kind] = !slices.Contains(settings.DisabledKinds, kind)
+	}
+
+	return resp
+}
+
+// Emails recipient about a notification they've just been sent. A failure is logged, the notification itself
+// has already been stored.
+func (cfg *apiConfig) emailNotification(ctx context.Context, recipient uuid.UUID, notification database.Notification) {
+	user, err := cfg.databaseQueries.GetUserByIDNoPassword(ctx, recipient)
+	if err != nil {
+		log.Printf("GetUserByIDNoPassword failed: %v", err)
+		return
+	}
+
+	link := cfg.publicURL + "/api/notifications"
+	if notification.ChirpID.Valid {
+		link = fmt.Sprintf("%s/api/chirps/%s", cfg.publicURL, notification.ChirpID.UUID)
+	}
+
+	err = cfg.sendMail(ctx, mail.Message{
+		To:      user.Email,
+		Subject: notificationEmailSubjects[notification.Kind],
+		Body: fmt.Sprintf("%s on Chirpy:\n\n  %s\n\n"+
+			"You're getting this because notification emails are on, turn them off at "+
+			"/api/me/notification_settings.\n",
+			notificationEmailSubjects[notification.Kind], link),
+	})
+
+	if err != nil {
+		log.Printf("Queueing notification email failed: %v", err)
+	}
+}
+
+// GET /api/me/notification_settings
+func (cfg *apiConfig) getNotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	settings, err := cfg.notificationSettings(ctx, userID)
+	if err != nil {
+		log.Printf("GetNotificationSettings failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, newNotificationSettingsResponse(settings))
+}
+
+// PUT /api/me/notification_settings, {"kinds": {"like": false}, "email": true}. Kinds left out and a missing
+// "email" keep their current setting.
+func (cfg *apiConfig) putNotificationSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var params struct {
+		Kinds map[string]bool `json:"kinds"`
+		Email *bool           `json:"email"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	fieldErrs := validate.Errors{}
+	for kind := range params.Kinds {
+		if !slices.Contains(notificationKinds, kind) {
+			fieldErrs["kinds."+kind] = "isn't a kind of notification"
+		}
+	}
+
+	if len(fieldErrs) > 0 {
+		respondWithFieldErrors(w, fieldErrs)
+		return
+	}
+
+	settings, err := cfg.notificationSettings(ctx, userID)
+	if err != nil {
+		log.Printf("GetNotificationSettings failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	disabled := []string{}
+	for _, kind := range notificationKinds {
+		on, ok := params.Kinds[kind]
+		if !ok {
+			on = !slices.Contains(settings.DisabledKinds, kind)
+		}
+
+		if !on {
+			disabled = append(disabled, kind)
+		}
+	}
+
+	settings.DisabledKinds = disabled
+	if params.Email != nil {
+		settings.Email = *params.Email
+	}
+
+	err = cfg.databaseQueries.UpsertNotificationSettings(ctx, database.UpsertNotificationSettingsParams{
+		UserID:        userID,
+		DisabledKinds: settings.DisabledKinds,
+		Email:         settings.Email,
+	})
+
+	if err != nil {
+		log.Printf("UpsertNotificationSettings failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, newNotificationSettingsResponse(settings))
+}
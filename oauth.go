@@ -0,0 +1,614 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/captcha"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// How long a client has to exchange an authorization code, RFC 6749 recommends at most 10 minutes
+const oauthCodeTTL = 10 * time.Minute
+
+var errOAuthClientRegistration = errors.New("Personal access tokens can't register OAuth clients")
+
+// What the consent screen tells the user each scope lets the app do
+var oauthScopeDescriptions = map[string]string{
+	auth.ScopeChirpsRead:  "Read chirps",
+	auth.ScopeChirpsWrite: "Post and delete chirps as you",
+	auth.ScopeUsersWrite:  "Change your profile, follows and account settings",
+}
+
+// An OAuth client as its owner sees it. The secret is only ever in the create response.
+type oauthClientResponse struct {
+	ID           uuid.UUID `json:"client_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Public       bool      `json:"public"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+func newOAuthClientResponse(client database.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		CreatedAt:    client.CreatedAt,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Public:       !client.SecretHash.Valid,
+	}
+}
+
+// Redirect URIs have to be https, except on loopback where native apps listen for the redirect. Fragments aren't
+// allowed since the code is added to the query.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	}
+
+	return false
+}
+
+// POST /api/oauth/clients, registers a third-party app. Public clients (browser and mobile apps) get no secret and
+// rely on PKCE, which every client has to use anyway.
+func (cfg *apiConfig) createOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Name         string   `json:"name" validate:"required,max=100"`
+		RedirectURIs []string `json:"redirect_uris" validate:"required"`
+		Public       bool     `json:"public"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated OAuth client request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if usingPersonalAccessToken(r) {
+		respondWithError(w, http.StatusForbidden, errOAuthClientRegistration.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	for _, uri := range params.RedirectURIs {
+		if !validRedirectURI(uri) {
+			respondWithFieldErrors(w, validate.Errors{"redirect_uris": "must be https (http only on localhost) without a fragment: " + uri})
+			return
+		}
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if !params.Public {
+		if secret, err = auth.MakeRefreshToken(); err != nil {
+			log.Printf("Making OAuth client secret failed: %v", err)
+			respondWithError(w, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		secretHash = sql.NullString{String: auth.HashOAuthSecret(secret), Valid: true}
+	}
+
+	client, err := cfg.databaseQueries.CreateOAuthClient(ctx, database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         params.Name,
+		RedirectUris: params.RedirectURIs,
+		SecretHash:   secretHash,
+	})
+
+	if err != nil {
+		log.Printf("CreateOAuthClient failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := newOAuthClientResponse(client)
+	resp.Secret = secret
+
+	respondWithJson(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) getOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated OAuth client request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	clients, err := cfg.databaseQueries.GetOAuthClientsForOwner(ctx, userID)
+	if err != nil {
+		log.Printf("GetOAuthClientsForOwner failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := make([]oauthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		resp = append(resp, newOAuthClientResponse(client))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+// Deleting a client also deletes every token it was given
+func (cfg *apiConfig) deleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated OAuth client request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid client ID")
+		return
+	}
+
+	deleted, err := cfg.databaseQueries.DeleteOAuthClient(ctx, database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: userID,
+	})
+
+	if err != nil {
+		log.Printf("DeleteOAuthClient failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Someone else's client looks the same as a missing one
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "OAuth client not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// A checked /oauth/authorize request
+type oauthAuthorization struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	State         string
+	Scopes        []string
+	CodeChallenge string
+}
+
+// Sends the user back to the client with params added to its redirect URI
+func (a oauthAuthorization) redirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+	u, _ := url.Parse(a.RedirectURI)
+
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+
+	if a.State != "" {
+		q.Set("state", a.State)
+	}
+
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// Checks the parameters of an authorization request. Until the client and redirect URI are known good the error
+// is shown to the user, since redirecting to an unchecked URI would make us an open redirector. After that errors
+// go back to the client as RFC 6749 error redirects, which is what redirectErr being set means.
+func (cfg *apiConfig) parseOAuthAuthorization(r *http.Request, values url.Values) (a oauthAuthorization, redirectErr string, err error) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	clientID, err := uuid.Parse(values.Get("client_id"))
+	if err != nil {
+		return a, "", errors.New("Unknown application")
+	}
+
+	a.Client, err = cfg.databaseQueries.GetOAuthClient(ctx, database.GetOAuthClientParams{
+		ID:       clientID,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return a, "", errors.New("Unknown application")
+	}
+
+	if err != nil {
+		log.Printf("GetOAuthClient failed: %v", err)
+		return a, "", errors.New("Something went wrong, try again shortly")
+	}
+
+	// Leaving redirect_uri out is only unambiguous when the client registered exactly one
+	a.RedirectURI = values.Get("redirect_uri")
+	if a.RedirectURI == "" && len(a.Client.RedirectUris) == 1 {
+		a.RedirectURI = a.Client.RedirectUris[0]
+	}
+
+	if !slices.Contains(a.Client.RedirectUris, a.RedirectURI) {
+		return a, "", errors.New("The application sent you here with a redirect URI it hasn't registered")
+	}
+
+	a.State = values.Get("state")
+
+	if values.Get("response_type") != "code" {
+		return a, "unsupported_response_type", errors.New("response_type must be code")
+	}
+
+	if values.Get("code_challenge_method") != "S256" || !auth.ValidPKCEChallenge(values.Get("code_challenge")) {
+		return a, "invalid_request", errors.New("an S256 code_challenge is required")
+	}
+	a.CodeChallenge = values.Get("code_challenge")
+
+	a.Scopes = strings.Fields(values.Get("scope"))
+	if len(a.Scopes) == 0 {
+		a.Scopes = []string{auth.ScopeChirpsRead}
+	}
+
+	for _, scope := range a.Scopes {
+		// Admin powers are never handed to third parties
+		if !auth.ValidScope(scope) || scope == auth.ScopeAdmin {
+			return a, "invalid_scope", errors.New("unknown scope: " + scope)
+		}
+	}
+	a.Scopes = slices.Compact(slices.Sorted(slices.Values(a.Scopes)))
+
+	return a, "", nil
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>{{if .Failure}}Chirpy{{else}}Authorize {{.Client.Name}}{{end}}</title>
+</head>
+<body>
+	{{if .Failure}}
+	<h1>Can't authorize this application</h1>
+	<p>{{.Failure}}</p>
+	{{else}}
+	<h1>Authorize {{.Client.Name}}?</h1>
+	<p>{{.Client.Name}} wants to:</p>
+	<ul>
+		{{range .Scopes}}<li>{{index $.Descriptions .}}</li>
+		{{end}}
+	</ul>
+	<p>It won't see your password. You can revoke its access at any time.</p>
+	{{if .LoginError}}<p role="alert">{{.LoginError}}</p>{{end}}
+	<form method="post">
+		<input type="hidden" name="client_id" value="{{.Client.ID}}">
+		<input type="hidden" name="redirect_uri" value="{{.RedirectURI}}">
+		<input type="hidden" name="response_type" value="code">
+		<input type="hidden" name="state" value="{{.State}}">
+		<input type="hidden" name="scope" value="{{.Scope}}">
+		<input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+		<input type="hidden" name="code_challenge_method" value="S256">
+		<label>Email <input type="email" name="email" value="{{.Email}}" required autocomplete="username"></label>
+		<label>Password <input type="password" name="password" autocomplete="current-password"></label>
+		<button type="submit" name="decision" value="allow">Allow</button>
+		<button type="submit" name="decision" value="deny" formnovalidate>Deny</button>
+	</form>
+	{{end}}
+</body>
+</html>
+`))
+
+type consentPage struct {
+	oauthAuthorization
+	Scope        string
+	Descriptions map[string]string
+	Email        string
+	LoginError   string
+	Failure      string
+}
+
+func renderConsentPage(w http.ResponseWriter, status int, page consentPage) {
+	page.Scope = strings.Join(page.Scopes, " ")
+	page.Descriptions = oauthScopeDescriptions
+
+	// The consent screen must never be framed, or another site could trick users into clicking Allow
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := consentTemplate.Execute(w, page); err != nil {
+		log.Printf("Rendering consent page failed: %v", err)
+	}
+}
+
+// GET /oauth/authorize, the consent screen. There are no browser sessions, so the user signs in on the same form.
+func (cfg *apiConfig) oauthAuthorizeHandler(w http.ResponseWriter, r *http.Request) {
+	a, redirectErr, err := cfg.parseOAuthAuthorization(r, r.URL.Query())
+	if redirectErr != "" {
+		a.redirect(w, r, url.Values{"error": {redirectErr}, "error_description": {err.Error()}})
+		return
+	}
+
+	if err != nil {
+		renderConsentPage(w, http.StatusBadRequest, consentPage{Failure: err.Error()})
+		return
+	}
+
+	renderConsentPage(w, http.StatusOK, consentPage{oauthAuthorization: a})
+}
+
+// POST /oauth/authorize, the consent form. Allowing signs the user in and sends a single-use code to the client.
+func (cfg *apiConfig) oauthApproveHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if err := r.ParseForm(); err != nil {
+		renderConsentPage(w, http.StatusBadRequest, consentPage{Failure: "The form couldn't be read"})
+		return
+	}
+
+	a, redirectErr, err := cfg.parseOAuthAuthorization(r, r.PostForm)
+	if redirectErr != "" {
+		a.redirect(w, r, url.Values{"error": {redirectErr}, "error_description": {err.Error()}})
+		return
+	}
+
+	if err != nil {
+		renderConsentPage(w, http.StatusBadRequest, consentPage{Failure: err.Error()})
+		return
+	}
+
+	if r.PostForm.Get("decision") != "allow" {
+		a.redirect(w, r, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	email := r.PostForm.Get("email")
+
+	// Same gates as POST /api/login, the code this hands out is as good as a login
+	if err := cfg.verifyCaptcha(ctx, r, captchaFormToken(r.PostForm)); err != nil {
+		status, message := http.StatusBadRequest, "CAPTCHA check failed, try again"
+		if !errors.Is(err, captcha.ErrFailed) {
+			log.Printf("Verifying captcha failed: %v", err)
+			status, message = http.StatusServiceUnavailable, "Couldn't verify the CAPTCHA, try again shortly"
+		}
+
+		renderConsentPage(w, status, consentPage{oauthAuthorization: a, Email: email, LoginError: message})
+		return
+	}
+
+	user, err := cfg.databaseQueries.GetUserByEmail(ctx, database.GetUserByEmailParams{
+		Email:    email,
+		TenantID: tenantFromContext(r.Context()),
+	})
+
+	if err == nil {
+		err = auth.CheckPasswordHash(user.HashedPassword, r.PostForm.Get("password"))
+	}
+
+	if err == nil && user.DeactivatedAt.Valid {
+		renderConsentPage(w, http.StatusForbidden, consentPage{
+			oauthAuthorization: a,
+			Email:              email,
+			LoginError:         errAccountDeactivated.Error(),
+		})
+		return
+	}
+
+	if err == nil && userBanned(user) {
+		renderConsentPage(w, http.StatusForbidden, consentPage{
+			oauthAuthorization: a,
+			Email:              email,
+			LoginError:         errAccountBanned.Error(),
+		})
+		return
+	}
+
+	if err != nil {
+		renderConsentPage(w, http.StatusUnauthorized, consentPage{
+			oauthAuthorization: a,
+			Email:              email,
+			LoginError:         "Email or password is incorrect",
+		})
+		return
+	}
+
+	if err := cfg.verifyDevice(ctx, w, r, user); err != nil {
+		if !errors.Is(err, errDeviceUnconfirmed) {
+			a.redirect(w, r, url.Values{"error": {"temporarily_unavailable"}})
+			return
+		}
+
+		renderConsentPage(w, http.StatusForbidden, consentPage{
+			oauthAuthorization: a,
+			Email:              email,
+			LoginError:         err.Error(),
+		})
+		return
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Making authorization code failed: %v", err)
+		a.redirect(w, r, url.Values{"error": {"server_error"}})
+		return
+	}
+
+	err = cfg.databaseQueries.CreateOAuthAuthorizationCode(ctx, database.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      auth.HashOAuthSecret(code),
+		ClientID:      a.Client.ID,
+		UserID:        user.ID,
+		RedirectUri:   a.RedirectURI,
+		Scopes:        a.Scopes,
+		CodeChallenge: a.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	})
+
+	if err != nil {
+		log.Printf("CreateOAuthAuthorizationCode failed: %v", err)
+		a.redirect(w, r, url.Values{"error": {"temporarily_unavailable"}})
+		return
+	}
+
+	a.redirect(w, r, url.Values{"code": {code}})
+}
+
+// RFC 6749's token response
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// Token endpoint errors have their own shape in RFC 6749, clients' OAuth libraries expect exactly this
+func respondWithOAuthError(w http.ResponseWriter, code int, oauthErr, description string) {
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, code, map[string]string{"error": oauthErr, "error_description": description})
+}
+
+// POST /oauth/token, swaps an authorization code and its PKCE verifier for an access token. Confidential clients
+// authenticate with HTTP Basic or client_id/client_secret form fields, public ones just send client_id.
+func (cfg *apiConfig) oauthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_request", "body must be form encoded")
+		return
+	}
+
+	if grant := r.PostForm.Get("grant_type"); grant != "authorization_code" {
+		respondWithOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	rawClientID, secret, basic := r.BasicAuth()
+	if !basic {
+		rawClientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	clientID, err := uuid.Parse(rawClientID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "unknown client")
+		return
+	}
+
+	client, err := cfg.databaseQueries.GetOAuthClient(ctx, database.GetOAuthClientParams{
+		ID:       clientID,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "unknown client")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetOAuthClient failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if client.SecretHash.Valid &&
+		subtle.ConstantTimeCompare([]byte(auth.HashOAuthSecret(secret)), []byte(client.SecretHash.String)) != 1 {
+		respondWithOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	// Deleted on the way out, so a code can't be exchanged twice even by concurrent requests
+	code, err := cfg.databaseQueries.ConsumeOAuthAuthorizationCode(ctx, auth.HashOAuthSecret(r.PostForm.Get("code")))
+	err = database.Wrap(err)
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "unknown or already used code")
+		return
+	}
+
+	if err != nil {
+		log.Printf("ConsumeOAuthAuthorizationCode failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	switch {
+	case code.ClientID != client.ID:
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "code was issued to another client")
+		return
+	case time.Now().After(code.ExpiresAt):
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "code has expired")
+		return
+	case r.PostForm.Get("redirect_uri") != code.RedirectUri:
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri doesn't match the authorization request")
+		return
+	case !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge):
+		respondWithOAuthError(w, http.StatusBadRequest, "invalid_grant", "code_verifier doesn't match the code_challenge")
+		return
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		log.Printf("MakePersonalAccessToken failed: %v", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, "server_error", "couldn't issue a token")
+		return
+	}
+
+	_, err = cfg.databaseQueries.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID:    code.UserID,
+		Name:      client.Name,
+		TokenHash: auth.HashPersonalAccessToken(token),
+		Scopes:    code.Scopes,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(cfg.oauthTokenTTL), Valid: true},
+		ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+	})
+
+	if err != nil {
+		log.Printf("CreatePersonalAccessToken failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJson(w, http.StatusOK, oauthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(cfg.oauthTokenTTL.Seconds()),
+		Scope:       strings.Join(code.Scopes, " "),
+	})
+}
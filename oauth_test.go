@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/captcha"
+)
+
+// A consent form allowing the fake's OAuth client, signed in with testUserPassword
+func consentForm() url.Values {
+	return url.Values{
+		"client_id":             {testUserID.String()},
+		"redirect_uri":          {"https://app.example/callback"},
+		"response_type":         {"code"},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+		"email":                 {testUserEmail},
+		"password":              {testUserPassword},
+		"decision":              {"allow"},
+	}
+}
+
+func newOAuthTestConfig(t *testing.T) (*apiConfig, *fakeDB) {
+	t.Helper()
+
+	hash, err := auth.HashedPassword(testUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeDB(map[string]driver.Value{
+		"email":           testUserEmail,
+		"hashed_password": hash,
+		"redirect_uris":   "{https://app.example/callback}",
+		"scopes":          "{chirps:read}",
+	})
+	return newFakeConfig(t, fake), fake
+}
+
+func postConsent(cfg *apiConfig, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/authorize", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	cfg.oauthApproveHandler(rec, req)
+	return rec
+}
+
+// Approving hands out a code as good as a login, so a device nobody has confirmed can't get one
+func TestOAuthApproveChecksDevice(t *testing.T) {
+	cfg, fake := newOAuthTestConfig(t)
+
+	rec := postConsent(cfg, consentForm())
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected 302 with confirmation off, got %d: %s", rec.Code, rec.Body)
+	}
+
+	cfg, fake = newOAuthTestConfig(t)
+	cfg.requireDeviceConfirmation = true
+
+	rec = postConsent(cfg, consentForm())
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an unconfirmed device, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if fake.wasSaved("CreateOAuthAuthorizationCode") {
+		t.Error("expected no authorization code for an unconfirmed device")
+	}
+}
+
+func TestOAuthApproveChecksCaptcha(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("response") == "good" {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	defer provider.Close()
+
+	for _, tt := range []struct {
+		token  string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"bad", http.StatusBadRequest},
+		{"good", http.StatusFound},
+	} {
+		cfg, fake := newOAuthTestConfig(t)
+		cfg.captcha = &captcha.Verifier{URL: provider.URL, Secret: "secret"}
+
+		form := consentForm()
+		form.Set("h-captcha-response", tt.token)
+
+		rec := postConsent(cfg, form)
+		if rec.Code != tt.status {
+			t.Errorf("token %q: expected %d, got %d: %s", tt.token, tt.status, rec.Code, rec.Body)
+		}
+
+		if saved := fake.wasSaved("CreateOAuthAuthorizationCode"); saved != (tt.status == http.StatusFound) {
+			t.Errorf("token %q: authorization code saved = %v", tt.token, saved)
+		}
+	}
+}
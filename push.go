@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+	"github.com/itsmandrew/server-go/internal/webpush"
+)
+
+// Notification kinds that are worth interrupting someone's browser for
+var pushNotificationKinds = map[string]bool{
+	notificationReply:   true,
+	notificationMention: true,
+}
+
+type pushJob struct {
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	Message        json.RawMessage `json:"message"`
+}
+
+// Handler for GET /api/push/vapid_public_key, the browser needs this as applicationServerKey to subscribe
+func (cfg *apiConfig) vapidPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	type validResponse struct {
+		PublicKey string `json:"public_key"`
+	}
+
+	if cfg.push == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Web Push is not configured")
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, validResponse{PublicKey: cfg.push.PublicKey()})
+}
+
+// Handler for POST /api/push/subscriptions, takes the browser's PushSubscription.toJSON() as is
+func (cfg *apiConfig) createPushSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Endpoint string `json:"endpoint" validate:"required,https"`
+		Keys     struct {
+			P256dh string `json:"p256dh" validate:"required"`
+			Auth   string `json:"auth" validate:"required"`
+		} `json:"keys"`
+	}
+
+	if cfg.push == nil {
+		respondWithError(w, http.StatusServiceUnavailable, "Web Push is not configured")
+		return
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated push subscription request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	if err := webpush.CheckEndpoint(ctx, params.Endpoint); err != nil {
+		log.Printf("Refusing push endpoint: %v", err)
+		respondWithFieldErrors(w, validate.Errors{"endpoint": "must be a public https URL"})
+		return
+	}
+
+	_, err = cfg.databaseQueries.UpsertPushSubscription(ctx, database.UpsertPushSubscriptionParams{
+		UserID:   userID,
+		Endpoint: params.Endpoint,
+		P256dh:   params.Keys.P256dh,
+		Auth:     params.Keys.Auth,
+	})
+
+	if err != nil {
+		log.Printf("UpsertPushSubscription failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Handler for DELETE /api/push/subscriptions, body is {"endpoint": "..."}
+func (cfg *apiConfig) deletePushSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Endpoint string `json:"endpoint" validate:"required"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated push subscription request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	deleted, err := cfg.databaseQueries.DeletePushSubscription(ctx, database.DeletePushSubscriptionParams{
+		Endpoint: params.Endpoint,
+		UserID:   userID,
+	})
+
+	if err != nil {
+		log.Printf("DeletePushSubscription failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Push subscription not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Queues a push to each of the user's browsers
+func (cfg *apiConfig) queuePush(ctx context.Context, userID uuid.UUID, message any) {
+	if cfg.push == nil {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Encoding push message failed: %v", err)
+		return
+	}
+
+	subs, err := cfg.databaseQueries.GetPushSubscriptionsForUser(ctx, userID)
+	if err != nil {
+		log.Printf("GetPushSubscriptionsForUser failed: %v", err)
+		return
+	}
+
+	for _, sub := range subs {
+		if err := cfg.jobs.Enqueue(ctx, jobPushDelivery, pushJob{SubscriptionID: sub.ID, Message: data}); err != nil {
+			log.Printf("Queueing push failed: %v", err)
+		}
+	}
+}
+
+// Job handler that sends one push, subscriptions the push service says are gone get deleted
+func (cfg *apiConfig) runPushJob(ctx context.Context, raw json.RawMessage) error {
+	var job pushJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return err
+	}
+
+	if cfg.push == nil {
+		return nil
+	}
+
+	sub, err := cfg.databaseQueries.GetPushSubscription(ctx, job.SubscriptionID)
+	err = database.Wrap(err)
+
+	// Unsubscribed in the meantime
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = cfg.push.Send(ctx, webpush.Subscription{Endpoint: sub.Endpoint, P256dh: sub.P256dh, Auth: sub.Auth}, job.Message)
+
+	if errors.Is(err, webpush.ErrSubscriptionGone) {
+		log.Printf("Pruning expired push subscription %s", sub.ID)
+		return cfg.databaseQueries.DeletePushSubscriptionByID(ctx, sub.ID)
+	}
+
+	return err
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/ratelimit"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Who a request counts against. Anonymous requests are counted per client IP, the rest per user.
+const (
+	rateTierAnonymous = "anonymous"
+	rateTierUser      = "user"
+	// Chirpy Red members
+	rateTierPremium = "premium"
+	// Personal access tokens, scripts and bots
+	rateTierToken = "token"
+)
+
+var rateTiers = []string{rateTierAnonymous, rateTierUser, rateTierPremium, rateTierToken}
+
+// The limits for one group of routes, a limiter per tier. A tier whose limit is 0 isn't limited.
+type rateLimitPolicy struct {
+	name     string
+	limiters map[string]*ratelimit.Limiter
+}
+
+// Builds a policy from NAME (requests per RATE_LIMIT_WINDOW), which NAME_ANONYMOUS, NAME_USER, NAME_PREMIUM and
+// NAME_TOKEN override for their tier. Setting a limit to 0 turns it off.
+func newRateLimitPolicy(name, env string, fallback int) *rateLimitPolicy {
+	window := envDuration("RATE_LIMIT_WINDOW", time.Minute)
+	limit := envInt(env, fallback)
+
+	p := &rateLimitPolicy{name: name, limiters: make(map[string]*ratelimit.Limiter, len(rateTiers))}
+	for _, tier := range rateTiers {
+		p.limiters[tier] = ratelimit.New(envInt(env+"_"+strings.ToUpper(tier), limit), window)
+	}
+
+	return p
+}
+
+// Works out which tier r is in and the key it's counted under. A token that doesn't check out counts as anonymous,
+// and so does everyone when the user can't be looked up.
+func (cfg *apiConfig) rateLimitIdentity(r *http.Request) (string, string) {
+	if _, err := requestToken(r); err != nil {
+		return rateTierAnonymous, remoteIP(r)
+	}
+
+	p, err := cfg.requestPrincipal(r)
+	if err != nil {
+		return rateTierAnonymous, remoteIP(r)
+	}
+
+	if p.personalToken {
+		return rateTierToken, p.userID.String()
+	}
+
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	user, err := cfg.databaseQueries.GetUserByIDNoPassword(ctx, p.userID)
+	if err != nil {
+		log.Printf("GetUserByIDNoPassword failed: %v", err)
+		return rateTierUser, p.userID.String()
+	}
+
+	if user.IsChirpyRed {
+		return rateTierPremium, p.userID.String()
+	}
+
+	return rateTierUser, p.userID.String()
+}
+
+// Limits next with the caller's tier of policy. Every response carries both the de facto X-RateLimit-* headers and
+// the IETF draft's RateLimit-* ones so clients can pace themselves, and going over gets a 429 with Retry-After.
+func (cfg *apiConfig) rateLimited(policy *rateLimitPolicy, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tier, key := cfg.rateLimitIdentity(r)
+		limiter := policy.limiters[tier]
+		if limiter.Limit() <= 0 {
+			next(w, r)
+			return
+		}
+
+		res := limiter.Allow(key)
+
+		// X-RateLimit-Reset is a Unix time, the draft's RateLimit-Reset is seconds from now
+		resetIn := strconv.Itoa(int(math.Ceil(time.Until(res.Reset).Seconds())))
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+		h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", resetIn)
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit, int(limiter.Window().Seconds())))
+
+		if !res.Allowed {
+			h.Set("Retry-After", resetIn)
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// Periodically drops finished windows so a limiter's memory tracks active clients, not every IP it has ever seen
+func runRateLimitPruner(ctx context.Context, policies ...*rateLimitPolicy) {
+	ticker := time.NewTicker(envDuration("RATE_LIMIT_WINDOW", time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, p := range policies {
+				for _, l := range p.limiters {
+					l.Prune()
+				}
+			}
+		}
+	}
+}
+
+type rateLimitsResponse struct {
+	WindowSeconds int `json:"window_seconds"`
+	// Requests per window for each tier of each policy, 0 when the tier isn't limited
+	Policies map[string]map[string]int `json:"policies"`
+}
+
+// The limits in force, policy name to tier to limit
+func (cfg *apiConfig) currentRateLimits() map[string]map[string]int {
+	policies := make(map[string]map[string]int, len(cfg.rateLimits))
+	for _, p := range cfg.rateLimits {
+		limits := make(map[string]int, len(p.limiters))
+		for tier, l := range p.limiters {
+			limits[tier] = l.Limit()
+		}
+		policies[p.name] = limits
+	}
+
+	return policies
+}
+
+// Changes the limits named in policies, unknown policies and tiers are ignored
+func (cfg *apiConfig) applyRateLimits(policies map[string]map[string]int) {
+	for _, p := range cfg.rateLimits {
+		for tier, l := range p.limiters {
+			if limit, ok := policies[p.name][tier]; ok {
+				l.SetLimit(limit)
+			}
+		}
+	}
+}
+
+// Adds an error to errs, under prefix, for every unknown policy or tier and negative limit in policies
+func (cfg *apiConfig) checkRateLimits(policies map[string]map[string]int, prefix string, errs validate.Errors) {
+	current := cfg.currentRateLimits()
+
+	for name, limits := range policies {
+		tiers, ok := current[name]
+		if !ok {
+			errs[prefix+name] = "isn't a rate limit policy"
+			continue
+		}
+
+		for tier, limit := range limits {
+			if _, ok := tiers[tier]; !ok {
+				errs[prefix+name+"."+tier] = "must be anonymous, user, premium or token"
+			} else if limit < 0 {
+				errs[prefix+name+"."+tier] = "must be at least 0"
+			}
+		}
+	}
+}
+
+// The current limits with changes laid over them, tiers left out of changes keep their limit
+func (cfg *apiConfig) mergeRateLimits(changes map[string]map[string]int) map[string]map[string]int {
+	merged := cfg.currentRateLimits()
+	for name, limits := range changes {
+		for tier, limit := range limits {
+			merged[name][tier] = limit
+		}
+	}
+
+	return merged
+}
+
+// GET /admin/rate_limits
+func (cfg *apiConfig) getRateLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, rateLimitsResponse{
+		WindowSeconds: int(envDuration("RATE_LIMIT_WINDOW", time.Minute).Seconds()),
+		Policies:      cfg.currentRateLimits(),
+	})
+}
+
+// PUT /admin/rate_limits, {"policies": {"write": {"premium": 300}}}. Tiers left out keep their limit. The limits are
+// saved as a setting, other instances follow after a reload.
+func (cfg *apiConfig) setRateLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireGlobalAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var params struct {
+		Policies map[string]map[string]int `json:"policies" validate:"required"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	fieldErrs := validate.Errors{}
+	cfg.checkRateLimits(params.Policies, "policies.", fieldErrs)
+	if len(fieldErrs) > 0 {
+		respondWithFieldErrors(w, fieldErrs)
+		return
+	}
+
+	err := cfg.saveSettings(ctx, adminID, map[string]any{settingRateLimits: cfg.mergeRateLimits(params.Policies)})
+	if err != nil {
+		log.Printf("Saving rate limits failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, rateLimitsResponse{
+		WindowSeconds: int(envDuration("RATE_LIMIT_WINDOW", time.Minute).Seconds()),
+		Policies:      cfg.currentRateLimits(),
+	})
+}
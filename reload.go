@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// Maps LOG_LEVEL (debug/info/warn/error) to a slog level, anything else is info
+func parseLogLevel(v string) slog.Level {
+	switch strings.ToLower(v) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Re-reads .env and applies the settings that are safe to change on a running server: log level,
+// the moderation pipeline (duplicate/burst limits, classifier), the banned word list, the IP deny list, the tenants
+// and whatever admins saved at /admin/settings.
+// Things like DB_URL, JWT_SECRET and the listen timeouts need a restart, changing the secret live would log everyone out.
+func (cfg *apiConfig) reloadConfig(ctx context.Context) error {
+	if err := godotenv.Overload(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	cfg.logLevel.Set(parseLogLevel(os.Getenv("LOG_LEVEL")))
+	cfg.moderation.Store(cfg.newModerationPipeline())
+
+	reloadCtx, cancel := cfg.dbContext(ctx)
+	defer cancel()
+
+	if cfg.tenants != nil {
+		if err := cfg.tenants.reload(reloadCtx, cfg.databaseQueries); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.bannedWords.reload(reloadCtx, cfg.databaseQueries); err != nil {
+		return err
+	}
+
+	if err := cfg.ipDenyList.reload(reloadCtx, cfg.databaseQueries); err != nil {
+		return err
+	}
+
+	return cfg.loadSettings(reloadCtx)
+}
+
+// Reloads config on every SIGHUP until ctx is cancelled
+func (cfg *apiConfig) runConfigReloader(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-hup:
+			if err := cfg.reloadConfig(ctx); err != nil {
+				log.Printf("Config reload failed: %v", err)
+				continue
+			}
+			log.Println("Config reloaded")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Same as sending SIGHUP, for when you can't signal the process (containers, multiple hosts behind a proxy)
+func (cfg *apiConfig) reloadConfigHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireGlobalAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	if err := cfg.reloadConfig(r.Context()); err != nil {
+		log.Printf("Config reload failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	log.Printf("Config reloaded by %s", adminID)
+	respondWithJson(w, http.StatusOK, map[string]string{"msg": "Config reloaded"})
+}
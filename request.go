@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/i18n"
+	"github.com/itsmandrew/server-go/internal/language"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// 400 body for a request with bad fields, keyed by the field's json name
+type validationErrorResponse struct {
+	Errors validate.Errors `json:"errors"`
+}
+
+// Responds 400 with errs, translating each message like respondWithError does
+func respondWithFieldErrors(w http.ResponseWriter, errs validate.Errors) error {
+	lang := w.Header().Get("Content-Language")
+
+	translated := make(validate.Errors, len(errs))
+	for field, msg := range errs {
+		translated[field] = i18n.Translate(lang, msg)
+	}
+
+	return respondWithJson(w, http.StatusBadRequest, validationErrorResponse{Errors: translated})
+}
+
+// Decodes the JSON body into dst and checks its validate tags. On failure it responds with a 400 itself and
+// returns false: unparseable JSON gets a single error, bad fields get {"errors": {"email": "must be a valid email"}}.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	defer r.Body.Close()
+
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			respondWithFieldErrors(w, validate.Errors{typeErr.Field: "has the wrong type"})
+			return false
+		}
+
+		respondWithError(w, http.StatusBadRequest, "Request body must be valid JSON")
+		return false
+	}
+
+	if errs := validate.Struct(dst); errs != nil {
+		respondWithFieldErrors(w, errs)
+		return false
+	}
+
+	return true
+}
+
+// Reads a positive integer query param, falling back when it's missing or invalid and capping it at max
+func queryLimit(r *http.Request, key string, fallback, max int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || v <= 0 {
+		return fallback
+	}
+
+	if v > max {
+		return max
+	}
+
+	return v
+}
+
+// Reads an RFC3339 timestamp query param, falling back when it's missing. Unlike queryLimit a bad value is
+// an error, silently ignoring a typo'd since would hand back the whole table.
+func queryTime(r *http.Request, key string, fallback time.Time) (time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return fallback, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp", key)
+	}
+
+	// created_at is a plain TIMESTAMP written in UTC, so compare in UTC too
+	return t.UTC(), nil
+}
+
+// Reads the lang query param listings filter by, empty when it's missing. A bad code is an error like in queryTime.
+func queryLanguage(r *http.Request) (string, error) {
+	v := r.URL.Query().Get("lang")
+	if v != "" && !language.Valid(v) {
+		return "", errors.New("lang must be a two letter ISO 639-1 code like en")
+	}
+
+	return v, nil
+}
+
+// Picks whichever of offers the Accept header likes best, ties go to whatever the client listed first.
+// offers[0] is the default when there's no Accept header or nothing matches, and is what */* picks.
+func negotiate(r *http.Request, offers ...string) string {
+	best, bestQ := offers[0], -1.0
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			q = v
+		}
+
+		for _, offer := range offers {
+			if q > bestQ && q > 0 && mediaTypeMatches(mediaType, offer) {
+				best, bestQ = offer, q
+			}
+		}
+	}
+
+	return best
+}
+
+func mediaTypeMatches(pattern, offer string) bool {
+	if pattern == "*/*" || pattern == offer {
+		return true
+	}
+
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	return ok && strings.HasPrefix(offer, prefix+"/")
+}
+
+// Position in a keyset-paginated list, ordered by time with ID as the tie breaker
+type pageCursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+// Starting cursors for each direction, they sort after (or before) every real row
+var (
+	newestFirst = pageCursor{Time: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)}
+	oldestFirst = pageCursor{Time: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)}
+)
+
+// Opaque to clients, they just hand back whatever next_cursor was
+func (c pageCursor) String() string {
+	raw := c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Reads the cursor query param, no cursor means the first page which starts at start
+func queryCursor(r *http.Request, start pageCursor) (pageCursor, error) {
+	v := r.URL.Query().Get("cursor")
+	if v == "" {
+		return start, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+
+	timePart, idPart, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return pageCursor{}, errInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timePart)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+
+	id, err := uuid.Parse(idPart)
+	if err != nil {
+		return pageCursor{}, errInvalidCursor
+	}
+
+	return pageCursor{Time: t, ID: id}, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+type resetResponse struct {
+	Msg string `json:"msg"`
+}
+
+// Zeroes the hit counter, per-route stats and query timings, in memory and in the metrics table
+func (cfg *apiConfig) resetMetrics(ctx context.Context) error {
+	cfg.fileserverHits.Store(0)
+	cfg.unflushedHits.Store(0)
+	cfg.requestMetrics.Reset()
+	cfg.queryStats.Reset()
+	return cfg.databaseQueries.SetMetric(ctx, database.SetMetricParams{Name: fileserverHitsMetric, Value: 0})
+}
+
+// Empties the tables the test suites write to, in one transaction so a failure halfway leaves everything as it was.
+// Children go first even though the users truncate cascades, so it's obvious what gets wiped.
+// In multi-tenant mode only the requesting tenant's users and chirps go, their refresh tokens cascade with them.
+func (cfg *apiConfig) resetDatabase(ctx context.Context) error {
+	if cfg.tenants != nil {
+		tenantID := tenantFromContext(ctx)
+		return database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+			if err := qtx.DeleteChirpsForTenant(ctx, tenantID); err != nil {
+				return err
+			}
+			return qtx.DeleteUsersForTenant(ctx, tenantID)
+		})
+	}
+
+	return database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		if err := qtx.DeleteRefreshTokens(ctx); err != nil {
+			return err
+		}
+		if err := qtx.DeleteChirps(ctx); err != nil {
+			return err
+		}
+		return qtx.DeleteUsers(ctx)
+	})
+}
+
+// Does both resets, kept around because existing scripts call it
+func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if cfg.platform != "dev" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := cfg.resetMetrics(ctx); err != nil {
+		log.Printf("Flushing metrics failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := cfg.resetDatabase(ctx); err != nil {
+		log.Printf("Resetting database failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, resetResponse{Msg: "Metrics and users table were reset"})
+	log.Println("Metrics and table reset")
+}
+
+func (cfg *apiConfig) resetMetricsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if cfg.platform != "dev" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := cfg.resetMetrics(ctx); err != nil {
+		log.Printf("Flushing metrics failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, resetResponse{Msg: "Metrics were reset"})
+	log.Println("Metrics reset")
+}
+
+func (cfg *apiConfig) resetDatabaseHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if cfg.platform != "dev" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	if err := cfg.resetDatabase(ctx); err != nil {
+		log.Printf("Resetting database failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, resetResponse{Msg: "Users, chirps and refresh tokens were reset"})
+	log.Println("Database reset")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Wraps the mux so the errors it generates itself use the same {"error": ...} envelope as every handler,
+// instead of Go's plain text. Only requests that didn't match a pattern are touched, so real handlers
+// (and the /app file server) write their responses untouched.
+//
+// Unknown /api/ paths are handled here rather than with a "/api/" catch-all pattern, since a catch-all would
+// match wrong-method requests too and turn every 405 into a 404.
+func withRoutingErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		mux.ServeHTTP(&routingErrorWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+// Swaps the mux's text 405s (and 404s under /api/) for JSON ones. The mux has already set the Allow header
+// listing the methods the path does support by the time WriteHeader is called, so that's kept.
+type routingErrorWriter struct {
+	http.ResponseWriter
+	r         *http.Request
+	rewritten bool
+}
+
+func (w *routingErrorWriter) WriteHeader(code int) {
+	var msg string
+
+	switch {
+	case code == http.StatusMethodNotAllowed:
+		msg = "Method not allowed"
+	case code == http.StatusNotFound && strings.HasPrefix(w.r.URL.Path, "/api/"):
+		log.Printf("No route for %s %s", w.r.Method, w.r.URL.Path)
+		msg = "Not found"
+	default:
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	w.rewritten = true
+	// Left over from http.Error, it only makes sense for the text body we're dropping
+	w.Header().Del("X-Content-Type-Options")
+	respondWithError(w.ResponseWriter, code, msg)
+}
+
+func (w *routingErrorWriter) Write(b []byte) (int, error) {
+	if w.rewritten {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *routingErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+)
+
+// How far ahead a chirp can be scheduled
+const maxPublishDelay = 365 * 24 * time.Hour
+
+type scheduledChirpResponse struct {
+	PublishAt time.Time `json:"publish_at"`
+}
+
+func validPublishAt(t time.Time) bool {
+	now := time.Now()
+	return t.After(now) && t.Before(now.Add(maxPublishDelay))
+}
+
+// Queues params to be posted at publishAt by a chirps.publish job. It's been checked and moderated already, so
+// the job only has to create it. Answers 202, the chirp doesn't have an ID until then.
+func (cfg *apiConfig) scheduleChirp(ctx context.Context, w http.ResponseWriter, params database.CreateChirpParams, publishAt time.Time) {
+	if err := cfg.jobs.EnqueueAt(ctx, jobChirpPublish, params, publishAt.UTC()); err != nil {
+		log.Printf("Scheduling chirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusAccepted, scheduledChirpResponse{PublishAt: publishAt.UTC()})
+}
+
+// Posts a chirp scheduled with publish_at. One whose author or parent chirp has been deleted since is dropped,
+// retrying won't bring them back.
+func (cfg *apiConfig) runChirpPublishJob(ctx context.Context, raw json.RawMessage) error {
+	var params database.CreateChirpParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return err
+	}
+
+	chirp, err := cfg.databaseQueries.CreateChirp(ctx, params)
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrForeignKey) {
+		log.Printf("Dropping scheduled chirp by %s, what it replies to is gone", params.UserID)
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cfg.events.Publish(ctx, events.ChirpCreated{Chirp: chirp})
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+func TestCreateChirpWithPublishAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		publishAt time.Time
+		status    int
+	}{
+		{"next hour", time.Now().Add(time.Hour), http.StatusAccepted},
+		{"in the past", time.Now().Add(-time.Hour), http.StatusBadRequest},
+		{"too far ahead", time.Now().Add(2 * maxPublishDelay), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeDB(nil)
+			cfg := newFakeConfig(t, fake)
+			h := cfg.requireScope(auth.ScopeChirpsWrite, cfg.requireAuth(cfg.createChirpHandler))
+
+			body, _ := json.Marshal(map[string]any{"body": "See you tomorrow", "publish_at": tt.publishAt})
+			req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+			rec := httptest.NewRecorder()
+			h(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+
+			if fake.wasSaved("CreateChirp") {
+				t.Error("expected the chirp not to be posted yet")
+			}
+
+			if queued := fake.wasSaved("EnqueueJob"); queued != (tt.status == http.StatusAccepted) {
+				t.Errorf("publish job queued = %v", queued)
+			}
+		})
+	}
+}
+
+func TestChirpPublishJob(t *testing.T) {
+	fake := newFakeDB(nil)
+	cfg := newFakeConfig(t, fake)
+
+	raw, err := json.Marshal(database.CreateChirpParams{Body: "See you tomorrow", UserID: testUserID, Visibility: visibilityPublic})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.runChirpPublishJob(t.Context(), raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if !fake.wasSaved("CreateChirp") {
+		t.Error("expected the job to post the chirp")
+	}
+}
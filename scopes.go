@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+)
+
+var errInsufficientScope = errors.New("Token doesn't have the scope this needs")
+
+// Whoever a request's token belongs to and what the token lets them do
+type principal struct {
+	userID uuid.UUID
+	scopes []string
+	// Authenticated with a personal access token rather than a login
+	personalToken bool
+}
+
+func (p principal) hasScope(scope string) bool {
+	return slices.Contains(p.scopes, scope)
+}
+
+// Whether the token can do everything a user can, what routes without requireScope ask for
+func (p principal) hasUserScopes() bool {
+	return p.hasScope(auth.ScopeChirpsRead) && p.hasScope(auth.ScopeChirpsWrite) && p.hasScope(auth.ScopeUsersWrite)
+}
+
+type principalKey struct{}
+
+// Reads and checks the bearer token (or session cookie), either a JWT or a personal access token
+func (cfg *apiConfig) authenticatePrincipal(r *http.Request) (principal, error) {
+	token, err := requestToken(r)
+	if err != nil {
+		return principal{}, err
+	}
+
+	if auth.IsPersonalAccessToken(token) {
+		pat, err := cfg.lookupPersonalAccessToken(r, token)
+		if err != nil {
+			return principal{}, err
+		}
+
+		cfg.activity.record(pat.UserID)
+		return principal{userID: pat.UserID, scopes: pat.Scopes, personalToken: true}, nil
+	}
+
+	// Checks to see if the token is a AccessToken vs RefreshToken (accessToken has 3 dots) -> Sanity Check
+	if len(strings.Split(token, ".")) != 3 {
+		return principal{}, errors.New("invalid token format")
+	}
+
+	userID, scopes, err := cfg.tokenConfig(r.Context()).ValidateScopes(token)
+	if err != nil {
+		return principal{}, err
+	}
+
+	cfg.activity.record(userID)
+	return principal{userID: userID, scopes: scopes}, nil
+}
+
+// The principal requireScope already authenticated, or a fresh one on routes without it
+func (cfg *apiConfig) requestPrincipal(r *http.Request) (principal, error) {
+	if p, ok := r.Context().Value(principalKey{}).(principal); ok {
+		return p, nil
+	}
+
+	return cfg.authenticatePrincipal(r)
+}
+
+// Reads and validates the access token (a JWT or a personal access token) on a request, returning the user it belongs to.
+// On routes that don't declare a scope with requireScope only tokens with every non-admin scope are accepted.
+func (cfg *apiConfig) authenticateRequest(r *http.Request) (uuid.UUID, error) {
+	if p, ok := r.Context().Value(principalKey{}).(principal); ok {
+		return p.userID, nil
+	}
+
+	p, err := cfg.authenticatePrincipal(r)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+
+	if !p.hasUserScopes() {
+		return uuid.UUID{}, errInsufficientScope
+	}
+
+	return p.userID, nil
+}
+
+// For endpoints that work logged out but show a bit more when logged in, a missing or bad token just means anonymous
+func (cfg *apiConfig) optionalUser(r *http.Request) (uuid.UUID, bool) {
+	if _, err := requestToken(r); err != nil {
+		return uuid.UUID{}, false
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		return uuid.UUID{}, false
+	}
+
+	return userID, true
+}
+
+// Rejects tokens without scope with a 403, as well as cookie sessions missing their CSRF token. Requests without a
+// token, or with one that doesn't check out, go through untouched so the handler can answer 401 or treat them as
+// anonymous, whichever it does anyway.
+func (cfg *apiConfig) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := requestToken(r); err != nil {
+			if errors.Is(err, errCSRFToken) {
+				respondWithError(w, http.StatusForbidden, err.Error())
+				return
+			}
+
+			next(w, r)
+			return
+		}
+
+		p, err := cfg.authenticatePrincipal(r)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		if !p.hasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+			respondWithError(w, http.StatusForbidden, errInsufficientScope.Error())
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// Answers 401 for requests without a valid token, so the handler behind it can take the user from
+// userIDFromContext instead of checking the token itself. Inside requireScope the token has already been checked
+// and isn't parsed again.
+func (cfg *apiConfig) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(principalKey{}).(principal); ok {
+			next(w, r)
+			return
+		}
+
+		p, err := cfg.authenticatePrincipal(r)
+		if err == nil && !p.hasUserScopes() {
+			err = errInsufficientScope
+		}
+
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	}
+}
+
+// The user requireAuth let through. Only for handlers behind it, anywhere else it's the zero UUID.
+func userIDFromContext(ctx context.Context) uuid.UUID {
+	p, _ := ctx.Value(principalKey{}).(principal)
+	return p.userID
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+	"github.com/itsmandrew/server-go/internal/search"
+)
+
+const (
+	// Longest ?q= accepted
+	searchMaxQuery = 200
+	// Chirps sent to the search cluster per reindex job
+	searchReindexBatch = 500
+)
+
+// Adapts the chirp queries to search.Backend. The chirps table is its own index, so there's nothing to sync.
+type postgresSearch struct {
+	q *database.Queries
+}
+
+func (postgresSearch) Index(context.Context, ...search.Document) error { return nil }
+
+func (postgresSearch) Delete(context.Context, uuid.UUID) error { return nil }
+
+func (p postgresSearch) Search(ctx context.Context, q search.Query) ([]search.Hit, error) {
+	rows, err := p.q.SearchChirps(ctx, database.SearchChirpsParams{
+		TenantID:   q.TenantID,
+		Query:      q.Text,
+		Language:   q.Language,
+		CursorTime: q.Before,
+		CursorID:   q.BeforeID,
+		PageLimit:  int32(q.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]search.Hit, len(rows))
+	for i, row := range rows {
+		hits[i] = search.Hit{ID: row.ID, CreatedAt: row.CreatedAt}
+	}
+
+	return hits, nil
+}
+
+type searchIndexJob struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+type searchReindexJob struct {
+	TenantID   uuid.UUID `json:"tenant_id"`
+	CursorTime time.Time `json:"cursor_time"`
+	CursorID   uuid.UUID `json:"cursor_id"`
+}
+
+func newSearchDocument(chirp database.Chirp) search.Document {
+	return search.Document{
+		ID:        chirp.ID,
+		TenantID:  chirp.TenantID,
+		UserID:    chirp.UserID,
+		Body:      chirp.Body,
+		Language:  chirp.Language.String,
+		CreatedAt: chirp.CreatedAt,
+	}
+}
+
+// Keeps an external search index in step with new and deleted chirps. It goes through the job queue so a cluster
+// that's down gets retried rather than missing chirps.
+func (cfg *apiConfig) subscribeSearchIndex(bus events.Bus) {
+	enqueue := func(ctx context.Context, job searchIndexJob) {
+		ctx, cancel := cfg.dbContext(ctx)
+		defer cancel()
+
+		if err := cfg.jobs.Enqueue(ctx, jobSearchIndex, job); err != nil {
+			log.Printf("Queueing search index update failed: %v", err)
+		}
+	}
+
+	bus.Subscribe(events.TypeChirpCreated, func(ctx context.Context, event events.Event) {
+		chirp := event.(events.ChirpCreated).Chirp
+		enqueue(ctx, searchIndexJob{ChirpID: chirp.ID, TenantID: chirp.TenantID})
+	})
+
+	bus.Subscribe(events.TypeChirpDeleted, func(ctx context.Context, event events.Event) {
+		enqueue(ctx, searchIndexJob{ChirpID: event.(events.ChirpDeleted).ChirpID, TenantID: tenantFromContext(ctx)})
+	})
+}
+
+// Job handler that brings one chirp's index entry up to date: indexed while it exists, removed once it doesn't
+func (cfg *apiConfig) runSearchIndexJob(ctx context.Context, raw json.RawMessage) error {
+	var job searchIndexJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return err
+	}
+
+	chirp, err := cfg.databaseQueries.GetIndividualChirp(ctx, database.GetIndividualChirpParams{
+		ID:       job.ChirpID,
+		TenantID: job.TenantID,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return cfg.search.Delete(ctx, job.ChirpID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.search.Index(ctx, newSearchDocument(chirp))
+}
+
+// Job handler that sends one batch of a tenant's chirps to the search cluster, then queues the next batch
+func (cfg *apiConfig) runSearchReindexJob(ctx context.Context, raw json.RawMessage) error {
+	var job searchReindexJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return err
+	}
+
+	chirps, err := cfg.databaseQueries.GetChirpsPage(ctx, database.GetChirpsPageParams{
+		TenantID:   job.TenantID,
+		CursorTime: job.CursorTime,
+		CursorID:   job.CursorID,
+		PageLimit:  searchReindexBatch,
+	})
+	if err != nil {
+		return err
+	}
+
+	docs := make([]search.Document, len(chirps))
+	for i, chirp := range chirps {
+		docs[i] = newSearchDocument(chirp)
+	}
+
+	if err := cfg.search.Index(ctx, docs...); err != nil {
+		return err
+	}
+
+	if len(chirps) < searchReindexBatch {
+		log.Printf("Search reindex of tenant %s finished", job.TenantID)
+		return nil
+	}
+
+	last := chirps[len(chirps)-1]
+	job.CursorTime, job.CursorID = last.CreatedAt, last.ID
+
+	return cfg.jobs.Enqueue(ctx, jobSearchReindex, job)
+}
+
+// POST /admin/search/reindex, sends every chirp in the tenant to the search cluster in the background. Needed
+// after switching SEARCH_BACKEND, chirps posted before then aren't in the index.
+func (cfg *apiConfig) searchReindexHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	if _, ok := cfg.search.(postgresSearch); ok {
+		respondWithError(w, http.StatusConflict, "Search uses Postgres, there's nothing to reindex")
+		return
+	}
+
+	err := cfg.jobs.Enqueue(ctx, jobSearchReindex, searchReindexJob{
+		TenantID:   tenantFromContext(r.Context()),
+		CursorTime: oldestFirst.Time,
+	})
+
+	if err != nil {
+		log.Printf("Queueing search reindex failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GET /api/search?q=..., chirps matching every word of q, newest first and paged with ?cursor=. Takes ?lang=
+// like the other lists. Pages can come back short when matches were hidden from the viewer.
+func (cfg *apiConfig) searchHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if text == "" {
+		respondWithError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	if len(text) > searchMaxQuery {
+		respondWithError(w, http.StatusBadRequest, "q is too long")
+		return
+	}
+
+	lang, err := queryLanguage(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	cursor, err := queryCursor(r, newestFirst)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := queryLimit(r, "limit", 20, 100)
+	tenantID := tenantFromContext(r.Context())
+	viewerID, _ := cfg.optionalUser(r)
+
+	// One extra hit tells us whether there's another page, same as the other lists
+	hits, err := cfg.search.Search(ctx, search.Query{
+		TenantID: tenantID,
+		Text:     text,
+		Language: lang,
+		Before:   cursor.Time,
+		BeforeID: cursor.ID,
+		Limit:    limit + 1,
+	})
+
+	if err != nil {
+		log.Printf("Searching chirps failed: %v", err)
+		respondWithError(w, http.StatusServiceUnavailable, "Search is unavailable right now")
+		return
+	}
+
+	var next string
+	if len(hits) > limit {
+		hits = hits[:limit]
+		last := hits[len(hits)-1]
+		next = pageCursor{Time: last.CreatedAt, ID: last.ID}.String()
+	}
+
+	ids := make([]uuid.UUID, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+
+	// An external index can be a little behind, anything deleted or hidden since just doesn't come back
+	chirps, err := cfg.databaseQueries.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{
+		Ids:      ids,
+		TenantID: tenantID,
+		ViewerID: viewerID,
+	})
+
+	if err != nil {
+		log.Printf("GetChirpsByIDs failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	slices.SortFunc(chirps, func(a, b database.Chirp) int {
+		return slices.Index(ids, a.ID) - slices.Index(ids, b.ID)
+	})
+
+	hidden, err := cfg.hiddenChirps(ctx, viewerID)
+	if err != nil {
+		log.Printf("Loading hidden chirp filters failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+	chirps = slices.DeleteFunc(chirps, hidden)
+
+	cfg.chirpViews.record(chirps, viewerID)
+	response, err := cfg.chirpResponses(ctx, chirps, viewerID)
+
+	if err != nil {
+		log.Printf("Loading chirp links/engagement failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, chirpListResponse{Chirps: response, NextCursor: next})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/auth"
+)
+
+// Cookies for session mode logins. The access token goes everywhere, the refresh token only to /api/session.
+const (
+	sessionAccessCookie  = "chirpy_session"
+	sessionRefreshCookie = "chirpy_refresh"
+	sessionRefreshPath   = "/api/session"
+)
+
+// Access tokens last an hour and refresh tokens 60 days, the cookies go away with them
+const (
+	sessionAccessMaxAge  = time.Hour
+	sessionRefreshMaxAge = 60 * 24 * time.Hour
+)
+
+func (cfg *apiConfig) setSessionCookies(w http.ResponseWriter, accessToken, refreshToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionAccessCookie,
+		Value:    accessToken,
+		Path:     "/",
+		MaxAge:   int(sessionAccessMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.platform != "dev",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionRefreshCookie,
+		Value:    refreshToken,
+		Path:     sessionRefreshPath,
+		MaxAge:   int(sessionRefreshMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.platform != "dev",
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+func (cfg *apiConfig) clearSessionCookies(w http.ResponseWriter) {
+	for name, path := range map[string]string{sessionAccessCookie: "/", sessionRefreshCookie: sessionRefreshPath} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Path:     path,
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   cfg.platform != "dev",
+		})
+	}
+}
+
+// The access token a request carries: the Authorization header, or failing that the session cookie. The cookie
+// only counts on state-changing requests when they also carry the CSRF token.
+func requestToken(r *http.Request) (string, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err == nil {
+		return token, nil
+	}
+
+	if cookie, cookieErr := r.Cookie(sessionAccessCookie); cookieErr == nil && cookie.Value != "" {
+		if !csrfSafeMethod(r.Method) && !validCSRFToken(r) {
+			return "", errCSRFToken
+		}
+
+		return cookie.Value, nil
+	}
+
+	return "", err
+}
+
+// POST /api/session/refresh, the cookie version of /api/refresh: rotates the refresh cookie and renews the access one
+func (cfg *apiConfig) sessionRefreshHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if !validCSRFToken(r) {
+		respondWithError(w, http.StatusForbidden, errCSRFToken.Error())
+		return
+	}
+
+	cookie, err := r.Cookie(sessionRefreshCookie)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "no session")
+		return
+	}
+
+	rotated, err := cfg.rotateRefreshToken(ctx, r, cookie.Value)
+	if err != nil {
+		cfg.clearSessionCookies(w)
+		respondWithRefreshError(w, err)
+		return
+	}
+
+	accessToken, err := cfg.tokenConfig(r.Context()).Make(rotated.UserID, sessionAccessMaxAge, auth.Scopes...)
+	if err != nil {
+		log.Printf("Making session access token failed: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	cfg.setSessionCookies(w, accessToken, rotated.Token)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/session/logout, revokes the session's refresh token and clears both cookies
+func (cfg *apiConfig) sessionLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if !validCSRFToken(r) {
+		respondWithError(w, http.StatusForbidden, errCSRFToken.Error())
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionRefreshCookie); err == nil {
+		if err := cfg.databaseQueries.RevokeRefreshToken(ctx, cookie.Value); err != nil {
+			log.Printf("RevokeRefreshToken failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	cfg.clearSessionCookies(w)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Settings admins can change on a running server, the keys they're saved under
+const (
+	settingRateLimits     = "rate_limits"
+	settingMaxChirpLength = "max_chirp_length"
+	settingSignupsOpen    = "signups_open"
+	settingMaintenance    = "maintenance"
+)
+
+// Longest max_chirp_length an admin can set
+const maxChirpLengthCeiling = 1000
+
+type settingsResponse struct {
+	RateLimits     map[string]map[string]int `json:"rate_limits"`
+	MaxChirpLength int                       `json:"max_chirp_length"`
+	SignupsOpen    bool                      `json:"signups_open"`
+	Maintenance    maintenanceResponse       `json:"maintenance"`
+}
+
+func (cfg *apiConfig) newSettingsResponse() settingsResponse {
+	return settingsResponse{
+		RateLimits:     cfg.currentRateLimits(),
+		MaxChirpLength: int(cfg.maxChirpLength.Load()),
+		SignupsOpen:    !cfg.signupsClosed.Load(),
+		Maintenance:    newMaintenanceResponse(cfg.maintenance.Load()),
+	}
+}
+
+// The value key has on this instance right now, as it's saved
+func (cfg *apiConfig) currentSetting(key string) any {
+	switch key {
+	case settingRateLimits:
+		return cfg.currentRateLimits()
+	case settingMaxChirpLength:
+		return cfg.maxChirpLength.Load()
+	case settingSignupsOpen:
+		return !cfg.signupsClosed.Load()
+	case settingMaintenance:
+		return newMaintenanceResponse(cfg.maintenance.Load())
+	}
+	return nil
+}
+
+// Puts a saved setting into effect on this instance. Keys it doesn't know, say from a newer version, are skipped.
+func (cfg *apiConfig) applySetting(key string, value json.RawMessage) error {
+	switch key {
+	case settingRateLimits:
+		var policies map[string]map[string]int
+		if err := json.Unmarshal(value, &policies); err != nil {
+			return err
+		}
+		cfg.applyRateLimits(policies)
+
+	case settingMaxChirpLength:
+		var n int64
+		if err := json.Unmarshal(value, &n); err != nil {
+			return err
+		}
+		cfg.maxChirpLength.Store(n)
+		cfg.moderation.Store(cfg.newModerationPipeline())
+
+	case settingSignupsOpen:
+		var open bool
+		if err := json.Unmarshal(value, &open); err != nil {
+			return err
+		}
+		cfg.signupsClosed.Store(!open)
+
+	case settingMaintenance:
+		var m maintenanceResponse
+		if err := json.Unmarshal(value, &m); err != nil {
+			return err
+		}
+
+		if !m.Enabled {
+			cfg.maintenance.Store(nil)
+			break
+		}
+
+		state := newMaintenanceState(maintenanceParams{
+			Enabled:           true,
+			Message:           m.Message,
+			RetryAfterSeconds: m.RetryAfterSeconds,
+		})
+		if m.Since != nil {
+			state.Since = *m.Since
+		}
+		cfg.maintenance.Store(state)
+	}
+
+	return nil
+}
+
+// Applies the saved settings, at startup and on every reload. They win over the env.
+func (cfg *apiConfig) loadSettings(ctx context.Context) error {
+	rows, err := cfg.databaseQueries.GetSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := cfg.applySetting(row.Key, row.Value); err != nil {
+			log.Printf("Applying setting %s failed: %v", row.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// Saves changes, setting key to value, and puts them into effect on this instance. Each change is recorded in the
+// audit log with what it was before, in the same transaction.
+func (cfg *apiConfig) saveSettings(ctx context.Context, adminID uuid.UUID, changes map[string]any) error {
+	values := make(map[string]json.RawMessage, len(changes))
+	for key, value := range changes {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		values[key] = data
+	}
+
+	err := database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		for key, value := range changes {
+			err := qtx.UpsertSetting(ctx, database.UpsertSettingParams{
+				Key:       key,
+				Value:     values[key],
+				UpdatedBy: uuid.NullUUID{UUID: adminID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+
+			err = recordAudit(ctx, qtx, adminID, auditSettingChanged, uuid.Nil, map[string]any{
+				"setting": key,
+				"from":    cfg.currentSetting(key),
+				"to":      value,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		if err := cfg.applySetting(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GET /admin/settings
+func (cfg *apiConfig) getSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	if _, ok := cfg.requireGlobalAdmin(ctx, w, r); !ok {
+		return
+	}
+
+	respondWithJson(w, http.StatusOK, cfg.newSettingsResponse())
+}
+
+// PUT /admin/settings, {"signups_open": false, "max_chirp_length": 280}. Settings left out stay as they are, and so
+// do rate limit tiers left out of "rate_limits". Other instances pick changes up on their next reload.
+func (cfg *apiConfig) putSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	adminID, ok := cfg.requireGlobalAdmin(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var params struct {
+		RateLimits     map[string]map[string]int `json:"rate_limits"`
+		MaxChirpLength *int                      `json:"max_chirp_length"`
+		SignupsOpen    *bool                     `json:"signups_open"`
+		Maintenance    *maintenanceParams        `json:"maintenance"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	fieldErrs := validate.Errors{}
+	changes := map[string]any{}
+
+	if params.RateLimits != nil {
+		cfg.checkRateLimits(params.RateLimits, settingRateLimits+".", fieldErrs)
+	}
+
+	if params.MaxChirpLength != nil {
+		if n := *params.MaxChirpLength; n < 1 || n > maxChirpLengthCeiling {
+			fieldErrs[settingMaxChirpLength] = "must be between 1 and 1000"
+		}
+		changes[settingMaxChirpLength] = *params.MaxChirpLength
+	}
+
+	if params.SignupsOpen != nil {
+		changes[settingSignupsOpen] = *params.SignupsOpen
+	}
+
+	if params.Maintenance != nil {
+		for field, msg := range validate.Struct(params.Maintenance) {
+			fieldErrs[settingMaintenance+"."+field] = msg
+		}
+		changes[settingMaintenance] = newMaintenanceResponse(newMaintenanceState(*params.Maintenance))
+	}
+
+	if len(fieldErrs) > 0 {
+		respondWithFieldErrors(w, fieldErrs)
+		return
+	}
+
+	// Merging needs every policy and tier to exist, so it waits until they've been checked
+	if params.RateLimits != nil {
+		changes[settingRateLimits] = cfg.mergeRateLimits(params.RateLimits)
+	}
+
+	if len(changes) > 0 {
+		if err := cfg.saveSettings(ctx, adminID, changes); err != nil {
+			log.Printf("Saving settings failed: %v", err)
+			respondWithDBError(w, http.StatusInternalServerError, err)
+			return
+		}
+		log.Printf("Settings changed by %s", adminID)
+	}
+
+	respondWithJson(w, http.StatusOK, cfg.newSettingsResponse())
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPutSettingsUnknownRateLimit(t *testing.T) {
+	cfg := newFakeConfig(t, newFakeDB(map[string]driver.Value{"is_admin": true, "tenant_id": defaultTenantID.String()}))
+	cfg.rateLimits = []*rateLimitPolicy{newRateLimitPolicy("write", "WRITE_RATE_LIMIT", 30)}
+
+	tests := []struct {
+		name, body string
+		status     int
+	}{
+		{"unknown policy", `{"rate_limits":{"bogus":{"user":1}}}`, http.StatusBadRequest},
+		{"unknown tier", `{"rate_limits":{"write":{"bogus":1}}}`, http.StatusBadRequest},
+		{"known tier", `{"rate_limits":{"write":{"user":1}}}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/admin/settings", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+			rec := httptest.NewRecorder()
+			cfg.putSettingsHandler(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+-- name: RecordUserActivity :exec
+INSERT INTO user_activity (user_id, day, tenant_id)
+SELECT id, CURRENT_DATE, tenant_id
+FROM users
+WHERE id = ANY(sqlc.arg(user_ids)::uuid[])
+ON CONFLICT (user_id, day) DO NOTHING;
+
+-- name: GetActiveUserCounts :one
+SELECT
+    COUNT(DISTINCT user_id) FILTER (WHERE day = CURRENT_DATE) AS dau,
+    COUNT(DISTINCT user_id) FILTER (WHERE day > CURRENT_DATE - 7) AS wau,
+    COUNT(DISTINCT user_id) AS mau
+FROM user_activity
+WHERE tenant_id = $1 AND day > CURRENT_DATE - 30;
+
+-- name: GetDailyActiveUsers :many
+SELECT day, COUNT(*) AS count
+FROM user_activity
+WHERE tenant_id = $1 AND day >= sqlc.arg(since)::date
+GROUP BY day
+ORDER BY day ASC;
+
+-- name: GetSignupsPerDay :many
+SELECT created_at::date AS day, COUNT(*) AS count
+FROM users
+WHERE tenant_id = $1 AND created_at >= sqlc.arg(since)::date
+GROUP BY day
+ORDER BY day ASC;
+
+-- name: GetChirpCounts :many
+-- granularity is anything date_trunc takes, the handlers only pass hour, day, week or month
+SELECT date_trunc(sqlc.arg(granularity)::text, created_at)::timestamp AS bucket, COUNT(*) AS count
+FROM chirps
+WHERE tenant_id = sqlc.arg(tenant_id) AND created_at >= sqlc.arg(since)::timestamp
+GROUP BY bucket
+ORDER BY bucket ASC;
@@ -0,0 +1,11 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (tenant_id, actor_id, action, target_id, details)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetAuditLog :many
+SELECT *
+FROM audit_log
+WHERE tenant_id = sqlc.arg(tenant_id)
+    AND (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,20 @@
+-- name: GetBannedWords :many
+SELECT *
+FROM banned_words
+ORDER BY word ASC;
+
+-- name: CreateBannedWord :one
+INSERT INTO banned_words (word, created_at, is_pattern, severity)
+VALUES (
+    $1, NOW(), $2, $3
+)
+ON CONFLICT (word) DO UPDATE
+SET is_pattern = EXCLUDED.is_pattern,
+    severity = EXCLUDED.severity
+RETURNING *;
+
+-- name: DeleteBannedWord :execrows
+DELETE
+FROM banned_words
+WHERE (is_pattern AND word = $1)
+    OR (NOT is_pattern AND word = LOWER($1));
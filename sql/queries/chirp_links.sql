@@ -0,0 +1,26 @@
+-- name: CreateChirpLink :one
+INSERT INTO chirp_links (id, created_at, chirp_id, position, url)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetChirpLink :one
+SELECT *
+FROM chirp_links
+WHERE id = $1;
+
+-- name: UpdateChirpLinkPreview :exec
+UPDATE chirp_links
+    SET title = $2,
+        description = $3,
+        image_url = $4,
+        fetch_error = $5,
+        fetched_at = NOW()
+WHERE id = $1;
+
+-- name: GetLinksForChirps :many
+SELECT *
+FROM chirp_links
+WHERE chirp_id = ANY(@chirp_ids::uuid[])
+ORDER BY chirp_id, position;
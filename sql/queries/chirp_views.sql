@@ -0,0 +1,14 @@
+-- name: AddChirpViews :exec
+-- Chirps deleted since the views were counted are dropped by the join rather than failing the batch
+INSERT INTO chirp_views (chirp_id, day, views)
+SELECT v.chirp_id, CURRENT_DATE, v.views
+FROM unnest(sqlc.arg(chirp_ids)::uuid[], sqlc.arg(views)::bigint[]) AS v(chirp_id, views)
+JOIN chirps c ON c.id = v.chirp_id
+ON CONFLICT (chirp_id, day) DO UPDATE
+SET views = chirp_views.views + EXCLUDED.views;
+
+-- name: GetChirpViewsByDay :many
+SELECT day, views
+FROM chirp_views
+WHERE chirp_id = $1 AND day >= sqlc.arg(since)::date
+ORDER BY day ASC;
@@ -1,7 +1,7 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, content_hash, reply_to_id, tenant_id, sensitive, content_warning, language, visibility)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7, $8, $9
 )
 RETURNING *;
 
@@ -9,15 +9,139 @@ RETURNING *;
 -- name: GetChirps :many
 SELECT *
 FROM chirps
-ORDER BY created_at ASC;
+WHERE tenant_id = sqlc.arg(tenant_id)
+    AND created_at >= sqlc.arg(since)::timestamp AND created_at < sqlc.arg(before)::timestamp
+    AND (created_at, id) > (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR user_id NOT IN (
+            SELECT id FROM users
+            WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+                OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+        ))
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+
+-- name: GetChirpsByIDs :many
+-- The chirps in ids that viewer_id can see, in no particular order
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND tenant_id = sqlc.arg(tenant_id)
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR user_id NOT IN (
+            SELECT id FROM users
+            WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+                OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+        ))
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)));
 
 
 -- name: GetIndividualChirp :one
 SELECT *
 FROM chirps
-WHERE id = $1;
+WHERE id = $1 AND tenant_id = $2
+    AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL);
+
+-- name: GetVisibleChirp :one
+-- GetIndividualChirp for when viewer_id is asking, no rows when it isn't theirs to see
+SELECT *
+FROM chirps
+WHERE id = sqlc.arg(id) AND tenant_id = sqlc.arg(tenant_id)
+    AND user_id NOT IN (SELECT id FROM users WHERE deactivated_at IS NOT NULL)
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)));
 
 -- name: DeleteChirpByID :exec
 DELETE 
 FROM chirps 
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: DeleteChirps :exec
+TRUNCATE TABLE chirps CASCADE;
+
+-- name: DeleteChirpsForTenant :exec
+DELETE FROM chirps
+WHERE tenant_id = $1;
+
+-- name: CountRecentDuplicateChirps :one
+SELECT COUNT(*)
+FROM chirps
+WHERE user_id = $1 AND content_hash = $2 AND created_at >= $3;
+
+-- name: CountRecentChirpsByUser :one
+SELECT COUNT(*)
+FROM chirps
+WHERE user_id = $1 AND created_at >= $2;
+
+-- name: GetChirpsByUser :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND tenant_id = sqlc.arg(tenant_id)
+    AND (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+    AND (user_id = sqlc.arg(viewer_id)::uuid OR user_id NOT IN (
+        SELECT id FROM users
+        WHERE shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+    ))
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpsByUserAsc :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg(user_id) AND tenant_id = sqlc.arg(tenant_id)
+    AND (created_at, id) > (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+    AND (user_id = sqlc.arg(viewer_id)::uuid OR user_id NOT IN (
+        SELECT id FROM users
+        WHERE shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+    ))
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (user_id = sqlc.arg(viewer_id)::uuid
+        OR (visibility = 'public' AND user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (visibility <> 'private' AND user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpAuthors :many
+SELECT DISTINCT u.id, u.handle, u.display_name, u.avatar_url
+FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: CreateChirps :many
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, content_hash, tenant_id, language)
+SELECT gen_random_uuid(), c.created_at, c.created_at, c.body, c.user_id, c.content_hash, sqlc.arg(tenant_id)::uuid,
+    NULLIF(c.language, '')
+FROM unnest(
+    sqlc.arg(bodies)::text[],
+    sqlc.arg(user_ids)::uuid[],
+    sqlc.arg(content_hashes)::text[],
+    sqlc.arg(created_ats)::timestamp[],
+    sqlc.arg(languages)::text[]
+) AS c(body, user_id, content_hash, created_at, language)
+RETURNING *;
+
+-- name: GetChirpsPage :many
+SELECT *
+FROM chirps
+WHERE tenant_id = sqlc.arg(tenant_id)
+    AND (created_at, id) > (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,66 @@
+-- name: GetChirpAncestors :many
+-- The chirps chirp_id replies to, its parent's parent and so on up to max_depth levels, root first.
+-- depth counts up from the chirp, its parent is 1.
+WITH RECURSIVE ancestors AS (
+    SELECT c.reply_to_id AS id, 1 AS depth
+    FROM chirps c
+    WHERE c.id = sqlc.arg(chirp_id)::uuid AND c.reply_to_id IS NOT NULL
+    UNION ALL
+    SELECT c.reply_to_id, a.depth + 1
+    FROM chirps c
+    JOIN ancestors a ON c.id = a.id
+    WHERE c.reply_to_id IS NOT NULL AND a.depth < sqlc.arg(max_depth)::int
+)
+SELECT sqlc.embed(c), a.depth
+FROM ancestors a
+JOIN chirps c ON c.id = a.id
+WHERE c.tenant_id = sqlc.arg(tenant_id)
+    AND (c.user_id = sqlc.arg(viewer_id)::uuid OR c.user_id NOT IN (
+        SELECT id FROM users
+        WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+    ))
+    AND (c.user_id = sqlc.arg(viewer_id)::uuid
+        OR (c.visibility = 'public' AND c.user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (c.visibility <> 'private' AND c.user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+ORDER BY a.depth DESC;
+
+-- name: GetChirpReplyTree :many
+-- A page of chirp_id's direct replies, oldest first after the cursor, with their own replies down to max_depth.
+-- Replies from hidden users, or that viewer_id can't see, are left out along with everything under them.
+-- Shallowest first, capped at max_rows.
+WITH RECURSIVE hidden_users AS (
+    SELECT id FROM users
+    WHERE id <> sqlc.arg(viewer_id)::uuid
+        AND (deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW())))
+),
+thread AS (
+    (SELECT c.id, 1 AS depth
+    FROM chirps c
+    WHERE c.reply_to_id = sqlc.arg(chirp_id)::uuid AND c.tenant_id = sqlc.arg(tenant_id)
+        AND (c.created_at, c.id) > (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+        AND c.user_id NOT IN (SELECT id FROM hidden_users)
+        AND (c.user_id = sqlc.arg(viewer_id)::uuid
+            OR (c.visibility = 'public' AND c.user_id NOT IN (SELECT id FROM users WHERE protected))
+            OR (c.visibility <> 'private' AND c.user_id IN (
+                SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+    ORDER BY c.created_at ASC, c.id ASC
+    LIMIT sqlc.arg(page_limit)::int)
+    UNION ALL
+    SELECT c.id, t.depth + 1
+    FROM chirps c
+    JOIN thread t ON c.reply_to_id = t.id
+    WHERE t.depth < sqlc.arg(max_depth)::int
+        AND c.user_id NOT IN (SELECT id FROM hidden_users)
+        AND (c.user_id = sqlc.arg(viewer_id)::uuid
+            OR (c.visibility = 'public' AND c.user_id NOT IN (SELECT id FROM users WHERE protected))
+            OR (c.visibility <> 'private' AND c.user_id IN (
+                SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+)
+SELECT sqlc.embed(c), t.depth
+FROM thread t
+JOIN chirps c ON c.id = t.id
+ORDER BY t.depth ASC, c.created_at ASC, c.id ASC
+LIMIT sqlc.arg(max_rows);
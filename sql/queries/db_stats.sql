@@ -0,0 +1,7 @@
+-- name: GetTableStats :many
+-- Every table's live row count as Postgres last estimated it (counting them exactly means scanning every table) and its size on disk including indexes
+SELECT relname::text AS table_name,
+    n_live_tup AS row_estimate,
+    pg_total_relation_size(relid) AS total_bytes
+FROM pg_stat_user_tables
+ORDER BY relname;
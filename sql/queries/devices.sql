@@ -0,0 +1,45 @@
+-- name: CreateDevice :one
+INSERT INTO devices (user_id, token_hash, user_agent, last_ip, confirmed_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetDeviceByToken :one
+SELECT *
+FROM devices
+WHERE user_id = $1 AND token_hash = $2;
+
+-- name: GetDevicesForUser :many
+SELECT *
+FROM devices
+WHERE user_id = $1
+ORDER BY last_seen_at DESC;
+
+-- name: CountDevicesForUser :one
+SELECT COUNT(*)
+FROM devices
+WHERE user_id = $1;
+
+-- name: TouchDevice :exec
+UPDATE devices
+    SET last_seen_at = NOW(), user_agent = $2, last_ip = $3
+WHERE id = $1;
+
+-- name: RenameDevice :execrows
+UPDATE devices
+    SET name = $3
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteDevice :execrows
+DELETE FROM devices
+WHERE id = $1 AND user_id = $2;
+
+-- name: SetDeviceConfirmToken :exec
+UPDATE devices
+    SET confirm_token_hash = $2, confirm_expires_at = $3
+WHERE id = $1;
+
+-- name: ConfirmDevice :one
+UPDATE devices
+    SET confirmed_at = NOW(), confirm_token_hash = NULL, confirm_expires_at = NULL
+WHERE confirm_token_hash = $1 AND confirm_expires_at > NOW()
+RETURNING *;
@@ -0,0 +1,15 @@
+-- name: CreateEmailChange :one
+INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id) DO UPDATE
+    SET created_at = NOW(), new_email = EXCLUDED.new_email, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: ConsumeEmailChange :one
+DELETE FROM email_changes
+WHERE token_hash = $1 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredEmailChanges :exec
+DELETE FROM email_changes
+WHERE expires_at <= NOW();
@@ -0,0 +1,30 @@
+-- name: LikeChirp :execrows
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: Rechirp :execrows
+INSERT INTO rechirps (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UndoRechirp :execrows
+DELETE FROM rechirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetChirpEngagement :many
+SELECT c.id AS chirp_id,
+    (SELECT COUNT(*) FROM likes l WHERE l.chirp_id = c.id) AS like_count,
+    (SELECT COUNT(*) FROM chirps r WHERE r.reply_to_id = c.id) AS reply_count,
+    (SELECT COUNT(*) FROM rechirps rc WHERE rc.chirp_id = c.id) AS rechirp_count,
+    (SELECT COALESCE(SUM(v.views), 0) FROM chirp_views v WHERE v.chirp_id = c.id)::bigint AS view_count,
+    EXISTS (
+        SELECT 1 FROM likes mine
+        WHERE mine.chirp_id = c.id AND mine.user_id = sqlc.arg(viewer_id)
+    ) AS liked_by_me
+FROM chirps c
+WHERE c.id = ANY(sqlc.arg(chirp_ids)::uuid[]);
@@ -0,0 +1,62 @@
+-- name: GetLatestFeed :many
+-- The viewer's own chirps and those of everyone they follow, newest first
+SELECT c.*
+FROM chirps c
+WHERE c.tenant_id = sqlc.arg(tenant_id)
+    AND (c.user_id = sqlc.arg(viewer_id)::uuid
+        OR c.user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid))
+    AND (c.created_at, c.id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+    AND (c.user_id = sqlc.arg(viewer_id)::uuid OR c.user_id NOT IN (
+        SELECT id FROM users
+        WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+            OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+    ))
+    AND (sqlc.arg(language)::text = '' OR c.language = sqlc.arg(language)::text)
+    AND (c.user_id = sqlc.arg(viewer_id)::uuid
+        OR (c.visibility = 'public' AND c.user_id NOT IN (SELECT id FROM users WHERE protected))
+        OR (c.visibility <> 'private' AND c.user_id IN (
+            SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)))
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetFeedCandidates :many
+-- Chirps a ranked feed picks from: the newest from the viewer and who they follow, plus the most engaged-with
+-- from anyone, all posted since since and in language when it isn't empty
+WITH followed AS (
+    SELECT followee_id AS user_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid
+    UNION ALL
+    SELECT sqlc.arg(viewer_id)::uuid
+),
+candidates AS (
+    (SELECT c.id
+    FROM chirps c
+    WHERE c.tenant_id = sqlc.arg(tenant_id) AND c.created_at >= sqlc.arg(since)::timestamp
+        AND (sqlc.arg(language)::text = '' OR c.language = sqlc.arg(language)::text)
+        AND c.user_id IN (SELECT user_id FROM followed)
+    ORDER BY c.created_at DESC
+    LIMIT sqlc.arg(followed_limit)::int)
+    UNION
+    (SELECT c.id
+    FROM chirps c
+    WHERE c.tenant_id = sqlc.arg(tenant_id) AND c.created_at >= sqlc.arg(since)::timestamp
+        AND (sqlc.arg(language)::text = '' OR c.language = sqlc.arg(language)::text)
+    ORDER BY (SELECT COUNT(*) FROM likes l WHERE l.chirp_id = c.id)
+        + (SELECT COUNT(*) FROM rechirps rc WHERE rc.chirp_id = c.id) DESC
+    LIMIT sqlc.arg(popular_limit)::int)
+)
+SELECT sqlc.embed(c),
+    (c.user_id IN (SELECT user_id FROM followed))::boolean AS followed,
+    (SELECT COUNT(*) FROM likes l WHERE l.chirp_id = c.id) AS like_count,
+    (SELECT COUNT(*) FROM chirps r WHERE r.reply_to_id = c.id) AS reply_count,
+    (SELECT COUNT(*) FROM rechirps rc WHERE rc.chirp_id = c.id) AS rechirp_count
+FROM chirps c
+JOIN candidates USING (id)
+WHERE (c.user_id = sqlc.arg(viewer_id)::uuid OR c.user_id NOT IN (
+    SELECT id FROM users
+    WHERE deactivated_at IS NOT NULL OR shadow_banned_at IS NOT NULL
+        OR (banned_at IS NOT NULL AND (banned_until IS NULL OR banned_until > NOW()))
+))
+AND (c.user_id = sqlc.arg(viewer_id)::uuid
+    OR (c.visibility = 'public' AND c.user_id NOT IN (SELECT id FROM users WHERE protected))
+    OR (c.visibility <> 'private' AND c.user_id IN (
+        SELECT followee_id FROM follows WHERE follower_id = sqlc.arg(viewer_id)::uuid)));
@@ -0,0 +1,32 @@
+-- name: CreateFollowRequest :execrows
+INSERT INTO follow_requests (requester_id, target_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests
+WHERE requester_id = $1 AND target_id = $2;
+
+-- name: GetFollowRequests :many
+-- Who's waiting on target_id to approve them, newest first after the cursor
+SELECT u.id, u.handle, fr.created_at AS requested_at
+FROM follow_requests fr
+JOIN users u ON u.id = fr.requester_id
+WHERE fr.target_id = sqlc.arg(target_id)
+    AND u.deactivated_at IS NULL
+    AND (fr.created_at, fr.requester_id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY fr.created_at DESC, fr.requester_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: AcceptAllFollowRequests :many
+-- Turns every request waiting on target_id into a follow, returning who now follows them
+WITH accepted AS (
+    DELETE FROM follow_requests
+    WHERE target_id = $1
+    RETURNING requester_id, target_id
+)
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT requester_id, target_id, NOW()
+FROM accepted
+ON CONFLICT DO NOTHING
+RETURNING follower_id;
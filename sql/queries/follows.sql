@@ -0,0 +1,42 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    WHERE follower_id = $1 AND followee_id = $2
+);
+
+-- name: GetFollowers :many
+SELECT u.id, u.handle, f.created_at AS followed_at,
+    EXISTS (
+        SELECT 1 FROM follows mine
+        WHERE mine.follower_id = sqlc.arg(viewer_id) AND mine.followee_id = u.id
+    ) AS is_following
+FROM follows f
+JOIN users u ON u.id = f.follower_id
+WHERE f.followee_id = sqlc.arg(user_id)
+    AND u.deactivated_at IS NULL
+    AND (f.created_at, f.follower_id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY f.created_at DESC, f.follower_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetFollowing :many
+SELECT u.id, u.handle, f.created_at AS followed_at,
+    EXISTS (
+        SELECT 1 FROM follows mine
+        WHERE mine.follower_id = sqlc.arg(viewer_id) AND mine.followee_id = u.id
+    ) AS is_following
+FROM follows f
+JOIN users u ON u.id = f.followee_id
+WHERE f.follower_id = sqlc.arg(user_id)
+    AND u.deactivated_at IS NULL
+    AND (f.created_at, f.followee_id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY f.created_at DESC, f.followee_id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,16 @@
+-- name: AddHandleHistory :exec
+INSERT INTO handle_history (tenant_id, handle, user_id)
+VALUES ($1, $2, $3);
+
+-- name: CountHandleChangesSince :one
+SELECT COUNT(*)
+FROM handle_history
+WHERE user_id = $1 AND changed_at > $2;
+
+-- name: GetUserByOldHandle :one
+SELECT u.id, u.handle
+FROM handle_history h
+JOIN users u ON u.id = h.user_id
+WHERE h.tenant_id = $1 AND h.handle = $2 AND u.deactivated_at IS NULL
+ORDER BY h.changed_at DESC
+LIMIT 1;
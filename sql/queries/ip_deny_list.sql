@@ -0,0 +1,16 @@
+-- name: GetDeniedIPs :many
+SELECT *
+FROM ip_deny_list
+ORDER BY cidr ASC;
+
+-- name: CreateDeniedIP :one
+INSERT INTO ip_deny_list (cidr, reason)
+VALUES ($1, $2)
+ON CONFLICT (cidr) DO UPDATE
+SET reason = EXCLUDED.reason
+RETURNING *;
+
+-- name: DeleteDeniedIP :execrows
+DELETE
+FROM ip_deny_list
+WHERE cidr = $1;
@@ -0,0 +1,53 @@
+-- name: EnqueueJob :one
+INSERT INTO jobs (id, created_at, updated_at, kind, payload, status, attempts, max_attempts, run_at)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, 'pending', 0, $3, $4
+)
+RETURNING *;
+
+-- name: ClaimJob :one
+UPDATE jobs
+    SET status = 'running',
+        attempts = attempts + 1,
+        updated_at = NOW()
+WHERE id = (
+    SELECT id
+    FROM jobs
+    WHERE status = 'pending' AND run_at <= NOW()
+    ORDER BY run_at ASC
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+    SET status = 'done',
+        last_error = NULL,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+    SET status = 'pending',
+        run_at = $2,
+        last_error = $3,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailJob :exec
+UPDATE jobs
+    SET status = 'dead',
+        last_error = $2,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: RequeueStaleJobs :execrows
+UPDATE jobs
+    SET status = 'pending',
+        updated_at = NOW()
+WHERE status = 'running' AND updated_at < $1;
+
+-- name: DeleteFinishedJobs :execrows
+DELETE FROM jobs
+WHERE status = 'done' AND updated_at < $1;
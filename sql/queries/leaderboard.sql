@@ -0,0 +1,24 @@
+-- name: GetTopChirpers :many
+SELECT u.id, u.handle, u.display_name, u.avatar_url, COUNT(*) AS score
+FROM chirps c
+JOIN users u ON u.id = c.user_id
+WHERE c.tenant_id = sqlc.arg(tenant_id) AND c.created_at >= sqlc.arg(since)::timestamp
+    AND u.deactivated_at IS NULL AND u.shadow_banned_at IS NULL
+    AND NOT (u.banned_at IS NOT NULL AND (u.banned_until IS NULL OR u.banned_until > NOW()))
+GROUP BY u.id
+ORDER BY score DESC, u.id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetTopLikedUsers :many
+-- Likes are counted by when they were given, and liking your own chirps doesn't count
+SELECT u.id, u.handle, u.display_name, u.avatar_url, COUNT(*) AS score
+FROM likes l
+JOIN chirps c ON c.id = l.chirp_id
+JOIN users u ON u.id = c.user_id
+WHERE c.tenant_id = sqlc.arg(tenant_id) AND l.created_at >= sqlc.arg(since)::timestamp
+    AND l.user_id <> c.user_id
+    AND u.deactivated_at IS NULL AND u.shadow_banned_at IS NULL
+    AND NOT (u.banned_at IS NOT NULL AND (u.banned_until IS NULL OR u.banned_until > NOW()))
+GROUP BY u.id
+ORDER BY score DESC, u.id ASC
+LIMIT sqlc.arg(page_limit);
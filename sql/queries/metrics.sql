@@ -0,0 +1,23 @@
+-- name: GetMetric :one
+SELECT value
+FROM metrics
+WHERE name = $1;
+
+-- name: UpsertMetric :exec
+-- Adds value to the stored count, so several instances flushing don't overwrite each other's
+INSERT INTO metrics (name, value, updated_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT (name) DO UPDATE
+SET value = metrics.value + EXCLUDED.value,
+    updated_at = NOW();
+
+-- name: SetMetric :exec
+INSERT INTO metrics (name, value, updated_at)
+VALUES (
+    $1, $2, NOW()
+)
+ON CONFLICT (name) DO UPDATE
+SET value = EXCLUDED.value,
+    updated_at = NOW();
@@ -0,0 +1,32 @@
+-- name: GetMutedWords :many
+SELECT *
+FROM muted_words
+WHERE user_id = $1
+ORDER BY phrase ASC;
+
+-- name: CountMutedWords :one
+SELECT COUNT(*)
+FROM muted_words
+WHERE user_id = $1;
+
+-- name: AddMutedWord :one
+INSERT INTO muted_words (user_id, phrase)
+VALUES ($1, $2)
+ON CONFLICT (user_id, phrase) DO UPDATE
+SET phrase = EXCLUDED.phrase
+RETURNING *;
+
+-- name: DeleteMutedWord :execrows
+DELETE
+FROM muted_words
+WHERE user_id = $1 AND phrase = $2;
+
+-- name: IsChirpMuted :one
+-- Whether chirpID contains any of userID's muted words. Their own chirps never count as muted.
+SELECT EXISTS (
+    SELECT 1
+    FROM chirps c
+    JOIN muted_words m ON m.user_id = sqlc.arg(user_id)
+    WHERE c.id = sqlc.arg(chirp_id) AND c.user_id <> sqlc.arg(user_id)
+        AND position(m.phrase IN lower(c.body)) > 0
+) AS muted;
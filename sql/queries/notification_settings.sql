@@ -0,0 +1,12 @@
+-- name: GetNotificationSettings :one
+SELECT *
+FROM notification_settings
+WHERE user_id = $1;
+
+-- name: UpsertNotificationSettings :exec
+INSERT INTO notification_settings (user_id, disabled_kinds, email)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET disabled_kinds = EXCLUDED.disabled_kinds,
+    email = EXCLUDED.email,
+    updated_at = NOW();
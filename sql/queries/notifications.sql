@@ -0,0 +1,43 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetNotificationsForUser :many
+-- Leaves out notifications about other people's chirps that contain one of the user's muted words
+SELECT n.*
+FROM notifications n
+WHERE n.user_id = $1
+    AND NOT EXISTS (
+        SELECT 1
+        FROM chirps c
+        JOIN muted_words m ON m.user_id = n.user_id
+        WHERE c.id = n.chirp_id AND c.user_id <> n.user_id
+            AND position(m.phrase IN lower(c.body)) > 0
+    )
+ORDER BY n.created_at DESC
+LIMIT $2;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications n
+WHERE n.user_id = $1 AND n.read_at IS NULL
+    AND NOT EXISTS (
+        SELECT 1
+        FROM chirps c
+        JOIN muted_words m ON m.user_id = n.user_id
+        WHERE c.id = n.chirp_id AND c.user_id <> n.user_id
+            AND position(m.phrase IN lower(c.body)) > 0
+    );
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+    SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+    SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
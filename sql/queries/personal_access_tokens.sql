@@ -0,0 +1,34 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at, client_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT personal_access_tokens.*
+FROM personal_access_tokens
+JOIN users ON users.id = personal_access_tokens.user_id
+WHERE personal_access_tokens.token_hash = $1
+    AND users.tenant_id = $2
+    AND personal_access_tokens.revoked_at IS NULL
+    AND (personal_access_tokens.expires_at IS NULL OR personal_access_tokens.expires_at > NOW());
+
+-- name: GetPersonalAccessTokensForUser :many
+SELECT *
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+    SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+    SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: RevokePersonalAccessTokensForUser :exec
+UPDATE personal_access_tokens
+    SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,31 @@
+-- name: UpsertPushSubscription :one
+INSERT INTO push_subscriptions (id, created_at, updated_at, user_id, endpoint, p256dh, auth)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+ON CONFLICT (endpoint) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+    p256dh = EXCLUDED.p256dh,
+    auth = EXCLUDED.auth,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetPushSubscriptionsForUser :many
+SELECT *
+FROM push_subscriptions
+WHERE user_id = $1;
+
+-- name: GetPushSubscription :one
+SELECT *
+FROM push_subscriptions
+WHERE id = $1;
+
+-- name: DeletePushSubscription :execrows
+DELETE
+FROM push_subscriptions
+WHERE endpoint = $1 AND user_id = $2;
+
+-- name: DeletePushSubscriptionByID :exec
+DELETE
+FROM push_subscriptions
+WHERE id = $1;
@@ -16,4 +16,25 @@ UPDATE refresh_tokens
 SET 
     revoked_at = NOW(),
     updated_at = NOW()
-WHERE token = $1;
\ No newline at end of file
+WHERE token = $1;
+
+-- name: GetRefreshTokenForUpdate :one
+SELECT *
+FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE;
+
+-- name: DeleteStaleRefreshTokens :execrows
+DELETE
+FROM refresh_tokens
+WHERE revoked_at IS NOT NULL OR expires_at < NOW();
+
+-- name: DeleteRefreshTokens :exec
+TRUNCATE TABLE refresh_tokens;
+
+-- name: RevokeRefreshTokensForUser :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
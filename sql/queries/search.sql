@@ -0,0 +1,10 @@
+-- name: SearchChirps :many
+-- Chirps in tenant_id matching query in websearch syntax, newest first after the cursor
+SELECT id, created_at
+FROM chirps
+WHERE tenant_id = sqlc.arg(tenant_id)
+    AND to_tsvector('simple', body) @@ websearch_to_tsquery('simple', sqlc.arg(query)::text)
+    AND (sqlc.arg(language)::text = '' OR language = sqlc.arg(language)::text)
+    AND (created_at, id) < (sqlc.arg(cursor_time)::timestamp, sqlc.arg(cursor_id)::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
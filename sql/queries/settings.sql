@@ -0,0 +1,12 @@
+-- name: GetSettings :many
+SELECT *
+FROM settings
+ORDER BY key ASC;
+
+-- name: UpsertSetting :exec
+INSERT INTO settings (key, value, updated_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (key) DO UPDATE
+SET value = EXCLUDED.value,
+    updated_at = NOW(),
+    updated_by = EXCLUDED.updated_by;
@@ -0,0 +1,4 @@
+-- name: GetTenants :many
+SELECT *
+FROM tenants
+ORDER BY slug ASC;
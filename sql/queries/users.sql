@@ -1,29 +1,38 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, tenant_id)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
 )
-RETURNING id, created_at, updated_at, email;
+RETURNING id, created_at, updated_at, email, is_chirpy_red;
 
 -- name: DeleteUsers :exec
 TRUNCATE TABLE users CASCADE;
 
+-- name: DeleteUsersForTenant :exec
+DELETE FROM users
+WHERE tenant_id = $1;
+
 -- name: GetUserByEmail :one
 SELECT *
 FROM users
-WHERE email = $1;
+WHERE email = $1 AND tenant_id = $2;
 
 
 -- name: UpdateUserPassword :exec
 UPDATE users
     SET hashed_password = $1,
-        email = $2,
         updated_at = NOW()
-WHERE id = $3;
+WHERE id = $2;
+
+-- name: UpdateUserEmail :exec
+UPDATE users
+    SET email = $2,
+        updated_at = NOW()
+WHERE id = $1;
 
 
 -- name: GetUserByIDNoPassword :one
-SELECT id, created_at, updated_at, email
+SELECT id, created_at, updated_at, email, is_chirpy_red
 FROM users
 WHERE id = $1;
 
@@ -32,4 +41,101 @@ WHERE id = $1;
 UPDATE users
     SET is_chirpy_red = false,
         updated_at = NOW()
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserIsAdmin :one
+SELECT is_admin, tenant_id
+FROM users
+WHERE id = $1;
+
+-- name: GetUserProfile :one
+SELECT id, created_at, email, is_chirpy_red, pinned_chirp_id, handle, locale, timezone, sensitive_content, protected
+FROM users
+WHERE id = $1 AND tenant_id = $2 AND deactivated_at IS NULL;
+
+-- name: SetPinnedChirp :exec
+UPDATE users
+    SET pinned_chirp_id = $2,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: ClearPinnedChirp :execrows
+UPDATE users
+    SET pinned_chirp_id = NULL,
+        updated_at = NOW()
+WHERE id = $1 AND pinned_chirp_id = $2;
+
+-- name: GetUserIDByHandle :one
+SELECT id
+FROM users
+WHERE handle = $1 AND tenant_id = $2 AND deactivated_at IS NULL;
+
+-- name: SetUserHandle :exec
+UPDATE users
+    SET handle = $2,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: UserExists :one
+SELECT EXISTS (
+    SELECT 1 FROM users WHERE id = $1 AND tenant_id = $2 AND deactivated_at IS NULL
+);
+
+-- name: SetUserPreferences :exec
+-- An empty sensitive_content leaves the current setting alone
+UPDATE users
+    SET locale = $2,
+        timezone = $3,
+        sensitive_content = COALESCE(NULLIF(sqlc.arg(sensitive_content)::text, ''), sensitive_content),
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserProtected :exec
+UPDATE users
+    SET protected = $2,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUserHandleForUpdate :one
+SELECT handle, tenant_id
+FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: DeactivateUser :execrows
+UPDATE users
+    SET deactivated_at = NOW(),
+        updated_at = NOW()
+WHERE id = $1 AND deactivated_at IS NULL;
+
+-- name: ReactivateUser :exec
+UPDATE users
+    SET deactivated_at = NULL,
+        updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteDeactivatedUsers :execrows
+DELETE FROM users
+WHERE deactivated_at < $1;
+
+-- name: SetUserShadowBan :execrows
+UPDATE users
+    SET shadow_banned_at = $2,
+        updated_at = NOW()
+WHERE id = $1 AND tenant_id = $3;
+
+-- name: BanUser :execrows
+UPDATE users
+    SET banned_at = NOW(),
+        banned_until = $2,
+        ban_reason = $3,
+        updated_at = NOW()
+WHERE id = $1 AND tenant_id = $4;
+
+-- name: UnbanUser :execrows
+UPDATE users
+    SET banned_at = NULL,
+        banned_until = NULL,
+        ban_reason = NULL,
+        updated_at = NOW()
+WHERE id = $1 AND tenant_id = $2;
@@ -0,0 +1,28 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, events, secret)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetWebhooksByUser :many
+SELECT *
+FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetWebhooksForEvent :many
+SELECT w.*
+FROM webhooks w
+JOIN users u ON u.id = w.user_id
+WHERE @event::text = ANY(w.events) AND u.tenant_id = @tenant_id;
+
+-- name: DeleteWebhook :execrows
+DELETE
+FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhook :one
+SELECT *
+FROM webhooks
+WHERE id = $1;
@@ -0,0 +1,9 @@
+-- 011_users_is_admin.sql
+
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN is_admin;
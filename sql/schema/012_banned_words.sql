@@ -0,0 +1,14 @@
+-- 012_banned_words.sql
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS banned_words (
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO banned_words (word, created_at)
+VALUES ('kerfuffle', NOW()), ('sharbert', NOW()), ('fornax', NOW())
+ON CONFLICT (word) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS banned_words;
@@ -0,0 +1,13 @@
+-- 013_chirps_content_hash.sql
+
+-- +goose Up
+ALTER TABLE chirps
+    ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS chirps_user_hash_idx ON chirps (user_id, content_hash, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_user_hash_idx;
+
+ALTER TABLE chirps
+    DROP COLUMN content_hash;
@@ -0,0 +1,19 @@
+-- 014_chirp_links.sql
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS chirp_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    title TEXT,
+    description TEXT,
+    image_url TEXT,
+    fetched_at TIMESTAMP,
+    fetch_error TEXT,
+    UNIQUE (chirp_id, position)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_links;
@@ -0,0 +1,9 @@
+-- 016_users_handle.sql
+
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN handle TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN handle;
@@ -0,0 +1,15 @@
+-- 017_follows.sql
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS follows_followee_idx ON follows (followee_id, created_at DESC, follower_id DESC);
+CREATE INDEX IF NOT EXISTS follows_follower_idx ON follows (follower_id, created_at DESC, followee_id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS follows;
@@ -0,0 +1,33 @@
+-- 018_chirp_engagement.sql
+
+-- +goose Up
+ALTER TABLE chirps
+    ADD COLUMN reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS chirps_reply_to_idx ON chirps (reply_to_id);
+
+CREATE TABLE IF NOT EXISTS likes (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX IF NOT EXISTS likes_chirp_idx ON likes (chirp_id);
+
+CREATE TABLE IF NOT EXISTS rechirps (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX IF NOT EXISTS rechirps_chirp_idx ON rechirps (chirp_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS rechirps;
+DROP TABLE IF EXISTS likes;
+DROP INDEX IF EXISTS chirps_reply_to_idx;
+
+ALTER TABLE chirps
+    DROP COLUMN reply_to_id;
@@ -0,0 +1,11 @@
+-- 019_users_display_name.sql
+
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN display_name TEXT,
+    ADD COLUMN avatar_url TEXT;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN avatar_url,
+    DROP COLUMN display_name;
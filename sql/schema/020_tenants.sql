@@ -0,0 +1,46 @@
+-- 020_tenants.sql
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tenants (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    slug TEXT NOT NULL UNIQUE,
+    host TEXT UNIQUE,
+    name TEXT NOT NULL
+);
+
+-- Everything that existed before tenants lands here, and it's the only tenant used when MULTI_TENANT is off
+INSERT INTO tenants (id, slug, name)
+VALUES ('00000000-0000-0000-0000-000000000000', 'default', 'Default');
+
+ALTER TABLE users
+    ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id) ON DELETE CASCADE;
+
+ALTER TABLE chirps
+    ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000' REFERENCES tenants(id) ON DELETE CASCADE;
+
+-- Emails and handles only have to be unique within a tenant
+ALTER TABLE users
+    DROP CONSTRAINT users_email_key,
+    DROP CONSTRAINT users_handle_key,
+    ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email),
+    ADD CONSTRAINT users_tenant_handle_key UNIQUE (tenant_id, handle);
+
+CREATE INDEX IF NOT EXISTS chirps_tenant_created_idx ON chirps (tenant_id, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_tenant_created_idx;
+
+ALTER TABLE users
+    DROP CONSTRAINT users_tenant_handle_key,
+    DROP CONSTRAINT users_tenant_email_key,
+    ADD CONSTRAINT users_email_key UNIQUE (email),
+    ADD CONSTRAINT users_handle_key UNIQUE (handle);
+
+ALTER TABLE chirps
+    DROP COLUMN tenant_id;
+
+ALTER TABLE users
+    DROP COLUMN tenant_id;
+
+DROP TABLE IF EXISTS tenants;
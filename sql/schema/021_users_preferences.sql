@@ -0,0 +1,11 @@
+-- 021_users_preferences.sql
+
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN locale TEXT NOT NULL DEFAULT 'en',
+    ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN timezone,
+    DROP COLUMN locale;
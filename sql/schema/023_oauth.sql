@@ -0,0 +1,34 @@
+-- 023_oauth.sql
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    redirect_uris TEXT[] NOT NULL,
+    -- SHA-256 of the client secret, NULL for public clients (browser and mobile apps) that rely on PKCE alone
+    secret_hash TEXT
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    -- SHA-256 of the code, codes are single use so the row is deleted when it's exchanged
+    code_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    code_challenge TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- Access tokens handed to a client are personal access tokens tied to it, revoking the client revokes them
+ALTER TABLE personal_access_tokens ADD COLUMN IF NOT EXISTS client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE personal_access_tokens DROP COLUMN IF EXISTS client_id;
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
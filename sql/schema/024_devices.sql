@@ -0,0 +1,22 @@
+-- 024_devices.sql
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- SHA-256 of the device cookie, the same browser gets one row per user that signs in on it
+    token_hash TEXT NOT NULL,
+    name TEXT,
+    user_agent TEXT NOT NULL DEFAULT '',
+    last_ip TEXT NOT NULL DEFAULT '',
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMP,
+    -- SHA-256 of the emailed confirmation token while one is outstanding
+    confirm_token_hash TEXT UNIQUE,
+    confirm_expires_at TIMESTAMP,
+    UNIQUE (user_id, token_hash)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS devices;
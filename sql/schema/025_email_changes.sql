@@ -0,0 +1,15 @@
+-- 025_email_changes.sql
+
+-- +goose Up
+-- An email change waiting for the new address to confirm it, a newer request replaces the user's older one
+CREATE TABLE IF NOT EXISTS email_changes (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    new_email TEXT NOT NULL,
+    -- SHA-256 of the token emailed to new_email
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS email_changes;
@@ -0,0 +1,18 @@
+-- 026_handle_history.sql
+
+-- +goose Up
+-- Handles users have moved away from, so links to them still find the user. A handle can show up more than once
+-- if it's changed hands, the newest row wins.
+CREATE TABLE IF NOT EXISTS handle_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    handle TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS handle_history_handle_idx ON handle_history (tenant_id, handle, changed_at DESC);
+CREATE INDEX IF NOT EXISTS handle_history_user_idx ON handle_history (user_id, changed_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS handle_history;
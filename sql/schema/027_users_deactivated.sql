@@ -0,0 +1,15 @@
+-- 027_users_deactivated.sql
+
+-- +goose Up
+-- Set while the user has deactivated their account: their profile and chirps are hidden, and the account is
+-- deleted for good if it stays that way long enough
+ALTER TABLE users
+    ADD COLUMN deactivated_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS users_deactivated_idx ON users (deactivated_at) WHERE deactivated_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_deactivated_idx;
+
+ALTER TABLE users
+    DROP COLUMN deactivated_at;
@@ -0,0 +1,10 @@
+-- 028_users_shadow_banned.sql
+
+-- +goose Up
+-- Set when a moderator shadow-bans the user: they can keep posting and see their own chirps, nobody else does
+ALTER TABLE users
+    ADD COLUMN shadow_banned_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN shadow_banned_at;
@@ -0,0 +1,18 @@
+-- 029_audit_log.sql
+
+-- +goose Up
+-- What admins did to whom. Entries outlive the admin's account so actor_id goes NULL rather than taking them along.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    target_id UUID,
+    details JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_tenant_idx ON audit_log (tenant_id, created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,14 @@
+-- 030_users_banned.sql
+
+-- +goose Up
+-- A ban is in force from banned_at until banned_until, or for good when banned_until is NULL
+ALTER TABLE users
+    ADD COLUMN banned_at TIMESTAMP,
+    ADD COLUMN banned_until TIMESTAMP,
+    ADD COLUMN ban_reason TEXT;
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN ban_reason,
+    DROP COLUMN banned_until,
+    DROP COLUMN banned_at;
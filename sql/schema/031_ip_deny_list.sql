@@ -0,0 +1,12 @@
+-- 031_ip_deny_list.sql
+
+-- +goose Up
+-- Networks refused outright, across every tenant. Single addresses are stored as /32 or /128.
+CREATE TABLE IF NOT EXISTS ip_deny_list (
+    cidr TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    reason TEXT NOT NULL DEFAULT ''
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS ip_deny_list;
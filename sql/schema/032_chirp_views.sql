@@ -0,0 +1,13 @@
+-- 032_chirp_views.sql
+
+-- +goose Up
+-- One row per chirp per day it was seen, written in batches from the in-memory counter
+CREATE TABLE IF NOT EXISTS chirp_views (
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    views BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (chirp_id, day)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chirp_views;
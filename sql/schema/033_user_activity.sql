@@ -0,0 +1,16 @@
+-- 033_user_activity.sql
+
+-- +goose Up
+-- One row per user per day they made an authenticated request, which is all DAU/WAU/MAU need
+CREATE TABLE IF NOT EXISTS user_activity (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX IF NOT EXISTS user_activity_tenant_day_idx ON user_activity (tenant_id, day);
+
+-- +goose Down
+DROP INDEX IF EXISTS user_activity_tenant_day_idx;
+DROP TABLE IF EXISTS user_activity;
@@ -0,0 +1,13 @@
+-- 034_muted_words.sql
+
+-- +goose Up
+-- Words and phrases a user doesn't want to see, stored lowercase
+CREATE TABLE IF NOT EXISTS muted_words (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    phrase TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, phrase)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS muted_words;
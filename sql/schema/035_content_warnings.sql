@@ -0,0 +1,18 @@
+-- 035_content_warnings.sql
+
+-- +goose Up
+ALTER TABLE chirps
+    ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN content_warning TEXT;
+
+-- blur, expand or hide
+ALTER TABLE users
+    ADD COLUMN sensitive_content TEXT NOT NULL DEFAULT 'blur';
+
+-- +goose Down
+ALTER TABLE users
+    DROP COLUMN sensitive_content;
+
+ALTER TABLE chirps
+    DROP COLUMN content_warning,
+    DROP COLUMN sensitive;
@@ -0,0 +1,14 @@
+-- 036_chirp_language.sql
+
+-- +goose Up
+-- ISO 639-1 code, NULL when the author didn't give one and it couldn't be detected
+ALTER TABLE chirps
+    ADD COLUMN language TEXT;
+
+CREATE INDEX IF NOT EXISTS chirps_language_idx ON chirps (tenant_id, language, created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_language_idx;
+
+ALTER TABLE chirps
+    DROP COLUMN language;
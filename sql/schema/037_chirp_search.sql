@@ -0,0 +1,8 @@
+-- 037_chirp_search.sql
+
+-- +goose Up
+-- Full-text search over chirp bodies. The 'simple' configuration doesn't stem, chirps come in many languages.
+CREATE INDEX IF NOT EXISTS chirps_body_search_idx ON chirps USING GIN (to_tsvector('simple', body));
+
+-- +goose Down
+DROP INDEX IF EXISTS chirps_body_search_idx;
@@ -0,0 +1,10 @@
+-- 038_chirp_visibility.sql
+
+-- +goose Up
+-- public, followers (the author's followers and the author) or private (just the author)
+ALTER TABLE chirps
+    ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public';
+
+-- +goose Down
+ALTER TABLE chirps
+    DROP COLUMN visibility;
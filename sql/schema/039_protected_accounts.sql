@@ -0,0 +1,22 @@
+-- 039_protected_accounts.sql
+
+-- +goose Up
+-- Protected accounts approve their followers, and only followers see their chirps
+ALTER TABLE users
+    ADD COLUMN protected BOOLEAN NOT NULL DEFAULT false;
+
+-- Follows of protected accounts waiting on the owner
+CREATE TABLE IF NOT EXISTS follow_requests (
+    requester_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (requester_id, target_id)
+);
+
+CREATE INDEX IF NOT EXISTS follow_requests_target_idx ON follow_requests (target_id, created_at DESC, requester_id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS follow_requests;
+
+ALTER TABLE users
+    DROP COLUMN protected;
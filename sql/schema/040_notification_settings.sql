@@ -0,0 +1,13 @@
+-- 040_notification_settings.sql
+
+-- +goose Up
+-- Per-user notification preferences. Users without a row get the defaults: every kind on, no email.
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    disabled_kinds TEXT[] NOT NULL DEFAULT '{}',
+    email BOOLEAN NOT NULL DEFAULT false,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS notification_settings;
@@ -0,0 +1,13 @@
+-- 041_settings.sql
+
+-- +goose Up
+-- Operational settings changed at /admin/settings, each value is JSON. They override the env on every instance.
+CREATE TABLE IF NOT EXISTS settings (
+    key TEXT PRIMARY KEY,
+    value JSONB NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS settings;
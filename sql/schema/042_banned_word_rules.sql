@@ -0,0 +1,12 @@
+-- 042_banned_word_rules.sql
+
+-- +goose Up
+-- A pattern is a regular expression rather than a word. censor replaces what matched, reject refuses the chirp.
+ALTER TABLE banned_words
+    ADD COLUMN is_pattern BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN severity TEXT NOT NULL DEFAULT 'censor';
+
+-- +goose Down
+ALTER TABLE banned_words
+    DROP COLUMN severity,
+    DROP COLUMN is_pattern;
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// The /app frontend. Only what's under static/ is ever served, so nothing else in the repo (.env, the source)
+// can be fetched through the file server.
+//
+//go:embed static
+var staticFiles embed.FS
+
+// Served from the binary, except in dev where static/ is read from disk so edits show up without a rebuild
+func frontendFS(platform string) http.FileSystem {
+	if platform == "dev" {
+		return http.Dir("static")
+	}
+
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(sub)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+const (
+	// Longest ?range= /api/stats/chirps accepts
+	statsMaxRange = 366 * 24 * time.Hour
+	// Most buckets one response can have, an hourly series over a year would be 8,784
+	statsMaxBuckets = 1000
+)
+
+var (
+	errStatsGranularity = errors.New("granularity must be hour, day, week or month")
+	errStatsRange       = errors.New("range must look like 24h, 30d or 12w, up to a year")
+	errStatsBuckets     = errors.New("Too many buckets, use a coarser granularity or a shorter range")
+)
+
+// A date_trunc unit and how to get from one bucket to the next
+type statsGranularity struct {
+	name string
+	next func(time.Time) time.Time
+}
+
+var statsGranularities = map[string]statsGranularity{
+	"hour":  {"hour", func(t time.Time) time.Time { return t.Add(time.Hour) }},
+	"day":   {"day", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	"week":  {"week", func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }},
+	"month": {"month", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+}
+
+// Start of the bucket t falls in, the same as Postgres' date_trunc for a UTC timestamp (weeks start on Monday)
+func (g statsGranularity) truncate(t time.Time) time.Time {
+	t = t.UTC()
+	switch g.name {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Parses a ?range= like 24h, 30d or 12w
+func parseStatsRange(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, errStatsRange
+	}
+
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, errStatsRange
+	}
+
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, errStatsRange
+	}
+
+	if n > int(statsMaxRange/unit) {
+		return 0, errStatsRange
+	}
+
+	return time.Duration(n) * unit, nil
+}
+
+type statsBucketResponse struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+type chirpStatsResponse struct {
+	Granularity string                `json:"granularity"`
+	Range       string                `json:"range"`
+	Buckets     []statsBucketResponse `json:"buckets"`
+}
+
+// Chirp counts for the tenant from since up to now, one entry per bucket oldest first, including empty ones
+func (cfg *apiConfig) chirpCountSeries(r *http.Request, g statsGranularity, since time.Time) ([]statsBucketResponse, error) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	start := g.truncate(since)
+
+	rows, err := cfg.databaseQueries.GetChirpCounts(ctx, database.GetChirpCountsParams{
+		Granularity: g.name,
+		TenantID:    tenantFromContext(r.Context()),
+		Since:       start,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[time.Time]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket.UTC()] = row.Count
+	}
+
+	var buckets []statsBucketResponse
+	for t, now := start, time.Now(); !t.After(now); t = g.next(t) {
+		buckets = append(buckets, statsBucketResponse{Start: t, Count: counts[t]})
+	}
+
+	return buckets, nil
+}
+
+// GET /api/stats/chirps?granularity=day&range=30d, how many chirps were posted per hour, day, week or month over
+// the range for activity charts. The first bucket is the whole one the start of the range falls in.
+func (cfg *apiConfig) chirpStatsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	name := query.Get("granularity")
+	if name == "" {
+		name = "day"
+	}
+
+	g, ok := statsGranularities[name]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, errStatsGranularity.Error())
+		return
+	}
+
+	rangeParam := query.Get("range")
+	if rangeParam == "" {
+		rangeParam = "30d"
+	}
+
+	span, err := parseStatsRange(rangeParam)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	since := time.Now().Add(-span)
+
+	// Counting the buckets before querying keeps an hourly series over a year from ever reaching the database
+	n := 0
+	for t := g.truncate(since); !t.After(time.Now()); t = g.next(t) {
+		if n++; n > statsMaxBuckets {
+			respondWithError(w, http.StatusBadRequest, errStatsBuckets.Error())
+			return
+		}
+	}
+
+	buckets, err := cfg.chirpCountSeries(r, g, since)
+	if err != nil {
+		log.Printf("GetChirpCounts failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	respondWithJson(w, http.StatusOK, chirpStatsResponse{
+		Granularity: g.name,
+		Range:       rangeParam,
+		Buckets:     buckets,
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// Writes a listing one element at a time instead of marshalling the whole thing into a single []byte.
+// Clients get a JSON array by default, newline-delimited JSON when they ask for application/x-ndjson,
+// or an XML document with each element under a root named root when they prefer application/xml.
+type listStream struct {
+	w      io.Writer
+	format string
+	root   string
+	json   *json.Encoder
+	xml    *xml.Encoder
+	n      int
+}
+
+// Sends the headers and status, so anything that goes wrong after this can only cut the body short
+func newListStream(w http.ResponseWriter, r *http.Request, root string) (*listStream, error) {
+	format := negotiate(r, "application/json", ndjsonContentType, "application/xml", "text/xml")
+	if format == "text/xml" {
+		format = "application/xml"
+	}
+
+	contentType := format
+	if format == "application/xml" {
+		contentType += "; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusOK)
+
+	s := &listStream{w: w, format: format, root: root}
+
+	var opening string
+	switch format {
+	case "application/json":
+		s.json = json.NewEncoder(w)
+		opening = "["
+	case ndjsonContentType:
+		s.json = json.NewEncoder(w)
+	case "application/xml":
+		s.xml = xml.NewEncoder(w)
+		opening = xml.Header + "<" + root + ">"
+	}
+
+	if _, err := io.WriteString(w, opening); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *listStream) Write(v any) error {
+	if s.xml != nil {
+		// Encode flushes, so each element goes out as soon as it's written
+		return s.xml.Encode(v)
+	}
+
+	if s.format == "application/json" && s.n > 0 {
+		if _, err := io.WriteString(s.w, ","); err != nil {
+			return err
+		}
+	}
+
+	s.n++
+	// Encode ends every value with a newline, which is exactly the NDJSON framing and harmless inside an array
+	return s.json.Encode(v)
+}
+
+func (s *listStream) Close() error {
+	var closing string
+	switch s.format {
+	case "application/json":
+		closing = "]\n"
+	case "application/xml":
+		closing = "</" + s.root + ">\n"
+	default:
+		return nil
+	}
+
+	_, err := io.WriteString(s.w, closing)
+	return err
+}
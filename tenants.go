@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+)
+
+// Owns everything created before tenants existed, and everything at all when MULTI_TENANT is off.
+// It's the zero UUID so a context with no tenant on it lands here without any special casing.
+var defaultTenantID = uuid.Nil
+
+// Requests under /t/{slug}/ belong to that tenant, the prefix is stripped before routing
+const tenantPathPrefix = "/t/"
+
+// In-memory copy of the tenants table, looked up on every request so it mustn't hit the database
+type tenantRegistry struct {
+	mu     sync.RWMutex
+	byHost map[string]uuid.UUID
+	bySlug map[string]uuid.UUID
+}
+
+func newTenantRegistry() *tenantRegistry {
+	return &tenantRegistry{byHost: map[string]uuid.UUID{}, bySlug: map[string]uuid.UUID{}}
+}
+
+// Replaces the registry with whatever is in the database right now
+func (t *tenantRegistry) reload(ctx context.Context, q *database.Queries) error {
+	rows, err := q.GetTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	byHost := make(map[string]uuid.UUID, len(rows))
+	bySlug := make(map[string]uuid.UUID, len(rows))
+	for _, row := range rows {
+		bySlug[row.Slug] = row.ID
+		if row.Host.Valid {
+			byHost[strings.ToLower(row.Host.String)] = row.ID
+		}
+	}
+
+	t.mu.Lock()
+	t.byHost, t.bySlug = byHost, bySlug
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *tenantRegistry) lookupSlug(slug string) (uuid.UUID, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	id, ok := t.bySlug[slug]
+	return id, ok
+}
+
+func (t *tenantRegistry) lookupHost(host string) (uuid.UUID, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	id, ok := t.byHost[strings.ToLower(host)]
+	return id, ok
+}
+
+// The tenant middlewareTenant resolved for this request, the default tenant when there isn't one
+func tenantFromContext(ctx context.Context) uuid.UUID {
+	id, _ := ctx.Value(tenantKey).(uuid.UUID)
+	return id
+}
+
+// Works out which tenant a request is for: a /t/{slug}/ prefix first, then the Host header, then the default tenant
+// so the main domain keeps working. An unknown slug is a 404 rather than a silent fallback.
+// A nil registry means multi-tenant mode is off and every request is the default tenant's.
+func middlewareTenant(tenants *tenantRegistry, next http.Handler) http.Handler {
+	if tenants == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID := defaultTenantID
+
+		if rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix); ok {
+			slug, path, _ := strings.Cut(rest, "/")
+
+			id, found := tenants.lookupSlug(slug)
+			if !found {
+				respondWithError(w, http.StatusNotFound, "Unknown tenant")
+				return
+			}
+
+			tenantID = id
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + path
+			r.URL.RawPath = ""
+		} else if id, found := tenants.lookupHost(r.Host); found {
+			tenantID = id
+		}
+
+		ctx := context.WithValue(r.Context(), tenantKey, tenantID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Access tokens are pinned to the tenant they were issued for through the audience claim,
+// so a token from one community is useless on another even though they share a signing secret.
+func (cfg *apiConfig) tokenConfig(ctx context.Context) auth.JWTConfig {
+	if cfg.tenants == nil {
+		return cfg.jwt
+	}
+
+	c := cfg.jwt
+	c.Audience = "tenant:" + tenantFromContext(ctx).String()
+	return c
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/auth"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// How stale last_used_at has to be before a request bothers updating it, a busy script shouldn't write on every call
+const personalAccessTokenTouchInterval = time.Minute
+
+var errPersonalAccessTokenMint = errors.New("Personal access tokens can't create other tokens")
+
+// A personal access token as its owner sees it. The token itself is only ever in the create response.
+type personalAccessTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Set on tokens an OAuth client was given rather than ones the user made
+	ClientID *uuid.UUID `json:"client_id,omitempty"`
+	Token    string     `json:"token,omitempty"`
+}
+
+func newPersonalAccessTokenResponse(pat database.PersonalAccessToken) personalAccessTokenResponse {
+	resp := personalAccessTokenResponse{
+		ID:        pat.ID,
+		CreatedAt: pat.CreatedAt,
+		Name:      pat.Name,
+		Scopes:    pat.Scopes,
+	}
+
+	if pat.ExpiresAt.Valid {
+		resp.ExpiresAt = &pat.ExpiresAt.Time
+	}
+
+	if pat.LastUsedAt.Valid {
+		resp.LastUsedAt = &pat.LastUsedAt.Time
+	}
+
+	if pat.ClientID.Valid {
+		resp.ClientID = &pat.ClientID.UUID
+	}
+
+	return resp
+}
+
+// Looks a personal access token up, only ones that haven't been revoked or expired are found
+func (cfg *apiConfig) lookupPersonalAccessToken(r *http.Request, token string) (database.PersonalAccessToken, error) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	pat, err := cfg.databaseQueries.GetPersonalAccessTokenByHash(ctx, database.GetPersonalAccessTokenByHashParams{
+		TokenHash: auth.HashPersonalAccessToken(token),
+		TenantID:  tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		return database.PersonalAccessToken{}, auth.ErrInvalidToken
+	}
+
+	if err != nil {
+		log.Printf("GetPersonalAccessTokenByHash failed: %v", err)
+		return database.PersonalAccessToken{}, errors.New("couldn't check token")
+	}
+
+	if !pat.LastUsedAt.Valid || time.Since(pat.LastUsedAt.Time) > personalAccessTokenTouchInterval {
+		if err := cfg.databaseQueries.TouchPersonalAccessToken(ctx, pat.ID); err != nil {
+			log.Printf("TouchPersonalAccessToken failed: %v", err)
+		}
+	}
+
+	return pat, nil
+}
+
+// Whether the request carries a personal access token rather than a JWT
+func usingPersonalAccessToken(r *http.Request) bool {
+	token, err := auth.GetBearerToken(r.Header)
+	return err == nil && auth.IsPersonalAccessToken(token)
+}
+
+// POST /api/tokens, mints a token for scripts and bots. It's only shown in this response, the database keeps a hash.
+func (cfg *apiConfig) createPersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		Name          string   `json:"name" validate:"required,max=100"`
+		Scopes        []string `json:"scopes" validate:"required"`
+		ExpiresInDays int      `json:"expires_in_days" validate:"min=0,max=3650"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated token request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// A leaked token shouldn't be able to mint itself replacements
+	if usingPersonalAccessToken(r) {
+		respondWithError(w, http.StatusForbidden, errPersonalAccessTokenMint.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	for _, scope := range params.Scopes {
+		if !auth.ValidScope(scope) {
+			respondWithFieldErrors(w, validate.Errors{"scopes": "unknown scope: " + scope})
+			return
+		}
+	}
+
+	if slices.Contains(params.Scopes, auth.ScopeAdmin) {
+		if _, ok := cfg.requireAdmin(ctx, w, r); !ok {
+			return
+		}
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		log.Printf("MakePersonalAccessToken failed: %v", err)
+		respondWithError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if params.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, params.ExpiresInDays), Valid: true}
+	}
+
+	pat, err := cfg.databaseQueries.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      params.Name,
+		TokenHash: auth.HashPersonalAccessToken(token),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(params.Scopes))),
+		ExpiresAt: expiresAt,
+	})
+
+	if err != nil {
+		log.Printf("CreatePersonalAccessToken failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := newPersonalAccessTokenResponse(pat)
+	resp.Token = token
+
+	respondWithJson(w, http.StatusCreated, resp)
+}
+
+func (cfg *apiConfig) getPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated token request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	pats, err := cfg.databaseQueries.GetPersonalAccessTokensForUser(ctx, userID)
+	if err != nil {
+		log.Printf("GetPersonalAccessTokensForUser failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := make([]personalAccessTokenResponse, 0, len(pats))
+	for _, pat := range pats {
+		resp = append(resp, newPersonalAccessTokenResponse(pat))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) revokePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated token request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid token ID")
+		return
+	}
+
+	revoked, err := cfg.databaseQueries.RevokePersonalAccessToken(ctx, database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		log.Printf("RevokePersonalAccessToken failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Someone else's token looks the same as a missing one
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Personal access token not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
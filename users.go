@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/i18n"
+	"github.com/itsmandrew/server-go/internal/validate"
+)
+
+// Handles are stored lowercase, so lookups are case-insensitive
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// Body of the 301 for an old handle, for clients that don't follow redirects
+type handleRedirectResponse struct {
+	ID     uuid.UUID `json:"id"`
+	Handle string    `json:"handle"`
+}
+
+var errHandleChangeLimit = errors.New("Handle changed too many times, try again later")
+
+// Lowercases a handle and drops the @ clients put in front of mentions
+func normalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// Public profile plus the pinned chirp, if there is one requester can see
+func (cfg *apiConfig) newProfile(ctx context.Context, user database.GetUserProfileRow, requester uuid.UUID) (api.Profile, error) {
+	profile := api.NewProfile(user, user.ID == requester)
+
+	if !user.PinnedChirpID.Valid {
+		return profile, nil
+	}
+
+	chirp, err := cfg.databaseQueries.GetVisibleChirp(ctx, database.GetVisibleChirpParams{
+		ID:       user.PinnedChirpID.UUID,
+		TenantID: tenantFromContext(ctx),
+		ViewerID: requester,
+	})
+	err = database.Wrap(err)
+
+	// A pinned chirp only for followers doesn't show to everyone else
+	if errors.Is(err, database.ErrNotFound) {
+		return profile, nil
+	}
+
+	if err != nil {
+		return profile, err
+	}
+
+	pinned, err := cfg.chirpResponses(ctx, []database.Chirp{chirp}, requester)
+	if err != nil {
+		return profile, err
+	}
+
+	profile.PinnedChirp = &pinned[0]
+	return profile, nil
+}
+
+func (cfg *apiConfig) getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	requester, _ := cfg.optionalUser(r)
+	cfg.respondWithUserProfile(ctx, w, r, userID, requester)
+}
+
+// GET /api/me, the caller's own profile with the private fields (email, preferences) filled in
+func (cfg *apiConfig) getMeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	cfg.respondWithUserProfile(ctx, w, r, userID, userID)
+}
+
+// Resolves a handle (with or without the leading @) to the same profile GET /api/users/{userID} returns. A handle
+// the user has since changed answers 301 pointing at the current one, unless someone else has claimed it.
+func (cfg *apiConfig) getUserByHandleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	handle := normalizeHandle(r.PathValue("handle"))
+	if !handlePattern.MatchString(handle) {
+		respondWithError(w, http.StatusBadRequest, "invalid handle")
+		return
+	}
+
+	userID, err := cfg.databaseQueries.GetUserIDByHandle(ctx, database.GetUserIDByHandleParams{
+		Handle:   sql.NullString{String: handle, Valid: true},
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		cfg.redirectOldHandle(ctx, w, r, handle)
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetUserIDByHandle failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	requester, _ := cfg.optionalUser(r)
+	cfg.respondWithUserProfile(ctx, w, r, userID, requester)
+}
+
+// Sends a lookup of a handle someone moved away from on to their current one. The Location is relative so it keeps
+// any /t/{slug} prefix the request came in with.
+func (cfg *apiConfig) redirectOldHandle(ctx context.Context, w http.ResponseWriter, r *http.Request, handle string) {
+	user, err := cfg.databaseQueries.GetUserByOldHandle(ctx, database.GetUserByOldHandleParams{
+		TenantID: tenantFromContext(r.Context()),
+		Handle:   handle,
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) || (err == nil && !user.Handle.Valid) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetUserByOldHandle failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Location", url.PathEscape(user.Handle.String))
+	respondWithJson(w, http.StatusMovedPermanently, handleRedirectResponse{
+		ID:     user.ID,
+		Handle: user.Handle.String,
+	})
+}
+
+// Checks userID exists in the request's tenant, responding with 404 (or 500) itself when it doesn't.
+// Returns false if the handler should stop.
+func (cfg *apiConfig) requireUser(ctx context.Context, w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	exists, err := cfg.databaseQueries.UserExists(ctx, database.UserExistsParams{
+		ID:       userID,
+		TenantID: tenantFromContext(r.Context()),
+	})
+
+	if err != nil {
+		log.Printf("UserExists failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return false
+	}
+
+	return true
+}
+
+// GET /api/users/{userID}/followers, /following and /chirps. They're registered as one {list} pattern because
+// ServeMux treats /api/users/{userID}/followers and /api/users/by_handle/{handle} as conflicting and panics.
+func (cfg *apiConfig) userListHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.PathValue("list") {
+	case "followers":
+		cfg.getFollowersHandler(w, r)
+	case "following":
+		cfg.getFollowingHandler(w, r)
+	case "chirps":
+		cfg.getUserChirpsHandler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// requester is the zero UUID for anonymous requests, which never matches a real user
+func (cfg *apiConfig) respondWithUserProfile(ctx context.Context, w http.ResponseWriter, r *http.Request, userID, requester uuid.UUID) {
+	user, err := cfg.databaseQueries.GetUserProfile(ctx, database.GetUserProfileParams{
+		ID:       userID,
+		TenantID: tenantFromContext(ctx),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetUserProfile failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp, err := cfg.newProfile(ctx, user, requester)
+	if err != nil {
+		log.Printf("Loading pinned chirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respond(w, r, http.StatusOK, resp)
+}
+
+// Claims or changes the caller's handle. The old one is kept in handle_history so lookups of it still find the
+// user, at most HANDLE_CHANGE_LIMIT changes every HANDLE_CHANGE_WINDOW.
+func (cfg *apiConfig) setHandleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var params struct {
+		Handle string `json:"handle" validate:"required"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	handle := normalizeHandle(params.Handle)
+	if !handlePattern.MatchString(handle) {
+		respondWithFieldErrors(w, validate.Errors{"handle": "must be 3-30 letters, digits or underscores"})
+		return
+	}
+
+	err = database.WithTx(ctx, cfg.db, cfg.databaseQueries, func(qtx *database.Queries) error {
+		current, err := qtx.GetUserHandleForUpdate(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if current.Handle.String == handle {
+			return nil
+		}
+
+		// Claiming a first handle is free, only moving away from one counts
+		if current.Handle.Valid {
+			if cfg.handleChangeLimit > 0 {
+				changes, err := qtx.CountHandleChangesSince(ctx, database.CountHandleChangesSinceParams{
+					UserID:    userID,
+					ChangedAt: time.Now().Add(-cfg.handleChangeWindow),
+				})
+				if err != nil {
+					return err
+				}
+
+				if changes >= int64(cfg.handleChangeLimit) {
+					return errHandleChangeLimit
+				}
+			}
+
+			err = qtx.AddHandleHistory(ctx, database.AddHandleHistoryParams{
+				TenantID: current.TenantID,
+				Handle:   current.Handle.String,
+				UserID:   userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return qtx.SetUserHandle(ctx, database.SetUserHandleParams{
+			ID:     userID,
+			Handle: sql.NullString{String: handle, Valid: true},
+		})
+	})
+
+	if errors.Is(err, errHandleChangeLimit) {
+		respondWithError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+
+	if errors.Is(err, database.ErrDuplicate) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken")
+		return
+	}
+
+	if err != nil {
+		log.Printf("SetUserHandle failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	cfg.respondWithUserProfile(ctx, w, r, userID, userID)
+}
+
+// Sets the caller's locale (any language with an error catalog) and IANA timezone, used for anything the server
+// renders for them such as the timestamps in their exports. sensitive_content says how sensitive chirps reach them:
+// blurred behind their warning (the default), expanded, or hidden from listings. Leaving it out keeps the current one.
+func (cfg *apiConfig) setPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var params struct {
+		Locale           string `json:"locale" validate:"required"`
+		Timezone         string `json:"timezone" validate:"required"`
+		SensitiveContent string `json:"sensitive_content"`
+	}
+
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	errs := validate.Errors{}
+	if !slices.Contains(i18n.Languages(), params.Locale) {
+		errs["locale"] = "must be one of " + strings.Join(i18n.Languages(), ", ")
+	}
+
+	// "Local" would mean whatever timezone the server happens to run in
+	if _, err := time.LoadLocation(params.Timezone); err != nil || params.Timezone == "Local" {
+		errs["timezone"] = "must be an IANA timezone like Europe/Paris"
+	}
+
+	if params.SensitiveContent != "" && !slices.Contains(sensitiveContentModes, params.SensitiveContent) {
+		errs["sensitive_content"] = "must be one of " + strings.Join(sensitiveContentModes, ", ")
+	}
+
+	if len(errs) > 0 {
+		respondWithFieldErrors(w, errs)
+		return
+	}
+
+	err = cfg.databaseQueries.SetUserPreferences(ctx, database.SetUserPreferencesParams{
+		ID:               userID,
+		Locale:           params.Locale,
+		Timezone:         params.Timezone,
+		SensitiveContent: params.SensitiveContent,
+	})
+
+	if err != nil {
+		log.Printf("SetUserPreferences failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	cfg.respondWithUserProfile(ctx, w, r, userID, userID)
+}
+
+// The user's timezone preference, falling back to UTC if it can't be loaded. Only used for presentation, so a
+// failed lookup isn't worth failing the request over.
+func (cfg *apiConfig) userLocation(ctx context.Context, userID uuid.UUID) *time.Location {
+	user, err := cfg.databaseQueries.GetUserProfile(ctx, database.GetUserProfileParams{
+		ID:       userID,
+		TenantID: tenantFromContext(ctx),
+	})
+
+	if err != nil {
+		log.Printf("Loading timezone for %s failed: %v", userID, err)
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
+// Pins one of the caller's own chirps to their profile, replacing whatever was pinned before
+func (cfg *apiConfig) pinChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID")
+		return
+	}
+
+	chirp, err := cfg.databaseQueries.GetIndividualChirp(ctx, database.GetIndividualChirpParams{
+		ID:       chirpID,
+		TenantID: tenantFromContext(r.Context()),
+	})
+	err = database.Wrap(err)
+
+	if errors.Is(err, database.ErrNotFound) {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	if err != nil {
+		log.Printf("GetIndividualChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		respondWithError(w, http.StatusForbidden, "User not the author of the chirp")
+		return
+	}
+
+	err = cfg.databaseQueries.SetPinnedChirp(ctx, database.SetPinnedChirpParams{
+		ID:            userID,
+		PinnedChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+
+	if err != nil {
+		log.Printf("SetPinnedChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) unpinChirpHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid chirp ID")
+		return
+	}
+
+	cleared, err := cfg.databaseQueries.ClearPinnedChirp(ctx, database.ClearPinnedChirpParams{
+		ID:            userID,
+		PinnedChirpID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+
+	if err != nil {
+		log.Printf("ClearPinnedChirp failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if cleared == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp is not pinned")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
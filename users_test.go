@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/itsmandrew/server-go/internal/auth"
+)
+
+const (
+	testUserEmail    = "walt@example.com"
+	testUserPassword = "hunter2hunter2"
+)
+
+// An apiConfig backed by a fakeDB whose user rows carry a real hash of testUserPassword, and a mux with the
+// user routes wrapped the way main wraps them
+func newUserTestServer(t *testing.T) (*apiConfig, *fakeDB, http.Handler) {
+	t.Helper()
+
+	hash, err := auth.HashedPassword(testUserPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := newFakeDB(map[string]driver.Value{
+		"email":           testUserEmail,
+		"hashed_password": hash,
+		"pinned_chirp_id": nil,
+	})
+	cfg := newFakeConfig(t, fake)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", cfg.createUserHandler)
+	mux.HandleFunc("POST /api/login", cfg.loginUserHandler)
+	mux.Handle("PUT /api/users", cfg.requireScope(auth.ScopeUsersWrite, cfg.requireAuth(cfg.updateUserHandler)))
+	mux.Handle("PATCH /api/users", cfg.requireScope(auth.ScopeUsersWrite, cfg.requireAuth(cfg.patchUserHandler)))
+	mux.Handle("GET /api/users/{userID}", cfg.requireScope(auth.ScopeChirpsRead, cfg.getUserProfileHandler))
+
+	return cfg, fake, mux
+}
+
+// The hash is in every user row the handlers read, none of them may pass it on
+func TestUserResponsesOmitPasswordHash(t *testing.T) {
+	cfg, _, h := newUserTestServer(t)
+	token := testAccessToken(t, cfg)
+
+	tests := []struct {
+		name, method, path, body string
+		auth                     bool
+		status                   int
+	}{
+		{"create", http.MethodPost, "/api/users", `{"email":"walt@example.com","password":"hunter2hunter2"}`, false, http.StatusCreated},
+		{"login", http.MethodPost, "/api/login", `{"email":"walt@example.com","password":"hunter2hunter2"}`, false, http.StatusOK},
+		{"session login", http.MethodPost, "/api/login", `{"email":"walt@example.com","password":"hunter2hunter2","session":true}`, false, http.StatusOK},
+		{"update", http.MethodPut, "/api/users", `{"password":"hunter3hunter3"}`, true, http.StatusOK},
+		{"patch", http.MethodPatch, "/api/users", `{"password":"hunter3hunter3"}`, true, http.StatusOK},
+		{"profile", http.MethodGet, "/api/users/" + testUserID.String(), "", false, http.StatusOK},
+		{"own profile", http.MethodGet, "/api/users/" + testUserID.String(), "", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			body := rec.Body.String()
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, rec.Code, body)
+			}
+
+			for _, leak := range []string{"hashed_password", "$2a$"} {
+				if strings.Contains(body, leak) {
+					t.Errorf("response contains %q: %s", leak, body)
+				}
+			}
+		})
+	}
+}
+
+func TestPatchUserRequiresAuth(t *testing.T) {
+	_, _, h := newUserTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/users", strings.NewReader(`{"password":"hunter3hunter3"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+// The fake has a user for every email, so the new one is always taken and the password change has to go with it
+func TestUpdateUserTakenEmailKeepsPassword(t *testing.T) {
+	for _, method := range []string{http.MethodPut, http.MethodPatch} {
+		t.Run(method, func(t *testing.T) {
+			cfg, fake, h := newUserTestServer(t)
+
+			req := httptest.NewRequest(method, "/api/users",
+				strings.NewReader(`{"email":"jesse@example.com","password":"hunter3hunter3"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body)
+			}
+
+			if fake.wasSaved("UpdateUserPassword") {
+				t.Error("expected the password change to be rolled back")
+			}
+		})
+	}
+}
+
+// bcrypt won't hash more than 72 bytes, so longer passwords are a field error rather than a 500
+func TestLongPasswordRejected(t *testing.T) {
+	cfg, fake, h := newUserTestServer(t)
+	long := strings.Repeat("a", 73)
+
+	for _, tt := range []struct{ method, query string }{
+		{http.MethodPost, "CreateUser"},
+		{http.MethodPut, "UpdateUserPassword"},
+	} {
+		req := httptest.NewRequest(tt.method, "/api/users",
+			strings.NewReader(`{"email":"walt@example.com","password":"`+long+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", tt.method, rec.Code, rec.Body)
+		}
+		if fake.wasSaved(tt.query) {
+			t.Errorf("%s: expected %s not to run", tt.method, tt.query)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Wrote the chirps the fake returns, testUserID asks for them
+var testAuthorID = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+
+// How many chirps each read endpoint hands testUserID, one chirp by testAuthorID being all there is
+var chirpReaders = map[string]func(t *testing.T, cfg *apiConfig) int{
+	"list": func(t *testing.T, cfg *apiConfig) int {
+		rec := visibilityRequest(t, cfg, "/api/chirps", cfg.getChirpsHandler)
+		var chirps []json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &chirps); err != nil {
+			t.Fatalf("list: %v: %s", err, rec.Body)
+		}
+		return len(chirps)
+	},
+	"get": func(t *testing.T, cfg *apiConfig) int {
+		rec := visibilityRequest(t, cfg, "/api/chirps/"+testUserID.String(), cfg.getIndividualChirpHandler)
+		switch rec.Code {
+		case http.StatusOK:
+			return 1
+		case http.StatusNotFound:
+			return 0
+		}
+		t.Fatalf("get: unexpected %d: %s", rec.Code, rec.Body)
+		return 0
+	},
+	"search": func(t *testing.T, cfg *apiConfig) int {
+		rec := visibilityRequest(t, cfg, "/api/search?q=chirp", cfg.searchHandler)
+		var list chirpListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("search: %v: %s", err, rec.Body)
+		}
+		return len(list.Chirps)
+	},
+}
+
+func visibilityRequest(t *testing.T, cfg *apiConfig, target string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.SetPathValue("chirpID", testUserID.String())
+	req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNotFound {
+		t.Fatalf("%s: unexpected %d: %s", target, rec.Code, rec.Body)
+	}
+	return rec
+}
+
+func newVisibilityTestConfig(t *testing.T, visibility string, follows bool) *apiConfig {
+	t.Helper()
+
+	fake := newFakeDB(map[string]driver.Value{
+		"user_id":    testAuthorID.String(),
+		"visibility": visibility,
+		"body":       "a chirp",
+		// The viewer's muted phrase, which mustn't be what hides the chirp
+		"phrase": "unrelated",
+	})
+	fake.follows = follows
+
+	cfg := newFakeConfig(t, fake)
+	cfg.search = postgresSearch{q: cfg.databaseQueries}
+	return cfg
+}
+
+func TestChirpVisibilityOnReads(t *testing.T) {
+	tests := []struct {
+		name       string
+		visibility string
+		follows    bool
+		visible    bool
+	}{
+		{"public", visibilityPublic, false, true},
+		{"followers, following", visibilityFollowers, true, true},
+		{"followers, not following", visibilityFollowers, false, false},
+		{"private, following", visibilityPrivate, true, false},
+	}
+
+	for _, tt := range tests {
+		for reader, read := range chirpReaders {
+			t.Run(tt.name+"/"+reader, func(t *testing.T) {
+				want := 0
+				if tt.visible {
+					want = 1
+				}
+
+				if got := read(t, newVisibilityTestConfig(t, tt.visibility, tt.follows)); got != want {
+					t.Errorf("expected %d chirps, got %d", want, got)
+				}
+			})
+		}
+	}
+}
+
+// An export is only ever the caller's own chirps, someone else's hidden ones mustn't come along
+func TestExportSkipsOthersHiddenChirps(t *testing.T) {
+	for _, tt := range []struct {
+		visibility string
+		follows    bool
+	}{
+		{visibilityFollowers, false},
+		{visibilityPrivate, true},
+	} {
+		cfg := newVisibilityTestConfig(t, tt.visibility, tt.follows)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/chirps/export", nil)
+		req.Header.Set("Authorization", "Bearer "+testAccessToken(t, cfg))
+		rec := httptest.NewRecorder()
+		cfg.exportChirpsHandler(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tt.visibility, rec.Code, rec.Body)
+		}
+		if body := bytes.TrimSpace(rec.Body.Bytes()); len(body) != 0 {
+			t.Errorf("%s: expected an empty export, got %s", tt.visibility, body)
+		}
+	}
+}
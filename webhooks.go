@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itsmandrew/server-go/internal/api"
+	"github.com/itsmandrew/server-go/internal/database"
+	"github.com/itsmandrew/server-go/internal/events"
+	"github.com/itsmandrew/server-go/internal/validate"
+	"github.com/itsmandrew/server-go/internal/webhooks"
+)
+
+// Webhook as returned by the API, the secret is write-only
+type webhookResponse struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+}
+
+func newWebhookResponse(hook database.Webhook) webhookResponse {
+	return webhookResponse{
+		ID:        hook.ID,
+		CreatedAt: hook.CreatedAt,
+		UpdatedAt: hook.UpdatedAt,
+		URL:       hook.Url,
+		Events:    hook.Events,
+	}
+}
+
+func (cfg *apiConfig) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	type parameters struct {
+		URL    string   `json:"url" validate:"required,url"`
+		Events []string `json:"events" validate:"required"`
+		Secret string   `json:"secret" validate:"required"`
+	}
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated webhook request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	params := parameters{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	for _, event := range params.Events {
+		if !webhooks.ValidEvent(event) {
+			respondWithFieldErrors(w, validate.Errors{"events": "unknown event type: " + event})
+			return
+		}
+	}
+
+	if err := webhooks.CheckTarget(ctx, params.URL); err != nil {
+		log.Printf("Refusing webhook URL: %v", err)
+		respondWithFieldErrors(w, validate.Errors{"url": "must be a public http or https URL"})
+		return
+	}
+
+	hook, err := cfg.databaseQueries.CreateWebhook(ctx, database.CreateWebhookParams{
+		UserID: userID,
+		Url:    params.URL,
+		Events: params.Events,
+		Secret: params.Secret,
+	})
+
+	if err != nil {
+		log.Printf("CreateWebhook failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithJson(w, http.StatusCreated, newWebhookResponse(hook))
+}
+
+func (cfg *apiConfig) getWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated webhook request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	hooks, err := cfg.databaseQueries.GetWebhooksByUser(ctx, userID)
+	if err != nil {
+		log.Printf("GetWebhooksByUser failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := make([]webhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		resp = append(resp, newWebhookResponse(hook))
+	}
+
+	respondWithJson(w, http.StatusOK, resp)
+}
+
+func (cfg *apiConfig) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := cfg.dbContext(r.Context())
+	defer cancel()
+
+	userID, err := cfg.authenticateRequest(r)
+	if err != nil {
+		log.Println("Unauthenticated webhook request")
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	webhookID, err := uuid.Parse(r.PathValue("webhookID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid webhook ID")
+		return
+	}
+
+	deleted, err := cfg.databaseQueries.DeleteWebhook(ctx, database.DeleteWebhookParams{
+		ID:     webhookID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		log.Printf("DeleteWebhook failed: %v", err)
+		respondWithDBError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Someone else's webhook looks the same as a missing one
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Turns bus events into webhook deliveries
+func (cfg *apiConfig) subscribeWebhooks(bus events.Bus) {
+	bus.Subscribe(events.TypeChirpCreated, func(ctx context.Context, event events.Event) {
+		// Webhooks go to anyone in the tenant who asks, so only chirps everyone can see
+		chirp := event.(events.ChirpCreated).Chirp
+		if chirp.Visibility != visibilityPublic || !cfg.publiclyVisible(ctx, chirp) {
+			return
+		}
+
+		cfg.deliverWebhookEvent(ctx, webhooks.EventChirpCreated, api.NewChirp(chirp))
+	})
+
+	bus.Subscribe(events.TypeChirpDeleted, func(ctx context.Context, event events.Event) {
+		e := event.(events.ChirpDeleted)
+		cfg.deliverWebhookEvent(ctx, webhooks.EventChirpDeleted, map[string]any{
+			"id":      e.ChirpID,
+			"user_id": e.UserID,
+		})
+	})
+
+	bus.Subscribe(events.TypeUserCreated, func(ctx context.Context, event events.Event) {
+		e := event.(events.UserCreated)
+		cfg.deliverWebhookEvent(ctx, webhooks.EventUserCreated, map[string]any{
+			"id":         e.User.ID,
+			"created_at": e.User.CreatedAt,
+		})
+	})
+}
+
+// Job payload for a single delivery, the secret is looked up at send time so it never sits in the jobs table
+type webhookJob struct {
+	WebhookID uuid.UUID        `json:"webhook_id"`
+	Payload   webhooks.Payload `json:"payload"`
+}
+
+// Looks up everyone subscribed to eventType and queues a delivery job for each
+func (cfg *apiConfig) deliverWebhookEvent(ctx context.Context, eventType string, data any) {
+	ctx, cancel := cfg.dbContext(ctx)
+	defer cancel()
+
+	hooks, err := cfg.databaseQueries.GetWebhooksForEvent(ctx, database.GetWebhooksForEventParams{
+		Event:    eventType,
+		TenantID: tenantFromContext(ctx),
+	})
+	if err != nil {
+		log.Printf("GetWebhooksForEvent failed: %v", err)
+		return
+	}
+
+	payload := webhooks.Payload{
+		ID:        uuid.New(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+
+	for _, hook := range hooks {
+		err := cfg.jobs.Enqueue(ctx, jobWebhookDelivery, webhookJob{WebhookID: hook.ID, Payload: payload})
+		if err != nil {
+			log.Printf("Queueing %s webhook for %s failed: %v", eventType, hook.Url, err)
+		}
+	}
+}
+
+// Job handler that sends one webhook, a returned error makes the queue retry it with backoff
+func (cfg *apiConfig) runWebhookJob(ctx context.Context, raw json.RawMessage) error {
+	var job webhookJob
+	if err := json.Unmarshal(raw, &job); err != nil {
+		return err
+	}
+
+	hook, err := cfg.databaseQueries.GetWebhook(ctx, job.WebhookID)
+	err = database.Wrap(err)
+
+	// Unsubscribed since the event fired, nothing left to do
+	if errors.Is(err, database.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return cfg.webhookDispatcher.Deliver(ctx, webhooks.Target{URL: hook.Url, Secret: hook.Secret}, job.Payload)
+}